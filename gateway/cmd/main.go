@@ -1,25 +1,40 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/gorilla/websocket"
+	"github.com/vektah/gqlparser/v2/ast"
 
 	"github.com/microservices-go/gateway/graph"
 	"github.com/microservices-go/gateway/graph/generated"
+	"github.com/microservices-go/gateway/internal/client"
+	"github.com/microservices-go/gateway/internal/subscription"
 	"github.com/microservices-go/gateway/middleware"
+	"github.com/microservices-go/shared/apikey"
 	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/health"
 	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/metrics"
 	sharedMiddleware "github.com/microservices-go/shared/middleware"
+	"github.com/microservices-go/shared/rabbitmq"
 	"github.com/microservices-go/shared/redis"
+	"github.com/microservices-go/shared/session"
+	"github.com/microservices-go/shared/tracing"
 )
 
 const defaultPort = "4000"
@@ -30,8 +45,17 @@ func main() {
 		port = defaultPort
 	}
 
-	// Get service URLs
-	userServiceURL, orderServiceURL, paymentServiceURL := graph.GetServiceURLs()
+	// Initialize tracing
+	tracingConfig := config.LoadTracingConfig()
+	shutdownTracer, err := tracing.InitTracer("gateway", tracingConfig)
+	if err != nil {
+		log.Printf("Warning: Failed to initialize tracing: %v", err)
+	} else {
+		defer shutdownTracer(context.Background())
+	}
+
+	// Get service gRPC addresses
+	userServiceAddr, orderServiceAddr, paymentServiceAddr := graph.GetServiceAddrs()
 
 	// Load JWT config
 	jwtConfig := config.LoadJWTConfig()
@@ -59,21 +83,119 @@ func main() {
 		logger.New("gateway").Info("Rate limiting enabled")
 	}
 
+	// Shared gRPC upstreams (connection, retries, circuit breaker per service)
+	serviceAuthConfig := config.LoadServiceAuthConfig("gateway")
+	upstreams, err := graph.NewUpstreams(userServiceAddr, orderServiceAddr, paymentServiceAddr, config.LoadUpstreamConfig(), serviceAuthConfig)
+	if err != nil {
+		log.Fatalf("Failed to create upstreams: %v", err)
+	}
+	defer upstreams.Close()
+
+	// GraphQL requests are bounded by the write timeout; downstream calls inherit the deadline
+	serverConfig := config.LoadServerConfig("gateway")
+
+	// Subscription events from RabbitMQ
+	events := subscription.NewBroker()
+	rabbitClient, err := rabbitmq.NewClient(config.LoadRabbitMQConfig().URL())
+	if err != nil {
+		log.Printf("Warning: Failed to connect to RabbitMQ: %v", err)
+		log.Println("Subscriptions will not receive events")
+	} else {
+		defer rabbitClient.Close()
+		if err := events.Start(rabbitClient); err != nil {
+			log.Printf("Warning: Failed to start subscription consumer: %v", err)
+		}
+	}
+
+	// Auth middleware; API keys and sessions are checked by the user service
+	apiKeys := apikey.NewRemote(upstreams.User.Conn, serviceAuthConfig, config.LoadAPIKeyConfig())
+	sessionConfig := config.LoadSessionConfig()
+	sessions := session.NewRemote(upstreams.User.Conn, serviceAuthConfig, sessionConfig)
+	authMiddleware := middleware.NewAuthMiddleware(jwtConfig).
+		WithAPIKeys(apiKeys, rateLimiter).
+		WithSessions(sessions)
+
 	// Create resolver
-	resolver := graph.NewResolver(userServiceURL, orderServiceURL, paymentServiceURL)
+	resolver := graph.NewResolver(upstreams, events)
 
 	// Create GraphQL server
-	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: resolver}))
+	srv := handler.New(generated.NewExecutableSchema(generated.Config{
+		Resolvers:  resolver,
+		Complexity: graph.Complexity(),
+	}))
 
 	// Add transports
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		Upgrader: websocket.Upgrader{
+			// CORS allows any origin for HTTP; apply the same policy to websockets
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		// Browsers can't set headers on websockets, so the token comes in connection_init
+		// and the connection closes once the token expires or its session is revoked
+		InitFunc: func(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+			if auth := payload.Authorization(); auth != "" {
+				ctx, err := authMiddleware.Authenticate(ctx, auth)
+				if err != nil {
+					return ctx, nil, err
+				}
+				return authMiddleware.Watch(ctx, time.Duration(sessionConfig.CacheTTL)*time.Second), &payload, nil
+			}
+			return ctx, &payload, nil
+		},
+	})
 	srv.AddTransport(transport.Options{})
 	srv.AddTransport(transport.GET{})
 	srv.AddTransport(transport.POST{})
 	srv.AddTransport(transport.MultipartForm{})
 
+	// Map downstream AppErrors into GraphQL error extensions
+	srv.SetErrorPresenter(graph.ErrorPresenter(config.IsProduction()))
+
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+
 	// Add extensions
-	srv.Use(extension.Introspection{})
-	srv.Use(extension.FixedComplexityLimit(100))
+	graphqlConfig := config.LoadGraphQLConfig()
+	if graphqlConfig.Introspection {
+		srv.Use(extension.Introspection{})
+	}
+
+	// The allowlist must run before APQ so hash-only requests resolve from the manifest
+	var allowlist *graph.Allowlist
+	if graphqlConfig.AllowlistEnabled {
+		allowlist, err = graph.LoadAllowlist(graphqlConfig.AllowlistPath)
+		if err != nil {
+			log.Fatalf("Failed to load operation allowlist: %v", err)
+		}
+		srv.Use(allowlist)
+		log.Printf("Operation allowlist enabled with %d operations", allowlist.Len())
+	}
+
+	// Automatic persisted queries, shared across replicas through Redis
+	var apqCache graphql.Cache[string] = lru.New[string](100)
+	if redisClient != nil {
+		apqCache = graph.NewAPQCache(redisClient.GetClient(), time.Duration(graphqlConfig.APQCacheTTL)*time.Second)
+	}
+	srv.Use(extension.AutomaticPersistedQuery{Cache: apqCache})
+	srv.Use(graph.NewQueryCost(graphqlConfig))
+	srv.Use(graph.NewConstraintValidator())
+
+	// Full-response cache driven by @cacheControl hints, invalidated by domain events
+	var responseCache *graph.ResponseCache
+	if redisClient != nil && graphqlConfig.ResponseCache {
+		responseCache = graph.NewResponseCache(redisClient.GetClient(), time.Duration(graphqlConfig.ResponseCacheTTL)*time.Second)
+		if rabbitClient == nil {
+			log.Println("Warning: Response cache entries will only expire by maxAge")
+		} else if err := responseCache.StartInvalidation(rabbitClient); err != nil {
+			log.Printf("Warning: Failed to start response cache invalidation: %v", err)
+		}
+	}
+	srv.Use(graph.NewCacheControl(responseCache))
+	if graphqlConfig.LoaderStats {
+		srv.Use(graph.LoaderStatsExtension{})
+	}
+	srv.Use(graph.MetricsExtension{Allowlist: allowlist})
+	srv.Use(graph.TracingExtension{})
 
 	// Setup router
 	r := chi.NewRouter()
@@ -81,10 +203,13 @@ func main() {
 	// Middleware
 	r.Use(chiMiddleware.RequestID)
 	r.Use(chiMiddleware.RealIP)
+	r.Use(sharedMiddleware.ClientInfoMiddleware)
+	r.Use(sharedMiddleware.TracingMiddleware)
 	r.Use(sharedMiddleware.LoggingMiddleware)
 	r.Use(sharedMiddleware.RecoveryMiddleware)
 	r.Use(sharedMiddleware.SecurityHeadersMiddleware)
 	r.Use(sharedMiddleware.CORSMiddleware([]string{"*"}))
+	r.Use(sharedMiddleware.MetricsMiddleware("gateway"))
 
 	// Rate limiting middleware
 	if rateLimiter != nil {
@@ -92,23 +217,48 @@ func main() {
 	}
 
 	// DataLoader middleware
-	r.Use(graph.DataLoaderMiddleware(userServiceURL, orderServiceURL, paymentServiceURL))
+	r.Use(graph.DataLoaderMiddleware(upstreams))
 
 	// Auth middleware
-	authMiddleware := middleware.NewAuthMiddleware(jwtConfig)
 	r.Use(authMiddleware.Middleware)
 
 	// Routes
-	r.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	r.Handle("/query", srv)
+	if graphqlConfig.Playground {
+		r.Handle("/", playground.Handler("GraphQL playground", "/query"))
+	}
+	r.With(middleware.Deadline(time.Duration(serverConfig.WriteTimeout)*time.Second), graph.CacheHeaders).Handle("/query", srv)
 
 	// Health check endpoint
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		breakers := upstreams.BreakerStates()
+		status := "healthy"
+		for _, state := range breakers {
+			if state != client.BreakerClosed {
+				status = "degraded"
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"healthy"}`))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":   status,
+			"breakers": breakers,
+		})
 	})
 
-	log.Printf("Connect to http://localhost:%s/ for GraphQL playground", port)
+	// Liveness and readiness probes
+	healthRegistry := health.NewRegistry("gateway")
+	healthRegistry.RegisterOptional("redis", health.RedisCheck(redisClient.GetClient()))
+	upstreams.RegisterHealthChecks(healthRegistry)
+	r.Get("/livez", healthRegistry.LivenessHandler)
+	r.Get("/readyz", healthRegistry.ReadinessHandler)
+
+	// Prometheus metrics
+	r.Handle("/metrics", metrics.Handler())
+
+	if graphqlConfig.Playground {
+		log.Printf("Connect to http://localhost:%s/ for GraphQL playground", port)
+	}
 
 	fmt.Print(`
 
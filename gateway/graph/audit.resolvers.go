@@ -0,0 +1,23 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.86
+
+import (
+	"context"
+
+	"github.com/microservices-go/gateway/graph/model"
+	"github.com/microservices-go/gateway/internal/audit"
+)
+
+// AuditLog is the resolver for the auditLog field.
+func (r *queryResolver) AuditLog(ctx context.Context, service model.AuditService, filter *audit.Filter, limit *int, offset *int) ([]*audit.Entry, error) {
+	return r.AuditClient.ListEntries(ctx, string(service), filter, limit, offset)
+}
+
+// VerifyAuditLog is the resolver for the verifyAuditLog field.
+func (r *queryResolver) VerifyAuditLog(ctx context.Context, service model.AuditService) (*audit.Verification, error) {
+	return r.AuditClient.VerifyChain(ctx, string(service))
+}
@@ -20,3 +20,93 @@ func (r *mutationResolver) Register(ctx context.Context, input user.RegisterInpu
 func (r *mutationResolver) Login(ctx context.Context, input user.LoginInput) (*user.AuthResponse, error) {
 	return r.UserClient.Login(ctx, input)
 }
+
+// VerifyMfa is the resolver for the verifyMfa field.
+func (r *mutationResolver) VerifyMfa(ctx context.Context, input user.VerifyMFAInput) (*user.AuthResponse, error) {
+	return r.UserClient.VerifyMFA(ctx, input)
+}
+
+// StartOidcLogin is the resolver for the startOidcLogin field.
+func (r *mutationResolver) StartOidcLogin(ctx context.Context, provider string) (*user.OIDCAuthorization, error) {
+	return r.UserClient.StartOIDCLogin(ctx, provider)
+}
+
+// CompleteOidcLogin is the resolver for the completeOidcLogin field.
+func (r *mutationResolver) CompleteOidcLogin(ctx context.Context, input user.OIDCCallbackInput) (*user.AuthResponse, error) {
+	return r.UserClient.CompleteOIDCLogin(ctx, input)
+}
+
+// EnrollMfa is the resolver for the enrollMfa field.
+func (r *mutationResolver) EnrollMfa(ctx context.Context) (*user.MFAEnrollment, error) {
+	return r.UserClient.EnrollMFA(ctx)
+}
+
+// ConfirmMfa is the resolver for the confirmMfa field.
+func (r *mutationResolver) ConfirmMfa(ctx context.Context, code string) (*user.MFABackupCodes, error) {
+	return r.UserClient.ConfirmMFA(ctx, code)
+}
+
+// DisableMfa is the resolver for the disableMfa field.
+func (r *mutationResolver) DisableMfa(ctx context.Context, code string) (bool, error) {
+	return r.UserClient.DisableMFA(ctx, code)
+}
+
+// RegenerateMfaBackupCodes is the resolver for the regenerateMfaBackupCodes field.
+func (r *mutationResolver) RegenerateMfaBackupCodes(ctx context.Context, code string) (*user.MFABackupCodes, error) {
+	return r.UserClient.RegenerateBackupCodes(ctx, code)
+}
+
+// StartIdentityLink is the resolver for the startIdentityLink field.
+func (r *mutationResolver) StartIdentityLink(ctx context.Context, provider string) (*user.OIDCAuthorization, error) {
+	return r.UserClient.StartIdentityLink(ctx, provider)
+}
+
+// LinkIdentity is the resolver for the linkIdentity field.
+func (r *mutationResolver) LinkIdentity(ctx context.Context, input user.OIDCCallbackInput) (*user.Identity, error) {
+	return r.UserClient.LinkIdentity(ctx, input)
+}
+
+// UnlinkIdentity is the resolver for the unlinkIdentity field.
+func (r *mutationResolver) UnlinkIdentity(ctx context.Context, provider string) (bool, error) {
+	return r.UserClient.UnlinkIdentity(ctx, provider)
+}
+
+// CreateAPIKey is the resolver for the createApiKey field.
+func (r *mutationResolver) CreateAPIKey(ctx context.Context, input user.CreateAPIKeyInput) (*user.CreatedAPIKey, error) {
+	return r.UserClient.CreateAPIKey(ctx, input)
+}
+
+// RevokeAPIKey is the resolver for the revokeApiKey field.
+func (r *mutationResolver) RevokeAPIKey(ctx context.Context, id string) (*user.APIKey, error) {
+	return r.UserClient.RevokeAPIKey(ctx, id)
+}
+
+// RevokeSession is the resolver for the revokeSession field.
+func (r *mutationResolver) RevokeSession(ctx context.Context, id string) (bool, error) {
+	return r.UserClient.RevokeSession(ctx, id)
+}
+
+// RevokeAllSessions is the resolver for the revokeAllSessions field.
+func (r *mutationResolver) RevokeAllSessions(ctx context.Context) (int, error) {
+	return r.UserClient.RevokeAllSessions(ctx)
+}
+
+// OidcProviders is the resolver for the oidcProviders field.
+func (r *queryResolver) OidcProviders(ctx context.Context) ([]string, error) {
+	return r.UserClient.OIDCProviders(ctx)
+}
+
+// MyIdentities is the resolver for the myIdentities field.
+func (r *queryResolver) MyIdentities(ctx context.Context) ([]*user.Identity, error) {
+	return r.UserClient.ListIdentities(ctx)
+}
+
+// MyAPIKeys is the resolver for the myApiKeys field.
+func (r *queryResolver) MyAPIKeys(ctx context.Context) ([]*user.APIKey, error) {
+	return r.UserClient.ListAPIKeys(ctx)
+}
+
+// MySessions is the resolver for the mySessions field.
+func (r *queryResolver) MySessions(ctx context.Context) ([]*user.Session, error) {
+	return r.UserClient.ListSessions(ctx)
+}
@@ -0,0 +1,40 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/microservices-go/gateway/middleware"
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/policy"
+)
+
+// currentUser returns the authenticated caller or an UNAUTHORIZED error
+func currentUser(ctx context.Context) (*middleware.UserClaims, error) {
+	claims, ok := middleware.GetUserClaims(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "Authentication required")
+	}
+	return claims, nil
+}
+
+// readPermissions let their holders read other users' resources
+var readPermissions = []string{policy.PermUsersRead, policy.PermOrdersRead, policy.PermPaymentsRead}
+
+// seesAll reports whether the caller may read some other users' resources
+func seesAll(claims *middleware.UserClaims) bool {
+	for _, permission := range readPermissions {
+		if claims.HasPermission(permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// canAccess reports whether the caller may see a resource owned by ownerID,
+// mirroring the services' owner-or-permission read rules and API key scopes
+func canAccess(claims *middleware.UserClaims, ownerID, permission string) bool {
+	if claims.FromAPIKey() && !claims.HasScope(permission) {
+		return false
+	}
+	return claims.UserID == ownerID || claims.HasPermission(permission)
+}
@@ -0,0 +1,362 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/microservices-go/gateway/graph/model"
+	"github.com/microservices-go/gateway/middleware"
+)
+
+const cacheControlExtension = "CacheControl"
+
+// CachePolicy is the combined @cacheControl hint of an operation: the lowest
+// maxAge of any field, private if any field is private
+type CachePolicy struct {
+	MaxAge int                     `json:"maxAge"`
+	Scope  model.CacheControlScope `json:"scope"`
+}
+
+// Private reports whether the response may only be cached per user
+func (p CachePolicy) Private() bool {
+	return p.Scope == model.CacheControlScopePrivate
+}
+
+// CacheStats is the cache policy of an operation, returned in response extensions
+type CacheStats struct {
+	MaxAge int                     `json:"maxAge"`
+	Scope  model.CacheControlScope `json:"scope"`
+	Hit    bool                    `json:"hit"`
+}
+
+// cacheState is what CacheControl keeps per operation between validation and execution
+type cacheState struct {
+	policy CachePolicy
+	types  []string
+}
+
+// CacheControl computes the cache policy of queries from @cacheControl hints,
+// serves and stores full responses in the response cache, and hands the
+// policy to CacheHeaders for GET requests
+type CacheControl struct {
+	// Cache is the shared response cache; nil only sets HTTP headers
+	Cache *ResponseCache
+
+	schema *ast.Schema
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+	graphql.ResponseInterceptor
+} = &CacheControl{}
+
+// NewCacheControl creates the cache control extension
+func NewCacheControl(cache *ResponseCache) *CacheControl {
+	return &CacheControl{Cache: cache}
+}
+
+// ExtensionName returns the extension name
+func (c *CacheControl) ExtensionName() string {
+	return cacheControlExtension
+}
+
+// Validate stores the schema the hints are read from
+func (c *CacheControl) Validate(schema graphql.ExecutableSchema) error {
+	c.schema = schema.Schema()
+	return nil
+}
+
+// MutateOperationContext computes the cache policy of queries
+func (c *CacheControl) MutateOperationContext(ctx context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	op := opCtx.Doc.Operations.ForName(opCtx.OperationName)
+	if op == nil || op.Operation != ast.Query {
+		return nil
+	}
+
+	b := &policyBuilder{schema: c.schema, types: make(map[string]bool)}
+	b.walk(op.SelectionSet, nil)
+	opCtx.Stats.SetExtension(cacheControlExtension, b.state())
+	return nil
+}
+
+// InterceptResponse serves cacheable queries from the response cache and
+// stores successful responses
+func (c *CacheControl) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if !graphql.HasOperationContext(ctx) {
+		return next(ctx)
+	}
+	opCtx := graphql.GetOperationContext(ctx)
+	state, ok := opCtx.Stats.GetExtension(cacheControlExtension).(*cacheState)
+	if !ok || state.policy.MaxAge == 0 {
+		return next(ctx)
+	}
+
+	// Private responses are only cached for an authenticated caller
+	claims, authenticated := middleware.GetUserClaims(ctx)
+	if state.policy.Private() && !authenticated {
+		return next(ctx)
+	}
+
+	key := responseCacheKey(opCtx, state.policy, claims)
+	if c.Cache != nil {
+		if data, ok := c.Cache.Get(ctx, key); ok {
+			setHTTPPolicy(ctx, state.policy)
+			graphql.RegisterExtension(ctx, "cacheControl", state.stats(true))
+			return &graphql.Response{Data: data, Extensions: graphql.GetExtensions(ctx)}
+		}
+	}
+
+	graphql.RegisterExtension(ctx, "cacheControl", state.stats(false))
+	resp := next(ctx)
+	if resp == nil || len(resp.Errors) > 0 {
+		return resp
+	}
+
+	setHTTPPolicy(ctx, state.policy)
+	if c.Cache != nil {
+		ttl := time.Duration(state.policy.MaxAge) * time.Second
+		c.Cache.Set(ctx, key, resp.Data, ttl, responseTags(state, claims))
+	}
+	return resp
+}
+
+func (s *cacheState) stats(hit bool) *CacheStats {
+	return &CacheStats{MaxAge: s.policy.MaxAge, Scope: s.policy.Scope, Hit: hit}
+}
+
+// responseCacheKey hashes the operation, its variables and, for private
+// policies, the caller and the API key, whose scopes may narrow what it sees
+func responseCacheKey(opCtx *graphql.OperationContext, policy CachePolicy, claims *middleware.UserClaims) string {
+	h := sha256.New()
+	h.Write([]byte(opCtx.RawQuery))
+	h.Write([]byte{0})
+	h.Write([]byte(opCtx.OperationName))
+	h.Write([]byte{0})
+	variables, _ := json.Marshal(opCtx.Variables)
+	h.Write(variables)
+	if policy.Private() {
+		h.Write([]byte{0})
+		h.Write([]byte(claims.UserID + ":" + claims.Role + ":" + claims.APIKeyID))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseTags returns the invalidation tags of a response. Users only see
+// their own resources, so their entries are tagged per user; staff and
+// public entries are tagged per type and dropped on any change to that type.
+func responseTags(state *cacheState, claims *middleware.UserClaims) []string {
+	tags := make([]string, len(state.types))
+	for i, typ := range state.types {
+		if state.policy.Private() && !seesAll(claims) {
+			tags[i] = typ + ":" + claims.UserID
+		} else {
+			tags[i] = typ
+		}
+	}
+	return tags
+}
+
+// cacheHint is a parsed @cacheControl directive
+type cacheHint struct {
+	maxAge  *int
+	inherit bool
+	private bool
+}
+
+func readCacheHint(directives ast.DirectiveList) (cacheHint, bool) {
+	var hint cacheHint
+	d := directives.ForName("cacheControl")
+	if d == nil {
+		return hint, false
+	}
+
+	if arg := d.Arguments.ForName("maxAge"); arg != nil {
+		if maxAge, err := strconv.Atoi(arg.Value.Raw); err == nil {
+			hint.maxAge = &maxAge
+		}
+	}
+	if arg := d.Arguments.ForName("scope"); arg != nil {
+		hint.private = arg.Value.Raw == string(model.CacheControlScopePrivate)
+	}
+	if arg := d.Arguments.ForName("inheritMaxAge"); arg != nil {
+		hint.inherit = arg.Value.Raw == "true"
+	}
+	return hint, true
+}
+
+// policyBuilder walks an operation and folds the hints of every selected field
+type policyBuilder struct {
+	schema  *ast.Schema
+	maxAge  *int
+	private bool
+	types   map[string]bool
+}
+
+func (b *policyBuilder) walk(set ast.SelectionSet, parentMaxAge *int) {
+	for _, sel := range set {
+		switch s := sel.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") || s.Definition == nil {
+				continue
+			}
+			b.walk(s.SelectionSet, b.field(s.Definition, parentMaxAge))
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				b.walk(s.Definition.SelectionSet, parentMaxAge)
+			}
+		case *ast.InlineFragment:
+			b.walk(s.SelectionSet, parentMaxAge)
+		}
+	}
+}
+
+// field applies the hint of one field and returns the maxAge its children inherit.
+// Type hints apply first and field hints override them.
+func (b *policyBuilder) field(def *ast.FieldDefinition, parentMaxAge *int) *int {
+	typ := b.schema.Types[def.Type.Name()]
+	composite := typ != nil && typ.IsCompositeType()
+
+	var hint cacheHint
+	if composite {
+		hint, _ = readCacheHint(typ.Directives)
+		// Types with their own maxAge are entities that events invalidate
+		if hint.maxAge != nil {
+			b.types[typ.Name] = true
+		}
+	}
+	if fieldHint, ok := readCacheHint(def.Directives); ok {
+		if fieldHint.maxAge != nil || fieldHint.inherit {
+			hint.maxAge, hint.inherit = fieldHint.maxAge, fieldHint.inherit
+		}
+		hint.private = hint.private || fieldHint.private
+	}
+	if hint.private {
+		b.private = true
+	}
+
+	maxAge := hint.maxAge
+	switch {
+	case hint.inherit:
+		maxAge = parentMaxAge
+	case maxAge == nil && !composite && parentMaxAge != nil:
+		// Scalars inherit from their parent without restricting the policy
+		return parentMaxAge
+	}
+	if maxAge == nil {
+		zero := 0
+		maxAge = &zero
+	}
+
+	if b.maxAge == nil || *maxAge < *b.maxAge {
+		b.maxAge = maxAge
+	}
+	return maxAge
+}
+
+func (b *policyBuilder) state() *cacheState {
+	policy := CachePolicy{Scope: model.CacheControlScopePublic}
+	if b.maxAge != nil {
+		policy.MaxAge = *b.maxAge
+	}
+	if b.private {
+		policy.Scope = model.CacheControlScopePrivate
+	}
+
+	types := make([]string, 0, len(b.types))
+	for typ := range b.types {
+		types = append(types, typ)
+	}
+	return &cacheState{policy: policy, types: types}
+}
+
+// httpCacheKey is the context key for the policy holder set by CacheHeaders
+type httpCacheKey struct{}
+
+// httpCachePolicy receives the policy of a successful GET query
+type httpCachePolicy struct {
+	policy *CachePolicy
+}
+
+func setHTTPPolicy(ctx context.Context, policy CachePolicy) {
+	if holder, ok := ctx.Value(httpCacheKey{}).(*httpCachePolicy); ok {
+		holder.policy = &policy
+	}
+}
+
+// CacheHeaders sets Cache-Control and a weak ETag on GET queries and answers
+// matching If-None-Match requests with 304 Not Modified. Other requests pass
+// through untouched.
+func CacheHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		holder := &httpCachePolicy{}
+		buf := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(buf, r.WithContext(context.WithValue(r.Context(), httpCacheKey{}, holder)))
+
+		body := buf.body.Bytes()
+		if holder.policy == nil || buf.status != http.StatusOK {
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(buf.status)
+			w.Write(body)
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+		w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", strings.ToLower(string(holder.policy.Scope)), holder.policy.MaxAge))
+		w.Header().Set("ETag", etag)
+		if holder.policy.Private() {
+			w.Header().Add("Vary", "Authorization")
+		}
+
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.WriteHeader(buf.status)
+		w.Write(body)
+	})
+}
+
+// etagMatches compares If-None-Match against an ETag using weak comparison
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedResponse holds a response back so headers can depend on its body
+type bufferedResponse struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
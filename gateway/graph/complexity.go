@@ -0,0 +1,252 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/microservices-go/gateway/graph/generated"
+	"github.com/microservices-go/gateway/graph/model"
+	"github.com/microservices-go/gateway/internal/audit"
+	"github.com/microservices-go/gateway/internal/order"
+	"github.com/microservices-go/gateway/internal/payment"
+	"github.com/microservices-go/gateway/internal/user"
+	"github.com/microservices-go/gateway/middleware"
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/pagination"
+)
+
+// Complexity returns the field cost functions. List fields multiply the cost
+// of their selection by the requested page size.
+func Complexity() generated.ComplexityRoot {
+	var c generated.ComplexityRoot
+
+	c.Query.Users = func(child int, _ *user.UserFilter, _ []*model.UserSort, limit, _, first *int, _ *string, last *int, _ *string) int {
+		return listCost(child, pageSize(limit, first, last))
+	}
+	c.Query.Orders = func(child int, _ *order.OrderFilter, _ []*model.OrderSort, limit, _, first *int, _ *string, last *int, _ *string) int {
+		return listCost(child, pageSize(limit, first, last))
+	}
+	c.Query.MyOrders = func(child int, limit, _, first *int, _ *string, last *int, _ *string) int {
+		return listCost(child, pageSize(limit, first, last))
+	}
+	c.Query.Payments = func(child int, _ *payment.PaymentFilter, _ []*model.PaymentSort, limit, _, first *int, _ *string, last *int, _ *string) int {
+		return listCost(child, pageSize(limit, first, last))
+	}
+	c.Query.MyPayments = func(child int, limit, _, first *int, _ *string, last *int, _ *string) int {
+		return listCost(child, pageSize(limit, first, last))
+	}
+	c.Query.AuditLog = func(child int, _ model.AuditService, _ *audit.Filter, limit, _ *int) int {
+		return listCost(child, pageSize(limit, nil, nil))
+	}
+	c.Query.ErasureRequests = func(child int, _ *string, limit, _ *int) int {
+		return listCost(child, pageSize(limit, nil, nil))
+	}
+	c.User.Orders = func(child int, limit, _ *int) int {
+		return listCost(child, pageSize(limit, nil, nil))
+	}
+	c.User.Payments = func(child int, limit, _ *int) int {
+		return listCost(child, pageSize(limit, nil, nil))
+	}
+
+	return c
+}
+
+// pageSize mirrors how the services resolve page arguments
+func pageSize(limit, first, last *int) int {
+	size := pagination.DefaultSize
+	switch {
+	case first != nil:
+		size = *first
+	case last != nil:
+		size = *last
+	case limit != nil:
+		size = *limit
+	}
+	if size < 1 {
+		size = 1
+	}
+	if size > pagination.MaxSize {
+		size = pagination.MaxSize
+	}
+	return size
+}
+
+// listCost multiplies the per-item cost by the page size, saturating on overflow
+func listCost(child, size int) int {
+	if child > 0 && size > (math.MaxInt-1)/child {
+		return math.MaxInt
+	}
+	return child*size + 1
+}
+
+const (
+	queryCostExtension = "QueryCost"
+	errQueryTooDeep    = "QUERY_TOO_DEEP"
+	errQueryTooCostly  = "QUERY_COST_EXCEEDED"
+)
+
+// CostStats is the computed cost of an operation, returned in response extensions
+type CostStats struct {
+	Cost      int            `json:"cost"`
+	Budget    int            `json:"budget"`
+	Depth     int            `json:"depth"`
+	MaxDepth  int            `json:"maxDepth"`
+	Breakdown map[string]int `json:"breakdown"`
+}
+
+// QueryCost rejects operations that are nested too deeply or cost more than
+// the caller's role budget, and reports the cost of accepted operations
+type QueryCost struct {
+	MaxDepth      int
+	Budgets       map[string]int
+	DefaultBudget int
+
+	es graphql.ExecutableSchema
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+	graphql.ResponseInterceptor
+} = &QueryCost{}
+
+// NewQueryCost creates the cost extension from GraphQL config
+func NewQueryCost(cfg *config.GraphQLConfig) *QueryCost {
+	return &QueryCost{
+		MaxDepth:      cfg.MaxDepth,
+		Budgets:       cfg.CostBudgets,
+		DefaultBudget: cfg.DefaultBudget,
+	}
+}
+
+// ExtensionName returns the extension name
+func (q *QueryCost) ExtensionName() string {
+	return queryCostExtension
+}
+
+// Validate stores the schema used for cost calculation
+func (q *QueryCost) Validate(schema graphql.ExecutableSchema) error {
+	q.es = schema
+	return nil
+}
+
+// MutateOperationContext computes depth and cost before execution
+func (q *QueryCost) MutateOperationContext(ctx context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	op := opCtx.Doc.Operations.ForName(opCtx.OperationName)
+	if op == nil {
+		return nil
+	}
+
+	stats := &CostStats{
+		Budget:    q.budget(ctx),
+		Depth:     selectionDepth(op.SelectionSet),
+		MaxDepth:  q.MaxDepth,
+		Breakdown: make(map[string]int),
+	}
+
+	if q.MaxDepth > 0 && stats.Depth > q.MaxDepth {
+		err := gqlerror.Errorf("Query depth %d exceeds the maximum of %d", stats.Depth, q.MaxDepth)
+		err.Extensions = map[string]interface{}{
+			"code":     errQueryTooDeep,
+			"depth":    stats.Depth,
+			"maxDepth": q.MaxDepth,
+		}
+		return err
+	}
+
+	// Cost each root field separately so rejections can say where the cost comes from
+	for _, sel := range op.SelectionSet {
+		field, ok := sel.(*ast.Field)
+		if !ok {
+			continue
+		}
+		root := &ast.OperationDefinition{Operation: op.Operation, SelectionSet: ast.SelectionSet{field}}
+		cost := complexity.Calculate(ctx, q.es, root, opCtx.Variables)
+		stats.Breakdown[field.Alias] += cost
+	}
+	stats.Cost = complexity.Calculate(ctx, q.es, op, opCtx.Variables)
+
+	opCtx.Stats.SetExtension(queryCostExtension, stats)
+
+	if stats.Cost > stats.Budget {
+		err := gqlerror.Errorf("Query cost %d exceeds the budget of %d (%s); request fewer items per page or fewer nested lists",
+			stats.Cost, stats.Budget, formatBreakdown(stats.Breakdown))
+		err.Extensions = map[string]interface{}{
+			"code":      errQueryTooCostly,
+			"cost":      stats.Cost,
+			"budget":    stats.Budget,
+			"breakdown": stats.Breakdown,
+		}
+		return err
+	}
+	return nil
+}
+
+// InterceptResponse adds the operation cost to the response extensions
+func (q *QueryCost) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if graphql.HasOperationContext(ctx) {
+		if stats, ok := graphql.GetOperationContext(ctx).Stats.GetExtension(queryCostExtension).(*CostStats); ok {
+			graphql.RegisterExtension(ctx, "cost", stats)
+		}
+	}
+	return next(ctx)
+}
+
+// budget returns the cost budget for the caller's role
+func (q *QueryCost) budget(ctx context.Context) int {
+	claims, ok := middleware.GetUserClaims(ctx)
+	if !ok {
+		return q.Budgets[""]
+	}
+	if budget, ok := q.Budgets[claims.Role]; ok {
+		return budget
+	}
+	return q.DefaultBudget
+}
+
+// selectionDepth returns the deepest field nesting, ignoring introspection
+func selectionDepth(set ast.SelectionSet) int {
+	depth := 0
+	for _, sel := range set {
+		var d int
+		switch s := sel.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") {
+				continue
+			}
+			d = 1 + selectionDepth(s.SelectionSet)
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				d = selectionDepth(s.Definition.SelectionSet)
+			}
+		case *ast.InlineFragment:
+			d = selectionDepth(s.SelectionSet)
+		}
+		if d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
+
+func formatBreakdown(breakdown map[string]int) string {
+	fields := make([]string, 0, len(breakdown))
+	for field := range breakdown {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = fmt.Sprintf("%s=%d", field, breakdown[field])
+	}
+	return strings.Join(parts, ", ")
+}
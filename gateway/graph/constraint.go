@@ -0,0 +1,271 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/validator"
+)
+
+// constraintFormats are the @constraint formats, named after their validate tags
+var constraintFormats = map[string]bool{"email": true, "uuid": true, "url": true}
+
+// constraint is a parsed @constraint directive. Rules are kept as validate
+// tags so the gateway checks values exactly like the services do.
+type constraint struct {
+	tag     string
+	pattern *regexp.Regexp
+}
+
+// parseConstraint converts a @constraint directive on a field of type typ
+// into validate tags: min/max become gte/lte for numbers and min/max
+// (length) for strings and lists, and element rules of lists are applied
+// through dive
+func parseConstraint(d *ast.Directive, typ *ast.Type) (*constraint, error) {
+	numeric := typ.Elem == nil && (typ.NamedType == "Int" || typ.NamedType == "Float")
+
+	var size, rules []string
+	for _, arg := range d.Arguments {
+		switch arg.Name {
+		case "min", "max", "exclusiveMin":
+			n, err := strconv.ParseFloat(arg.Value.Raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", arg.Name, err)
+			}
+			if typ.NamedType == "Int" && typ.Elem == nil && n != math.Trunc(n) {
+				return nil, fmt.Errorf("%s must be an integer", arg.Name)
+			}
+			tag := arg.Name
+			switch {
+			case arg.Name == "exclusiveMin":
+				tag = "gt"
+			case numeric && arg.Name == "min":
+				tag = "gte"
+			case numeric && arg.Name == "max":
+				tag = "lte"
+			}
+			size = append(size, tag+"="+strconv.FormatFloat(n, 'f', -1, 64))
+		case "format":
+			if !constraintFormats[arg.Value.Raw] {
+				return nil, fmt.Errorf("unknown format %q", arg.Value.Raw)
+			}
+			rules = append(rules, arg.Value.Raw)
+		case "oneOf":
+			values := make([]string, len(arg.Value.Children))
+			for i, child := range arg.Value.Children {
+				values[i] = child.Value.Raw
+			}
+			rules = append(rules, "oneof="+strings.Join(values, " "))
+		}
+	}
+
+	c := &constraint{}
+	if arg := d.Arguments.ForName("pattern"); arg != nil {
+		pattern, err := regexp.Compile(arg.Value.Raw)
+		if err != nil {
+			return nil, fmt.Errorf("pattern: %w", err)
+		}
+		c.pattern = pattern
+	}
+
+	tags := size
+	if len(rules) > 0 {
+		if typ.Elem != nil {
+			tags = append(tags, "dive")
+		}
+		tags = append(tags, rules...)
+	}
+	c.tag = strings.Join(tags, ",")
+	return c, nil
+}
+
+// ConstraintValidator enforces @constraint directives on arguments and input
+// fields before execution. Every violation is reported at once as a
+// VALIDATION_FAILED error with the offending argument paths in extensions.fields.
+type ConstraintValidator struct {
+	schema      *ast.Schema
+	constraints map[*ast.Directive]*constraint
+	validator   *validator.Validator
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = &ConstraintValidator{}
+
+// NewConstraintValidator creates the constraint extension
+func NewConstraintValidator() *ConstraintValidator {
+	return &ConstraintValidator{validator: validator.New()}
+}
+
+// ExtensionName returns the extension name
+func (v *ConstraintValidator) ExtensionName() string {
+	return "ConstraintValidator"
+}
+
+// Validate parses every @constraint in the schema, failing on invalid rules
+func (v *ConstraintValidator) Validate(schema graphql.ExecutableSchema) error {
+	v.schema = schema.Schema()
+	v.constraints = make(map[*ast.Directive]*constraint)
+
+	add := func(owner string, directives ast.DirectiveList, typ *ast.Type) error {
+		d := directives.ForName("constraint")
+		if d == nil {
+			return nil
+		}
+		c, err := parseConstraint(d, typ)
+		if err != nil {
+			return fmt.Errorf("@constraint on %s: %w", owner, err)
+		}
+		v.constraints[d] = c
+		return nil
+	}
+
+	for _, def := range v.schema.Types {
+		for _, field := range def.Fields {
+			if err := add(def.Name+"."+field.Name, field.Directives, field.Type); err != nil {
+				return err
+			}
+			for _, arg := range field.Arguments {
+				if err := add(def.Name+"."+field.Name+"("+arg.Name+")", arg.Directives, arg.Type); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// MutateOperationContext checks the arguments of every selected field
+func (v *ConstraintValidator) MutateOperationContext(ctx context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	op := opCtx.Doc.Operations.ForName(opCtx.OperationName)
+	if op == nil {
+		return nil
+	}
+
+	var fields []errors.FieldError
+	v.checkSelection(op.SelectionSet, opCtx.Variables, &fields)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	details := make([]string, len(fields))
+	for i, f := range fields {
+		details[i] = f.Field + ": " + f.Message
+	}
+	appErr := errors.New(errors.ErrValidationFailed, "Validation failed").
+		WithDetails(strings.Join(details, "; ")).
+		WithFields(fields)
+	return &gqlerror.Error{Message: appErr.Message, Err: appErr}
+}
+
+func (v *ConstraintValidator) checkSelection(set ast.SelectionSet, vars map[string]interface{}, fields *[]errors.FieldError) {
+	for _, sel := range set {
+		switch s := sel.(type) {
+		case *ast.Field:
+			if s.Definition == nil {
+				continue
+			}
+			args := s.ArgumentMap(vars)
+			for _, arg := range s.Definition.Arguments {
+				v.checkValue(arg.Name, arg.Type, arg.Directives, args[arg.Name], fields)
+			}
+			v.checkSelection(s.SelectionSet, vars, fields)
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				v.checkSelection(s.Definition.SelectionSet, vars, fields)
+			}
+		case *ast.InlineFragment:
+			v.checkSelection(s.SelectionSet, vars, fields)
+		}
+	}
+}
+
+// checkValue applies the constraint of one argument or input field and
+// descends into lists and input objects
+func (v *ConstraintValidator) checkValue(path string, typ *ast.Type, directives ast.DirectiveList, value interface{}, fields *[]errors.FieldError) {
+	if value == nil {
+		return
+	}
+
+	if d := directives.ForName("constraint"); d != nil {
+		if c, ok := v.constraints[d]; ok {
+			if msg := v.check(c, typ, value); msg != "" {
+				*fields = append(*fields, errors.FieldError{Field: path, Message: msg})
+				return
+			}
+		}
+	}
+
+	if typ.Elem != nil {
+		items, _ := value.([]interface{})
+		for i, item := range items {
+			v.checkValue(fmt.Sprintf("%s[%d]", path, i), typ.Elem, nil, item, fields)
+		}
+		return
+	}
+
+	def := v.schema.Types[typ.NamedType]
+	if def == nil || def.Kind != ast.InputObject {
+		return
+	}
+	object, _ := value.(map[string]interface{})
+	for _, field := range def.Fields {
+		v.checkValue(path+"."+field.Name, field.Type, field.Directives, object[field.Name], fields)
+	}
+}
+
+// check returns the message of the first rule value breaks, or ""
+func (v *ConstraintValidator) check(c *constraint, typ *ast.Type, value interface{}) string {
+	value = normalizeValue(typ, value)
+	if c.tag != "" {
+		if msg := v.validator.VarMessage(value, c.tag); msg != "" {
+			return msg
+		}
+	}
+	if s, ok := value.(string); ok && c.pattern != nil && !c.pattern.MatchString(s) {
+		return "Value must match " + c.pattern.String()
+	}
+	return ""
+}
+
+// normalizeValue converts numbers from literals and JSON variables into the
+// Go type of the GraphQL scalar so numeric rules compare values, not strings
+func normalizeValue(typ *ast.Type, value interface{}) interface{} {
+	if typ.Elem != nil || (typ.NamedType != "Int" && typ.NamedType != "Float") {
+		return value
+	}
+
+	var f float64
+	switch n := value.(type) {
+	case int:
+		f = float64(n)
+	case int32:
+		f = float64(n)
+	case int64:
+		f = float64(n)
+	case float64:
+		f = n
+	case json.Number:
+		f, _ = n.Float64()
+	case string:
+		f, _ = strconv.ParseFloat(n, 64)
+	default:
+		return value
+	}
+
+	if typ.NamedType == "Int" {
+		return int64(f)
+	}
+	return f
+}
@@ -0,0 +1,192 @@
+package graph
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode"
+
+	gqlast "github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/microservices-go/gateway/graph/generated"
+)
+
+// constraintSources pairs schema inputs with the service request types whose
+// validate tags they must mirror. Arguments are named Type.field.
+var constraintSources = []struct {
+	schema string
+	file   string
+	typ    string
+}{
+	{"RegisterInput", "../../services/user/internal/user/model.go", "CreateUserRequest"},
+	{"LoginInput", "../../services/user/internal/user/model.go", "LoginRequest"},
+	{"Mutation.updateUser", "../../services/user/internal/user/model.go", "UpdateUserRequest"},
+	{"Mutation.assignRole", "../../services/user/internal/user/role.go", "RoleRequest"},
+	{"Mutation.revokeRole", "../../services/user/internal/user/role.go", "RoleRequest"},
+	{"VerifyMfaInput", "../../services/user/internal/user/mfa.go", "MFAVerifyRequest"},
+	{"Mutation.confirmMfa", "../../services/user/internal/user/mfa.go", "MFACodeRequest"},
+	{"Mutation.disableMfa", "../../services/user/internal/user/mfa.go", "MFACodeRequest"},
+	{"Mutation.regenerateMfaBackupCodes", "../../services/user/internal/user/mfa.go", "MFACodeRequest"},
+	{"OidcCallbackInput", "../../services/user/internal/user/oidc.go", "OIDCCallbackRequest"},
+	{"CreateApiKeyInput", "../../services/user/internal/user/apikey.go", "CreateAPIKeyRequest"},
+	{"CreateOrderInput", "../../services/order/internal/order/model.go", "CreateOrderRequest"},
+	{"CreateOrderItemInput", "../../services/order/internal/order/model.go", "CreateOrderItemRequest"},
+	{"Mutation.updateOrderStatus", "../../services/order/internal/order/model.go", "UpdateOrderStatusRequest"},
+	{"CreatePaymentInput", "../../services/payment/internal/payment/model.go", "CreatePaymentRequest"},
+	{"Mutation.refundPayment", "../../services/payment/internal/payment/model.go", "RefundRequest"},
+}
+
+// TestConstraintsMatchServiceValidation fails when a @constraint and the
+// validate tag of the matching service request field drift apart
+func TestConstraintsMatchServiceValidation(t *testing.T) {
+	schema := generated.NewExecutableSchema(generated.Config{Resolvers: &Resolver{}}).Schema()
+
+	for _, src := range constraintSources {
+		t.Run(src.schema, func(t *testing.T) {
+			serviceRules := structRules(t, src.file, src.typ)
+
+			matched := 0
+			for _, field := range schemaFields(t, schema, src.schema) {
+				name, typ, directives := field.Name, field.Type, field.Directives
+				want, ok := serviceRules[camelToSnake(name)]
+				if !ok {
+					continue
+				}
+				matched++
+
+				var got []string
+				if d := directives.ForName("constraint"); d != nil {
+					c, err := parseConstraint(d, typ)
+					if err != nil {
+						t.Fatalf("%s: %v", name, err)
+					}
+					got = splitTags(c.tag)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: @constraint gives %v, %s has %v", name, got, src.typ, want)
+				}
+			}
+			if matched == 0 {
+				t.Errorf("no fields of %s match %s", src.schema, src.typ)
+			}
+		})
+	}
+}
+
+// schemaFields returns the input fields of an input type, or the arguments
+// of a Type.field as field definitions
+func schemaFields(t *testing.T, schema *gqlast.Schema, name string) gqlast.FieldList {
+	typeName, fieldName, isArgs := strings.Cut(name, ".")
+	def := schema.Types[typeName]
+	if def == nil {
+		t.Fatalf("type %s not in schema", typeName)
+	}
+	if !isArgs {
+		return def.Fields
+	}
+
+	field := def.Fields.ForName(fieldName)
+	if field == nil {
+		t.Fatalf("field %s not in schema", name)
+	}
+	fields := make(gqlast.FieldList, len(field.Arguments))
+	for i, arg := range field.Arguments {
+		fields[i] = &gqlast.FieldDefinition{Name: arg.Name, Type: arg.Type, Directives: arg.Directives}
+	}
+	return fields
+}
+
+// structRules reads the validate tags of a struct, keyed by JSON name, in
+// the normalized form parseConstraint produces
+func structRules(t *testing.T, file, typ string) map[string][]string {
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules := make(map[string][]string)
+	ast.Inspect(f, func(n ast.Node) bool {
+		spec, ok := n.(*ast.TypeSpec)
+		if !ok || spec.Name.Name != typ {
+			return true
+		}
+		for _, field := range spec.Type.(*ast.StructType).Fields.List {
+			if field.Tag == nil {
+				continue
+			}
+			raw, _ := strconv.Unquote(field.Tag.Value)
+			tag := reflect.StructTag(raw)
+			name, _, _ := strings.Cut(tag.Get("json"), ",")
+			rules[name] = normalizeTags(tag.Get("validate"), goTypeKind(field.Type))
+		}
+		return false
+	})
+	if len(rules) == 0 {
+		t.Fatalf("struct %s not found in %s", typ, file)
+	}
+	return rules
+}
+
+func goTypeKind(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.ArrayType:
+		return "list"
+	case *ast.Ident:
+		switch e.Name {
+		case "int", "int32", "int64", "float32", "float64":
+			return "number"
+		}
+	}
+	return "string"
+}
+
+// normalizeTags drops presence rules and rewrites size rules the way
+// parseConstraint emits them
+func normalizeTags(validate, kind string) []string {
+	var tags []string
+	for _, tag := range splitTags(validate) {
+		name, param, _ := strings.Cut(tag, "=")
+		switch {
+		case name == "required" || name == "omitempty":
+		case name == "len":
+			tags = append(tags, "min="+param, "max="+param)
+		case kind == "number" && name == "min":
+			tags = append(tags, "gte="+param)
+		case kind == "number" && name == "max":
+			tags = append(tags, "lte="+param)
+		default:
+			tags = append(tags, tag)
+		}
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+// splitTags splits a validate tag list, ignoring dive
+func splitTags(tag string) []string {
+	var tags []string
+	for _, t := range strings.Split(tag, ",") {
+		if t != "" && t != "dive" {
+			tags = append(tags, t)
+		}
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+func camelToSnake(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
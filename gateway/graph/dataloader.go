@@ -8,6 +8,7 @@ import (
 
 	"github.com/graph-gophers/dataloader/v7"
 
+	"github.com/microservices-go/gateway/internal/common"
 	"github.com/microservices-go/gateway/internal/order"
 	"github.com/microservices-go/gateway/internal/payment"
 	"github.com/microservices-go/gateway/internal/user"
@@ -22,23 +23,31 @@ type Loaders struct {
 	OrderLoader          *dataloader.Loader[string, *order.Order]
 	PaymentLoader        *dataloader.Loader[string, *payment.Payment]
 	PaymentByOrderLoader *dataloader.Loader[string, *payment.Payment]
+	OrdersByUserLoader   *dataloader.Loader[common.UserPageKey, []*order.Order]
+	PaymentsByUserLoader *dataloader.Loader[common.UserPageKey, []*payment.Payment]
+
+	Stats *LoaderStats
 }
 
 // NewLoaders creates new dataloaders with batch functions
-func NewLoaders(userServiceURL, orderServiceURL, paymentServiceURL string) *Loaders {
+func NewLoaders(upstreams *Upstreams) *Loaders {
+	stats := NewLoaderStats()
 	return &Loaders{
-		UserLoader:           dataloader.NewBatchedLoader(user.BatchLoadUsers(userServiceURL), dataloader.WithWait[string, *user.User](time.Millisecond*5)),
-		OrderLoader:          dataloader.NewBatchedLoader(order.BatchLoadOrders(orderServiceURL), dataloader.WithWait[string, *order.Order](time.Millisecond*5)),
-		PaymentLoader:        dataloader.NewBatchedLoader(payment.BatchLoadPayments(paymentServiceURL), dataloader.WithWait[string, *payment.Payment](time.Millisecond*5)),
-		PaymentByOrderLoader: dataloader.NewBatchedLoader(payment.BatchLoadPaymentsByOrder(paymentServiceURL), dataloader.WithWait[string, *payment.Payment](time.Millisecond*5)),
+		UserLoader:           dataloader.NewBatchedLoader(user.BatchLoadUsers(upstreams.User), dataloader.WithWait[string, *user.User](time.Millisecond*5), withStats[string, *user.User](stats, "user")),
+		OrderLoader:          dataloader.NewBatchedLoader(order.BatchLoadOrders(upstreams.Order), dataloader.WithWait[string, *order.Order](time.Millisecond*5), withStats[string, *order.Order](stats, "order")),
+		PaymentLoader:        dataloader.NewBatchedLoader(payment.BatchLoadPayments(upstreams.Payment), dataloader.WithWait[string, *payment.Payment](time.Millisecond*5), withStats[string, *payment.Payment](stats, "payment")),
+		PaymentByOrderLoader: dataloader.NewBatchedLoader(payment.BatchLoadPaymentsByOrder(upstreams.Payment), dataloader.WithWait[string, *payment.Payment](time.Millisecond*5), withStats[string, *payment.Payment](stats, "paymentByOrder")),
+		OrdersByUserLoader:   dataloader.NewBatchedLoader(order.BatchLoadOrdersByUser(upstreams.Order), dataloader.WithWait[common.UserPageKey, []*order.Order](time.Millisecond*5), withStats[common.UserPageKey, []*order.Order](stats, "ordersByUser")),
+		PaymentsByUserLoader: dataloader.NewBatchedLoader(payment.BatchLoadPaymentsByUser(upstreams.Payment), dataloader.WithWait[common.UserPageKey, []*payment.Payment](time.Millisecond*5), withStats[common.UserPageKey, []*payment.Payment](stats, "paymentsByUser")),
+		Stats:                stats,
 	}
 }
 
 // DataLoaderMiddleware injects dataloaders into context
-func DataLoaderMiddleware(userServiceURL, orderServiceURL, paymentServiceURL string) func(http.Handler) http.Handler {
+func DataLoaderMiddleware(upstreams *Upstreams) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			loaders := NewLoaders(userServiceURL, orderServiceURL, paymentServiceURL)
+			loaders := NewLoaders(upstreams)
 			ctx := context.WithValue(r.Context(), LoaderKey{}, loaders)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
@@ -51,13 +60,13 @@ func GetLoaders(ctx context.Context) *Loaders {
 	return loaders
 }
 
-// GetServiceURLs returns service URLs from environment
-func GetServiceURLs() (string, string, string) {
-	userServiceURL := getEnv("USER_SERVICE_URL", "http://localhost:4001")
-	orderServiceURL := getEnv("ORDER_SERVICE_URL", "http://localhost:4002")
-	paymentServiceURL := getEnv("PAYMENT_SERVICE_URL", "http://localhost:4003")
+// GetServiceAddrs returns the gRPC addresses of the services from environment
+func GetServiceAddrs() (string, string, string) {
+	userServiceAddr := getEnv("USER_SERVICE_GRPC_ADDR", "localhost:50051")
+	orderServiceAddr := getEnv("ORDER_SERVICE_GRPC_ADDR", "localhost:50052")
+	paymentServiceAddr := getEnv("PAYMENT_SERVICE_GRPC_ADDR", "localhost:50053")
 
-	return userServiceURL, orderServiceURL, paymentServiceURL
+	return userServiceAddr, orderServiceAddr, paymentServiceAddr
 }
 
 func getEnv(key, fallback string) string {
@@ -0,0 +1,67 @@
+package graph
+
+import (
+	"context"
+	stderrors "errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/tracing"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const internalErrorMessage = "Internal server error"
+
+// ErrorPresenter maps downstream AppErrors into GraphQL error extensions
+// (code, service, fields, traceId). When hideInternal is set, messages of
+// 5xx and unexpected errors are replaced with a generic one.
+func ErrorPresenter(hideInternal bool) graphql.ErrorPresenterFunc {
+	return func(ctx context.Context, err error) *gqlerror.Error {
+		gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+		if gqlErr.Extensions == nil {
+			gqlErr.Extensions = map[string]interface{}{}
+		}
+		if traceID := requestTraceID(ctx); traceID != "" {
+			gqlErr.Extensions["traceId"] = traceID
+		}
+
+		var appErr *errors.AppError
+		if stderrors.As(err, &appErr) {
+			gqlErr.Message = appErr.Message
+			gqlErr.Extensions["code"] = appErr.Code
+			if appErr.Service != "" {
+				gqlErr.Extensions["service"] = appErr.Service
+			}
+			if len(appErr.Fields) > 0 {
+				gqlErr.Extensions["fields"] = appErr.Fields
+			}
+			if hideInternal && appErr.IsServerError() {
+				gqlErr.Message = internalErrorMessage
+			} else if appErr.Details != "" && !hideInternal {
+				gqlErr.Extensions["details"] = appErr.Details
+			}
+			return gqlErr
+		}
+
+		// Parse and validation errors from gqlgen already carry a code
+		if _, ok := gqlErr.Extensions["code"]; ok {
+			return gqlErr
+		}
+
+		gqlErr.Extensions["code"] = errors.ErrInternalServer
+		if hideInternal {
+			logger.WithContext(ctx).WithError(err).Error("Unhandled GraphQL error")
+			gqlErr.Message = internalErrorMessage
+		}
+		return gqlErr
+	}
+}
+
+// requestTraceID returns the trace ID used in logs, falling back to the active span
+func requestTraceID(ctx context.Context) string {
+	if traceID := logger.GetTraceID(ctx); traceID != "" {
+		return traceID
+	}
+	return tracing.TraceID(ctx)
+}
@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/logger"
+)
+
+func TestErrorPresenter(t *testing.T) {
+	fields := []errors.FieldError{{Field: "email", Message: "is required"}}
+	validation := errors.New(errors.ErrValidationFailed, "Invalid input").WithService("user-service").WithFields(fields)
+	database := errors.Wrap(fmt.Errorf("connection refused"), errors.ErrDatabaseError, "Failed to load orders").WithService("order-service")
+	parse := &gqlerror.Error{Message: "Cannot query field", Extensions: map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED"}}
+
+	tests := []struct {
+		name         string
+		hideInternal bool
+		err          error
+		message      string
+		extensions   map[string]interface{}
+	}{
+		{"client error", false, validation, "Invalid input", map[string]interface{}{
+			"code": errors.ErrValidationFailed, "service": "user-service", "fields": fields,
+		}},
+		{"client error in production", true, validation, "Invalid input", map[string]interface{}{
+			"code": errors.ErrValidationFailed, "service": "user-service", "fields": fields,
+		}},
+		{"wrapped client error", true, fmt.Errorf("resolve user: %w", validation), "Invalid input", map[string]interface{}{
+			"code": errors.ErrValidationFailed, "service": "user-service", "fields": fields,
+		}},
+		{"server error", false, database, "Failed to load orders", map[string]interface{}{
+			"code": errors.ErrDatabaseError, "service": "order-service", "details": "connection refused",
+		}},
+		{"server error in production", true, database, internalErrorMessage, map[string]interface{}{
+			"code": errors.ErrDatabaseError, "service": "order-service",
+		}},
+		{"gqlgen error", true, parse, "Cannot query field", map[string]interface{}{
+			"code": "GRAPHQL_VALIDATION_FAILED",
+		}},
+		{"unexpected error", false, fmt.Errorf("nil pointer"), "nil pointer", map[string]interface{}{
+			"code": errors.ErrInternalServer,
+		}},
+		{"unexpected error in production", true, fmt.Errorf("nil pointer"), internalErrorMessage, map[string]interface{}{
+			"code": errors.ErrInternalServer,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := logger.SetTraceID(context.Background(), "trace-1")
+			want := map[string]interface{}{"traceId": "trace-1"}
+			for k, v := range tt.extensions {
+				want[k] = v
+			}
+
+			got := ErrorPresenter(tt.hideInternal)(ctx, tt.err)
+			if got.Message != tt.message {
+				t.Errorf("got message %q, want %q", got.Message, tt.message)
+			}
+			if !reflect.DeepEqual(got.Extensions, want) {
+				t.Errorf("got extensions %v, want %v", got.Extensions, want)
+			}
+		})
+	}
+}
+
+func TestErrorPresenterWithoutTraceID(t *testing.T) {
+	got := ErrorPresenter(true)(context.Background(), errors.New(errors.ErrNotFound, "Order not found"))
+	if _, ok := got.Extensions["traceId"]; ok {
+		t.Errorf("got traceId %v without a trace in context", got.Extensions["traceId"])
+	}
+}
@@ -0,0 +1,131 @@
+package graph
+
+import (
+	"context"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// LoaderStat is the activity of one dataloader during a request
+type LoaderStat struct {
+	Loads   int `json:"loads"`   // keys requested by resolvers
+	Hits    int `json:"hits"`    // loads served from the per-request cache
+	Batches int `json:"batches"` // downstream batch calls
+	Keys    int `json:"keys"`    // keys sent downstream
+	Errors  int `json:"errors"`  // keys that resolved to an error
+}
+
+// LoaderStats counts dataloader activity for one request
+type LoaderStats struct {
+	mu      sync.Mutex
+	loaders map[string]*LoaderStat
+}
+
+// NewLoaderStats creates empty loader stats
+func NewLoaderStats() *LoaderStats {
+	return &LoaderStats{loaders: make(map[string]*LoaderStat)}
+}
+
+// Snapshot returns the stats of every loader that was used
+func (s *LoaderStats) Snapshot() map[string]LoaderStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := make(map[string]LoaderStat, len(s.loaders))
+	for name, stat := range s.loaders {
+		st := *stat
+		st.Hits = st.Loads - st.Keys
+		if st.Hits < 0 {
+			st.Hits = 0
+		}
+		snapshot[name] = st
+	}
+	return snapshot
+}
+
+func (s *LoaderStats) record(name string, update func(*LoaderStat)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stat, ok := s.loaders[name]
+	if !ok {
+		stat = &LoaderStat{}
+		s.loaders[name] = stat
+	}
+	update(stat)
+}
+
+// loaderTracer feeds dataloader traces into LoaderStats
+type loaderTracer[K comparable, V any] struct {
+	name  string
+	stats *LoaderStats
+}
+
+func withStats[K comparable, V any](stats *LoaderStats, name string) dataloader.Option[K, V] {
+	return dataloader.WithTracer[K, V](loaderTracer[K, V]{name: name, stats: stats})
+}
+
+// TraceLoad counts a requested key
+func (t loaderTracer[K, V]) TraceLoad(ctx context.Context, key K) (context.Context, dataloader.TraceLoadFinishFunc[V]) {
+	t.stats.record(t.name, func(s *LoaderStat) { s.Loads++ })
+	return ctx, func(dataloader.Thunk[V]) {}
+}
+
+// TraceLoadMany is a no-op; LoadMany goes through TraceLoad for every key
+func (t loaderTracer[K, V]) TraceLoadMany(ctx context.Context, keys []K) (context.Context, dataloader.TraceLoadManyFinishFunc[V]) {
+	return ctx, func(dataloader.ThunkMany[V]) {}
+}
+
+// TraceBatch counts a downstream batch and its failed keys
+func (t loaderTracer[K, V]) TraceBatch(ctx context.Context, keys []K) (context.Context, dataloader.TraceBatchFinishFunc[V]) {
+	t.stats.record(t.name, func(s *LoaderStat) {
+		s.Batches++
+		s.Keys += len(keys)
+	})
+	return ctx, func(results []*dataloader.Result[V]) {
+		errs := 0
+		for _, result := range results {
+			if result != nil && result.Error != nil {
+				errs++
+			}
+		}
+		if errs > 0 {
+			t.stats.record(t.name, func(s *LoaderStat) { s.Errors += errs })
+		}
+	}
+}
+
+// LoaderStatsExtension reports dataloader activity in extensions.dataloaders
+type LoaderStatsExtension struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+} = LoaderStatsExtension{}
+
+// ExtensionName returns the extension name
+func (LoaderStatsExtension) ExtensionName() string {
+	return "LoaderStats"
+}
+
+// Validate is a no-op
+func (LoaderStatsExtension) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptResponse adds the request's loader stats once resolvers have run
+func (LoaderStatsExtension) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	resp := next(ctx)
+	loaders := GetLoaders(ctx)
+	if resp == nil || loaders == nil {
+		return resp
+	}
+
+	if resp.Extensions == nil {
+		resp.Extensions = make(map[string]interface{})
+	}
+	resp.Extensions["dataloaders"] = loaders.Stats.Snapshot()
+	return resp
+}
@@ -0,0 +1,135 @@
+package graph
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+var (
+	operationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "graphql_operation_duration_seconds",
+		Help:    "GraphQL operation latency in seconds",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation", "type", "status"})
+
+	resolverDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "graphql_resolver_duration_seconds",
+		Help:    "GraphQL resolver latency in seconds",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"object", "field", "status"})
+)
+
+// otherOperation labels operations that are neither in the allowlist nor
+// valid against the schema; their names are chosen by clients and would grow
+// the label set without bound
+const otherOperation = "other"
+
+// MetricsExtension records per-operation and per-resolver latency.
+// With an Allowlist, operations are labelled with their manifest name;
+// without one, with their type and sorted root fields, e.g.
+// "query:me,orders", which the schema bounds.
+type MetricsExtension struct {
+	Allowlist *Allowlist
+}
+
+// ExtensionName returns the extension name
+func (MetricsExtension) ExtensionName() string {
+	return "PrometheusMetrics"
+}
+
+// Validate validates the executable schema
+func (MetricsExtension) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptResponse measures the whole operation
+func (m MetricsExtension) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if !graphql.HasOperationContext(ctx) {
+		return next(ctx)
+	}
+
+	start := time.Now()
+	resp := next(ctx)
+
+	oc := graphql.GetOperationContext(ctx)
+	name := m.operationLabel(oc)
+	opType := "unknown"
+	if oc.Operation != nil {
+		opType = string(oc.Operation.Operation)
+	}
+	status := "ok"
+	if resp == nil || len(resp.Errors) > 0 {
+		status = "error"
+	}
+
+	operationDuration.WithLabelValues(name, opType, status).Observe(time.Since(start).Seconds())
+	return resp
+}
+
+// operationLabel returns the operation label of a validated operation
+func (m MetricsExtension) operationLabel(oc *graphql.OperationContext) string {
+	if m.Allowlist != nil {
+		name, ok := m.Allowlist.OperationName(oc.RawQuery)
+		switch {
+		case !ok:
+			return otherOperation
+		case name == "":
+			return "anonymous"
+		}
+		return name
+	}
+
+	if oc.Operation == nil {
+		return otherOperation
+	}
+	fields := map[string]bool{}
+	rootFields(oc.Operation.SelectionSet, fields)
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return string(oc.Operation.Operation) + ":" + strings.Join(names, ",")
+}
+
+// rootFields collects the names, not aliases, of the fields selected at the
+// root, looking through fragments
+func rootFields(set ast.SelectionSet, fields map[string]bool) {
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			fields[sel.Name] = true
+		case *ast.InlineFragment:
+			rootFields(sel.SelectionSet, fields)
+		case *ast.FragmentSpread:
+			if sel.Definition != nil {
+				rootFields(sel.Definition.SelectionSet, fields)
+			}
+		}
+	}
+}
+
+// InterceptField measures fields backed by a resolver function
+func (MetricsExtension) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+
+	start := time.Now()
+	res, err := next(ctx)
+
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	resolverDuration.WithLabelValues(fc.Object, fc.Field.Name, status).Observe(time.Since(start).Seconds())
+	return res, err
+}
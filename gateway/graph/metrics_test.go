@@ -0,0 +1,57 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2"
+
+	"github.com/microservices-go/gateway/graph/generated"
+)
+
+func TestOperationLabel(t *testing.T) {
+	allowlist, err := LoadAllowlist("../persisted-operations.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hash, body string
+	for hash, body = range allowlist.operations {
+		break
+	}
+	name := allowlist.names[hash]
+	if name == "" {
+		name = "anonymous"
+	}
+	schema := generated.NewExecutableSchema(generated.Config{Resolvers: &Resolver{}}).Schema()
+
+	tests := []struct {
+		name      string
+		allowlist *Allowlist
+		query     string
+		want      string
+	}{
+		{"allowlisted", allowlist, body, name},
+		{"not allowlisted", allowlist, "query MadeUp { me { id } }", otherOperation},
+		{"root fields", nil, "query ClientName { orders { data { id } } me { id } }", "query:me,orders"},
+		{"aliases and fragments", nil, "query { b: me { id } ...F } fragment F on Query { a: me { email } oidcProviders }", "query:me,oidcProviders"},
+		{"mutation", nil, `mutation { deleteUser(id: "u1") }`, "mutation:deleteUser"},
+		{"unparsed", nil, "", otherOperation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oc := &graphql.OperationContext{RawQuery: tt.query}
+			if tt.query != "" {
+				doc, errs := gqlparser.LoadQuery(schema, tt.query)
+				if len(errs) > 0 {
+					t.Fatal(errs)
+				}
+				oc.Doc = doc
+				oc.Operation = doc.Operations[0]
+			}
+			if got := (MetricsExtension{Allowlist: tt.allowlist}).operationLabel(oc); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,9 +9,12 @@ import (
 	"context"
 
 	"github.com/microservices-go/gateway/graph/generated"
+	"github.com/microservices-go/gateway/graph/model"
+	"github.com/microservices-go/gateway/internal/common"
 	"github.com/microservices-go/gateway/internal/order"
 	"github.com/microservices-go/gateway/internal/payment"
 	"github.com/microservices-go/gateway/internal/user"
+	"github.com/microservices-go/shared/policy"
 )
 
 // CreateOrder is the resolver for the createOrder field.
@@ -20,7 +23,7 @@ func (r *mutationResolver) CreateOrder(ctx context.Context, input order.CreateOr
 	if err != nil {
 		return nil, err
 	}
-	return r.OrderClient.CreateOrder(ctx, input, me.ID)
+	return r.OrderClient.CreateOrder(ctx, input, me.Id)
 }
 
 // UpdateOrderStatus is the resolver for the updateOrderStatus field.
@@ -31,14 +34,14 @@ func (r *mutationResolver) UpdateOrderStatus(ctx context.Context, id string, sta
 // User is the resolver for the user field.
 func (r *orderResolver) User(ctx context.Context, obj *order.Order) (*user.User, error) {
 	loaders := GetLoaders(ctx)
-	thunk := loaders.UserLoader.Load(ctx, obj.UserID)
+	thunk := loaders.UserLoader.Load(ctx, obj.UserId)
 	return thunk()
 }
 
 // Payment is the resolver for the payment field.
 func (r *orderResolver) Payment(ctx context.Context, obj *order.Order) (*payment.Payment, error) {
 	loaders := GetLoaders(ctx)
-	thunk := loaders.PaymentByOrderLoader.Load(ctx, obj.ID)
+	thunk := loaders.PaymentByOrderLoader.Load(ctx, obj.Id)
 	return thunk()
 }
 
@@ -50,32 +53,49 @@ func (r *queryResolver) Order(ctx context.Context, id string) (*order.Order, err
 }
 
 // Orders is the resolver for the orders field.
-func (r *queryResolver) Orders(ctx context.Context, limit *int, offset *int) (*order.OrderConnection, error) {
-	l := 10
-	o := 0
-	if limit != nil {
-		l = *limit
+func (r *queryResolver) Orders(ctx context.Context, filter *order.OrderFilter, sort []*model.OrderSort, limit *int, offset *int, first *int, after *string, last *int, before *string) (*order.OrderConnection, error) {
+	args := common.NewPageArgs(limit, offset, first, after, last, before)
+	for _, s := range sort {
+		args.Sort = append(args.Sort, sortKey(string(s.Field), s.Direction))
 	}
-	if offset != nil {
-		o = *offset
-	}
-	return r.OrderClient.ListOrders(ctx, l, o)
+	return r.OrderClient.ListOrders(ctx, filter, args)
 }
 
 // MyOrders is the resolver for the myOrders field.
-func (r *queryResolver) MyOrders(ctx context.Context, limit *int, offset *int) (*order.OrderConnection, error) {
-	l := 10
-	o := 0
-	if limit != nil {
-		l = *limit
+func (r *queryResolver) MyOrders(ctx context.Context, limit *int, offset *int, first *int, after *string, last *int, before *string) (*order.OrderConnection, error) {
+	return r.OrderClient.ListMyOrders(ctx, common.NewPageArgs(limit, offset, first, after, last, before))
+}
+
+// OrderStatusChanged is the resolver for the orderStatusChanged field.
+func (r *subscriptionResolver) OrderStatusChanged(ctx context.Context, orderID string) (<-chan *order.OrderStatusUpdate, error) {
+	claims, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
 	}
-	if offset != nil {
-		o = *offset
+
+	// Authorize per event: non-owners never receive updates for someone else's order
+	return r.Events.Orders.Subscribe(ctx, func(u *order.OrderStatusUpdate) bool {
+		return u.OrderID == orderID && canAccess(claims, u.UserID, policy.PermOrdersRead)
+	}), nil
+}
+
+// MyOrderUpdates is the resolver for the myOrderUpdates field.
+func (r *subscriptionResolver) MyOrderUpdates(ctx context.Context) (<-chan *order.OrderStatusUpdate, error) {
+	claims, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return r.OrderClient.ListMyOrders(ctx, l, o)
+
+	return r.Events.Orders.Subscribe(ctx, func(u *order.OrderStatusUpdate) bool {
+		return u.UserID == claims.UserID
+	}), nil
 }
 
 // Order returns generated.OrderResolver implementation.
 func (r *Resolver) Order() generated.OrderResolver { return &orderResolver{r} }
 
+// Subscription returns generated.SubscriptionResolver implementation.
+func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
+
 type orderResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
@@ -9,9 +9,12 @@ import (
 	"context"
 
 	"github.com/microservices-go/gateway/graph/generated"
+	"github.com/microservices-go/gateway/graph/model"
+	"github.com/microservices-go/gateway/internal/common"
 	"github.com/microservices-go/gateway/internal/order"
 	"github.com/microservices-go/gateway/internal/payment"
 	"github.com/microservices-go/gateway/internal/user"
+	"github.com/microservices-go/shared/policy"
 )
 
 // CreatePayment is the resolver for the createPayment field.
@@ -20,7 +23,7 @@ func (r *mutationResolver) CreatePayment(ctx context.Context, input payment.Crea
 	if err != nil {
 		return nil, err
 	}
-	return r.PaymentClient.CreatePayment(ctx, input, me.ID)
+	return r.PaymentClient.CreatePayment(ctx, input, me.Id)
 }
 
 // ProcessPayment is the resolver for the processPayment field.
@@ -36,14 +39,14 @@ func (r *mutationResolver) RefundPayment(ctx context.Context, id string, amount
 // Order is the resolver for the order field.
 func (r *paymentResolver) Order(ctx context.Context, obj *payment.Payment) (*order.Order, error) {
 	loaders := GetLoaders(ctx)
-	thunk := loaders.OrderLoader.Load(ctx, obj.OrderID)
+	thunk := loaders.OrderLoader.Load(ctx, obj.OrderId)
 	return thunk()
 }
 
 // User is the resolver for the user field.
 func (r *paymentResolver) User(ctx context.Context, obj *payment.Payment) (*user.User, error) {
 	loaders := GetLoaders(ctx)
-	thunk := loaders.UserLoader.Load(ctx, obj.UserID)
+	thunk := loaders.UserLoader.Load(ctx, obj.UserId)
 	return thunk()
 }
 
@@ -62,29 +65,29 @@ func (r *queryResolver) PaymentByOrder(ctx context.Context, orderID string) (*pa
 }
 
 // Payments is the resolver for the payments field.
-func (r *queryResolver) Payments(ctx context.Context, limit *int, offset *int) (*payment.PaymentConnection, error) {
-	l := 10
-	o := 0
-	if limit != nil {
-		l = *limit
+func (r *queryResolver) Payments(ctx context.Context, filter *payment.PaymentFilter, sort []*model.PaymentSort, limit *int, offset *int, first *int, after *string, last *int, before *string) (*payment.PaymentConnection, error) {
+	args := common.NewPageArgs(limit, offset, first, after, last, before)
+	for _, s := range sort {
+		args.Sort = append(args.Sort, sortKey(string(s.Field), s.Direction))
 	}
-	if offset != nil {
-		o = *offset
-	}
-	return r.PaymentClient.ListPayments(ctx, l, o)
+	return r.PaymentClient.ListPayments(ctx, filter, args)
 }
 
 // MyPayments is the resolver for the myPayments field.
-func (r *queryResolver) MyPayments(ctx context.Context, limit *int, offset *int) (*payment.PaymentConnection, error) {
-	l := 10
-	o := 0
-	if limit != nil {
-		l = *limit
-	}
-	if offset != nil {
-		o = *offset
+func (r *queryResolver) MyPayments(ctx context.Context, limit *int, offset *int, first *int, after *string, last *int, before *string) (*payment.PaymentConnection, error) {
+	return r.PaymentClient.ListMyPayments(ctx, common.NewPageArgs(limit, offset, first, after, last, before))
+}
+
+// PaymentStatusChanged is the resolver for the paymentStatusChanged field.
+func (r *subscriptionResolver) PaymentStatusChanged(ctx context.Context, paymentID string) (<-chan *payment.PaymentStatusUpdate, error) {
+	claims, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return r.PaymentClient.ListMyPayments(ctx, l, o)
+
+	return r.Events.Payments.Subscribe(ctx, func(u *payment.PaymentStatusUpdate) bool {
+		return u.PaymentID == paymentID && canAccess(claims, u.UserID, policy.PermPaymentsRead)
+	}), nil
 }
 
 // Payment returns generated.PaymentResolver implementation.
@@ -0,0 +1,170 @@
+package graph
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/go-redis/redis/v8"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/microservices-go/shared/logger"
+)
+
+const apqKeyPrefix = "gateway:apq:"
+
+// APQCache stores automatic persisted queries in Redis so every gateway
+// replica can serve a hash registered through any other replica
+type APQCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewAPQCache creates a new Redis-backed persisted query cache
+func NewAPQCache(client *redis.Client, ttl time.Duration) *APQCache {
+	return &APQCache{client: client, ttl: ttl}
+}
+
+// Get looks up a query by its sha256 hash
+func (c *APQCache) Get(ctx context.Context, key string) (string, bool) {
+	query, err := c.client.Get(ctx, apqKeyPrefix+key).Result()
+	if err != nil {
+		if err != redis.Nil {
+			logger.WithContext(ctx).WithError(err).Warn("Failed to read persisted query")
+		}
+		return "", false
+	}
+	return query, true
+}
+
+// Add stores a query under its sha256 hash
+func (c *APQCache) Add(ctx context.Context, key string, query string) {
+	if err := c.client.Set(ctx, apqKeyPrefix+key, query, c.ttl).Err(); err != nil {
+		logger.WithContext(ctx).WithError(err).Warn("Failed to store persisted query")
+	}
+}
+
+// errOperationNotAllowed is the error code for operations missing from the allowlist
+const errOperationNotAllowed = "OPERATION_NOT_ALLOWED"
+
+// manifest is an Apollo-style persisted operation manifest
+type manifest struct {
+	Operations []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Type string `json:"type"`
+		Body string `json:"body"`
+	} `json:"operations"`
+}
+
+// Allowlist only lets operations from a persisted operation manifest through.
+// Clients may send just the operation's sha256 hash in the persistedQuery
+// extension; the body is then filled in from the manifest.
+type Allowlist struct {
+	operations map[string]string // body by hash
+	names      map[string]string // manifest name by hash
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationParameterMutator
+} = &Allowlist{}
+
+// LoadAllowlist reads a manifest file; operation ids must be the sha256 of the body
+func LoadAllowlist(path string) (*Allowlist, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	a := &Allowlist{
+		operations: make(map[string]string, len(m.Operations)),
+		names:      make(map[string]string, len(m.Operations)),
+	}
+	for _, op := range m.Operations {
+		hash := queryHash(op.Body)
+		if op.ID != "" && op.ID != hash {
+			return nil, fmt.Errorf("operation %q: id does not match sha256 of body", op.Name)
+		}
+		a.operations[hash] = op.Body
+		a.names[hash] = op.Name
+	}
+	return a, nil
+}
+
+// OperationName returns the manifest name of an allowlisted query; a nil
+// allowlist allows nothing
+func (a *Allowlist) OperationName(query string) (string, bool) {
+	if a == nil {
+		return "", false
+	}
+	name, ok := a.names[queryHash(query)]
+	return name, ok
+}
+
+// Len returns the number of allowed operations
+func (a *Allowlist) Len() int {
+	return len(a.operations)
+}
+
+// ExtensionName returns the extension name
+func (a *Allowlist) ExtensionName() string {
+	return "OperationAllowlist"
+}
+
+// Validate is a no-op; the manifest is validated when loaded
+func (a *Allowlist) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationParameters resolves hash-only requests and rejects unknown operations
+func (a *Allowlist) MutateOperationParameters(ctx context.Context, params *graphql.RawParams) *gqlerror.Error {
+	if params.Query == "" {
+		hash := persistedQueryHash(params.Extensions)
+		if hash == "" {
+			return nil
+		}
+		body, ok := a.operations[hash]
+		if !ok {
+			return operationNotAllowed()
+		}
+		params.Query = body
+		return nil
+	}
+
+	if _, ok := a.operations[queryHash(params.Query)]; !ok {
+		return operationNotAllowed()
+	}
+	return nil
+}
+
+func operationNotAllowed() *gqlerror.Error {
+	return &gqlerror.Error{
+		Message:    "Operation is not in the allowlist",
+		Extensions: map[string]interface{}{"code": errOperationNotAllowed},
+	}
+}
+
+func persistedQueryHash(extensions map[string]interface{}) string {
+	pq, ok := extensions["persistedQuery"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	hash, _ := pq["sha256Hash"].(string)
+	return hash
+}
+
+func queryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
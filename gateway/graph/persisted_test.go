@@ -0,0 +1,25 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/vektah/gqlparser/v2"
+
+	"github.com/microservices-go/gateway/graph/generated"
+)
+
+// TestManifestOperationsValid fails when an allowlisted operation no longer
+// validates against the schema, which would reject it in production
+func TestManifestOperationsValid(t *testing.T) {
+	allowlist, err := LoadAllowlist("../persisted-operations.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := generated.NewExecutableSchema(generated.Config{Resolvers: &Resolver{}}).Schema()
+
+	for _, body := range allowlist.operations {
+		if _, errs := gqlparser.LoadQuery(schema, body); len(errs) > 0 {
+			t.Errorf("%s: %v", body, errs)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.86
+
+import (
+	"context"
+
+	"github.com/microservices-go/gateway/graph/model"
+	"github.com/microservices-go/gateway/internal/privacy"
+)
+
+// RequestErasure is the resolver for the requestErasure field.
+func (r *mutationResolver) RequestErasure(ctx context.Context, userID string) (*privacy.ErasureRequest, error) {
+	return r.PrivacyClient.RequestErasure(ctx, userID)
+}
+
+// ExportMyData is the resolver for the exportMyData field.
+func (r *queryResolver) ExportMyData(ctx context.Context, format *model.DataExportFormat) (*privacy.DataExport, error) {
+	f := model.DataExportFormatJSON
+	if format != nil {
+		f = *format
+	}
+	return r.PrivacyClient.Export(ctx, string(f))
+}
+
+// ErasureRequest is the resolver for the erasureRequest field.
+func (r *queryResolver) ErasureRequest(ctx context.Context, id string) (*privacy.ErasureRequest, error) {
+	return r.PrivacyClient.GetErasureRequest(ctx, id)
+}
+
+// ErasureRequests is the resolver for the erasureRequests field.
+func (r *queryResolver) ErasureRequests(ctx context.Context, status *string, limit *int, offset *int) ([]*privacy.ErasureRequest, error) {
+	return r.PrivacyClient.ListErasureRequests(ctx, status, limit, offset)
+}
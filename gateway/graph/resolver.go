@@ -1,9 +1,18 @@
 package graph
 
 import (
+	"context"
+	"fmt"
+
+	"github.com/microservices-go/gateway/internal/audit"
+	"github.com/microservices-go/gateway/internal/client"
 	"github.com/microservices-go/gateway/internal/order"
 	"github.com/microservices-go/gateway/internal/payment"
+	"github.com/microservices-go/gateway/internal/privacy"
+	"github.com/microservices-go/gateway/internal/subscription"
 	"github.com/microservices-go/gateway/internal/user"
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/health"
 )
 
 // Resolver is the root resolver
@@ -11,13 +20,83 @@ type Resolver struct {
 	UserClient    *user.Client
 	OrderClient   *order.Client
 	PaymentClient *payment.Client
+	AuditClient   *audit.Client
+	PrivacyClient *privacy.Client
+	Events        *subscription.Broker
 }
 
 // NewResolver creates a new resolver
-func NewResolver(userServiceURL, orderServiceURL, paymentServiceURL string) *Resolver {
+func NewResolver(upstreams *Upstreams, events *subscription.Broker) *Resolver {
 	return &Resolver{
-		UserClient:    user.NewClient(userServiceURL),
-		OrderClient:   order.NewClient(orderServiceURL),
-		PaymentClient: payment.NewClient(paymentServiceURL),
+		UserClient:    user.NewClient(upstreams.User),
+		OrderClient:   order.NewClient(upstreams.Order),
+		PaymentClient: payment.NewClient(upstreams.Payment),
+		AuditClient: audit.NewClient(map[string]*client.Upstream{
+			"USER":    upstreams.User,
+			"ORDER":   upstreams.Order,
+			"PAYMENT": upstreams.Payment,
+		}),
+		PrivacyClient: privacy.NewClient(upstreams.User, upstreams.Order, upstreams.Payment),
+		Events:        events,
+	}
+}
+
+// Upstreams holds the shared gRPC upstream for each downstream service
+type Upstreams struct {
+	User    *client.Upstream
+	Order   *client.Upstream
+	Payment *client.Upstream
+}
+
+// NewUpstreams creates one upstream per downstream service
+func NewUpstreams(userServiceAddr, orderServiceAddr, paymentServiceAddr string, cfg *config.UpstreamConfig, serviceAuth *config.ServiceAuthConfig) (*Upstreams, error) {
+	userUpstream, err := client.NewUpstream("user-service", userServiceAddr, cfg, serviceAuth)
+	if err != nil {
+		return nil, err
+	}
+	orderUpstream, err := client.NewUpstream("order-service", orderServiceAddr, cfg, serviceAuth)
+	if err != nil {
+		return nil, err
+	}
+	paymentUpstream, err := client.NewUpstream("payment-service", paymentServiceAddr, cfg, serviceAuth)
+	if err != nil {
+		return nil, err
+	}
+	return &Upstreams{User: userUpstream, Order: orderUpstream, Payment: paymentUpstream}, nil
+}
+
+// Close closes the connection to every downstream service
+func (u *Upstreams) Close() {
+	for _, up := range []*client.Upstream{u.User, u.Order, u.Payment} {
+		up.Close()
+	}
+}
+
+// RegisterHealthChecks adds a readiness check per downstream service that
+// fails while its breaker is open. The user service is critical, as API keys
+// and sessions are checked against it on every authenticated request; without
+// the order or payment service the gateway still serves the rest of the graph.
+func (u *Upstreams) RegisterHealthChecks(registry *health.Registry) {
+	registry.Register(u.User.Name, breakerCheck(u.User))
+	registry.RegisterOptional(u.Order.Name, breakerCheck(u.Order))
+	registry.RegisterOptional(u.Payment.Name, breakerCheck(u.Payment))
+}
+
+// breakerCheck reports an upstream down while its breaker is open
+func breakerCheck(up *client.Upstream) health.CheckFunc {
+	return func(ctx context.Context) error {
+		if up.BreakerState() == client.BreakerOpen {
+			return fmt.Errorf("circuit breaker open")
+		}
+		return nil
+	}
+}
+
+// BreakerStates returns the circuit breaker state per downstream service
+func (u *Upstreams) BreakerStates() map[string]string {
+	states := make(map[string]string, 3)
+	for _, up := range []*client.Upstream{u.User, u.Order, u.Payment} {
+		states[up.Name] = up.BreakerState()
 	}
+	return states
 }
@@ -0,0 +1,157 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/rabbitmq"
+)
+
+const (
+	responseKeyPrefix = "gateway:response:"
+	responseTagPrefix = "gateway:response:tag:"
+)
+
+// ResponseCache stores full GraphQL query responses in Redis. Every entry is
+// indexed under tags naming the types it contains, so domain events can drop
+// the entries they make stale.
+type ResponseCache struct {
+	client *redis.Client
+	maxTTL time.Duration
+}
+
+// NewResponseCache creates a new Redis-backed response cache; maxTTL caps
+// the maxAge of any entry
+func NewResponseCache(client *redis.Client, maxTTL time.Duration) *ResponseCache {
+	return &ResponseCache{client: client, maxTTL: maxTTL}
+}
+
+// Get returns a cached response body
+func (c *ResponseCache) Get(ctx context.Context, key string) (json.RawMessage, bool) {
+	data, err := c.client.Get(ctx, responseKeyPrefix+key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			logger.WithContext(ctx).WithError(err).Warn("Failed to read cached response")
+		}
+		return nil, false
+	}
+	return data, true
+}
+
+// Set stores a response body and indexes it under tags
+func (c *ResponseCache) Set(ctx context.Context, key string, data json.RawMessage, ttl time.Duration, tags []string) {
+	if ttl > c.maxTTL {
+		ttl = c.maxTTL
+	}
+
+	pipe := c.client.TxPipeline()
+	pipe.Set(ctx, responseKeyPrefix+key, []byte(data), ttl)
+	for _, tag := range tags {
+		pipe.SAdd(ctx, responseTagPrefix+tag, key)
+		pipe.Expire(ctx, responseTagPrefix+tag, c.maxTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.WithContext(ctx).WithError(err).Warn("Failed to store cached response")
+	}
+}
+
+// Invalidate deletes every response indexed under the given tags
+func (c *ResponseCache) Invalidate(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		keys, err := c.client.SMembers(ctx, responseTagPrefix+tag).Result()
+		if err != nil {
+			return err
+		}
+
+		del := make([]string, 0, len(keys)+1)
+		for _, key := range keys {
+			del = append(del, responseKeyPrefix+key)
+		}
+		del = append(del, responseTagPrefix+tag)
+		if err := c.client.Del(ctx, del...).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventsExchange is the topic exchange all services publish domain events to
+const eventsExchange = "microservices.events"
+
+// invalidatingEvents maps the events each service publishes onto the GraphQL
+// types whose cached responses they make stale
+var invalidatingEvents = []struct {
+	service string
+	event   string
+	typ     string
+}{
+	{"user-service", rabbitmq.EventUserCreated, "User"},
+	{"user-service", rabbitmq.EventUserUpdated, "User"},
+	{"user-service", rabbitmq.EventUserDeleted, "User"},
+	{"user-service", rabbitmq.EventUserMFAChanged, "User"},
+	{"user-service", rabbitmq.EventUserErasureRequested, "User"},
+	{"order-service", rabbitmq.EventOrderCreated, "Order"},
+	{"order-service", rabbitmq.EventOrderUpdated, "Order"},
+	{"order-service", rabbitmq.EventUserErased, "Order"},
+	{"payment-service", rabbitmq.EventPaymentCreated, "Payment"},
+	{"payment-service", rabbitmq.EventPaymentSuccess, "Payment"},
+	{"payment-service", rabbitmq.EventPaymentFailed, "Payment"},
+	{"payment-service", rabbitmq.EventPaymentRefunded, "Payment"},
+	{"payment-service", rabbitmq.EventUserErased, "Payment"},
+}
+
+// StartInvalidation consumes domain events on an exclusive per-instance queue
+// and drops the cached responses they affect
+func (c *ResponseCache) StartInvalidation(client *rabbitmq.Client) error {
+	queue, err := client.DeclareTemporaryQueue()
+	if err != nil {
+		return err
+	}
+
+	consumer := rabbitmq.NewConsumer(client)
+	for _, e := range invalidatingEvents {
+		if err := client.BindQueue(queue.Name, eventsExchange, e.service+"."+e.event); err != nil {
+			return err
+		}
+		consumer.RegisterHandler(e.event, c.invalidateEvent)
+	}
+
+	return consumer.Start(queue.Name)
+}
+
+// invalidatedTypes returns the types an event makes stale. Handlers are
+// registered by event type, and user.erased comes from more than one service.
+func invalidatedTypes(event *rabbitmq.Event) []string {
+	var types []string
+	for _, e := range invalidatingEvents {
+		if e.service == event.Service && e.event == event.Type {
+			types = append(types, e.typ)
+		}
+	}
+	return types
+}
+
+// invalidateEvent drops the shared entries of the types an event makes stale
+// and the per-user entries of the user named in the event
+func (c *ResponseCache) invalidateEvent(ctx context.Context, event *rabbitmq.Event) error {
+	var payload struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		logger.WithContext(ctx).WithError(err).Warnf("Dropping malformed %s event", event.Type)
+		return nil
+	}
+
+	var tags []string
+	for _, typ := range invalidatedTypes(event) {
+		tags = append(tags, typ)
+		if payload.UserID != "" {
+			tags = append(tags, typ+":"+payload.UserID)
+		}
+	}
+	return c.Invalidate(ctx, tags...)
+}
@@ -0,0 +1,33 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/microservices-go/shared/rabbitmq"
+)
+
+func TestInvalidatedTypes(t *testing.T) {
+	tests := []struct {
+		service string
+		event   string
+		want    []string
+	}{
+		{"payment-service", rabbitmq.EventPaymentRefunded, []string{"Payment"}},
+		{"payment-service", rabbitmq.EventPaymentCreated, []string{"Payment"}},
+		{"user-service", rabbitmq.EventUserMFAChanged, []string{"User"}},
+		{"user-service", rabbitmq.EventUserErasureRequested, []string{"User"}},
+		{"order-service", rabbitmq.EventUserErased, []string{"Order"}},
+		{"payment-service", rabbitmq.EventUserErased, []string{"Payment"}},
+		{"order-service", rabbitmq.EventPaymentRefunded, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.service+"."+tt.event, func(t *testing.T) {
+			got := invalidatedTypes(&rabbitmq.Event{Service: tt.service, Type: tt.event})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
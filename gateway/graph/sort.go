@@ -0,0 +1,17 @@
+package graph
+
+import (
+	"strings"
+
+	"github.com/microservices-go/gateway/graph/model"
+)
+
+// sortKey converts a GraphQL sort field and direction into the services'
+// "field" / "-field" sort key, e.g. TOTAL_AMOUNT DESC -> "-total_amount"
+func sortKey(field string, direction *model.SortDirection) string {
+	key := strings.ToLower(field)
+	if direction == nil || *direction == model.SortDirectionDesc {
+		return "-" + key
+	}
+	return key
+}
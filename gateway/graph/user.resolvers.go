@@ -9,7 +9,10 @@ import (
 	"context"
 
 	"github.com/microservices-go/gateway/graph/generated"
+	"github.com/microservices-go/gateway/graph/model"
+	"github.com/microservices-go/gateway/internal/common"
 	"github.com/microservices-go/gateway/internal/order"
+	"github.com/microservices-go/gateway/internal/payment"
 	"github.com/microservices-go/gateway/internal/user"
 )
 
@@ -23,6 +26,21 @@ func (r *mutationResolver) DeleteUser(ctx context.Context, id string) (bool, err
 	return r.UserClient.DeleteUser(ctx, id)
 }
 
+// AssignRole is the resolver for the assignRole field.
+func (r *mutationResolver) AssignRole(ctx context.Context, userID string, role string) (*user.User, error) {
+	return r.UserClient.AssignRole(ctx, userID, role)
+}
+
+// RevokeRole is the resolver for the revokeRole field.
+func (r *mutationResolver) RevokeRole(ctx context.Context, userID string, role string) (*user.User, error) {
+	return r.UserClient.RevokeRole(ctx, userID, role)
+}
+
+// SetRoleMfaRequired is the resolver for the setRoleMfaRequired field.
+func (r *mutationResolver) SetRoleMfaRequired(ctx context.Context, role string, required bool) (*user.Role, error) {
+	return r.UserClient.SetRoleMFARequired(ctx, role, required)
+}
+
 // Me is the resolver for the me field.
 func (r *queryResolver) Me(ctx context.Context) (*user.User, error) {
 	return r.UserClient.Me(ctx)
@@ -36,31 +54,26 @@ func (r *queryResolver) User(ctx context.Context, id string) (*user.User, error)
 }
 
 // Users is the resolver for the users field.
-func (r *queryResolver) Users(ctx context.Context, limit *int, offset *int) (*user.UserConnection, error) {
-	l := 10
-	o := 0
-	if limit != nil {
-		l = *limit
+func (r *queryResolver) Users(ctx context.Context, filter *user.UserFilter, sort []*model.UserSort, limit *int, offset *int, first *int, after *string, last *int, before *string) (*user.UserConnection, error) {
+	args := common.NewPageArgs(limit, offset, first, after, last, before)
+	for _, s := range sort {
+		args.Sort = append(args.Sort, sortKey(string(s.Field), s.Direction))
 	}
-	if offset != nil {
-		o = *offset
-	}
-	return r.UserClient.ListUsers(ctx, l, o)
+	return r.UserClient.ListUsers(ctx, filter, args)
 }
 
 // Orders is the resolver for the orders field.
 func (r *userResolver) Orders(ctx context.Context, obj *user.User, limit *int, offset *int) ([]*order.Order, error) {
-	l := 10
-	o := 0
-	if limit != nil {
-		l = *limit
-	}
-	if offset != nil {
-		o = *offset
-	}
+	args := common.NewPageArgs(limit, offset, nil, nil, nil, nil)
+	thunk := GetLoaders(ctx).OrdersByUserLoader.Load(ctx, common.UserPageKey{UserID: obj.Id, Limit: args.Limit, Offset: args.Offset})
+	return thunk()
+}
 
-	// Use OrderClient directly for list queries (DataLoader is for single entity lookups)
-	return r.OrderClient.GetOrdersByUser(ctx, obj.ID, l, o)
+// Payments is the resolver for the payments field.
+func (r *userResolver) Payments(ctx context.Context, obj *user.User, limit *int, offset *int) ([]*payment.Payment, error) {
+	args := common.NewPageArgs(limit, offset, nil, nil, nil, nil)
+	thunk := GetLoaders(ctx).PaymentsByUserLoader.Load(ctx, common.UserPageKey{UserID: obj.Id, Limit: args.Limit, Offset: args.Offset})
+	return thunk()
 }
 
 // User returns generated.UserResolver implementation.
@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/microservices-go/gateway/internal/client"
+	"github.com/microservices-go/gateway/internal/common"
+	"github.com/microservices-go/shared/errors"
+	auditv1 "github.com/microservices-go/shared/proto/audit/v1"
+)
+
+// Client calls the audit log of every service over gRPC
+type Client struct {
+	rpc map[string]auditv1.AuditServiceClient
+}
+
+// NewClient creates an audit client on top of the shared upstreams, keyed
+// by the values of the AuditService enum
+func NewClient(upstreams map[string]*client.Upstream) *Client {
+	rpc := make(map[string]auditv1.AuditServiceClient, len(upstreams))
+	for service, upstream := range upstreams {
+		rpc[service] = auditv1.NewAuditServiceClient(upstream.Conn)
+	}
+	return &Client{rpc: rpc}
+}
+
+func (c *Client) ListEntries(ctx context.Context, service string, filter *Filter, limit, offset *int) ([]*Entry, error) {
+	rpc, err := c.service(service)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &auditv1.ListEntriesRequest{}
+	if filter != nil {
+		req.ActorId = deref(filter.ActorID)
+		req.Action = deref(filter.Action)
+		req.TargetType = deref(filter.TargetType)
+		req.TargetId = deref(filter.TargetID)
+		req.Since = common.ProtoTime(filter.Since)
+		req.Until = common.ProtoTime(filter.Until)
+	}
+	if limit != nil {
+		req.Limit = int32(*limit)
+	}
+	if offset != nil {
+		req.Offset = int32(*offset)
+	}
+
+	resp, err := rpc.ListEntries(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*Entry, len(resp.Entries))
+	for i, entry := range resp.Entries {
+		entries[i] = newEntry(entry)
+	}
+	return entries, nil
+}
+
+func (c *Client) VerifyChain(ctx context.Context, service string) (*Verification, error) {
+	rpc, err := c.service(service)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := rpc.VerifyChain(ctx, &auditv1.VerifyChainRequest{})
+	if err != nil {
+		return nil, err
+	}
+	verification := &Verification{Valid: resp.Valid, Checked: resp.Checked, Reason: resp.Reason}
+	if !resp.Valid {
+		verification.BrokenAt = &resp.BrokenAt
+	}
+	return verification, nil
+}
+
+// service returns the audit client of a service
+func (c *Client) service(name string) (auditv1.AuditServiceClient, error) {
+	rpc, ok := c.rpc[name]
+	if !ok {
+		return nil, errors.New(errors.ErrInvalidInput, "Unknown service: "+name)
+	}
+	return rpc, nil
+}
+
+// deref returns *s, or the empty string for nil
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
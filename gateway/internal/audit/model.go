@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"sort"
+	"time"
+
+	auditv1 "github.com/microservices-go/shared/proto/audit/v1"
+)
+
+// Entry represents one action in a service's audit log
+type Entry struct {
+	ID         int64       `json:"id"`
+	ActorType  string      `json:"actorType"`
+	ActorID    string      `json:"actorID"`
+	ActorRole  string      `json:"actorRole"`
+	Action     string      `json:"action"`
+	TargetType string      `json:"targetType"`
+	TargetID   string      `json:"targetID"`
+	Changes    []*Change   `json:"changes"`
+	Metadata   []*Metadata `json:"metadata"`
+	RequestID  string      `json:"requestID"`
+	IPAddress  string      `json:"ipAddress"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	PrevHash   string      `json:"prevHash"`
+	Hash       string      `json:"hash"`
+}
+
+// Change represents how a field changed; From and To hold JSON values
+type Change struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// Metadata represents one piece of context of an entry
+type Metadata struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Verification represents the outcome of checking a hash chain
+type Verification struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`
+	BrokenAt *int64 `json:"brokenAt"`
+	Reason   string `json:"reason"`
+}
+
+// Filter represents the filters of an audit log listing
+type Filter struct {
+	ActorID    *string    `json:"actorID"`
+	Action     *string    `json:"action"`
+	TargetType *string    `json:"targetType"`
+	TargetID   *string    `json:"targetID"`
+	Since      *time.Time `json:"since"`
+	Until      *time.Time `json:"until"`
+}
+
+// newEntry converts a service audit entry
+func newEntry(entry *auditv1.Entry) *Entry {
+	e := &Entry{
+		ID:         entry.Id,
+		ActorType:  entry.ActorType,
+		ActorID:    entry.ActorId,
+		ActorRole:  entry.ActorRole,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetId,
+		Changes:    make([]*Change, len(entry.Changes)),
+		RequestID:  entry.RequestId,
+		IPAddress:  entry.IpAddress,
+		CreatedAt:  entry.CreatedAt.AsTime(),
+		PrevHash:   entry.PrevHash,
+		Hash:       entry.Hash,
+	}
+	for i, change := range entry.Changes {
+		e.Changes[i] = &Change{Field: change.Field, From: change.From, To: change.To}
+	}
+	for key, value := range entry.Metadata {
+		e.Metadata = append(e.Metadata, &Metadata{Key: key, Value: value})
+	}
+	sort.Slice(e.Metadata, func(i, j int) bool { return e.Metadata[i].Key < e.Metadata[j].Key })
+	if e.Metadata == nil {
+		e.Metadata = []*Metadata{}
+	}
+	return e
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+
+	// Steps: a = Allow (want the result), s = Success, f = Failure, x = Abort,
+	// w = wait out the cooldown; state is checked after every step
+	tests := []struct {
+		name  string
+		steps string
+		allow []bool
+		state string
+	}{
+		{"closed below threshold", "afaf", []bool{true, true}, BreakerClosed},
+		{"opens at threshold", "afafaf", []bool{true, true, true}, BreakerOpen},
+		{"success resets the count", "afafasafaf", []bool{true, true, true, true, true}, BreakerClosed},
+		{"open rejects", "afafafa", []bool{true, true, true, false}, BreakerOpen},
+		{"half-open after cooldown", "afafafw", []bool{true, true, true}, BreakerHalfOpen},
+		{"one probe at a time", "afafafwaa", []bool{true, true, true, true, false}, BreakerHalfOpen},
+		{"probe success closes", "afafafwasa", []bool{true, true, true, true, true}, BreakerClosed},
+		{"probe failure reopens", "afafafwafa", []bool{true, true, true, true, false}, BreakerOpen},
+		{"aborted probe lets another through", "afafafwaxa", []bool{true, true, true, true, true}, BreakerHalfOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewCircuitBreaker(3, cooldown)
+			allows := 0
+			for _, step := range tt.steps {
+				switch step {
+				case 'a':
+					if got := b.Allow(); got != tt.allow[allows] {
+						t.Fatalf("Allow #%d = %v, want %v", allows+1, got, tt.allow[allows])
+					}
+					allows++
+				case 's':
+					b.Success()
+				case 'f':
+					b.Failure()
+				case 'x':
+					b.Abort()
+				case 'w':
+					time.Sleep(cooldown + 5*time.Millisecond)
+				}
+			}
+			if got := b.State(); got != tt.state {
+				t.Errorf("State = %s, want %s", got, tt.state)
+			}
+		})
+	}
+}
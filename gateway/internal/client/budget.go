@@ -0,0 +1,42 @@
+package client
+
+import "sync"
+
+// maxBudgetTokens bounds how many retries and hedges a quiet upstream may
+// save up for a burst of failures
+const maxBudgetTokens = 10
+
+// retryBudget caps retries and hedges to a share of requests, so that an
+// upstream in trouble does not get its load multiplied. Every request earns
+// ratio tokens and every retry or hedge spends one.
+type retryBudget struct {
+	mu     sync.Mutex
+	tokens float64
+	ratio  float64
+}
+
+// newRetryBudget creates a full budget allowing percent retries and hedges
+// per 100 requests
+func newRetryBudget(percent int) *retryBudget {
+	return &retryBudget{tokens: maxBudgetTokens, ratio: float64(percent) / 100}
+}
+
+// deposit records a request
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.tokens+b.ratio, maxBudgetTokens)
+}
+
+// withdraw reports whether a retry or hedge may be sent, spending a token
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,400 @@
+package client
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	gatewayMiddleware "github.com/microservices-go/gateway/middleware"
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/metrics"
+	"github.com/microservices-go/shared/middleware"
+	"github.com/microservices-go/shared/rpc"
+	"github.com/microservices-go/shared/tracing"
+)
+
+// Upstream is the shared gRPC connection, retry policy and circuit breaker
+// for one downstream service. Create one per service and share it between
+// the client and the dataloaders.
+//
+// Every call carries the caller's token and trace context in metadata and is
+// bounded by the per-attempt timeout and the caller's deadline. Internal
+// methods (BatchGet) carry a gateway service token instead, with the caller
+// as its on-behalf-of user. Lookups (Get, List and BatchGet methods) are
+// retried, and unary ones hedged: sent again in parallel when the first
+// reply is slow. Retries and hedges share a budget. Errors come back as
+// AppErrors.
+type Upstream struct {
+	Name        string
+	Addr        string
+	Conn        *grpc.ClientConn
+	breaker     *CircuitBreaker
+	budget      *retryBudget
+	cfg         *config.UpstreamConfig
+	serviceAuth *config.ServiceAuthConfig
+}
+
+// NewUpstream creates a new upstream; the connection is established lazily
+func NewUpstream(name, addr string, cfg *config.UpstreamConfig, serviceAuth *config.ServiceAuthConfig) (*Upstream, error) {
+	u := &Upstream{
+		Name:        name,
+		Addr:        addr,
+		breaker:     NewCircuitBreaker(cfg.BreakerThreshold, time.Duration(cfg.BreakerCooldown)*time.Second),
+		budget:      newRetryBudget(cfg.RetryBudget),
+		cfg:         cfg,
+		serviceAuth: serviceAuth,
+	}
+
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(u.unary),
+		grpc.WithChainStreamInterceptor(u.stream),
+	)
+	if err != nil {
+		return nil, err
+	}
+	u.Conn = conn
+	return u, nil
+}
+
+// Close closes the connection
+func (u *Upstream) Close() error {
+	return u.Conn.Close()
+}
+
+// BreakerState returns the circuit breaker state (closed, open, half-open)
+func (u *Upstream) BreakerState() string {
+	return u.breaker.State()
+}
+
+// Receive runs a server-streaming lookup and passes every message to fn as
+// it arrives. The call is retried like unary lookups as long as nothing has
+// been received yet.
+func Receive[T any](ctx context.Context, u *Upstream, call func(context.Context) (grpc.ServerStreamingClient[T], error), fn func(*T)) error {
+	u.budget.deposit()
+
+	var lastErr error
+	for attempt := 0; attempt <= u.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if !u.budget.withdraw() {
+				break
+			}
+			if err := sleep(ctx, u.backoff(attempt)); err != nil {
+				return u.appError(err)
+			}
+			logger.WithContext(ctx).Warnf("Retrying %s stream (attempt %d)", u.Name, attempt+1)
+		}
+
+		received := false
+		err := func() error {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			stream, err := call(ctx)
+			if err != nil {
+				return err
+			}
+			for {
+				msg, err := stream.Recv()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				received = true
+				fn(msg)
+			}
+		}()
+		if err == nil || received || ctx.Err() != nil || !retryable(err) {
+			return u.appError(err)
+		}
+		lastErr = err
+	}
+	return u.appError(lastErr)
+}
+
+func (u *Upstream) unary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	maxAttempts := 1
+	if isLookup(method) {
+		maxAttempts += u.cfg.MaxRetries
+	}
+	u.budget.deposit()
+
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			if !u.budget.withdraw() {
+				break
+			}
+			if err := sleep(ctx, u.backoff(attempt)); err != nil {
+				return u.appError(err)
+			}
+			logger.WithContext(ctx).Warnf("Retrying %s (attempt %d)", method, attempt+1)
+		}
+
+		if !u.breaker.Allow() {
+			return u.unavailable()
+		}
+
+		call := func(ctx context.Context, reply interface{}) error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		if msg, ok := reply.(proto.Message); ok && isLookup(method) && u.cfg.HedgeDelay > 0 {
+			err = u.hedge(ctx, method, msg, call)
+		} else {
+			err = u.attempt(ctx, method, func(ctx context.Context) error { return call(ctx, reply) })
+		}
+		if err == nil || ctx.Err() != nil || !retryable(err) {
+			break
+		}
+	}
+	return u.appError(err)
+}
+
+// attempt makes one unary call inside a client span, bounded by the per-attempt timeout
+func (u *Upstream) attempt(ctx context.Context, method string, call func(context.Context) error) error {
+	callCtx, cancel := context.WithTimeout(ctx, time.Duration(u.cfg.RequestTimeout)*time.Millisecond)
+	defer cancel()
+
+	callCtx, span := u.startSpan(callCtx, method)
+	err := call(callCtx)
+	u.finish(ctx, span, method, err)
+	return err
+}
+
+// hedge sends a lookup and, when no reply has come after the hedge delay,
+// a second copy of it. The first reply wins and the other call is cancelled.
+func (u *Upstream) hedge(ctx context.Context, method string, reply proto.Message, call func(context.Context, interface{}) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		reply proto.Message
+		err   error
+	}
+	results := make(chan result, 2)
+	send := func() {
+		r := reply.ProtoReflect().New().Interface()
+		go func() {
+			results <- result{r, u.attempt(ctx, method, func(ctx context.Context) error { return call(ctx, r) })}
+		}()
+	}
+
+	send()
+	pending := 1
+	timer := time.NewTimer(time.Duration(u.cfg.HedgeDelay) * time.Millisecond)
+	defer timer.Stop()
+
+	var err error
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if u.budget.withdraw() && u.breaker.Allow() {
+				logger.WithContext(ctx).Debugf("Hedging %s", method)
+				send()
+				pending++
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				proto.Reset(reply)
+				proto.Merge(reply, res.reply)
+				return nil
+			}
+			// An answer from a healthy upstream needs no other copy
+			if !retryable(res.err) {
+				return res.err
+			}
+			err = res.err
+		}
+	}
+	return err
+}
+
+func (u *Upstream) stream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if !u.breaker.Allow() {
+		return nil, u.unavailable()
+	}
+
+	// The timeout covers the whole stream and is released when it ends
+	callCtx, cancel := context.WithTimeout(ctx, time.Duration(u.cfg.RequestTimeout)*time.Millisecond)
+	callCtx, span := u.startSpan(callCtx, method)
+
+	cs, err := streamer(callCtx, desc, cc, method, opts...)
+	if err != nil {
+		u.finish(ctx, span, method, err)
+		cancel()
+		return nil, err
+	}
+	return &clientStream{ClientStream: cs, done: func(err error) {
+		u.finish(ctx, span, method, err)
+		cancel()
+	}}, nil
+}
+
+// startSpan adds the auth and trace metadata and opens a client span
+func (u *Upstream) startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	ctx, span := tracing.StartSpan(ctx, method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.RPCSystemGRPC, semconv.RPCService(u.Name), semconv.PeerService(u.Name)),
+	)
+
+	authHeader := GetAuthHeader(ctx)
+	if isInternal(method) {
+		authHeader = u.serviceToken(ctx)
+	}
+	return rpc.OutgoingContext(ctx, authHeader), span
+}
+
+// serviceToken signs a service token for this upstream on behalf of the
+// caller in ctx. On failure the call goes out without a token and is
+// rejected upstream.
+func (u *Upstream) serviceToken(ctx context.Context) string {
+	var onBehalfOf *middleware.UserClaims
+	if claims, ok := gatewayMiddleware.GetUserClaims(ctx); ok {
+		onBehalfOf = &middleware.UserClaims{
+			UserID:      claims.UserID,
+			Email:       claims.Email,
+			Role:        claims.Role,
+			Permissions: claims.Permissions,
+			APIKeyID:    claims.APIKeyID,
+			Scopes:      claims.Scopes,
+			RateLimit:   claims.RateLimit,
+			SessionID:   claims.SessionID,
+		}
+	}
+
+	token, err := middleware.GenerateServiceToken(u.Name, onBehalfOf, u.serviceAuth)
+	if err != nil {
+		logger.WithContext(ctx).WithError(err).Error("Failed to sign service token")
+		return ""
+	}
+	return "Bearer " + token
+}
+
+// finish records the outcome of a call made on behalf of ctx in the breaker,
+// metrics and span. Only transport failures and timeouts count against the
+// breaker; error replies from a healthy service do not.
+func (u *Upstream) finish(ctx context.Context, span trace.Span, method string, err error) {
+	defer span.End()
+
+	code := status.Code(err)
+	metrics.GRPCClientRequestsTotal.WithLabelValues(u.Name, method, code.String()).Inc()
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+
+	switch {
+	case err == nil || !retryable(err):
+		u.breaker.Success()
+	case ctx.Err() != nil:
+		// Caller gave up; not the upstream's fault
+		u.breaker.Abort()
+	default:
+		u.breaker.Failure()
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// appError converts a call error into an AppError attributed to this upstream
+func (u *Upstream) appError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if stderrors.Is(err, context.DeadlineExceeded) || stderrors.Is(err, context.Canceled) {
+		return errors.Wrap(err, errors.ErrServiceUnavailable, u.Name+" request failed").WithService(u.Name)
+	}
+	return rpc.FromStatus(err, u.Name)
+}
+
+func (u *Upstream) unavailable() error {
+	return errors.New(errors.ErrServiceUnavailable, u.Name+" is temporarily unavailable").WithService(u.Name)
+}
+
+// backoff returns a full-jitter exponential delay for the given retry attempt
+func (u *Upstream) backoff(attempt int) time.Duration {
+	base := time.Duration(u.cfg.RetryBackoffBase) * time.Millisecond
+	limit := time.Duration(u.cfg.RetryBackoffMax) * time.Millisecond
+
+	ceiling := base << (attempt - 1)
+	if ceiling <= 0 || ceiling > limit {
+		ceiling = limit
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(ceiling)))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isLookup reports whether a full method name such as
+// /order.v1.OrderService/GetOrder is a read that is safe to retry
+func isLookup(method string) bool {
+	name := method[strings.LastIndex(method, "/")+1:]
+	return strings.HasPrefix(name, "Get") || strings.HasPrefix(name, "List") || strings.HasPrefix(name, "BatchGet")
+}
+
+// isInternal reports whether a full method name is an internal-only method
+// that takes service tokens, such as the dataloaders' BatchGet streams
+func isInternal(method string) bool {
+	return strings.HasPrefix(method[strings.LastIndex(method, "/")+1:], "BatchGet")
+}
+
+// retryable reports whether an error means the upstream could not serve the call
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case grpccodes.Unavailable, grpccodes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// clientStream reports the end of a stream once, on EOF or the first error
+type clientStream struct {
+	grpc.ClientStream
+	once sync.Once
+	done func(error)
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == io.EOF {
+		s.once.Do(func() { s.done(nil) })
+	} else if err != nil {
+		s.once.Do(func() { s.done(err) })
+	}
+	return err
+}
+
+// GetAuthHeader retrieves the caller's Authorization header from context
+func GetAuthHeader(ctx context.Context) string {
+	if auth, ok := ctx.Value("Authorization").(string); ok {
+		return auth
+	}
+	return ""
+}
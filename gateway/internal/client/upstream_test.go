@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/microservices-go/shared/config"
+)
+
+func newTestUpstream(cfg config.UpstreamConfig) *Upstream {
+	return &Upstream{
+		Name:    "test",
+		breaker: NewCircuitBreaker(100, time.Minute),
+		budget:  newRetryBudget(cfg.RetryBudget),
+		cfg:     &cfg,
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	b := newRetryBudget(50)
+	for i := 0; i < maxBudgetTokens; i++ {
+		if !b.withdraw() {
+			t.Fatalf("withdraw #%d refused from a full budget", i+1)
+		}
+	}
+	if b.withdraw() {
+		t.Fatal("withdraw allowed from an empty budget")
+	}
+
+	// At 50% two requests earn a retry
+	b.deposit()
+	if b.withdraw() {
+		t.Fatal("retry allowed after one request")
+	}
+	b.deposit()
+	b.deposit()
+	if !b.withdraw() {
+		t.Fatal("retry refused after two requests")
+	}
+
+	for i := 0; i < 100; i++ {
+		b.deposit()
+	}
+	if b.tokens != maxBudgetTokens {
+		t.Errorf("tokens = %v, want the cap %d", b.tokens, maxBudgetTokens)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	u := newTestUpstream(config.UpstreamConfig{RetryBackoffBase: 10, RetryBackoffMax: 50})
+	tests := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{1, 10 * time.Millisecond},
+		{2, 20 * time.Millisecond},
+		{3, 40 * time.Millisecond},
+		{4, 50 * time.Millisecond},
+		{60, 50 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		var longest time.Duration
+		for i := 0; i < 200; i++ {
+			d := u.backoff(tt.attempt)
+			if d < 0 || d >= tt.ceiling {
+				t.Fatalf("backoff(%d) = %v, want in [0, %v)", tt.attempt, d, tt.ceiling)
+			}
+			longest = max(longest, d)
+		}
+		if longest < tt.ceiling/2 {
+			t.Errorf("backoff(%d) never above %v; jitter does not span the ceiling %v", tt.attempt, longest, tt.ceiling)
+		}
+	}
+
+	if d := newTestUpstream(config.UpstreamConfig{}).backoff(1); d != 0 {
+		t.Errorf("backoff without a base = %v, want 0", d)
+	}
+}
+
+// failingInvoker fails the first failures calls with code, then succeeds
+func failingInvoker(calls *atomic.Int32, failures int32, code grpccodes.Code) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		if calls.Add(1) <= failures {
+			return status.Error(code, "failed")
+		}
+		reply.(*wrapperspb.StringValue).Value = "ok"
+		return nil
+	}
+}
+
+func TestUnaryRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		failures  int32
+		code      grpccodes.Code
+		budget    int
+		wantCalls int32
+		wantErr   bool
+	}{
+		{"lookup recovers", "/test.v1.TestService/GetThing", 2, grpccodes.Unavailable, 20, 3, false},
+		{"lookup gives up after max retries", "/test.v1.TestService/GetThing", 5, grpccodes.Unavailable, 20, 3, true},
+		{"timeouts are retried", "/test.v1.TestService/ListThings", 1, grpccodes.DeadlineExceeded, 20, 2, false},
+		{"replies are not retried", "/test.v1.TestService/GetThing", 1, grpccodes.NotFound, 20, 1, true},
+		{"writes are not retried", "/test.v1.TestService/CreateThing", 1, grpccodes.Unavailable, 20, 1, true},
+		{"empty budget stops retries", "/test.v1.TestService/GetThing", 1, grpccodes.Unavailable, 0, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newTestUpstream(config.UpstreamConfig{RequestTimeout: 1000, MaxRetries: 2, RetryBudget: tt.budget})
+			if tt.budget == 0 {
+				u.budget.tokens = 0
+			}
+
+			var calls atomic.Int32
+			reply := &wrapperspb.StringValue{}
+			err := u.unary(context.Background(), tt.method, &wrapperspb.StringValue{}, reply, nil, failingInvoker(&calls, tt.failures, tt.code))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+			if !tt.wantErr && reply.Value != "ok" {
+				t.Errorf("reply = %q", reply.Value)
+			}
+		})
+	}
+}
+
+func TestUnaryHedging(t *testing.T) {
+	// The first call hangs until cancelled; later ones answer at once
+	hangingFirst := func(calls *atomic.Int32) grpc.UnaryInvoker {
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			n := calls.Add(1)
+			if n == 1 {
+				<-ctx.Done()
+				return status.FromContextError(ctx.Err()).Err()
+			}
+			reply.(*wrapperspb.StringValue).Value = "hedge"
+			return nil
+		}
+	}
+
+	tests := []struct {
+		name      string
+		method    string
+		budget    float64
+		wantCalls int32
+		wantReply string
+	}{
+		{"slow lookup is hedged", "/test.v1.TestService/GetThing", maxBudgetTokens, 2, "hedge"},
+		{"hedges need budget", "/test.v1.TestService/GetThing", 0, 1, ""},
+		{"writes are not hedged", "/test.v1.TestService/CreateThing", maxBudgetTokens, 1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newTestUpstream(config.UpstreamConfig{RequestTimeout: 200, HedgeDelay: 10, RetryBudget: 20})
+			u.budget.tokens = tt.budget
+
+			var calls atomic.Int32
+			reply := &wrapperspb.StringValue{}
+			err := u.unary(context.Background(), tt.method, &wrapperspb.StringValue{}, reply, nil, hangingFirst(&calls))
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+			if reply.Value != tt.wantReply {
+				t.Errorf("reply = %q, want %q", reply.Value, tt.wantReply)
+			}
+			if (err == nil) != (tt.wantReply != "") {
+				t.Errorf("err = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package common
+
+// UserPageKey identifies one page of a user's resources in a by-user loader
+type UserPageKey struct {
+	UserID string
+	Limit  int
+	Offset int
+}
+
+// UserPage is the page a by-user batch request fetches for every user in it
+type UserPage struct {
+	Limit  int
+	Offset int
+}
+
+// GroupUserPages groups loader keys by page, so each distinct page is fetched
+// with one batch request for all of its users
+func GroupUserPages(keys []UserPageKey) map[UserPage][]string {
+	groups := make(map[UserPage][]string)
+	seen := make(map[UserPageKey]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		page := UserPage{Limit: key.Limit, Offset: key.Offset}
+		groups[page] = append(groups[page], key.UserID)
+	}
+	return groups
+}
@@ -2,8 +2,12 @@ package common
 
 // PageInfo represents pagination info in GraphQL
 type PageInfo struct {
-	Total   int  `json:"total"`
-	Limit   int  `json:"limit"`
-	Offset  int  `json:"offset"`
-	HasMore bool `json:"hasMore"`
+	Total           int     `json:"total"`
+	Limit           int     `json:"limit"`
+	Offset          int     `json:"offset"`
+	HasMore         bool    `json:"hasMore"`
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor"`
+	EndCursor       *string `json:"endCursor"`
 }
@@ -0,0 +1,91 @@
+package common
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/microservices-go/shared/pagination"
+	commonv1 "github.com/microservices-go/shared/proto/common/v1"
+)
+
+// PageArgs holds the offset, Relay cursor and sort arguments of a list query
+type PageArgs struct {
+	Limit  int
+	Offset int
+	First  *int
+	After  *string
+	Last   *int
+	Before *string
+
+	// Sort holds service sort keys such as "created_at" or "-amount"
+	Sort []string
+}
+
+// NewPageArgs creates page arguments from optional GraphQL arguments
+func NewPageArgs(limit, offset, first *int, after *string, last *int, before *string) PageArgs {
+	args := PageArgs{Limit: pagination.DefaultSize, First: first, After: after, Last: last, Before: before}
+	if limit != nil {
+		args.Limit = *limit
+	}
+	if offset != nil {
+		args.Offset = *offset
+	}
+	return args
+}
+
+// IsCursor reports whether any cursor argument was given
+func (a PageArgs) IsCursor() bool {
+	return a.First != nil || a.After != nil || a.Last != nil || a.Before != nil
+}
+
+// Request converts the arguments into a service page request; cursor
+// arguments take precedence over limit/offset
+func (a PageArgs) Request() *commonv1.PageRequest {
+	page := &commonv1.PageRequest{Sort: a.Sort}
+	if !a.IsCursor() {
+		page.Limit = int32(a.Limit)
+		page.Offset = int32(a.Offset)
+		return page
+	}
+
+	if a.First != nil {
+		page.First = proto.Int32(int32(*a.First))
+	}
+	page.After = a.After
+	if a.Last != nil {
+		page.Last = proto.Int32(int32(*a.Last))
+	}
+	page.Before = a.Before
+	return page
+}
+
+// Cursor returns the opaque cursor of a node, matching the services' encoding
+func Cursor(createdAt *timestamppb.Timestamp, id string) string {
+	return pagination.NewCursor(createdAt.AsTime(), id).Encode()
+}
+
+// NewPageInfo builds GraphQL page info from a service list meta and the edge cursors
+func NewPageInfo(meta *commonv1.PageMeta, args PageArgs, cursors []string) PageInfo {
+	info := PageInfo{
+		Total:  int(meta.GetTotal()),
+		Limit:  int(meta.GetLimit()),
+		Offset: int(meta.GetOffset()),
+	}
+
+	if cursor := meta.GetCursorInfo(); cursor != nil {
+		info.HasNextPage = cursor.HasNextPage
+		info.HasPreviousPage = cursor.HasPreviousPage
+	} else {
+		info.Limit = args.Limit
+		info.Offset = args.Offset
+		info.HasNextPage = args.Offset+args.Limit < info.Total
+		info.HasPreviousPage = args.Offset > 0
+	}
+	info.HasMore = info.HasNextPage
+
+	if len(cursors) > 0 {
+		info.StartCursor = &cursors[0]
+		info.EndCursor = &cursors[len(cursors)-1]
+	}
+	return info
+}
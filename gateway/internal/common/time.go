@@ -0,0 +1,37 @@
+package common
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// MarshalTimestamp marshals a protobuf timestamp as an RFC 3339 Time scalar
+func MarshalTimestamp(t *timestamppb.Timestamp) graphql.Marshaler {
+	if t == nil {
+		return graphql.Null
+	}
+	return graphql.WriterFunc(func(w io.Writer) {
+		io.WriteString(w, strconv.Quote(t.AsTime().Format(time.RFC3339Nano)))
+	})
+}
+
+// UnmarshalTimestamp parses a Time scalar into a protobuf timestamp
+func UnmarshalTimestamp(v interface{}) (*timestamppb.Timestamp, error) {
+	t, err := graphql.UnmarshalTime(v)
+	if err != nil {
+		return nil, err
+	}
+	return timestamppb.New(t), nil
+}
+
+// ProtoTime converts an optional time into a protobuf timestamp
+func ProtoTime(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -2,172 +2,66 @@ package order
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"net/http"
 
 	"github.com/microservices-go/gateway/internal/client"
 	"github.com/microservices-go/gateway/internal/common"
+	orderv1 "github.com/microservices-go/shared/proto/order/v1"
 )
 
+// Client calls the order service over gRPC
 type Client struct {
-	*client.BaseClient
-	url string
+	rpc orderv1.OrderServiceClient
 }
 
-func NewClient(url string) *Client {
-	return &Client{
-		BaseClient: client.NewBaseClient(),
-		url:        url,
-	}
+// NewClient creates an order client on top of a shared upstream
+func NewClient(upstream *client.Upstream) *Client {
+	return &Client{rpc: orderv1.NewOrderServiceClient(upstream.Conn)}
 }
 
 func (c *Client) CreateOrder(ctx context.Context, input CreateOrderInput, userID string) (*Order, error) {
-	// Build request body
-	items := make([]map[string]interface{}, len(input.Items))
+	items := make([]*orderv1.CreateOrderItem, len(input.Items))
 	for i, item := range input.Items {
-		items[i] = map[string]interface{}{
-			"product_id":   item.ProductID,
-			"product_name": item.ProductName,
-			"quantity":     item.Quantity,
-			"unit_price":   item.UnitPrice,
+		items[i] = &orderv1.CreateOrderItem{
+			ProductId:   item.ProductID,
+			ProductName: item.ProductName,
+			Quantity:    int32(item.Quantity),
+			UnitPrice:   item.UnitPrice,
 		}
 	}
 
-	body := map[string]interface{}{
-		"user_id":          userID,
-		"currency":         input.Currency,
-		"shipping_address": input.ShippingAddress,
-		"notes":            input.Notes,
-		"items":            items,
-	}
-
-	url := fmt.Sprintf("%s/api/v1/orders", c.url)
-	resp, err := c.MakeRequest(ctx, "POST", url, body, client.GetAuthHeader(ctx))
+	order, err := c.rpc.CreateOrder(ctx, &orderv1.CreateOrderRequest{
+		UserId:          userID,
+		Currency:        input.Currency,
+		ShippingAddress: input.ShippingAddress,
+		Notes:           input.Notes,
+		Items:           items,
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		var errResp map[string]interface{}
-		json.NewDecoder(resp.Body).Decode(&errResp)
-		return nil, fmt.Errorf("failed to create order: %v", errResp)
-	}
-
-	var result struct {
-		Data *Order `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	return result.Data, nil
+	return &Order{order}, nil
 }
 
 func (c *Client) UpdateStatus(ctx context.Context, id string, status string) (*Order, error) {
-	body := map[string]interface{}{"status": status}
-
-	url := fmt.Sprintf("%s/api/v1/orders/%s/status", c.url, id)
-	resp, err := c.MakeRequest(ctx, "PATCH", url, body, client.GetAuthHeader(ctx))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to update order status")
-	}
-
-	var result struct {
-		Data *Order `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	return result.Data, nil
-}
-
-func (c *Client) ListOrders(ctx context.Context, limit, offset int) (*OrderConnection, error) {
-	url := fmt.Sprintf("%s/api/v1/orders?limit=%d&offset=%d", c.url, limit, offset)
-	resp, err := c.MakeRequest(ctx, "GET", url, nil, client.GetAuthHeader(ctx))
+	order, err := c.rpc.UpdateOrderStatus(ctx, &orderv1.UpdateOrderStatusRequest{Id: id, Status: status})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Data []*Order               `json:"data"`
-		Meta map[string]interface{} `json:"meta"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	total := 0
-	if t, ok := result.Meta["total"].(float64); ok {
-		total = int(t)
-	}
-
-	return &OrderConnection{
-		Data: result.Data,
-		PageInfo: common.PageInfo{
-			Total:   total,
-			Limit:   limit,
-			Offset:  offset,
-			HasMore: offset+limit < total,
-		},
-	}, nil
+	return &Order{order}, nil
 }
 
-func (c *Client) ListMyOrders(ctx context.Context, limit, offset int) (*OrderConnection, error) {
-	url := fmt.Sprintf("%s/api/v1/orders/my-orders?limit=%d&offset=%d", c.url, limit, offset)
-	resp, err := c.MakeRequest(ctx, "GET", url, nil, client.GetAuthHeader(ctx))
+func (c *Client) ListOrders(ctx context.Context, filter *OrderFilter, args common.PageArgs) (*OrderConnection, error) {
+	resp, err := c.rpc.ListOrders(ctx, &orderv1.ListOrdersRequest{Filter: filter.Proto(), Page: args.Request()})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Data []*Order               `json:"data"`
-		Meta map[string]interface{} `json:"meta"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	total := 0
-	if t, ok := result.Meta["total"].(float64); ok {
-		total = int(t)
-	}
-
-	return &OrderConnection{
-		Data: result.Data,
-		PageInfo: common.PageInfo{
-			Total:   total,
-			Limit:   limit,
-			Offset:  offset,
-			HasMore: offset+limit < total,
-		},
-	}, nil
+	return newOrderConnection(resp.Orders, resp.Meta, args), nil
 }
 
-// GetOrdersByUser gets orders for a specific user (used by DataLoader)
-func (c *Client) GetOrdersByUser(ctx context.Context, userID string, limit, offset int) ([]*Order, error) {
-	url := fmt.Sprintf("%s/api/v1/orders/user/%s?limit=%d&offset=%d", c.url, userID, limit, offset)
-	resp, err := c.MakeRequest(ctx, "GET", url, nil, client.GetAuthHeader(ctx))
+func (c *Client) ListMyOrders(ctx context.Context, args common.PageArgs) (*OrderConnection, error) {
+	resp, err := c.rpc.ListMyOrders(ctx, &orderv1.ListMyOrdersRequest{Page: args.Request()})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Data []*Order `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	return result.Data, nil
+	return newOrderConnection(resp.Orders, resp.Meta, args), nil
 }
@@ -1,18 +1,20 @@
 package order
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"time"
 
 	"github.com/graph-gophers/dataloader/v7"
+	"github.com/microservices-go/gateway/internal/client"
+	"github.com/microservices-go/gateway/internal/common"
+	"github.com/microservices-go/shared/errors"
+	orderv1 "github.com/microservices-go/shared/proto/order/v1"
+	"google.golang.org/grpc"
 )
 
 // BatchLoadOrders returns a batch function for loading orders
-func BatchLoadOrders(orderServiceURL string) dataloader.BatchFunc[string, *Order] {
+func BatchLoadOrders(upstream *client.Upstream) dataloader.BatchFunc[string, *Order] {
+	rpc := orderv1.NewOrderServiceClient(upstream.Conn)
+
 	return func(ctx context.Context, keys []string) []*dataloader.Result[*Order] {
 		results := make([]*dataloader.Result[*Order], len(keys))
 
@@ -20,11 +22,13 @@ func BatchLoadOrders(orderServiceURL string) dataloader.BatchFunc[string, *Order
 			return results
 		}
 
-		// Prepare request body
-		reqBody, _ := json.Marshal(map[string][]string{"ids": keys})
-
-		url := fmt.Sprintf("%s/api/v1/orders/batch", orderServiceURL)
-		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+		// Collect the streamed orders for O(1) lookup
+		orderMap := make(map[string]*Order, len(keys))
+		err := client.Receive(ctx, upstream, func(ctx context.Context) (grpc.ServerStreamingClient[orderv1.Order], error) {
+			return rpc.BatchGetOrders(ctx, &orderv1.BatchGetOrdersRequest{Ids: keys})
+		}, func(order *orderv1.Order) {
+			orderMap[order.Id] = &Order{order}
+		})
 		if err != nil {
 			for i := range results {
 				results[i] = &dataloader.Result[*Order]{Error: err}
@@ -32,54 +36,52 @@ func BatchLoadOrders(orderServiceURL string) dataloader.BatchFunc[string, *Order
 			return results
 		}
 
-		req.Header.Set("Content-Type", "application/json")
-
-		// Add auth header if present in ctx
-		if auth, ok := ctx.Value("Authorization").(string); ok {
-			req.Header.Set("Authorization", auth)
-		}
-
-		client := &http.Client{Timeout: 5 * time.Second}
-		resp, err := client.Do(req)
-		if err != nil {
-			for i := range results {
-				results[i] = &dataloader.Result[*Order]{Error: err}
+		// Map results to keys (maintain order)
+		for i, key := range keys {
+			if order, ok := orderMap[key]; ok {
+				results[i] = &dataloader.Result[*Order]{Data: order}
+			} else {
+				results[i] = &dataloader.Result[*Order]{Error: errors.New(errors.ErrNotFound, "Order not found: "+key).WithService(upstream.Name)}
 			}
-			return results
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			err := fmt.Errorf("failed to load orders: %d", resp.StatusCode)
-			for i := range results {
-				results[i] = &dataloader.Result[*Order]{Error: err}
-			}
-			return results
-		}
+		return results
+	}
+}
 
-		var result struct {
-			Data []*Order `json:"data"`
-		}
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-			for i := range results {
-				results[i] = &dataloader.Result[*Order]{Error: err}
-			}
-			return results
-		}
+// BatchLoadOrdersByUser returns a batch function for loading a page of orders per user.
+// Keys asking for the same page share one streaming call to BatchGetOrdersByUser.
+func BatchLoadOrdersByUser(upstream *client.Upstream) dataloader.BatchFunc[common.UserPageKey, []*Order] {
+	rpc := orderv1.NewOrderServiceClient(upstream.Conn)
+
+	return func(ctx context.Context, keys []common.UserPageKey) []*dataloader.Result[[]*Order] {
+		results := make([]*dataloader.Result[[]*Order], len(keys))
 
-		// Create map for O(1) lookup
-		orderMap := make(map[string]*Order)
-		for _, order := range result.Data {
-			orderMap[order.ID] = order
+		byKey := make(map[common.UserPageKey][]*Order)
+		failed := make(map[common.UserPage]error)
+		for page, userIDs := range common.GroupUserPages(keys) {
+			req := &orderv1.BatchGetOrdersByUserRequest{UserIds: userIDs, Limit: int32(page.Limit), Offset: int32(page.Offset)}
+			err := client.Receive(ctx, upstream, func(ctx context.Context) (grpc.ServerStreamingClient[orderv1.UserOrders], error) {
+				return rpc.BatchGetOrdersByUser(ctx, req)
+			}, func(msg *orderv1.UserOrders) {
+				byKey[common.UserPageKey{UserID: msg.UserId, Limit: page.Limit, Offset: page.Offset}] = newOrders(msg.Orders)
+			})
+			if err != nil {
+				failed[page] = err
+			}
 		}
 
-		// Map results to keys (maintain order)
+		// Map results to keys (maintain order); users without orders get an empty page
 		for i, key := range keys {
-			if order, ok := orderMap[key]; ok {
-				results[i] = &dataloader.Result[*Order]{Data: order}
-			} else {
-				results[i] = &dataloader.Result[*Order]{Error: fmt.Errorf("order not found: %s", key)}
+			if err, ok := failed[common.UserPage{Limit: key.Limit, Offset: key.Offset}]; ok {
+				results[i] = &dataloader.Result[[]*Order]{Error: err}
+				continue
+			}
+			orders := byKey[key]
+			if orders == nil {
+				orders = []*Order{}
 			}
+			results[i] = &dataloader.Result[[]*Order]{Data: orders}
 		}
 
 		return results
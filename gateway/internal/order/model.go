@@ -4,37 +4,65 @@ import (
 	"time"
 
 	"github.com/microservices-go/gateway/internal/common"
+	commonv1 "github.com/microservices-go/shared/proto/common/v1"
+	orderv1 "github.com/microservices-go/shared/proto/order/v1"
 )
 
-// OrderItem represents an order item in GraphQL
-type OrderItem struct {
-	ID          string  `json:"id"`
-	ProductID   string  `json:"product_id"`
-	ProductName string  `json:"product_name"`
-	Quantity    int     `json:"quantity"`
-	UnitPrice   float64 `json:"unit_price"`
+// Order represents an order as served by the order service. The generated
+// message is embedded by pointer so Order values can be copied freely.
+type Order struct {
+	*orderv1.Order
 }
 
-// Order represents an order in GraphQL
-type Order struct {
-	ID              string       `json:"id"`
-	UserID          string       `json:"user_id"`
-	Status          string       `json:"status"`
-	TotalAmount     float64      `json:"total_amount"`
-	Currency        string       `json:"currency"`
-	ShippingAddress string       `json:"shipping_address"`
-	Notes           *string      `json:"notes"`
-	Items           []*OrderItem `json:"items"`
-	CreatedAt       time.Time    `json:"created_at"`
-	UpdatedAt       time.Time    `json:"updated_at"`
+// newOrders wraps a list of service messages
+func newOrders(orders []*orderv1.Order) []*Order {
+	list := make([]*Order, len(orders))
+	for i, order := range orders {
+		list[i] = &Order{order}
+	}
+	return list
+}
+
+// OrderEdge represents a order with its cursor in GraphQL
+type OrderEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Order `json:"node"`
 }
 
 // OrderConnection represents order connection in GraphQL
 type OrderConnection struct {
+	Edges    []*OrderEdge    `json:"edges"`
 	Data     []*Order        `json:"data"`
 	PageInfo common.PageInfo `json:"pageInfo"`
 }
 
+// newOrderConnection builds a connection from a service list response
+func newOrderConnection(orders []*orderv1.Order, meta *commonv1.PageMeta, args common.PageArgs) *OrderConnection {
+	data := make([]*Order, len(orders))
+	edges := make([]*OrderEdge, len(orders))
+	cursors := make([]string, len(orders))
+	for i, order := range orders {
+		data[i] = &Order{order}
+		cursors[i] = common.Cursor(order.CreatedAt, order.Id)
+		edges[i] = &OrderEdge{Cursor: cursors[i], Node: data[i]}
+	}
+
+	return &OrderConnection{
+		Edges:    edges,
+		Data:     data,
+		PageInfo: common.NewPageInfo(meta, args, cursors),
+	}
+}
+
+// OrderStatusUpdate represents an order status change pushed to subscribers
+type OrderStatusUpdate struct {
+	OrderID   string    `json:"order_id"`
+	UserID    string    `json:"user_id"`
+	OldStatus string    `json:"old_status"`
+	Status    string    `json:"new_status"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
 // CreateOrderInput represents order creation input
 type CreateOrderInput struct {
 	Currency        *string                 `json:"currency"`
@@ -50,3 +78,30 @@ type CreateOrderItemInput struct {
 	Quantity    int     `json:"quantity"`
 	UnitPrice   float64 `json:"unit_price"`
 }
+
+// OrderFilter represents order list filter input
+type OrderFilter struct {
+	Status      []string   `json:"status"`
+	UserID      *string    `json:"user_id"`
+	Currency    *string    `json:"currency"`
+	CreatedFrom *time.Time `json:"created_from"`
+	CreatedTo   *time.Time `json:"created_to"`
+	MinAmount   *float64   `json:"min_amount"`
+	MaxAmount   *float64   `json:"max_amount"`
+}
+
+// Proto converts the filter to its gRPC message
+func (f *OrderFilter) Proto() *orderv1.OrderFilter {
+	if f == nil {
+		return nil
+	}
+	return &orderv1.OrderFilter{
+		Status:      f.Status,
+		UserId:      f.UserID,
+		Currency:    f.Currency,
+		CreatedFrom: common.ProtoTime(f.CreatedFrom),
+		CreatedTo:   common.ProtoTime(f.CreatedTo),
+		MinAmount:   f.MinAmount,
+		MaxAmount:   f.MaxAmount,
+	}
+}
@@ -2,171 +2,60 @@ package payment
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"net/http"
 
 	"github.com/microservices-go/gateway/internal/client"
 	"github.com/microservices-go/gateway/internal/common"
+	paymentv1 "github.com/microservices-go/shared/proto/payment/v1"
 )
 
+// Client calls the payment service over gRPC
 type Client struct {
-	*client.BaseClient
-	url string
+	rpc paymentv1.PaymentServiceClient
 }
 
-func NewClient(url string) *Client {
-	return &Client{
-		BaseClient: client.NewBaseClient(),
-		url:        url,
-	}
+// NewClient creates a payment client on top of a shared upstream
+func NewClient(upstream *client.Upstream) *Client {
+	return &Client{rpc: paymentv1.NewPaymentServiceClient(upstream.Conn)}
 }
 
 func (c *Client) CreatePayment(ctx context.Context, input CreatePaymentInput, userID string) (*Payment, error) {
-	body := map[string]interface{}{
-		"order_id":    input.OrderID,
-		"user_id":     userID,
-		"amount":      input.Amount,
-		"currency":    input.Currency,
-		"method":      input.Method,
-		"description": input.Description,
-	}
-
-	url := fmt.Sprintf("%s/api/v1/payments", c.url)
-	resp, err := c.MakeRequest(ctx, "POST", url, body, client.GetAuthHeader(ctx))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		var errResp map[string]interface{}
-		json.NewDecoder(resp.Body).Decode(&errResp)
-		return nil, fmt.Errorf("failed to create payment: %v", errResp)
-	}
-
-	var result struct {
-		Data *Payment `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	return result.Data, nil
+	return wrap(c.rpc.CreatePayment(ctx, &paymentv1.CreatePaymentRequest{
+		OrderId:     input.OrderID,
+		UserId:      userID,
+		Amount:      input.Amount,
+		Currency:    input.Currency,
+		Method:      input.Method,
+		Description: input.Description,
+	}))
 }
 
 func (c *Client) ProcessPayment(ctx context.Context, id string) (*Payment, error) {
-	url := fmt.Sprintf("%s/api/v1/payments/%s/process", c.url, id)
-	resp, err := c.MakeRequest(ctx, "POST", url, nil, client.GetAuthHeader(ctx))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to process payment")
-	}
-
-	var result struct {
-		Data *Payment `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	return result.Data, nil
+	return wrap(c.rpc.ProcessPayment(ctx, &paymentv1.ProcessPaymentRequest{Id: id}))
 }
 
 func (c *Client) RefundPayment(ctx context.Context, id string, amount *float64, reason *string) (*Payment, error) {
-	body := map[string]interface{}{}
-	if amount != nil {
-		body["amount"] = *amount
-	}
-	if reason != nil {
-		body["reason"] = *reason
-	}
+	return wrap(c.rpc.RefundPayment(ctx, &paymentv1.RefundPaymentRequest{Id: id, Amount: amount, Reason: reason}))
+}
 
-	url := fmt.Sprintf("%s/api/v1/payments/%s/refund", c.url, id)
-	resp, err := c.MakeRequest(ctx, "POST", url, body, client.GetAuthHeader(ctx))
+func (c *Client) ListPayments(ctx context.Context, filter *PaymentFilter, args common.PageArgs) (*PaymentConnection, error) {
+	resp, err := c.rpc.ListPayments(ctx, &paymentv1.ListPaymentsRequest{Filter: filter.Proto(), Page: args.Request()})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to refund payment")
-	}
-
-	var result struct {
-		Data *Payment `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	return result.Data, nil
+	return newPaymentConnection(resp.Payments, resp.Meta, args), nil
 }
 
-func (c *Client) ListPayments(ctx context.Context, limit, offset int) (*PaymentConnection, error) {
-	url := fmt.Sprintf("%s/api/v1/payments?limit=%d&offset=%d", c.url, limit, offset)
-	resp, err := c.MakeRequest(ctx, "GET", url, nil, client.GetAuthHeader(ctx))
+func (c *Client) ListMyPayments(ctx context.Context, args common.PageArgs) (*PaymentConnection, error) {
+	resp, err := c.rpc.ListMyPayments(ctx, &paymentv1.ListMyPaymentsRequest{Page: args.Request()})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Data []*Payment             `json:"data"`
-		Meta map[string]interface{} `json:"meta"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	total := 0
-	if t, ok := result.Meta["total"].(float64); ok {
-		total = int(t)
-	}
-
-	return &PaymentConnection{
-		Data: result.Data,
-		PageInfo: common.PageInfo{
-			Total:   total,
-			Limit:   limit,
-			Offset:  offset,
-			HasMore: offset+limit < total,
-		},
-	}, nil
+	return newPaymentConnection(resp.Payments, resp.Meta, args), nil
 }
 
-func (c *Client) ListMyPayments(ctx context.Context, limit, offset int) (*PaymentConnection, error) {
-	url := fmt.Sprintf("%s/api/v1/payments/my-payments?limit=%d&offset=%d", c.url, limit, offset)
-	resp, err := c.MakeRequest(ctx, "GET", url, nil, client.GetAuthHeader(ctx))
+func wrap(payment *paymentv1.Payment, err error) (*Payment, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Data []*Payment             `json:"data"`
-		Meta map[string]interface{} `json:"meta"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	total := 0
-	if t, ok := result.Meta["total"].(float64); ok {
-		total = int(t)
-	}
-
-	return &PaymentConnection{
-		Data: result.Data,
-		PageInfo: common.PageInfo{
-			Total:   total,
-			Limit:   limit,
-			Offset:  offset,
-			HasMore: offset+limit < total,
-		},
-	}, nil
+	return &Payment{payment}, nil
 }
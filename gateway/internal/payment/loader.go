@@ -1,18 +1,20 @@
 package payment
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"time"
 
 	"github.com/graph-gophers/dataloader/v7"
+	"github.com/microservices-go/gateway/internal/client"
+	"github.com/microservices-go/gateway/internal/common"
+	"github.com/microservices-go/shared/errors"
+	paymentv1 "github.com/microservices-go/shared/proto/payment/v1"
+	"google.golang.org/grpc"
 )
 
 // BatchLoadPayments returns a batch function for loading payments by ID
-func BatchLoadPayments(paymentServiceURL string) dataloader.BatchFunc[string, *Payment] {
+func BatchLoadPayments(upstream *client.Upstream) dataloader.BatchFunc[string, *Payment] {
+	rpc := paymentv1.NewPaymentServiceClient(upstream.Conn)
+
 	return func(ctx context.Context, keys []string) []*dataloader.Result[*Payment] {
 		results := make([]*dataloader.Result[*Payment], len(keys))
 
@@ -20,11 +22,13 @@ func BatchLoadPayments(paymentServiceURL string) dataloader.BatchFunc[string, *P
 			return results
 		}
 
-		// Prepare request body
-		reqBody, _ := json.Marshal(map[string][]string{"ids": keys})
-
-		url := fmt.Sprintf("%s/api/v1/payments/batch", paymentServiceURL)
-		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+		// Collect the streamed payments for O(1) lookup
+		paymentMap := make(map[string]*Payment, len(keys))
+		err := client.Receive(ctx, upstream, func(ctx context.Context) (grpc.ServerStreamingClient[paymentv1.Payment], error) {
+			return rpc.BatchGetPayments(ctx, &paymentv1.BatchGetPaymentsRequest{Ids: keys})
+		}, func(payment *paymentv1.Payment) {
+			paymentMap[payment.Id] = &Payment{payment}
+		})
 		if err != nil {
 			for i := range results {
 				results[i] = &dataloader.Result[*Payment]{Error: err}
@@ -32,53 +36,12 @@ func BatchLoadPayments(paymentServiceURL string) dataloader.BatchFunc[string, *P
 			return results
 		}
 
-		req.Header.Set("Content-Type", "application/json")
-
-		// Add auth header if present in ctx
-		if auth, ok := ctx.Value("Authorization").(string); ok {
-			req.Header.Set("Authorization", auth)
-		}
-
-		client := &http.Client{Timeout: 5 * time.Second}
-		resp, err := client.Do(req)
-		if err != nil {
-			for i := range results {
-				results[i] = &dataloader.Result[*Payment]{Error: err}
-			}
-			return results
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			err := fmt.Errorf("failed to load payments: %d", resp.StatusCode)
-			for i := range results {
-				results[i] = &dataloader.Result[*Payment]{Error: err}
-			}
-			return results
-		}
-
-		var result struct {
-			Data []*Payment `json:"data"`
-		}
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-			for i := range results {
-				results[i] = &dataloader.Result[*Payment]{Error: err}
-			}
-			return results
-		}
-
-		// Create map for O(1) lookup
-		paymentMap := make(map[string]*Payment)
-		for _, payment := range result.Data {
-			paymentMap[payment.ID] = payment
-		}
-
 		// Map results to keys (maintain order)
 		for i, key := range keys {
 			if payment, ok := paymentMap[key]; ok {
 				results[i] = &dataloader.Result[*Payment]{Data: payment}
 			} else {
-				results[i] = &dataloader.Result[*Payment]{Error: fmt.Errorf("payment not found: %s", key)}
+				results[i] = &dataloader.Result[*Payment]{Error: errors.New(errors.ErrNotFound, "Payment not found: "+key).WithService(upstream.Name)}
 			}
 		}
 
@@ -87,7 +50,9 @@ func BatchLoadPayments(paymentServiceURL string) dataloader.BatchFunc[string, *P
 }
 
 // BatchLoadPaymentsByOrder returns a batch function for loading payments by order ID
-func BatchLoadPaymentsByOrder(paymentServiceURL string) dataloader.BatchFunc[string, *Payment] {
+func BatchLoadPaymentsByOrder(upstream *client.Upstream) dataloader.BatchFunc[string, *Payment] {
+	rpc := paymentv1.NewPaymentServiceClient(upstream.Conn)
+
 	return func(ctx context.Context, keys []string) []*dataloader.Result[*Payment] {
 		results := make([]*dataloader.Result[*Payment], len(keys))
 
@@ -95,11 +60,13 @@ func BatchLoadPaymentsByOrder(paymentServiceURL string) dataloader.BatchFunc[str
 			return results
 		}
 
-		// Prepare request body
-		reqBody, _ := json.Marshal(map[string][]string{"order_ids": keys})
-
-		url := fmt.Sprintf("%s/api/v1/payments/batch-by-order", paymentServiceURL)
-		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+		// Collect the streamed payments for O(1) lookup (by order ID)
+		paymentMap := make(map[string]*Payment, len(keys))
+		err := client.Receive(ctx, upstream, func(ctx context.Context) (grpc.ServerStreamingClient[paymentv1.Payment], error) {
+			return rpc.BatchGetPaymentsByOrder(ctx, &paymentv1.BatchGetPaymentsByOrderRequest{OrderIds: keys})
+		}, func(payment *paymentv1.Payment) {
+			paymentMap[payment.OrderId] = &Payment{payment}
+		})
 		if err != nil {
 			for i := range results {
 				results[i] = &dataloader.Result[*Payment]{Error: err}
@@ -107,55 +74,48 @@ func BatchLoadPaymentsByOrder(paymentServiceURL string) dataloader.BatchFunc[str
 			return results
 		}
 
-		req.Header.Set("Content-Type", "application/json")
-
-		// Add auth header if present in ctx
-		if auth, ok := ctx.Value("Authorization").(string); ok {
-			req.Header.Set("Authorization", auth)
-		}
-
-		client := &http.Client{Timeout: 5 * time.Second}
-		resp, err := client.Do(req)
-		if err != nil {
-			for i := range results {
-				results[i] = &dataloader.Result[*Payment]{Error: err}
-			}
-			return results
+		// Map results to keys (maintain order); an order might not have a payment yet
+		for i, key := range keys {
+			results[i] = &dataloader.Result[*Payment]{Data: paymentMap[key]}
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			err := fmt.Errorf("failed to load payments by order: %d", resp.StatusCode)
-			for i := range results {
-				results[i] = &dataloader.Result[*Payment]{Error: err}
-			}
-			return results
-		}
+		return results
+	}
+}
 
-		var result struct {
-			Data []*Payment `json:"data"`
-		}
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-			for i := range results {
-				results[i] = &dataloader.Result[*Payment]{Error: err}
+// BatchLoadPaymentsByUser returns a batch function for loading a page of payments per user.
+// Keys asking for the same page share one streaming call to BatchGetPaymentsByUser.
+func BatchLoadPaymentsByUser(upstream *client.Upstream) dataloader.BatchFunc[common.UserPageKey, []*Payment] {
+	rpc := paymentv1.NewPaymentServiceClient(upstream.Conn)
+
+	return func(ctx context.Context, keys []common.UserPageKey) []*dataloader.Result[[]*Payment] {
+		results := make([]*dataloader.Result[[]*Payment], len(keys))
+
+		byKey := make(map[common.UserPageKey][]*Payment)
+		failed := make(map[common.UserPage]error)
+		for page, userIDs := range common.GroupUserPages(keys) {
+			req := &paymentv1.BatchGetPaymentsByUserRequest{UserIds: userIDs, Limit: int32(page.Limit), Offset: int32(page.Offset)}
+			err := client.Receive(ctx, upstream, func(ctx context.Context) (grpc.ServerStreamingClient[paymentv1.UserPayments], error) {
+				return rpc.BatchGetPaymentsByUser(ctx, req)
+			}, func(msg *paymentv1.UserPayments) {
+				byKey[common.UserPageKey{UserID: msg.UserId, Limit: page.Limit, Offset: page.Offset}] = newPayments(msg.Payments)
+			})
+			if err != nil {
+				failed[page] = err
 			}
-			return results
 		}
 
-		// Create map for O(1) lookup (by order ID)
-		paymentMap := make(map[string]*Payment)
-		for _, payment := range result.Data {
-			paymentMap[payment.OrderID] = payment
-		}
-
-		// Map results to keys (maintain order)
+		// Map results to keys (maintain order); users without payments get an empty page
 		for i, key := range keys {
-			if payment, ok := paymentMap[key]; ok {
-				results[i] = &dataloader.Result[*Payment]{Data: payment}
-			} else {
-				// Payment might not exist for order
-				results[i] = &dataloader.Result[*Payment]{Data: nil}
+			if err, ok := failed[common.UserPage{Limit: key.Limit, Offset: key.Offset}]; ok {
+				results[i] = &dataloader.Result[[]*Payment]{Error: err}
+				continue
+			}
+			payments := byKey[key]
+			if payments == nil {
+				payments = []*Payment{}
 			}
+			results[i] = &dataloader.Result[[]*Payment]{Data: payments}
 		}
 
 		return results
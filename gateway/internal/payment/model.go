@@ -4,32 +4,69 @@ import (
 	"time"
 
 	"github.com/microservices-go/gateway/internal/common"
+	commonv1 "github.com/microservices-go/shared/proto/common/v1"
+	paymentv1 "github.com/microservices-go/shared/proto/payment/v1"
 )
 
-// Payment represents a payment in GraphQL
+// PaymentStatusUpdate represents a payment outcome pushed to subscribers
+type PaymentStatusUpdate struct {
+	PaymentID     string    `json:"payment_id"`
+	OrderID       string    `json:"order_id"`
+	UserID        string    `json:"user_id"`
+	Status        string    `json:"status"`
+	Amount        float64   `json:"amount"`
+	Currency      string    `json:"currency"`
+	TransactionID *string   `json:"transaction_id"`
+	FailureReason *string   `json:"failure_reason"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
+// Payment represents a payment as served by the payment service. The generated
+// message is embedded by pointer so Payment values can be copied freely.
 type Payment struct {
-	ID            string     `json:"id"`
-	OrderID       string     `json:"order_id"`
-	UserID        string     `json:"user_id"`
-	Amount        float64    `json:"amount"`
-	Currency      string     `json:"currency"`
-	Status        string     `json:"status"`
-	Method        string     `json:"method"`
-	TransactionID *string    `json:"transaction_id"`
-	Provider      *string    `json:"provider"`
-	Description   *string    `json:"description"`
-	FailureReason *string    `json:"failure_reason"`
-	PaidAt        *time.Time `json:"paid_at"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
+	*paymentv1.Payment
+}
+
+// newPayments wraps a list of service messages
+func newPayments(payments []*paymentv1.Payment) []*Payment {
+	list := make([]*Payment, len(payments))
+	for i, payment := range payments {
+		list[i] = &Payment{payment}
+	}
+	return list
+}
+
+// PaymentEdge represents a payment with its cursor in GraphQL
+type PaymentEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Payment `json:"node"`
 }
 
 // PaymentConnection represents payment connection in GraphQL
 type PaymentConnection struct {
+	Edges    []*PaymentEdge  `json:"edges"`
 	Data     []*Payment      `json:"data"`
 	PageInfo common.PageInfo `json:"pageInfo"`
 }
 
+// newPaymentConnection builds a connection from a service list response
+func newPaymentConnection(payments []*paymentv1.Payment, meta *commonv1.PageMeta, args common.PageArgs) *PaymentConnection {
+	data := make([]*Payment, len(payments))
+	edges := make([]*PaymentEdge, len(payments))
+	cursors := make([]string, len(payments))
+	for i, payment := range payments {
+		data[i] = &Payment{payment}
+		cursors[i] = common.Cursor(payment.CreatedAt, payment.Id)
+		edges[i] = &PaymentEdge{Cursor: cursors[i], Node: data[i]}
+	}
+
+	return &PaymentConnection{
+		Edges:    edges,
+		Data:     data,
+		PageInfo: common.NewPageInfo(meta, args, cursors),
+	}
+}
+
 // CreatePaymentInput represents payment creation input
 type CreatePaymentInput struct {
 	OrderID     string  `json:"order_id"`
@@ -38,3 +75,34 @@ type CreatePaymentInput struct {
 	Method      string  `json:"method"`
 	Description *string `json:"description"`
 }
+
+// PaymentFilter represents payment list filter input
+type PaymentFilter struct {
+	Status      []string   `json:"status"`
+	Method      []string   `json:"method"`
+	UserID      *string    `json:"user_id"`
+	OrderID     *string    `json:"order_id"`
+	Currency    *string    `json:"currency"`
+	CreatedFrom *time.Time `json:"created_from"`
+	CreatedTo   *time.Time `json:"created_to"`
+	MinAmount   *float64   `json:"min_amount"`
+	MaxAmount   *float64   `json:"max_amount"`
+}
+
+// Proto converts the filter to its gRPC message
+func (f *PaymentFilter) Proto() *paymentv1.PaymentFilter {
+	if f == nil {
+		return nil
+	}
+	return &paymentv1.PaymentFilter{
+		Status:      f.Status,
+		Method:      f.Method,
+		UserId:      f.UserID,
+		OrderId:     f.OrderID,
+		Currency:    f.Currency,
+		CreatedFrom: common.ProtoTime(f.CreatedFrom),
+		CreatedTo:   common.ProtoTime(f.CreatedTo),
+		MinAmount:   f.MinAmount,
+		MaxAmount:   f.MaxAmount,
+	}
+}
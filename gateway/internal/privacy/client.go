@@ -0,0 +1,166 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/microservices-go/gateway/internal/client"
+	"github.com/microservices-go/shared/errors"
+	commonv1 "github.com/microservices-go/shared/proto/common/v1"
+	orderv1 "github.com/microservices-go/shared/proto/order/v1"
+	paymentv1 "github.com/microservices-go/shared/proto/payment/v1"
+	userv1 "github.com/microservices-go/shared/proto/user/v1"
+)
+
+// Client assembles data exports from every service and manages erasure
+// requests, which the user service coordinates
+type Client struct {
+	user    userv1.UserServiceClient
+	order   orderv1.OrderServiceClient
+	payment paymentv1.PaymentServiceClient
+}
+
+// NewClient creates a privacy client on top of the shared upstreams
+func NewClient(user, order, payment *client.Upstream) *Client {
+	return &Client{
+		user:    userv1.NewUserServiceClient(user.Conn),
+		order:   orderv1.NewOrderServiceClient(order.Conn),
+		payment: paymentv1.NewPaymentServiceClient(payment.Conn),
+	}
+}
+
+// part is one service's share of an export
+type part struct {
+	name string
+	data []byte
+}
+
+// Export collects the calling user's data from every service into one JSON
+// document or, for format ZIP, a ZIP archive of one file per service
+func (c *Client) Export(ctx context.Context, format string) (*DataExport, error) {
+	parts, err := c.collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	generatedAt := time.Now().UTC()
+	name := "my-data-" + generatedAt.Format("20060102T150405Z")
+
+	if format == "ZIP" {
+		var buf bytes.Buffer
+		archive := zip.NewWriter(&buf)
+		for _, p := range parts {
+			w, err := archive.CreateHeader(&zip.FileHeader{Name: p.name + ".json", Method: zip.Deflate, Modified: generatedAt})
+			if err != nil {
+				return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to build export archive")
+			}
+			if _, err := w.Write(p.data); err != nil {
+				return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to build export archive")
+			}
+		}
+		if err := archive.Close(); err != nil {
+			return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to build export archive")
+		}
+		return &DataExport{
+			Filename:    name + ".zip",
+			ContentType: "application/zip",
+			Content:     base64.StdEncoding.EncodeToString(buf.Bytes()),
+			GeneratedAt: generatedAt,
+		}, nil
+	}
+
+	doc := map[string]interface{}{"generated_at": generatedAt}
+	for _, p := range parts {
+		doc[p.name] = json.RawMessage(p.data)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to encode export")
+	}
+	return &DataExport{
+		Filename:    name + ".json",
+		ContentType: "application/json",
+		Content:     string(data),
+		GeneratedAt: generatedAt,
+	}, nil
+}
+
+// collect asks every service for its export; an export is complete or not
+// returned at all
+func (c *Client) collect(ctx context.Context) ([]part, error) {
+	req := &commonv1.ExportMyDataRequest{}
+	exports := []struct {
+		name   string
+		export func(context.Context) (*commonv1.DataExport, error)
+	}{
+		{"user", func(ctx context.Context) (*commonv1.DataExport, error) { return c.user.ExportMyData(ctx, req) }},
+		{"orders", func(ctx context.Context) (*commonv1.DataExport, error) { return c.order.ExportMyData(ctx, req) }},
+		{"payments", func(ctx context.Context) (*commonv1.DataExport, error) { return c.payment.ExportMyData(ctx, req) }},
+	}
+
+	parts := make([]part, len(exports))
+	errs := make([]error, len(exports))
+	var wg sync.WaitGroup
+	for i, e := range exports {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := e.export(ctx)
+			if err == nil {
+				parts[i] = part{name: e.name, data: resp.Data}
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parts, nil
+}
+
+func (c *Client) RequestErasure(ctx context.Context, userID string) (*ErasureRequest, error) {
+	resp, err := c.user.RequestErasure(ctx, &userv1.RequestErasureRequest{UserId: userID})
+	if err != nil {
+		return nil, err
+	}
+	return newErasureRequest(resp), nil
+}
+
+func (c *Client) GetErasureRequest(ctx context.Context, id string) (*ErasureRequest, error) {
+	resp, err := c.user.GetErasureRequest(ctx, &userv1.GetErasureRequestRequest{Id: id})
+	if err != nil {
+		return nil, err
+	}
+	return newErasureRequest(resp), nil
+}
+
+func (c *Client) ListErasureRequests(ctx context.Context, status *string, limit, offset *int) ([]*ErasureRequest, error) {
+	req := &userv1.ListErasureRequestsRequest{}
+	if status != nil {
+		req.Status = *status
+	}
+	if limit != nil {
+		req.Limit = int32(*limit)
+	}
+	if offset != nil {
+		req.Offset = int32(*offset)
+	}
+
+	resp, err := c.user.ListErasureRequests(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	requests := make([]*ErasureRequest, len(resp.Requests))
+	for i, request := range resp.Requests {
+		requests[i] = newErasureRequest(request)
+	}
+	return requests, nil
+}
@@ -0,0 +1,51 @@
+package privacy
+
+import (
+	"time"
+
+	userv1 "github.com/microservices-go/shared/proto/user/v1"
+)
+
+// DataExport represents a personal data export in GraphQL
+type DataExport struct {
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"contentType"`
+	Content     string    `json:"content"`
+	GeneratedAt time.Time `json:"generatedAt"`
+}
+
+// ErasureRequest represents the erasure of a user's personal data in GraphQL
+type ErasureRequest struct {
+	ID               string     `json:"id"`
+	UserID           string     `json:"userID"`
+	RequestedBy      string     `json:"requestedBy"`
+	Status           string     `json:"status"`
+	OrdersErasedAt   *time.Time `json:"ordersErasedAt"`
+	PaymentsErasedAt *time.Time `json:"paymentsErasedAt"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	CompletedAt      *time.Time `json:"completedAt"`
+}
+
+// newErasureRequest converts a service erasure request
+func newErasureRequest(request *userv1.ErasureRequest) *ErasureRequest {
+	r := &ErasureRequest{
+		ID:          request.Id,
+		UserID:      request.UserId,
+		RequestedBy: request.RequestedBy,
+		Status:      request.Status,
+		CreatedAt:   request.CreatedAt.AsTime(),
+	}
+	if request.OrdersErasedAt != nil {
+		t := request.OrdersErasedAt.AsTime()
+		r.OrdersErasedAt = &t
+	}
+	if request.PaymentsErasedAt != nil {
+		t := request.PaymentsErasedAt.AsTime()
+		r.PaymentsErasedAt = &t
+	}
+	if request.CompletedAt != nil {
+		t := request.CompletedAt.AsTime()
+		r.CompletedAt = &t
+	}
+	return r
+}
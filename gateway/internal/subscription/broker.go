@@ -0,0 +1,102 @@
+package subscription
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/microservices-go/gateway/internal/order"
+	"github.com/microservices-go/gateway/internal/payment"
+	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/rabbitmq"
+)
+
+// exchange is the topic exchange all services publish domain events to
+const exchange = "microservices.events"
+
+// Broker turns RabbitMQ domain events into GraphQL subscription updates
+type Broker struct {
+	Orders   *Hub[*order.OrderStatusUpdate]
+	Payments *Hub[*payment.PaymentStatusUpdate]
+}
+
+// NewBroker creates a new broker
+func NewBroker() *Broker {
+	return &Broker{
+		Orders:   NewHub[*order.OrderStatusUpdate](),
+		Payments: NewHub[*payment.PaymentStatusUpdate](),
+	}
+}
+
+// Start consumes order and payment events on an exclusive per-instance queue,
+// so every gateway replica sees every event
+func (b *Broker) Start(client *rabbitmq.Client) error {
+	queue, err := client.DeclareTemporaryQueue()
+	if err != nil {
+		return err
+	}
+
+	routingKeys := []string{
+		"order-service." + rabbitmq.EventOrderUpdated,
+		"payment-service." + rabbitmq.EventPaymentSuccess,
+		"payment-service." + rabbitmq.EventPaymentFailed,
+	}
+	for _, key := range routingKeys {
+		if err := client.BindQueue(queue.Name, exchange, key); err != nil {
+			return err
+		}
+	}
+
+	consumer := rabbitmq.NewConsumer(client)
+	consumer.RegisterHandler(rabbitmq.EventOrderUpdated, b.handleOrderUpdated)
+	consumer.RegisterHandler(rabbitmq.EventPaymentSuccess, b.handlePaymentSuccess)
+	consumer.RegisterHandler(rabbitmq.EventPaymentFailed, b.handlePaymentFailed)
+
+	return consumer.Start(queue.Name)
+}
+
+func (b *Broker) handleOrderUpdated(ctx context.Context, event *rabbitmq.Event) error {
+	var update order.OrderStatusUpdate
+	if err := json.Unmarshal(event.Payload, &update); err != nil {
+		// Malformed payloads can never succeed; drop instead of requeueing
+		logger.WithContext(ctx).WithError(err).Warn("Dropping malformed order.updated event")
+		return nil
+	}
+
+	b.Orders.Publish(&update)
+	return nil
+}
+
+func (b *Broker) handlePaymentSuccess(ctx context.Context, event *rabbitmq.Event) error {
+	var payload struct {
+		payment.PaymentStatusUpdate
+		PaidAt time.Time `json:"paid_at"`
+	}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		logger.WithContext(ctx).WithError(err).Warn("Dropping malformed payment.success event")
+		return nil
+	}
+
+	update := payload.PaymentStatusUpdate
+	update.Status = "success"
+	update.OccurredAt = payload.PaidAt
+	b.Payments.Publish(&update)
+	return nil
+}
+
+func (b *Broker) handlePaymentFailed(ctx context.Context, event *rabbitmq.Event) error {
+	var payload struct {
+		payment.PaymentStatusUpdate
+		FailedAt time.Time `json:"failed_at"`
+	}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		logger.WithContext(ctx).WithError(err).Warn("Dropping malformed payment.failed event")
+		return nil
+	}
+
+	update := payload.PaymentStatusUpdate
+	update.Status = "failed"
+	update.OccurredAt = payload.FailedAt
+	b.Payments.Publish(&update)
+	return nil
+}
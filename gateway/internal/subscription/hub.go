@@ -0,0 +1,69 @@
+package subscription
+
+import (
+	"context"
+	"sync"
+)
+
+// subscriberBuffer is how many events a slow subscriber may lag behind before events are dropped
+const subscriberBuffer = 16
+
+type subscriber[T any] struct {
+	ch     chan T
+	filter func(T) bool
+}
+
+// Hub fans out events of one type to in-process subscribers
+type Hub[T any] struct {
+	mu   sync.RWMutex
+	subs map[*subscriber[T]]struct{}
+}
+
+// NewHub creates a new hub
+func NewHub[T any]() *Hub[T] {
+	return &Hub[T]{subs: make(map[*subscriber[T]]struct{})}
+}
+
+// Subscribe returns a channel receiving events accepted by filter.
+// The channel is closed once ctx is done (e.g. the websocket disconnects).
+func (h *Hub[T]) Subscribe(ctx context.Context, filter func(T) bool) <-chan T {
+	sub := &subscriber[T]{ch: make(chan T, subscriberBuffer), filter: filter}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		delete(h.subs, sub)
+		close(sub.ch)
+		h.mu.Unlock()
+	}()
+
+	return sub.ch
+}
+
+// Publish delivers event to every matching subscriber without blocking;
+// subscribers whose buffer is full miss the event
+func (h *Hub[T]) Publish(event T) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subs {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+		}
+	}
+}
+
+// Count returns the number of active subscribers
+func (h *Hub[T]) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
@@ -2,168 +2,259 @@ package user
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"net/http"
 
 	"github.com/microservices-go/gateway/internal/client"
 	"github.com/microservices-go/gateway/internal/common"
+	userv1 "github.com/microservices-go/shared/proto/user/v1"
 )
 
+// Client calls the user service over gRPC
 type Client struct {
-	*client.BaseClient
-	url string
+	rpc userv1.UserServiceClient
 }
 
-func NewClient(url string) *Client {
-	return &Client{
-		BaseClient: client.NewBaseClient(),
-		url:        url,
-	}
+// NewClient creates a user client on top of a shared upstream
+func NewClient(upstream *client.Upstream) *Client {
+	return &Client{rpc: userv1.NewUserServiceClient(upstream.Conn)}
 }
 
 func (c *Client) Register(ctx context.Context, input RegisterInput) (*AuthResponse, error) {
-	url := fmt.Sprintf("%s/api/v1/users/register", c.url)
-	resp, err := c.MakeRequest(ctx, "POST", url, input, "")
+	resp, err := c.rpc.Register(ctx, &userv1.RegisterRequest{
+		Email:     input.Email,
+		Password:  input.Password,
+		FirstName: input.FirstName,
+		LastName:  input.LastName,
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return newAuthResponse(resp), nil
+}
 
-	if resp.StatusCode != http.StatusCreated {
-		var errResp map[string]interface{}
-		json.NewDecoder(resp.Body).Decode(&errResp)
-		return nil, fmt.Errorf("registration failed: %v", errResp)
+func (c *Client) Login(ctx context.Context, input LoginInput) (*AuthResponse, error) {
+	resp, err := c.rpc.Login(ctx, &userv1.LoginRequest{Email: input.Email, Password: input.Password})
+	if err != nil {
+		return nil, err
 	}
+	return newAuthResponse(resp), nil
+}
 
-	var result struct {
-		Data *AuthResponse `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+func (c *Client) VerifyMFA(ctx context.Context, input VerifyMFAInput) (*AuthResponse, error) {
+	resp, err := c.rpc.VerifyMFA(ctx, &userv1.VerifyMFARequest{ChallengeToken: input.ChallengeToken, Code: input.Code})
+	if err != nil {
 		return nil, err
 	}
+	return newAuthResponse(resp), nil
+}
 
-	return result.Data, nil
+func (c *Client) OIDCProviders(ctx context.Context) ([]string, error) {
+	resp, err := c.rpc.ListOIDCProviders(ctx, &userv1.ListOIDCProvidersRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Providers, nil
 }
 
-func (c *Client) Login(ctx context.Context, input LoginInput) (*AuthResponse, error) {
-	url := fmt.Sprintf("%s/api/v1/users/login", c.url)
-	resp, err := c.MakeRequest(ctx, "POST", url, input, "")
+func (c *Client) StartOIDCLogin(ctx context.Context, provider string) (*OIDCAuthorization, error) {
+	resp, err := c.rpc.StartOIDCLogin(ctx, &userv1.StartOIDCLoginRequest{Provider: provider})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return &OIDCAuthorization{AuthorizationURL: resp.AuthorizationUrl, State: resp.State}, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		var errResp map[string]interface{}
-		json.NewDecoder(resp.Body).Decode(&errResp)
-		return nil, fmt.Errorf("login failed: %v", errResp)
+func (c *Client) CompleteOIDCLogin(ctx context.Context, input OIDCCallbackInput) (*AuthResponse, error) {
+	resp, err := c.rpc.CompleteOIDCLogin(ctx, &userv1.OIDCCallbackRequest{Code: input.Code, State: input.State})
+	if err != nil {
+		return nil, err
 	}
+	return newAuthResponse(resp), nil
+}
 
-	var result struct {
-		Data *AuthResponse `json:"data"`
+func (c *Client) EnrollMFA(ctx context.Context) (*MFAEnrollment, error) {
+	resp, err := c.rpc.EnrollMFA(ctx, &userv1.EnrollMFARequest{})
+	if err != nil {
+		return nil, err
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	return &MFAEnrollment{Secret: resp.Secret, ProvisioningURI: resp.ProvisioningUri}, nil
+}
+
+func (c *Client) ConfirmMFA(ctx context.Context, code string) (*MFABackupCodes, error) {
+	resp, err := c.rpc.ConfirmMFA(ctx, &userv1.MFACodeRequest{Code: code})
+	if err != nil {
 		return nil, err
 	}
+	return &MFABackupCodes{Codes: resp.Codes}, nil
+}
 
-	return result.Data, nil
+func (c *Client) DisableMFA(ctx context.Context, code string) (bool, error) {
+	if _, err := c.rpc.DisableMFA(ctx, &userv1.MFACodeRequest{Code: code}); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-func (c *Client) UpdateUser(ctx context.Context, id string, firstName *string, lastName *string, isActive *bool) (*User, error) {
-	body := map[string]interface{}{}
-	if firstName != nil {
-		body["first_name"] = *firstName
+func (c *Client) RegenerateBackupCodes(ctx context.Context, code string) (*MFABackupCodes, error) {
+	resp, err := c.rpc.RegenerateBackupCodes(ctx, &userv1.MFACodeRequest{Code: code})
+	if err != nil {
+		return nil, err
 	}
-	if lastName != nil {
-		body["last_name"] = *lastName
+	return &MFABackupCodes{Codes: resp.Codes}, nil
+}
+
+func (c *Client) ListIdentities(ctx context.Context) ([]*Identity, error) {
+	resp, err := c.rpc.ListIdentities(ctx, &userv1.ListIdentitiesRequest{})
+	if err != nil {
+		return nil, err
 	}
-	if isActive != nil {
-		body["is_active"] = *isActive
+	identities := make([]*Identity, len(resp.Identities))
+	for i, identity := range resp.Identities {
+		identities[i] = newIdentity(identity)
 	}
+	return identities, nil
+}
 
-	url := fmt.Sprintf("%s/api/v1/users/%s", c.url, id)
-	resp, err := c.MakeRequest(ctx, "PUT", url, body, client.GetAuthHeader(ctx))
+func (c *Client) StartIdentityLink(ctx context.Context, provider string) (*OIDCAuthorization, error) {
+	resp, err := c.rpc.StartIdentityLink(ctx, &userv1.StartOIDCLoginRequest{Provider: provider})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return &OIDCAuthorization{AuthorizationURL: resp.AuthorizationUrl, State: resp.State}, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to update user")
+func (c *Client) LinkIdentity(ctx context.Context, input OIDCCallbackInput) (*Identity, error) {
+	resp, err := c.rpc.LinkIdentity(ctx, &userv1.OIDCCallbackRequest{Code: input.Code, State: input.State})
+	if err != nil {
+		return nil, err
 	}
+	return newIdentity(resp), nil
+}
 
-	var result struct {
-		Data *User `json:"data"`
+func (c *Client) UnlinkIdentity(ctx context.Context, provider string) (bool, error) {
+	if _, err := c.rpc.UnlinkIdentity(ctx, &userv1.UnlinkIdentityRequest{Provider: provider}); err != nil {
+		return false, err
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	return true, nil
+}
+
+func (c *Client) ListAPIKeys(ctx context.Context) ([]*APIKey, error) {
+	resp, err := c.rpc.ListAPIKeys(ctx, &userv1.ListAPIKeysRequest{})
+	if err != nil {
 		return nil, err
 	}
-
-	return result.Data, nil
+	keys := make([]*APIKey, len(resp.ApiKeys))
+	for i, key := range resp.ApiKeys {
+		keys[i] = newAPIKey(key)
+	}
+	return keys, nil
 }
 
-func (c *Client) DeleteUser(ctx context.Context, id string) (bool, error) {
-	url := fmt.Sprintf("%s/api/v1/users/%s", c.url, id)
-	resp, err := c.MakeRequest(ctx, "DELETE", url, nil, client.GetAuthHeader(ctx))
+func (c *Client) CreateAPIKey(ctx context.Context, input CreateAPIKeyInput) (*CreatedAPIKey, error) {
+	req := &userv1.CreateAPIKeyRequest{Name: input.Name, Scopes: input.Scopes}
+	if input.ExpiresInDays != nil {
+		req.ExpiresInDays = int32(*input.ExpiresInDays)
+	}
+	if input.RateLimit != nil {
+		req.RateLimit = int32(*input.RateLimit)
+	}
+	resp, err := c.rpc.CreateAPIKey(ctx, req)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return &CreatedAPIKey{APIKey: newAPIKey(resp.ApiKey), Key: resp.Key}, nil
+}
 
-	return resp.StatusCode == http.StatusNoContent, nil
+func (c *Client) RevokeAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	resp, err := c.rpc.RevokeAPIKey(ctx, &userv1.RevokeAPIKeyRequest{Id: id})
+	if err != nil {
+		return nil, err
+	}
+	return newAPIKey(resp), nil
 }
 
-func (c *Client) Me(ctx context.Context) (*User, error) {
-	url := fmt.Sprintf("%s/api/v1/users/me", c.url)
-	resp, err := c.MakeRequest(ctx, "GET", url, nil, client.GetAuthHeader(ctx))
+func (c *Client) ListSessions(ctx context.Context) ([]*Session, error) {
+	resp, err := c.rpc.ListSessions(ctx, &userv1.ListSessionsRequest{})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	sessions := make([]*Session, len(resp.Sessions))
+	for i, session := range resp.Sessions {
+		sessions[i] = newSession(session)
+	}
+	return sessions, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get current user")
+func (c *Client) RevokeSession(ctx context.Context, id string) (bool, error) {
+	if _, err := c.rpc.RevokeSession(ctx, &userv1.RevokeSessionRequest{Id: id}); err != nil {
+		return false, err
 	}
+	return true, nil
+}
 
-	var result struct {
-		Data *User `json:"data"`
+func (c *Client) RevokeAllSessions(ctx context.Context) (int, error) {
+	resp, err := c.rpc.RevokeAllSessions(ctx, &userv1.RevokeAllSessionsRequest{})
+	if err != nil {
+		return 0, err
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	return int(resp.Revoked), nil
+}
+
+func (c *Client) UpdateUser(ctx context.Context, id string, firstName *string, lastName *string, isActive *bool) (*User, error) {
+	user, err := c.rpc.UpdateUser(ctx, &userv1.UpdateUserRequest{
+		Id:        id,
+		FirstName: firstName,
+		LastName:  lastName,
+		IsActive:  isActive,
+	})
+	if err != nil {
 		return nil, err
 	}
+	return &User{user}, nil
+}
 
-	return result.Data, nil
+func (c *Client) DeleteUser(ctx context.Context, id string) (bool, error) {
+	if _, err := c.rpc.DeleteUser(ctx, &userv1.DeleteUserRequest{Id: id}); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-func (c *Client) ListUsers(ctx context.Context, limit, offset int) (*UserConnection, error) {
-	url := fmt.Sprintf("%s/api/v1/users?limit=%d&offset=%d", c.url, limit, offset)
-	resp, err := c.MakeRequest(ctx, "GET", url, nil, client.GetAuthHeader(ctx))
+func (c *Client) AssignRole(ctx context.Context, userID, role string) (*User, error) {
+	user, err := c.rpc.AssignRole(ctx, &userv1.RoleRequest{UserId: userID, Role: role})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return &User{user}, nil
+}
 
-	var result struct {
-		Data []*User                `json:"data"`
-		Meta map[string]interface{} `json:"meta"`
+func (c *Client) RevokeRole(ctx context.Context, userID, role string) (*User, error) {
+	user, err := c.rpc.RevokeRole(ctx, &userv1.RoleRequest{UserId: userID, Role: role})
+	if err != nil {
+		return nil, err
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	return &User{user}, nil
+}
+
+func (c *Client) SetRoleMFARequired(ctx context.Context, role string, required bool) (*Role, error) {
+	resp, err := c.rpc.SetRoleMFARequired(ctx, &userv1.SetRoleMFARequiredRequest{Role: role, Required: required})
+	if err != nil {
 		return nil, err
 	}
+	return &Role{Name: resp.Name, Description: resp.Description, MFARequired: resp.MfaRequired}, nil
+}
 
-	total := 0
-	if t, ok := result.Meta["total"].(float64); ok {
-		total = int(t)
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	user, err := c.rpc.GetMe(ctx, &userv1.GetMeRequest{})
+	if err != nil {
+		return nil, err
 	}
+	return &User{user}, nil
+}
 
-	return &UserConnection{
-		Data: result.Data,
-		PageInfo: common.PageInfo{
-			Total:   total,
-			Limit:   limit,
-			Offset:  offset,
-			HasMore: offset+limit < total,
-		},
-	}, nil
+func (c *Client) ListUsers(ctx context.Context, filter *UserFilter, args common.PageArgs) (*UserConnection, error) {
+	resp, err := c.rpc.ListUsers(ctx, &userv1.ListUsersRequest{Filter: filter.Proto(), Page: args.Request()})
+	if err != nil {
+		return nil, err
+	}
+	return newUserConnection(resp.Users, resp.Meta, args), nil
 }
@@ -4,18 +4,14 @@ import (
 	"time"
 
 	"github.com/microservices-go/gateway/internal/common"
+	commonv1 "github.com/microservices-go/shared/proto/common/v1"
+	userv1 "github.com/microservices-go/shared/proto/user/v1"
 )
 
-// User represents a user in GraphQL
+// User represents a user as served by the user service. The generated
+// message is embedded by pointer so User values can be copied freely.
 type User struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Role      string    `json:"role"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	*userv1.User
 }
 
 // FullName returns user's full name
@@ -23,12 +19,178 @@ func (u *User) FullName() string {
 	return u.FirstName + " " + u.LastName
 }
 
+// AuthResponse represents auth response; see the schema for the MFA fields
+type AuthResponse struct {
+	Token                 *string `json:"token"`
+	User                  *User   `json:"user"`
+	MFARequired           bool    `json:"mfaRequired"`
+	ChallengeToken        *string `json:"challengeToken"`
+	MFAEnrollmentRequired bool    `json:"mfaEnrollmentRequired"`
+}
+
+// newAuthResponse converts a service auth response, leaving out what it did not issue
+func newAuthResponse(resp *userv1.AuthResponse) *AuthResponse {
+	auth := &AuthResponse{MFARequired: resp.MfaRequired, MFAEnrollmentRequired: resp.MfaEnrollmentRequired}
+	if resp.Token != "" {
+		auth.Token = &resp.Token
+	}
+	if resp.ChallengeToken != "" {
+		auth.ChallengeToken = &resp.ChallengeToken
+	}
+	if resp.User != nil {
+		auth.User = &User{resp.User}
+	}
+	return auth
+}
+
+// MFAEnrollment represents a started MFA enrolment
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+}
+
+// MFABackupCodes represents newly generated backup codes
+type MFABackupCodes struct {
+	Codes []string `json:"codes"`
+}
+
+// Role represents a role and its MFA policy
+type Role struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	MFARequired bool   `json:"mfaRequired"`
+}
+
+// OIDCAuthorization represents a started identity provider sign-in
+type OIDCAuthorization struct {
+	AuthorizationURL string `json:"authorizationUrl"`
+	State            string `json:"state"`
+}
+
+// Identity represents an identity provider account linked to a user
+type Identity struct {
+	ID          string     `json:"id"`
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"subject"`
+	Email       *string    `json:"email"`
+	LastLoginAt *time.Time `json:"lastLoginAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// newIdentity converts a service identity
+func newIdentity(identity *userv1.Identity) *Identity {
+	i := &Identity{
+		ID:        identity.Id,
+		Provider:  identity.Provider,
+		Subject:   identity.Subject,
+		CreatedAt: identity.CreatedAt.AsTime(),
+	}
+	if identity.Email != "" {
+		i.Email = &identity.Email
+	}
+	if identity.LastLoginAt != nil {
+		lastLoginAt := identity.LastLoginAt.AsTime()
+		i.LastLoginAt = &lastLoginAt
+	}
+	return i
+}
+
+// APIKey represents a personal API key of a user
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	RateLimit  int        `json:"rateLimit"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// newAPIKey converts a service API key
+func newAPIKey(key *userv1.APIKey) *APIKey {
+	k := &APIKey{
+		ID:        key.Id,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		RateLimit: int(key.RateLimit),
+		ExpiresAt: key.ExpiresAt.AsTime(),
+		CreatedAt: key.CreatedAt.AsTime(),
+	}
+	if key.LastUsedAt != nil {
+		lastUsedAt := key.LastUsedAt.AsTime()
+		k.LastUsedAt = &lastUsedAt
+	}
+	if key.RevokedAt != nil {
+		revokedAt := key.RevokedAt.AsTime()
+		k.RevokedAt = &revokedAt
+	}
+	return k
+}
+
+// CreatedAPIKey represents a new API key with the key itself, shown only once
+type CreatedAPIKey struct {
+	APIKey *APIKey `json:"apiKey"`
+	Key    string  `json:"key"`
+}
+
+// Session represents a login of a user on one device
+type Session struct {
+	ID         string    `json:"id"`
+	IPAddress  string    `json:"ipAddress"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"`
+}
+
+// newSession converts a service session
+func newSession(session *userv1.Session) *Session {
+	return &Session{
+		ID:         session.Id,
+		IPAddress:  session.IpAddress,
+		UserAgent:  session.UserAgent,
+		CreatedAt:  session.CreatedAt.AsTime(),
+		LastSeenAt: session.LastSeenAt.AsTime(),
+		ExpiresAt:  session.ExpiresAt.AsTime(),
+		Current:    session.Current,
+	}
+}
+
+// UserEdge represents a user with its cursor in GraphQL
+type UserEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *User  `json:"node"`
+}
+
 // UserConnection represents user connection in GraphQL
 type UserConnection struct {
+	Edges    []*UserEdge     `json:"edges"`
 	Data     []*User         `json:"data"`
 	PageInfo common.PageInfo `json:"pageInfo"`
 }
 
+// newUserConnection builds a connection from a service list response
+func newUserConnection(users []*userv1.User, meta *commonv1.PageMeta, args common.PageArgs) *UserConnection {
+	data := make([]*User, len(users))
+	edges := make([]*UserEdge, len(users))
+	cursors := make([]string, len(users))
+	for i, user := range users {
+		data[i] = &User{user}
+		cursors[i] = common.Cursor(user.CreatedAt, user.Id)
+		edges[i] = &UserEdge{Cursor: cursors[i], Node: data[i]}
+	}
+
+	return &UserConnection{
+		Edges:    edges,
+		Data:     data,
+		PageInfo: common.NewPageInfo(meta, args, cursors),
+	}
+}
+
 // RegisterInput represents registration input
 type RegisterInput struct {
 	Email     string `json:"email"`
@@ -43,8 +205,45 @@ type LoginInput struct {
 	Password string `json:"password"`
 }
 
-// AuthResponse represents auth response in GraphQL
-type AuthResponse struct {
-	Token string `json:"token"`
-	User  *User  `json:"user"`
+// VerifyMFAInput represents the second login step
+type VerifyMFAInput struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// OIDCCallbackInput represents the code and state an identity provider redirected back with
+type OIDCCallbackInput struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+// CreateAPIKeyInput represents API key creation input
+type CreateAPIKeyInput struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays *int     `json:"expires_in_days"`
+	RateLimit     *int     `json:"rate_limit"`
+}
+
+// UserFilter represents user list filter input
+type UserFilter struct {
+	Search      *string    `json:"search"`
+	Role        []string   `json:"role"`
+	IsActive    *bool      `json:"is_active"`
+	CreatedFrom *time.Time `json:"created_from"`
+	CreatedTo   *time.Time `json:"created_to"`
+}
+
+// Proto converts the filter to its gRPC message
+func (f *UserFilter) Proto() *userv1.UserFilter {
+	if f == nil {
+		return nil
+	}
+	return &userv1.UserFilter{
+		Search:      f.Search,
+		Role:        f.Role,
+		IsActive:    f.IsActive,
+		CreatedFrom: common.ProtoTime(f.CreatedFrom),
+		CreatedTo:   common.ProtoTime(f.CreatedTo),
+	}
 }
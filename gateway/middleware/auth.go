@@ -7,19 +7,55 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/errors"
+	sharedMiddleware "github.com/microservices-go/shared/middleware"
 )
 
-// UserClaims represents JWT claims
+// UserClaims represents JWT claims; callers using an API key also carry its
+// ID, scopes and rate limit, and tokens issued at login their session
 type UserClaims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID      string   `json:"user_id"`
+	Email       string   `json:"email"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions,omitempty"`
+	APIKeyID    string   `json:"api_key_id,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`
+	RateLimit   int      `json:"rate_limit,omitempty"`
+	SessionID   string   `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// HasPermission reports whether the claims grant permission
+func (c *UserClaims) HasPermission(permission string) bool {
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// FromAPIKey reports whether the caller authenticated with an API key
+func (c *UserClaims) FromAPIKey() bool {
+	return c.APIKeyID != ""
+}
+
+// HasScope reports whether an API key caller's scopes include permission
+func (c *UserClaims) HasScope(permission string) bool {
+	for _, s := range c.Scopes {
+		if s == permission {
+			return true
+		}
+	}
+	return false
+}
+
 // AuthMiddleware validates JWT token
 type AuthMiddleware struct {
 	jwtConfig *config.JWTConfig
+	apiKeys   sharedMiddleware.APIKeyVerifier
+	limiter   *sharedMiddleware.RateLimiter
+	sessions  sharedMiddleware.SessionChecker
 }
 
 // NewAuthMiddleware creates new auth middleware
@@ -27,34 +63,126 @@ func NewAuthMiddleware(jwtConfig *config.JWTConfig) *AuthMiddleware {
 	return &AuthMiddleware{jwtConfig: jwtConfig}
 }
 
+// claimsContextKey stores the validated UserClaims in context
+type claimsContextKey struct{}
+
+// WithAPIKeys makes the middleware accept API keys as bearer tokens. Each
+// request made with one counts against the key's rate limit in limiter,
+// when not nil.
+func (a *AuthMiddleware) WithAPIKeys(verifier sharedMiddleware.APIKeyVerifier, limiter *sharedMiddleware.RateLimiter) *AuthMiddleware {
+	a.apiKeys = verifier
+	a.limiter = limiter
+	return a
+}
+
+// WithSessions makes the middleware reject tokens whose session is no longer
+// active, and tokens issued without a session
+func (a *AuthMiddleware) WithSessions(checker sharedMiddleware.SessionChecker) *AuthMiddleware {
+	a.sessions = checker
+	return a
+}
+
 // Middleware returns the auth middleware function
 func (a *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			// Allow unauthenticated requests (some queries might be public)
-			next.ServeHTTP(w, r)
+		// Allow unauthenticated requests (some queries might be public), but
+		// not API keys over their rate limit or credentials that could not be
+		// checked
+		ctx, err := a.Authenticate(r.Context(), r.Header.Get("Authorization"))
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code != errors.ErrUnauthorized {
+			appErr.WriteHTTPResponse(w)
 			return
 		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			next.ServeHTTP(w, r)
-			return
-		}
+// Authenticate validates a "Bearer <token>" header holding a JWT or an API
+// key and, when valid, stores it for forwarding to services together with
+// the parsed claims. It is shared by the HTTP middleware and the websocket
+// connection_init handler.
+func (a *AuthMiddleware) Authenticate(ctx context.Context, authHeader string) (context.Context, error) {
+	if authHeader == "" {
+		return ctx, errors.New(errors.ErrUnauthorized, "Missing authorization header")
+	}
 
-		tokenString := parts[1]
-		token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return ctx, errors.New(errors.ErrUnauthorized, "Invalid authorization header format")
+	}
+
+	tokenString := parts[1]
+	var claims *UserClaims
+	if sharedMiddleware.IsAPIKey(tokenString) && a.apiKeys != nil {
+		var err error
+		if claims, err = a.verifyAPIKey(ctx, tokenString); err != nil {
+			return ctx, err
+		}
+	} else {
+		claims = &UserClaims{}
+		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 			return []byte(a.jwtConfig.Secret), nil
 		})
 
 		if err != nil || !token.Valid {
-			next.ServeHTTP(w, r)
-			return
+			return ctx, errors.New(errors.ErrUnauthorized, "Invalid or expired token")
 		}
+		if a.sessions != nil {
+			if err := a.checkSession(ctx, claims); err != nil {
+				return ctx, err
+			}
+		}
+	}
 
-		// Add auth header to context for forwarding to services
-		ctx := context.WithValue(r.Context(), "Authorization", authHeader)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+	// Add auth header to context for forwarding to services
+	ctx = context.WithValue(ctx, "Authorization", authHeader)
+	ctx = context.WithValue(ctx, claimsContextKey{}, claims)
+	return ctx, nil
+}
+
+// verifyAPIKey resolves an API key and applies its rate limit
+func (a *AuthMiddleware) verifyAPIKey(ctx context.Context, key string) (*UserClaims, error) {
+	claims, err := a.apiKeys.VerifyAPIKey(ctx, key)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.IsServerError() {
+			return nil, errors.New(errors.ErrServiceUnavailable, "Could not verify API key")
+		}
+		return nil, errors.New(errors.ErrUnauthorized, "Invalid, expired or revoked API key")
+	}
+
+	if a.limiter != nil {
+		if err := a.limiter.AllowAPIKey(claims); err != nil {
+			return nil, err
+		}
+	}
+	return &UserClaims{
+		UserID:      claims.UserID,
+		Email:       claims.Email,
+		Role:        claims.Role,
+		Permissions: claims.Permissions,
+		APIKeyID:    claims.APIKeyID,
+		Scopes:      claims.Scopes,
+		RateLimit:   claims.RateLimit,
+	}, nil
+}
+
+// checkSession rejects claims whose session is not active
+func (a *AuthMiddleware) checkSession(ctx context.Context, claims *UserClaims) error {
+	if claims.SessionID == "" {
+		return errors.New(errors.ErrUnauthorized, "Token has no session, please sign in again")
+	}
+
+	if err := a.sessions.CheckSession(ctx, claims.UserID, claims.SessionID); err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.IsServerError() {
+			return errors.New(errors.ErrServiceUnavailable, "Could not check session")
+		}
+		return errors.New(errors.ErrUnauthorized, "Session has been revoked or has expired")
+	}
+	return nil
+}
+
+// GetUserClaims returns the authenticated user's claims from context
+func GetUserClaims(ctx context.Context) (*UserClaims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*UserClaims)
+	return claims, ok
 }
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	sharedMiddleware "github.com/microservices-go/shared/middleware"
+)
+
+// Deadline bounds each GraphQL request by timeout (or a shorter client-supplied
+// X-Request-Timeout). Downstream calls inherit the deadline from the context.
+// WebSocket upgrades are long-lived and left untouched.
+func Deadline(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			budget := timeout
+			if ms, err := strconv.ParseInt(r.Header.Get(sharedMiddleware.RequestTimeoutHeader), 10, 64); err == nil && ms > 0 {
+				if requested := time.Duration(ms) * time.Millisecond; requested < budget {
+					budget = requested
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), budget)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
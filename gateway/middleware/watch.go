@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/microservices-go/shared/errors"
+)
+
+// Watch returns a context derived from one returned by Authenticate that is
+// cancelled once its credentials stop being valid: when the token expires,
+// or when a check every interval finds its session or API key revoked.
+// Websocket connections run on it, so that signing out, revoking a session
+// or deactivating the user ends their subscriptions.
+func (a *AuthMiddleware) Watch(ctx context.Context, interval time.Duration) context.Context {
+	claims, ok := GetUserClaims(ctx)
+	if !ok {
+		return ctx
+	}
+	authHeader, _ := ctx.Value("Authorization").(string)
+
+	var cancel context.CancelFunc
+	if claims.ExpiresAt != nil {
+		ctx, cancel = context.WithDeadline(ctx, claims.ExpiresAt.Time)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	go func() {
+		defer cancel()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := a.recheck(ctx, claims, authHeader); err != nil && !isServerError(err) {
+					return
+				}
+			}
+		}
+	}()
+	return ctx
+}
+
+// recheck checks that the session or API key of authenticated claims is
+// still active; unlike Authenticate it does not count against rate limits
+func (a *AuthMiddleware) recheck(ctx context.Context, claims *UserClaims, authHeader string) error {
+	if claims.FromAPIKey() {
+		if a.apiKeys == nil {
+			return nil
+		}
+		_, key, _ := strings.Cut(authHeader, " ")
+		_, err := a.apiKeys.VerifyAPIKey(ctx, key)
+		return err
+	}
+	if a.sessions == nil {
+		return nil
+	}
+	return a.checkSession(ctx, claims)
+}
+
+// isServerError reports whether err means credentials could not be checked,
+// rather than that they were rejected
+func isServerError(err error) bool {
+	appErr, ok := err.(*errors.AppError)
+	return ok && appErr.IsServerError()
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/errors"
+	sharedMiddleware "github.com/microservices-go/shared/middleware"
+)
+
+// fakeSession is one session, active until revoked
+type fakeSession struct {
+	revoked atomic.Bool
+	down    atomic.Bool
+}
+
+func (f *fakeSession) CheckSession(ctx context.Context, userID, sessionID string) error {
+	if f.down.Load() {
+		return errors.New(errors.ErrServiceUnavailable, "down")
+	}
+	if f.revoked.Load() {
+		return errors.New(errors.ErrUnauthorized, "Session has been revoked")
+	}
+	return nil
+}
+
+func TestWatchEndsWithCredentials(t *testing.T) {
+	jwtConfig := &config.JWTConfig{Secret: "user-secret", Issuer: "test"}
+	authenticate := func(t *testing.T, auth *AuthMiddleware, expiresAt time.Time) context.Context {
+		token, err := sharedMiddleware.GenerateToken("u1", "u1@example.com", "user", nil, "s1", expiresAt, jwtConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, err := auth.Authenticate(context.Background(), "Bearer "+token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ctx
+	}
+	done := func(ctx context.Context) bool {
+		select {
+		case <-ctx.Done():
+			return true
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}
+
+	t.Run("revoked session", func(t *testing.T) {
+		session := &fakeSession{}
+		auth := NewAuthMiddleware(jwtConfig).WithSessions(session)
+		ctx := auth.Watch(authenticate(t, auth, time.Now().Add(time.Hour)), 5*time.Millisecond)
+
+		if done(ctx) {
+			t.Fatal("connection ended while the session is active")
+		}
+		session.down.Store(true)
+		if done(ctx) {
+			t.Fatal("connection ended while sessions could not be checked")
+		}
+		session.down.Store(false)
+		session.revoked.Store(true)
+		if !done(ctx) {
+			t.Error("connection outlived its session")
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		auth := NewAuthMiddleware(jwtConfig).WithSessions(&fakeSession{})
+		ctx := auth.Watch(authenticate(t, auth, time.Now().Add(time.Second)), time.Hour)
+		select {
+		case <-ctx.Done():
+		case <-time.After(3 * time.Second):
+			t.Error("connection outlived its token")
+		}
+	})
+
+	t.Run("anonymous", func(t *testing.T) {
+		ctx := NewAuthMiddleware(jwtConfig).Watch(context.Background(), time.Millisecond)
+		if done(ctx) {
+			t.Error("anonymous connection ended")
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,17 +13,32 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
+	"github.com/microservices-go/shared/apikey"
+	"github.com/microservices-go/shared/audit"
 	"github.com/microservices-go/shared/cache"
 	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/encryption"
+	"github.com/microservices-go/shared/health"
 	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/metrics"
 	"github.com/microservices-go/shared/middleware"
 	"github.com/microservices-go/shared/migrate"
+	auditv1 "github.com/microservices-go/shared/proto/audit/v1"
+	orderv1 "github.com/microservices-go/shared/proto/order/v1"
 	"github.com/microservices-go/shared/rabbitmq"
 	"github.com/microservices-go/shared/redis"
+	"github.com/microservices-go/shared/rpc"
+	"github.com/microservices-go/shared/session"
+	"github.com/microservices-go/shared/subgraph"
+	"github.com/microservices-go/shared/tracing"
 
+	"github.com/microservices-go/services/order/internal/graph"
+	"github.com/microservices-go/services/order/internal/graph/generated"
 	"github.com/microservices-go/services/order/internal/order"
 	"github.com/microservices-go/services/order/internal/rabbit"
 )
@@ -36,8 +52,22 @@ func main() {
 	dbConfig := config.LoadDatabaseConfig("order")
 	serverConfig := config.LoadServerConfig("order")
 	jwtConfig := config.LoadJWTConfig()
+	serviceAuthConfig := config.LoadServiceAuthConfig("order-service")
 	rabbitConfig := config.LoadRabbitMQConfig()
 	redisConfig := config.LoadRedisConfig()
+	tracingConfig := config.LoadTracingConfig()
+	subgraphConfig := config.LoadSubgraphConfig("order")
+
+	// Initialize tracing
+	shutdownTracer, err := tracing.InitTracer("order-service", tracingConfig)
+	if err != nil {
+		log.Warn("Failed to initialize tracing: " + err.Error())
+	} else {
+		defer shutdownTracer(context.Background())
+		if tracingConfig.Enabled {
+			log.Infof("Tracing enabled, exporting to %s", tracingConfig.Endpoint)
+		}
+	}
 
 	// Connect to Redis
 	redisClient, err := redis.NewClient(redisConfig)
@@ -82,6 +112,16 @@ func main() {
 	}
 	log.Info("Connected to database via GORM")
 
+	// Export connection pool stats
+	if err := metrics.RegisterDBStats(sqlDB, "order"); err != nil {
+		log.Warn("Failed to register DB stats collector: " + err.Error())
+	}
+
+	// Trace GORM queries
+	if err := db.Use(tracing.NewGormPlugin("order")); err != nil {
+		log.Warn("Failed to register GORM tracing plugin: " + err.Error())
+	}
+
 	// Run database migrations (if enabled)
 	if shouldAutoMigrate() {
 		if err := runMigrations(sqlDB, log); err != nil {
@@ -113,11 +153,30 @@ func main() {
 		}
 	}
 
+	// Initialize field encryption of shipping addresses
+	encryptionConfig := config.LoadEncryptionConfig("order")
+	encryptor, err := encryption.NewFromConfig(encryptionConfig)
+	if err != nil {
+		log.Fatal("Failed to initialize encryption: " + err.Error())
+	}
+	if err := db.Use(encryption.NewGormPlugin(encryptor)); err != nil {
+		log.Fatal("Failed to register field encryption: " + err.Error())
+	}
+
+	// Re-encrypt values under older keys, or written before encryption
+	reencryptCtx, stopReencryption := context.WithCancel(context.Background())
+	defer stopReencryption()
+	go encryptor.RunReencryption(reencryptCtx, db, order.EncryptedTargets, encryptionConfig.ReencryptBatchSize,
+		time.Duration(encryptionConfig.ReencryptInterval)*time.Second)
+
 	// Initialize repository
 	orderRepo := order.NewRepository(db)
 
+	// Initialize audit log
+	auditLog := audit.NewLog(db)
+
 	// Initialize service
-	orderService := order.NewService(orderRepo, publisher, cacheClient)
+	orderService := order.NewService(orderRepo, publisher, cacheClient, auditLog)
 
 	// Initialize consumer
 	if rabbitClient != nil {
@@ -132,8 +191,21 @@ func main() {
 	// Initialize handler
 	orderHandler := order.NewHandler(orderService)
 
-	// Initialize auth middleware
-	authMiddleware := middleware.NewAuthMiddleware(jwtConfig)
+	// Initialize auth middleware; API keys and sessions are checked by the user service
+	userConn, err := grpc.NewClient(getEnv("USER_SERVICE_GRPC_ADDR", "localhost:50051"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatal("Failed to create user service client: " + err.Error())
+	}
+	defer userConn.Close()
+	apiKeys := apikey.NewRemote(userConn, serviceAuthConfig, config.LoadAPIKeyConfig())
+	sessions := session.NewRemote(userConn, serviceAuthConfig, config.LoadSessionConfig())
+	authMiddleware := middleware.NewAuthMiddleware(jwtConfig).
+		WithAPIKeys(apiKeys, rateLimiter).
+		WithSessions(sessions)
+	serviceAuth, err := middleware.NewServiceAuth(serviceAuthConfig, "order-service")
+	if err != nil {
+		log.Fatal("Failed to load service keys: " + err.Error())
+	}
 
 	// Setup router
 	r := chi.NewRouter()
@@ -141,21 +213,61 @@ func main() {
 	// Middleware
 	r.Use(chiMiddleware.RequestID)
 	r.Use(chiMiddleware.RealIP)
+	r.Use(middleware.ClientInfoMiddleware)
+	r.Use(middleware.TracingMiddleware)
+	r.Use(middleware.DeadlineMiddleware)
 	r.Use(middleware.LoggingMiddleware)
 	r.Use(middleware.RecoveryMiddleware)
 	r.Use(middleware.SecurityHeadersMiddleware)
 	r.Use(middleware.CORSMiddleware([]string{"*"}))
+	r.Use(middleware.MetricsMiddleware("order-service"))
 
 	// Rate limiting middleware
 	if rateLimiter != nil {
 		r.Use(rateLimiter.Middleware)
 	}
 
-	// Health check
-	r.Get("/health", orderHandler.HealthCheck)
+	// Health checks: /livez for the process, /readyz (and legacy /health) for dependencies
+	healthRegistry := health.NewRegistry("order-service")
+	healthRegistry.Register("database", health.SQLCheck(sqlDB))
+	healthRegistry.RegisterOptional("redis", health.RedisCheck(redisClient.GetClient()))
+	healthRegistry.RegisterOptional("rabbitmq", health.AMQPCheck(rabbitClient))
+	r.Get("/livez", healthRegistry.LivenessHandler)
+	r.Get("/readyz", healthRegistry.ReadinessHandler)
+	r.Get("/health", healthRegistry.ReadinessHandler)
+
+	// Prometheus metrics
+	r.Handle("/metrics", metrics.Handler())
 
 	// API routes
-	orderHandler.RegisterRoutes(r, authMiddleware)
+	orderHandler.RegisterRoutes(r, authMiddleware, serviceAuth)
+
+	// Federated GraphQL subgraph, composed by the router (see federation/)
+	if subgraphConfig.Enabled {
+		schema := generated.NewExecutableSchema(generated.Config{Resolvers: &graph.Resolver{Service: orderService}})
+		r.With(authMiddleware.Optional).Handle("/graphql", subgraph.NewHandler(schema))
+		log.Info("GraphQL subgraph enabled at /graphql")
+	}
+
+	// gRPC server for internal callers such as the gateway
+	grpcServer := rpc.NewServer("order-service", authMiddleware, serviceAuth, rpc.Methods{
+		Internal: []string{
+			orderv1.OrderService_BatchGetOrders_FullMethodName,
+			orderv1.OrderService_BatchGetOrdersByUser_FullMethodName,
+		},
+	})
+	orderv1.RegisterOrderServiceServer(grpcServer, order.NewGRPCServer(orderService))
+	auditv1.RegisterAuditServiceServer(grpcServer, audit.NewGRPCServer(auditLog))
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%d", serverConfig.GRPCPort))
+	if err != nil {
+		log.Fatal("Failed to listen for gRPC: " + err.Error())
+	}
+	go func() {
+		log.Infof("gRPC server starting on port %d", serverConfig.GRPCPort)
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatal("gRPC server failed: " + err.Error())
+		}
+	}()
 
 	// Create server
 	srv := &http.Server{
@@ -195,6 +307,7 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	grpcServer.GracefulStop()
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Error("Server forced to shutdown: " + err.Error())
 	}
@@ -0,0 +1,57 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.86
+
+import (
+	"context"
+
+	"github.com/microservices-go/services/order/internal/graph/generated"
+	"github.com/microservices-go/services/order/internal/graph/model"
+	"github.com/microservices-go/services/order/internal/order"
+	"github.com/microservices-go/shared/subgraph"
+)
+
+// FindManyOrderByIDs is the resolver for the findManyOrderByIDs field.
+func (r *entityResolver) FindManyOrderByIDs(ctx context.Context, reps []*model.OrderByIDsInput) ([]*order.OrderResponse, error) {
+	if _, err := subgraph.CurrentUser(ctx); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(reps))
+	for i, rep := range reps {
+		ids[i] = rep.ID
+	}
+	orders, err := r.Service.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*order.OrderResponse, len(orders))
+	for _, o := range order.Readable(ctx, orders) {
+		byID[o.ID] = o
+	}
+	// Entities must line up with the representations; unknown ids resolve to null
+	result := make([]*order.OrderResponse, len(ids))
+	for i, id := range ids {
+		result[i] = byID[id]
+	}
+	return result, nil
+}
+
+// FindManyUserByIDs is the resolver for the findManyUserByIDs field.
+func (r *entityResolver) FindManyUserByIDs(ctx context.Context, reps []*model.UserByIDsInput) ([]*model.User, error) {
+	// Users live in the user subgraph; a reference is all User.orders needs
+	users := make([]*model.User, len(reps))
+	for i, rep := range reps {
+		users[i] = &model.User{ID: rep.ID}
+	}
+	return users, nil
+}
+
+// Entity returns generated.EntityResolver implementation.
+func (r *Resolver) Entity() generated.EntityResolver { return &entityResolver{r} }
+
+type entityResolver struct{ *Resolver }
@@ -0,0 +1,18 @@
+package graph
+
+import (
+	"github.com/microservices-go/services/order/internal/order"
+)
+
+// Resolver resolves the order subgraph from the order service
+type Resolver struct {
+	Service *order.Service
+}
+
+// intValue dereferences an optional Int argument; services apply their defaults to 0
+func intValue(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
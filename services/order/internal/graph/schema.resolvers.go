@@ -0,0 +1,97 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.86
+
+import (
+	"context"
+
+	"github.com/microservices-go/services/order/internal/graph/generated"
+	"github.com/microservices-go/services/order/internal/graph/model"
+	"github.com/microservices-go/services/order/internal/order"
+	"github.com/microservices-go/shared/subgraph"
+)
+
+// CreateOrder is the resolver for the createOrder field.
+func (r *mutationResolver) CreateOrder(ctx context.Context, input order.CreateOrderRequest) (*order.OrderResponse, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	input.UserID = claims.UserID
+	if err := order.CanCreate.Authorize(ctx, input.UserID); err != nil {
+		return nil, err
+	}
+	return r.Service.Create(ctx, &input)
+}
+
+// UpdateOrderStatus is the resolver for the updateOrderStatus field.
+func (r *mutationResolver) UpdateOrderStatus(ctx context.Context, id string, status string) (*order.OrderResponse, error) {
+	if err := order.CanUpdateStatus.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+	return r.Service.UpdateStatus(ctx, id, &order.UpdateOrderStatusRequest{Status: order.OrderStatus(status)})
+}
+
+// User is the resolver for the user field.
+func (r *orderResolver) User(ctx context.Context, obj *order.OrderResponse) (*model.User, error) {
+	return &model.User{ID: obj.UserID}, nil
+}
+
+// Status is the resolver for the status field.
+func (r *orderResolver) Status(ctx context.Context, obj *order.OrderResponse) (string, error) {
+	return string(obj.Status), nil
+}
+
+// Order is the resolver for the order field.
+func (r *queryResolver) Order(ctx context.Context, id string) (*order.OrderResponse, error) {
+	if _, err := subgraph.CurrentUser(ctx); err != nil {
+		return nil, err
+	}
+
+	o, err := r.Service.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := order.CanRead.Authorize(ctx, o.UserID); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// MyOrders is the resolver for the myOrders field.
+func (r *queryResolver) MyOrders(ctx context.Context, limit *int, offset *int) ([]*order.OrderResponse, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Service.GetByUserID(ctx, claims.UserID, intValue(limit), intValue(offset))
+}
+
+// Orders is the resolver for the orders field.
+func (r *userResolver) Orders(ctx context.Context, obj *model.User, limit *int, offset *int) ([]*order.OrderResponse, error) {
+	if err := order.CanRead.Authorize(ctx, obj.ID); err != nil {
+		return nil, err
+	}
+	return r.Service.GetByUserID(ctx, obj.ID, intValue(limit), intValue(offset))
+}
+
+// Mutation returns generated.MutationResolver implementation.
+func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
+
+// Order returns generated.OrderResolver implementation.
+func (r *Resolver) Order() generated.OrderResolver { return &orderResolver{r} }
+
+// Query returns generated.QueryResolver implementation.
+func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
+
+// User returns generated.UserResolver implementation.
+func (r *Resolver) User() generated.UserResolver { return &userResolver{r} }
+
+type mutationResolver struct{ *Resolver }
+type orderResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
@@ -0,0 +1,95 @@
+package order
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/microservices-go/shared/filter"
+	"gorm.io/gorm"
+)
+
+// ListFilter narrows and sorts the order list
+type ListFilter struct {
+	Status      []string
+	UserID      string
+	Currency    string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	MinAmount   *float64
+	MaxAmount   *float64
+	Sort        []filter.Sort
+}
+
+var orderStatuses = []string{
+	string(OrderStatusPending),
+	string(OrderStatusConfirmed),
+	string(OrderStatusProcessing),
+	string(OrderStatusShipped),
+	string(OrderStatusDelivered),
+	string(OrderStatusCancelled),
+}
+
+var orderSortColumns = map[string]string{
+	"created_at":   "created_at",
+	"total_amount": "total_amount",
+	"status":       "status",
+}
+
+// ParseListFilter parses and validates the list filter query parameters
+func ParseListFilter(values url.Values) (*ListFilter, error) {
+	q := filter.FromQuery(values)
+	f := &ListFilter{
+		Status:      q.Enum("status", orderStatuses...),
+		UserID:      q.String("user_id", 36),
+		Currency:    strings.ToUpper(q.String("currency", 3)),
+		CreatedFrom: q.Time("created_from"),
+		CreatedTo:   q.Time("created_to"),
+		MinAmount:   q.Float("min_amount"),
+		MaxAmount:   q.Float("max_amount"),
+		Sort:        q.Sort("sort", orderSortColumns),
+	}
+
+	if f.UserID != "" {
+		if _, err := uuid.Parse(f.UserID); err != nil {
+			q.Fail("user_id", "must be a valid UUID")
+		}
+	}
+	q.TimeRange("created_from", f.CreatedFrom, f.CreatedTo)
+	q.FloatRange("min_amount", f.MinAmount, f.MaxAmount)
+
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Apply adds the filter conditions to a query
+func (f *ListFilter) Apply(db *gorm.DB) *gorm.DB {
+	if f == nil {
+		return db
+	}
+	if len(f.Status) > 0 {
+		db = db.Where("status IN ?", f.Status)
+	}
+	if f.UserID != "" {
+		db = db.Where("user_id = ?", f.UserID)
+	}
+	if f.Currency != "" {
+		db = db.Where("currency = ?", f.Currency)
+	}
+	if f.CreatedFrom != nil {
+		db = db.Where("created_at >= ?", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		db = db.Where("created_at <= ?", *f.CreatedTo)
+	}
+	if f.MinAmount != nil {
+		db = db.Where("total_amount >= ?", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		db = db.Where("total_amount <= ?", *f.MaxAmount)
+	}
+	return db
+}
@@ -0,0 +1,253 @@
+package order
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/filter"
+	"github.com/microservices-go/shared/middleware"
+	commonv1 "github.com/microservices-go/shared/proto/common/v1"
+	orderv1 "github.com/microservices-go/shared/proto/order/v1"
+	"github.com/microservices-go/shared/response"
+	"github.com/microservices-go/shared/rpc"
+)
+
+// GRPCServer serves the order service over gRPC
+type GRPCServer struct {
+	orderv1.UnimplementedOrderServiceServer
+	service *Service
+}
+
+// NewGRPCServer creates a new order gRPC server
+func NewGRPCServer(service *Service) *GRPCServer {
+	return &GRPCServer{service: service}
+}
+
+// CreateOrder creates an order
+func (s *GRPCServer) CreateOrder(ctx context.Context, req *orderv1.CreateOrderRequest) (*orderv1.Order, error) {
+	if err := CanCreate.Authorize(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	items := make([]CreateOrderItemRequest, len(req.Items))
+	for i, item := range req.Items {
+		items[i] = CreateOrderItemRequest{
+			ProductID:   item.ProductId,
+			ProductName: item.ProductName,
+			Quantity:    int(item.Quantity),
+			UnitPrice:   item.UnitPrice,
+		}
+	}
+
+	order, err := s.service.Create(ctx, &CreateOrderRequest{
+		UserID:       req.UserId,
+		Currency:     req.GetCurrency(),
+		ShippingAddr: req.ShippingAddress,
+		Notes:        req.GetNotes(),
+		Items:        items,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order.ToProto(), nil
+}
+
+// GetOrder gets an order by ID
+func (s *GRPCServer) GetOrder(ctx context.Context, req *orderv1.GetOrderRequest) (*orderv1.Order, error) {
+	order, err := s.service.GetByID(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := CanRead.Authorize(ctx, order.UserID); err != nil {
+		return nil, err
+	}
+	return order.ToProto(), nil
+}
+
+// ListOrders lists orders matching a filter
+func (s *GRPCServer) ListOrders(ctx context.Context, req *orderv1.ListOrdersRequest) (*orderv1.ListOrdersResponse, error) {
+	if err := CanList.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	q := rpc.NewQuery(req.Page)
+	if f := req.Filter; f != nil {
+		q.List("status", f.Status)
+		q.String("user_id", f.UserId)
+		q.String("currency", f.Currency)
+		q.Time("created_from", f.CreatedFrom)
+		q.Time("created_to", f.CreatedTo)
+		q.Float("min_amount", f.MinAmount)
+		q.Float("max_amount", f.MaxAmount)
+	}
+
+	f, err := ParseListFilter(q.Values())
+	if err != nil {
+		return nil, err
+	}
+	page, err := q.Page()
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []*OrderResponse
+	var meta response.Meta
+	if page != nil {
+		if err := filter.CheckPage(page, f.Sort); err != nil {
+			return nil, err
+		}
+		var info response.PageInfo
+		if orders, info, err = s.service.ListPage(ctx, f, page); err != nil {
+			return nil, err
+		}
+		count, _ := s.service.Count(ctx, f)
+		meta = response.NewMeta(count, page.Size(), 0).WithPageInfo(info)
+	} else {
+		limit, offset := q.Offset()
+		if orders, err = s.service.List(ctx, f, limit, offset); err != nil {
+			return nil, err
+		}
+		count, _ := s.service.Count(ctx, f)
+		meta = response.NewMeta(count, limit, offset)
+	}
+	return &orderv1.ListOrdersResponse{Orders: toProtoList(orders), Meta: rpc.PageMeta(meta)}, nil
+}
+
+// ExportMyData returns every order of the calling user as JSON
+func (s *GRPCServer) ExportMyData(ctx context.Context, req *commonv1.ExportMyDataRequest) (*commonv1.DataExport, error) {
+	export, err := s.service.Export(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(export)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to encode export")
+	}
+	return &commonv1.DataExport{Data: data}, nil
+}
+
+// ListMyOrders lists the orders of the calling user
+func (s *GRPCServer) ListMyOrders(ctx context.Context, req *orderv1.ListMyOrdersRequest) (*orderv1.ListOrdersResponse, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+
+	q := rpc.NewQuery(req.Page)
+	page, err := q.Page()
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []*OrderResponse
+	var meta response.Meta
+	if page != nil {
+		var info response.PageInfo
+		if orders, info, err = s.service.GetByUserIDPage(ctx, claims.UserID, page); err != nil {
+			return nil, err
+		}
+		count, _ := s.service.CountByUserID(ctx, claims.UserID)
+		meta = response.NewMeta(count, page.Size(), 0).WithPageInfo(info)
+	} else {
+		limit, offset := q.Offset()
+		if orders, err = s.service.GetByUserID(ctx, claims.UserID, limit, offset); err != nil {
+			return nil, err
+		}
+		count, _ := s.service.CountByUserID(ctx, claims.UserID)
+		meta = response.NewMeta(count, limit, offset)
+	}
+	return &orderv1.ListOrdersResponse{Orders: toProtoList(orders), Meta: rpc.PageMeta(meta)}, nil
+}
+
+// UpdateOrderStatus updates the status of an order
+func (s *GRPCServer) UpdateOrderStatus(ctx context.Context, req *orderv1.UpdateOrderStatusRequest) (*orderv1.Order, error) {
+	if err := CanUpdateStatus.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	order, err := s.service.UpdateStatus(ctx, req.Id, &UpdateOrderStatusRequest{Status: OrderStatus(req.Status)})
+	if err != nil {
+		return nil, err
+	}
+	return order.ToProto(), nil
+}
+
+// BatchGetOrders streams the orders found for the requested IDs
+func (s *GRPCServer) BatchGetOrders(req *orderv1.BatchGetOrdersRequest, stream orderv1.OrderService_BatchGetOrdersServer) error {
+	if len(req.Ids) == 0 {
+		return errors.New(errors.ErrInvalidInput, "IDs array is required")
+	}
+
+	orders, err := s.service.GetByIDs(stream.Context(), req.Ids)
+	if err != nil {
+		return err
+	}
+	for _, order := range Readable(stream.Context(), orders) {
+		if err := stream.Send(order.ToProto()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BatchGetOrdersByUser streams one page of orders per requested user
+func (s *GRPCServer) BatchGetOrdersByUser(req *orderv1.BatchGetOrdersByUserRequest, stream orderv1.OrderService_BatchGetOrdersByUserServer) error {
+	if len(req.UserIds) == 0 {
+		return errors.New(errors.ErrInvalidInput, "UserIDs array is required")
+	}
+
+	orders, err := s.service.GetByUserIDs(stream.Context(), req.UserIds, int(req.Limit), int(req.Offset))
+	if err != nil {
+		return err
+	}
+
+	byUser := make(map[string][]*orderv1.Order, len(req.UserIds))
+	for _, order := range Readable(stream.Context(), orders) {
+		byUser[order.UserID] = append(byUser[order.UserID], order.ToProto())
+	}
+	for _, userID := range req.UserIds {
+		if err := stream.Send(&orderv1.UserOrders{UserId: userID, Orders: byUser[userID]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ToProto converts OrderResponse to its gRPC message
+func (o *OrderResponse) ToProto() *orderv1.Order {
+	items := make([]*orderv1.OrderItem, len(o.Items))
+	for i, item := range o.Items {
+		items[i] = &orderv1.OrderItem{
+			Id:          item.ID,
+			ProductId:   item.ProductID,
+			ProductName: item.ProductName,
+			Quantity:    int32(item.Quantity),
+			UnitPrice:   item.UnitPrice,
+		}
+	}
+
+	order := &orderv1.Order{
+		Id:              o.ID,
+		UserId:          o.UserID,
+		Status:          string(o.Status),
+		TotalAmount:     o.TotalAmount,
+		Currency:        o.Currency,
+		ShippingAddress: o.ShippingAddr,
+		Items:           items,
+		CreatedAt:       rpc.Timestamp(o.CreatedAt),
+		UpdatedAt:       rpc.Timestamp(o.UpdatedAt),
+	}
+	if o.Notes != "" {
+		order.Notes = &o.Notes
+	}
+	return order
+}
+
+func toProtoList(orders []*OrderResponse) []*orderv1.Order {
+	list := make([]*orderv1.Order, len(orders))
+	for i, order := range orders {
+		list[i] = order.ToProto()
+	}
+	return list
+}
@@ -0,0 +1,64 @@
+package order
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/dbtest"
+	"github.com/microservices-go/shared/middleware"
+	orderv1 "github.com/microservices-go/shared/proto/order/v1"
+	"github.com/microservices-go/shared/policy"
+	"github.com/microservices-go/shared/rpc"
+	"github.com/microservices-go/shared/rpc/rpctest"
+)
+
+func TestBatchGetOrdersStream(t *testing.T) {
+	db := dbtest.Open(t, dbtest.Tables{"orders": {
+		{"id": "o1", "user_id": "owner", "status": "pending", "total_amount": 10.0, "currency": "USD"},
+		{"id": "o2", "user_id": "other", "status": "pending", "total_amount": 20.0, "currency": "USD"},
+		{"id": "o3", "user_id": "owner", "status": "shipped", "total_amount": 30.0, "currency": "EUR"},
+	}})
+	srv := rpc.NewServer("order-service", middleware.NewAuthMiddleware(&config.JWTConfig{Secret: "test"}), rpctest.ServiceAuth(t, "order-service"), rpc.Methods{
+		Internal: []string{orderv1.OrderService_BatchGetOrders_FullMethodName},
+	})
+	orderv1.RegisterOrderServiceServer(srv, NewGRPCServer(NewService(NewRepository(db), nil, nil, nil)))
+	client := orderv1.NewOrderServiceClient(rpctest.Dial(t, srv))
+
+	tests := []struct {
+		name string
+		user *middleware.UserClaims
+		want []string
+	}{
+		{"owner", &middleware.UserClaims{UserID: "owner", Role: policy.RoleUser}, []string{"o1", "o3"}},
+		{"support", &middleware.UserClaims{UserID: "s1", Role: policy.RoleSupport, Permissions: policy.DefaultRoles[policy.RoleSupport]}, []string{"o1", "o2", "o3"}},
+		{"other user", &middleware.UserClaims{UserID: "stranger", Role: policy.RoleUser}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := rpc.OutgoingContext(context.Background(), rpctest.ServiceToken(t, "order-service", tt.user))
+			stream, err := client.BatchGetOrders(ctx, &orderv1.BatchGetOrdersRequest{Ids: []string{"o1", "o2", "o3", "missing"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for {
+				order, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, order.Id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got orders %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	"github.com/microservices-go/shared/errors"
-	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/filter"
 	"github.com/microservices-go/shared/middleware"
+	"github.com/microservices-go/shared/pagination"
+	"github.com/microservices-go/shared/policy"
 	"github.com/microservices-go/shared/response"
 )
 
@@ -24,19 +26,29 @@ func NewHandler(service *Service) *Handler {
 }
 
 // RegisterRoutes registers all routes
-func (h *Handler) RegisterRoutes(r chi.Router, authMiddleware *middleware.AuthMiddleware) {
+func (h *Handler) RegisterRoutes(r chi.Router, authMiddleware *middleware.AuthMiddleware, serviceAuth *middleware.ServiceAuth) {
 	r.Route("/api/v1/orders", func(r chi.Router) {
+		// Internal routes: batch lookups for the gateway's dataloaders take
+		// service tokens only and drop what the on-behalf-of user may not read
+		r.Group(func(r chi.Router) {
+			r.Use(serviceAuth.Authenticate)
+
+			r.Post("/batch", h.GetBatch)
+			r.Post("/batch-by-user", h.GetBatchByUserID)
+		})
+
 		// Protected routes
 		r.Group(func(r chi.Router) {
 			r.Use(authMiddleware.Authenticate)
 
+			// Create checks CanCreate against the body
 			r.Post("/", h.Create)
-			r.Get("/", h.List)
-			r.Post("/batch", h.GetBatch)
+			r.With(policy.Require(CanList, nil)).Get("/", h.List)
 			r.Get("/my-orders", h.GetMyOrders)
-			r.Get("/user/{userId}", h.GetByUserID)
-			r.Get("/{id}", h.GetByID)
-			r.Patch("/{id}/status", h.UpdateStatus)
+			r.Get("/my-data", h.ExportMyData)
+			r.With(policy.Require(CanRead, policy.Param("userId"))).Get("/user/{userId}", h.GetByUserID)
+			r.With(policy.Require(CanRead, h.orderOwner)).Get("/{id}", h.GetByID)
+			r.With(policy.Require(CanUpdateStatus, nil)).Patch("/{id}/status", h.UpdateStatus)
 		})
 	})
 }
@@ -51,6 +63,11 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := CanCreate.Authorize(ctx, req.UserID); err != nil {
+		writeError(w, err, "Failed to create order")
+		return
+	}
+
 	order, err := h.service.Create(ctx, &req)
 	if err != nil {
 		if appErr, ok := err.(*errors.AppError); ok {
@@ -82,6 +99,16 @@ func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
 	response.OK(w, order)
 }
 
+// ExportMyData returns every order of the current user
+func (h *Handler) ExportMyData(w http.ResponseWriter, r *http.Request) {
+	export, err := h.service.Export(r.Context())
+	if err != nil {
+		writeError(w, err, "Failed to export orders")
+		return
+	}
+	response.OK(w, export)
+}
+
 // GetMyOrders gets orders for current user
 func (h *Handler) GetMyOrders(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -92,6 +119,22 @@ func (h *Handler) GetMyOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	page, err := pagination.FromQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, err, "Invalid pagination parameters")
+		return
+	}
+	if page != nil {
+		orders, info, err := h.service.GetByUserIDPage(ctx, claims.UserID, page)
+		if err != nil {
+			writeError(w, err, "Failed to get orders")
+			return
+		}
+		count, _ := h.service.CountByUserID(ctx, claims.UserID)
+		response.List(w, orders, response.NewMeta(count, page.Size(), 0).WithPageInfo(info))
+		return
+	}
+
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
 
@@ -111,14 +154,40 @@ func (h *Handler) GetMyOrders(w http.ResponseWriter, r *http.Request) {
 	response.List(w, orders, meta)
 }
 
-// List lists all orders (admin only)
+// List lists all orders (staff only)
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	f, err := ParseListFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, err, "Invalid filter parameters")
+		return
+	}
+
+	page, err := pagination.FromQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, err, "Invalid pagination parameters")
+		return
+	}
+	if page != nil {
+		if err := filter.CheckPage(page, f.Sort); err != nil {
+			writeError(w, err, "Invalid pagination parameters")
+			return
+		}
+		orders, info, err := h.service.ListPage(ctx, f, page)
+		if err != nil {
+			writeError(w, err, "Failed to list orders")
+			return
+		}
+		count, _ := h.service.Count(ctx, f)
+		response.List(w, orders, response.NewMeta(count, page.Size(), 0).WithPageInfo(info))
+		return
+	}
+
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
 
-	orders, err := h.service.List(ctx, limit, offset)
+	orders, err := h.service.List(ctx, f, limit, offset)
 	if err != nil {
 		if appErr, ok := err.(*errors.AppError); ok {
 			appErr.WriteHTTPResponse(w)
@@ -128,7 +197,7 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	count, _ := h.service.Count(ctx)
+	count, _ := h.service.Count(ctx, f)
 	meta := response.NewMeta(count, limit, offset)
 
 	response.List(w, orders, meta)
@@ -185,7 +254,35 @@ func (h *Handler) GetBatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response.Batch(w, orders)
+	response.Batch(w, Readable(ctx, orders))
+}
+
+// GetBatchByUserID gets a page of orders for each of multiple users
+func (h *Handler) GetBatchByUserID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req struct {
+		UserIDs []string `json:"user_ids"`
+		Limit   int      `json:"limit"`
+		Offset  int      `json:"offset"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.New(errors.ErrInvalidInput, "Invalid request body").WriteHTTPResponse(w)
+		return
+	}
+
+	if len(req.UserIDs) == 0 {
+		errors.New(errors.ErrInvalidInput, "UserIDs array is required").WriteHTTPResponse(w)
+		return
+	}
+
+	orders, err := h.service.GetByUserIDs(ctx, req.UserIDs, req.Limit, req.Offset)
+	if err != nil {
+		writeError(w, err, "Failed to get orders")
+		return
+	}
+
+	response.Batch(w, Readable(ctx, orders))
 }
 
 // GetByUserID gets orders for a specific user
@@ -209,12 +306,11 @@ func (h *Handler) GetByUserID(w http.ResponseWriter, r *http.Request) {
 	response.OK(w, orders)
 }
 
-// HealthCheck handles health check
-func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	logger.Info("Health check called")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status":  "healthy",
-		"service": "order-service",
-	})
+// writeError writes an AppError as-is and anything else as an internal error
+func writeError(w http.ResponseWriter, err error, message string) {
+	if appErr, ok := err.(*errors.AppError); ok {
+		appErr.WriteHTTPResponse(w)
+		return
+	}
+	errors.New(errors.ErrInternalServer, message).WriteHTTPResponse(w)
 }
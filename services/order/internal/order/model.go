@@ -5,6 +5,8 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+
+	"github.com/microservices-go/shared/encryption"
 )
 
 // OrderStatus represents order status
@@ -19,14 +21,20 @@ const (
 	OrderStatusCancelled  OrderStatus = "cancelled"
 )
 
-// Order represents an order entity
+// EncryptedTargets are the encrypted columns of the order service, which the
+// re-encryption job keeps under the primary key
+var EncryptedTargets = []encryption.Target{
+	{Table: "orders", Key: "id", Columns: []string{"shipping_address"}},
+}
+
+// Order represents an order entity. The shipping address is encrypted at rest.
 type Order struct {
 	ID           string         `json:"id" gorm:"primaryKey;column:id"`
 	UserID       string         `json:"user_id" gorm:"column:user_id"`
 	Status       OrderStatus    `json:"status" gorm:"column:status"`
 	TotalAmount  float64        `json:"total_amount" gorm:"column:total_amount"`
 	Currency     string         `json:"currency" gorm:"column:currency"`
-	ShippingAddr string         `json:"shipping_address" gorm:"column:shipping_address"`
+	ShippingAddr string         `json:"shipping_address" gorm:"column:shipping_address;serializer:encrypted"`
 	Notes        string         `json:"notes,omitempty" gorm:"column:notes"`
 	CreatedAt    time.Time      `json:"created_at" gorm:"column:created_at"`
 	UpdatedAt    time.Time      `json:"updated_at" gorm:"column:updated_at"`
@@ -141,6 +149,9 @@ type OrderItemResponse struct {
 	UnitPrice   float64 `json:"unit_price"`
 }
 
+// IsEntity marks OrderResponse as the Order entity of the order subgraph
+func (OrderResponse) IsEntity() {}
+
 // ToResponse converts Order to OrderResponse
 func (o *Order) ToResponse() *OrderResponse {
 	items := make([]*OrderItemResponse, len(o.Items))
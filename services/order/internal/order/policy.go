@@ -0,0 +1,33 @@
+package order
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/microservices-go/shared/policy"
+)
+
+// Authorization rules of the order service, checked by the HTTP routes, the
+// gRPC methods and the subgraph resolvers
+var (
+	CanCreate       = policy.OwnerOr("order.create", policy.PermOrdersWrite)
+	CanRead         = policy.OwnerOr("order.read", policy.PermOrdersRead)
+	CanList         = policy.Permission("order.list", policy.PermOrdersRead)
+	CanUpdateStatus = policy.Permission("order.update_status", policy.PermOrdersWrite)
+)
+
+// Readable keeps the orders the caller may read
+func Readable(ctx context.Context, orders []*OrderResponse) []*OrderResponse {
+	return policy.Filter(ctx, CanRead, orders, func(o *OrderResponse) string { return o.UserID })
+}
+
+// orderOwner resolves the owner of the order in the id route parameter
+func (h *Handler) orderOwner(r *http.Request) (string, error) {
+	order, err := h.service.GetByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		return "", err
+	}
+	return order.UserID, nil
+}
@@ -0,0 +1,110 @@
+package order
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/microservices-go/shared/audit"
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/dbtest"
+	"github.com/microservices-go/shared/middleware"
+	orderv1 "github.com/microservices-go/shared/proto/order/v1"
+	"github.com/microservices-go/shared/policy/policytest"
+	"github.com/microservices-go/shared/rpc"
+	"github.com/microservices-go/shared/rpc/rpctest"
+)
+
+// TestEndpointRules checks the role matrix of the order endpoints through
+// the HTTP routes and the gRPC methods: whether admin, support, finance, the
+// owning user and another user may call each one. my-orders is scoped to the
+// caller and needs no rule.
+func TestEndpointRules(t *testing.T) {
+	db := dbtest.Open(t, dbtest.Tables{"orders": {
+		{"id": "o1", "user_id": policytest.OwnerID, "status": "pending", "total_amount": 10.0, "currency": "USD", "shipping_address": "1 Main St"},
+	}})
+	jwtConfig := &config.JWTConfig{Secret: "test", Issuer: "test"}
+	service := NewService(NewRepository(db), nil, nil, audit.NewLog(db))
+	auth := middleware.NewAuthMiddleware(jwtConfig)
+	serviceAuth := rpctest.ServiceAuth(t, "order-service")
+
+	router := chi.NewRouter()
+	NewHandler(service).RegisterRoutes(router, auth, serviceAuth)
+
+	srv := rpc.NewServer("order-service", auth, serviceAuth, rpc.Methods{
+		Internal: []string{
+			orderv1.OrderService_BatchGetOrders_FullMethodName,
+			orderv1.OrderService_BatchGetOrdersByUser_FullMethodName,
+		},
+	})
+	orderv1.RegisterOrderServiceServer(srv, NewGRPCServer(service))
+	client := orderv1.NewOrderServiceClient(rpctest.Dial(t, srv))
+
+	createBody := `{"user_id":"` + policytest.OwnerID + `","shipping_address":"1 Main St","items":[{"product_id":"p1","product_name":"Widget","quantity":1,"unit_price":10}]}`
+	matrix := &policytest.Matrix{Service: "order-service", JWT: jwtConfig, Router: router}
+	matrix.Run(t, []policytest.Endpoint{
+		{
+			Name: "createOrder", Admin: true, Owner: true,
+			HTTP: &policytest.Request{Method: "POST", Path: "/api/v1/orders", Body: createBody},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.CreateOrder(ctx, &orderv1.CreateOrderRequest{
+					UserId:          policytest.OwnerID,
+					ShippingAddress: "1 Main St",
+					Items:           []*orderv1.CreateOrderItem{{ProductId: "p1", ProductName: "Widget", Quantity: 1, UnitPrice: 10}},
+				})
+				return err
+			},
+		},
+		{
+			Name: "orders", Admin: true, Support: true, Finance: true,
+			HTTP: &policytest.Request{Method: "GET", Path: "/api/v1/orders"},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.ListOrders(ctx, &orderv1.ListOrdersRequest{})
+				return err
+			},
+		},
+		{
+			Name: "_entities", Admin: true, Support: true, Finance: true, Owner: true, Internal: true,
+			HTTP: &policytest.Request{Method: "POST", Path: "/api/v1/orders/batch", Body: `{"ids":["o1"]}`, Returns: `"o1"`},
+			GRPC: func(ctx context.Context) error {
+				stream, err := client.BatchGetOrders(ctx, &orderv1.BatchGetOrdersRequest{Ids: []string{"o1"}})
+				if err != nil {
+					return err
+				}
+				return policytest.Drain(stream.Recv, func(o *orderv1.Order) bool { return o.Id == "o1" })
+			},
+		},
+		{
+			Name: "User.orders batch", Admin: true, Support: true, Finance: true, Owner: true, Internal: true,
+			HTTP: &policytest.Request{Method: "POST", Path: "/api/v1/orders/batch-by-user", Body: `{"user_ids":["` + policytest.OwnerID + `"]}`, Returns: `"o1"`},
+			GRPC: func(ctx context.Context) error {
+				stream, err := client.BatchGetOrdersByUser(ctx, &orderv1.BatchGetOrdersByUserRequest{UserIds: []string{policytest.OwnerID}})
+				if err != nil {
+					return err
+				}
+				return policytest.Drain(stream.Recv, func(u *orderv1.UserOrders) bool { return len(u.Orders) > 0 })
+			},
+		},
+		{
+			Name: "User.orders", Admin: true, Support: true, Finance: true, Owner: true,
+			HTTP: &policytest.Request{Method: "GET", Path: "/api/v1/orders/user/" + policytest.OwnerID},
+		},
+		{
+			Name: "order", Admin: true, Support: true, Finance: true, Owner: true,
+			HTTP: &policytest.Request{Method: "GET", Path: "/api/v1/orders/o1"},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.GetOrder(ctx, &orderv1.GetOrderRequest{Id: "o1"})
+				return err
+			},
+		},
+		{
+			Name: "updateOrderStatus", Admin: true,
+			HTTP: &policytest.Request{Method: "PATCH", Path: "/api/v1/orders/o1/status", Body: `{"status":"confirmed"}`},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.UpdateOrderStatus(ctx, &orderv1.UpdateOrderStatusRequest{Id: "o1", Status: "confirmed"})
+				return err
+			},
+		},
+	})
+}
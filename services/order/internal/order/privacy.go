@@ -0,0 +1,138 @@
+package order
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/microservices-go/shared/audit"
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/middleware"
+	"github.com/microservices-go/shared/rabbitmq"
+)
+
+// DataExport is everything the order service holds about a user
+type DataExport struct {
+	Orders []*OrderResponse `json:"orders"`
+}
+
+// UserDataErasedEvent reports that a service erased a user's personal data
+// for an erasure request
+type UserDataErasedEvent struct {
+	RequestID string    `json:"request_id"`
+	UserID    string    `json:"user_id"`
+	ErasedAt  time.Time `json:"erased_at"`
+}
+
+// ListAllByUserID lists every order of a user, deleted ones included
+func (r *Repository) ListAllByUserID(ctx context.Context, userID string) ([]*Order, error) {
+	var orders []*Order
+	err := r.db.WithContext(ctx).Unscoped().Preload("Items").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&orders).Error
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to get orders")
+	}
+	return orders, nil
+}
+
+// EraseUserDataWithDB blanks the shipping address and notes of every order
+// of a user and returns the IDs of the orders changed
+func (r *Repository) EraseUserDataWithDB(ctx context.Context, db *gorm.DB, userID string) ([]string, error) {
+	var ids []string
+	err := db.WithContext(ctx).Unscoped().Model(&Order{}).
+		Where("user_id = ? AND (shipping_address <> '' OR COALESCE(notes, '') <> '')", userID).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to get orders")
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	err = db.WithContext(ctx).Unscoped().Model(&Order{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"shipping_address": "",
+			"notes":            "",
+			"updated_at":       time.Now(),
+		}).Error
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to erase order data")
+	}
+	return ids, nil
+}
+
+// Export returns every order of the calling user
+func (s *Service) Export(ctx context.Context) (*DataExport, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+	if claims.FromAPIKey() {
+		return nil, errors.New(errors.ErrForbidden, "API keys cannot export personal data")
+	}
+
+	orders, err := s.repo.ListAllByUserID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &DataExport{Orders: toResponses(orders)}, nil
+}
+
+// HandleUserErasureRequested erases the personal data in a user's orders.
+// Amounts, items and statuses stay for accounting. Running it again for the
+// same request is harmless.
+func (s *Service) HandleUserErasureRequested(ctx context.Context, requestID, userID string) error {
+	var ids []string
+	err := s.repo.WithTransaction(ctx, func(tx *gorm.DB) error {
+		var err error
+		if ids, err = s.repo.EraseUserDataWithDB(ctx, tx, userID); err != nil {
+			return err
+		}
+		return s.auditLog.RecordWithDB(ctx, tx, &audit.Event{
+			Action:     "order.erase_user_data",
+			TargetType: "user",
+			TargetID:   userID,
+			Metadata: map[string]string{
+				"request_id": requestID,
+				"orders":     strconv.Itoa(len(ids)),
+			},
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	log := logger.WithContext(ctx)
+	if s.cache != nil {
+		for _, id := range ids {
+			if err := s.cache.Delete(ctx, "order:id:"+id); err != nil {
+				log.WithError(err).Warn("Failed to invalidate order cache")
+			}
+		}
+		if err := s.cache.DeletePattern(ctx, "orders:user:"+userID+":*"); err != nil {
+			log.WithError(err).Warn("Failed to invalidate user orders cache")
+		}
+		if err := s.cache.DeletePattern(ctx, "orders:list:*"); err != nil {
+			log.WithError(err).Warn("Failed to invalidate orders list cache")
+		}
+	}
+
+	if s.publisher != nil {
+		event := &UserDataErasedEvent{RequestID: requestID, UserID: userID, ErasedAt: time.Now()}
+		if err := s.publisher.PublishEvent(ctx, rabbitmq.EventUserErased, event); err != nil {
+			return err
+		}
+	}
+
+	log.WithField("audit", true).
+		WithField("user_id", userID).
+		WithField("orders", len(ids)).
+		Info("User data erased from orders")
+	return nil
+}
@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/filter"
 	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/pagination"
 	"gorm.io/gorm"
 )
 
@@ -84,13 +86,28 @@ func (r *Repository) GetByUserID(ctx context.Context, userID string, limit, offs
 	return orders, nil
 }
 
-// List lists all orders with pagination
-func (r *Repository) List(ctx context.Context, limit, offset int) ([]*Order, error) {
+// GetByUserIDPage gets a keyset page of orders for a user
+func (r *Repository) GetByUserIDPage(ctx context.Context, userID string, page *pagination.Page) ([]*Order, error) {
 	log := logger.WithContext(ctx)
 
 	var orders []*Order
-	err := r.db.WithContext(ctx).Preload("Items").
-		Order("created_at DESC").
+	err := page.Scope(r.db.WithContext(ctx).Preload("Items").Where("user_id = ?", userID)).
+		Find(&orders).Error
+
+	if err != nil {
+		log.WithError(err).Error("Failed to get orders by user")
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to get orders")
+	}
+
+	return orders, nil
+}
+
+// List lists orders matching the filter with pagination
+func (r *Repository) List(ctx context.Context, f *ListFilter, limit, offset int) ([]*Order, error) {
+	log := logger.WithContext(ctx)
+
+	var orders []*Order
+	err := filter.OrderBy(f.Apply(r.db.WithContext(ctx).Preload("Items")), f.Sort).
 		Limit(limit).
 		Offset(offset).
 		Find(&orders).Error
@@ -103,6 +120,21 @@ func (r *Repository) List(ctx context.Context, limit, offset int) ([]*Order, err
 	return orders, nil
 }
 
+// ListPage lists a keyset page of orders matching the filter
+func (r *Repository) ListPage(ctx context.Context, f *ListFilter, page *pagination.Page) ([]*Order, error) {
+	log := logger.WithContext(ctx)
+
+	var orders []*Order
+	err := page.Scope(f.Apply(r.db.WithContext(ctx).Preload("Items"))).Find(&orders).Error
+
+	if err != nil {
+		log.WithError(err).Error("Failed to list orders")
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to list orders")
+	}
+
+	return orders, nil
+}
+
 // UpdateStatus updates order status
 func (r *Repository) UpdateStatus(ctx context.Context, id string, status OrderStatus) error {
 	return r.UpdateStatusWithDB(ctx, r.db, id, status)
@@ -132,10 +164,10 @@ func (r *Repository) UpdateStatusWithDB(ctx context.Context, db *gorm.DB, id str
 	return nil
 }
 
-// Count returns total order count
-func (r *Repository) Count(ctx context.Context) (int, error) {
+// Count returns the number of orders matching the filter
+func (r *Repository) Count(ctx context.Context, f *ListFilter) (int, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(&Order{}).Count(&count).Error
+	err := f.Apply(r.db.WithContext(ctx).Model(&Order{})).Count(&count).Error
 	if err != nil {
 		return 0, errors.Wrap(err, errors.ErrDatabaseError, "Failed to count orders")
 	}
@@ -169,3 +201,30 @@ func (r *Repository) GetByIDs(ctx context.Context, ids []string) ([]*Order, erro
 
 	return orders, nil
 }
+
+// GetByUserIDs gets one page of orders for each of several users, newest first
+func (r *Repository) GetByUserIDs(ctx context.Context, userIDs []string, limit, offset int) ([]*Order, error) {
+	log := logger.WithContext(ctx)
+
+	if len(userIDs) == 0 {
+		return []*Order{}, nil
+	}
+
+	// Number each user's orders so the page applies per user, not to the whole result
+	ranked := r.db.WithContext(ctx).Model(&Order{}).
+		Select("orders.*, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at DESC, id DESC) AS row_num").
+		Where("user_id IN ?", userIDs)
+
+	var orders []*Order
+	err := r.db.WithContext(ctx).Preload("Items").
+		Table("(?) AS orders", ranked).
+		Where("row_num > ? AND row_num <= ?", offset, offset+limit).
+		Order("user_id, row_num").
+		Find(&orders).Error
+	if err != nil {
+		log.WithError(err).Error("Failed to get orders by user IDs")
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to get orders by user IDs")
+	}
+
+	return orders, nil
+}
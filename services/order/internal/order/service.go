@@ -4,8 +4,14 @@ import (
 	"context"
 	"time"
 
+	"gorm.io/gorm"
+
+	"github.com/microservices-go/shared/audit"
 	"github.com/microservices-go/shared/cache"
 	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/pagination"
+	"github.com/microservices-go/shared/rabbitmq"
+	"github.com/microservices-go/shared/response"
 	"github.com/microservices-go/shared/validator"
 )
 
@@ -15,6 +21,7 @@ type Service struct {
 	cache     *cache.Cache
 	validator *validator.Validator
 	publisher EventPublisher
+	auditLog  *audit.Log
 	cacheTTL  time.Duration
 }
 
@@ -24,12 +31,13 @@ type EventPublisher interface {
 }
 
 // NewService creates a new order service
-func NewService(repo *Repository, publisher EventPublisher, cacheClient *cache.Cache) *Service {
+func NewService(repo *Repository, publisher EventPublisher, cacheClient *cache.Cache, auditLog *audit.Log) *Service {
 	return &Service{
 		repo:      repo,
 		cache:     cacheClient,
 		validator: validator.New(),
 		publisher: publisher,
+		auditLog:  auditLog,
 		cacheTTL:  3 * time.Minute,
 	}
 }
@@ -88,7 +96,7 @@ func (s *Service) Create(ctx context.Context, req *CreateOrderRequest) (*OrderRe
 			Notes:       order.Notes,
 			CreatedAt:   order.CreatedAt,
 		}
-		if err := s.publisher.PublishEvent(ctx, "order.created", event); err != nil {
+		if err := s.publisher.PublishEvent(ctx, rabbitmq.EventOrderCreated, event); err != nil {
 			log.WithError(err).Warn("Failed to publish order created event")
 		}
 	}
@@ -166,8 +174,19 @@ func (s *Service) GetByUserID(ctx context.Context, userID string, limit, offset
 	return responses, nil
 }
 
-// List lists all orders
-func (s *Service) List(ctx context.Context, limit, offset int) ([]*OrderResponse, error) {
+// GetByUserIDPage gets a cursor page of orders for a user
+func (s *Service) GetByUserIDPage(ctx context.Context, userID string, page *pagination.Page) ([]*OrderResponse, response.PageInfo, error) {
+	orders, err := s.repo.GetByUserIDPage(ctx, userID, page)
+	if err != nil {
+		return nil, response.PageInfo{}, err
+	}
+
+	orders, info := pagination.Trim(orders, page, orderCursor)
+	return toResponses(orders), info, nil
+}
+
+// List lists orders matching the filter
+func (s *Service) List(ctx context.Context, f *ListFilter, limit, offset int) ([]*OrderResponse, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 10
 	}
@@ -175,7 +194,7 @@ func (s *Service) List(ctx context.Context, limit, offset int) ([]*OrderResponse
 		offset = 0
 	}
 
-	orders, err := s.repo.List(ctx, limit, offset)
+	orders, err := s.repo.List(ctx, f, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -187,6 +206,29 @@ func (s *Service) List(ctx context.Context, limit, offset int) ([]*OrderResponse
 	return responses, nil
 }
 
+// ListPage lists a cursor page of orders matching the filter
+func (s *Service) ListPage(ctx context.Context, f *ListFilter, page *pagination.Page) ([]*OrderResponse, response.PageInfo, error) {
+	orders, err := s.repo.ListPage(ctx, f, page)
+	if err != nil {
+		return nil, response.PageInfo{}, err
+	}
+
+	orders, info := pagination.Trim(orders, page, orderCursor)
+	return toResponses(orders), info, nil
+}
+
+func orderCursor(o *Order) pagination.Cursor {
+	return pagination.NewCursor(o.CreatedAt, o.ID)
+}
+
+func toResponses(orders []*Order) []*OrderResponse {
+	responses := make([]*OrderResponse, len(orders))
+	for i, order := range orders {
+		responses[i] = order.ToResponse()
+	}
+	return responses
+}
+
 // UpdateStatus updates order status
 func (s *Service) UpdateStatus(ctx context.Context, id string, req *UpdateOrderStatusRequest) (*OrderResponse, error) {
 	// Validate request
@@ -202,8 +244,20 @@ func (s *Service) UpdateStatus(ctx context.Context, id string, req *UpdateOrderS
 
 	oldStatus := order.Status
 
-	// Update status
-	if err := s.repo.UpdateStatus(ctx, id, req.Status); err != nil {
+	// Update status and record it in the audit log together
+	err = s.repo.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := s.repo.UpdateStatusWithDB(ctx, tx, id, req.Status); err != nil {
+			return err
+		}
+		return s.auditLog.RecordWithDB(ctx, tx, &audit.Event{
+			Action:     "order.update_status",
+			TargetType: "order",
+			TargetID:   id,
+			Changes:    audit.Changes{"status": {From: oldStatus, To: req.Status}},
+			Metadata:   map[string]string{"user_id": order.UserID},
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -230,7 +284,7 @@ func (s *Service) UpdateStatus(ctx context.Context, id string, req *UpdateOrderS
 			NewStatus: string(req.Status),
 			ChangedAt: order.UpdatedAt,
 		}
-		if err := s.publisher.PublishEvent(ctx, "order.status_changed", event); err != nil {
+		if err := s.publisher.PublishEvent(ctx, rabbitmq.EventOrderUpdated, event); err != nil {
 			logger.WithContext(ctx).WithError(err).Warn("Failed to publish order status changed event")
 		}
 	}
@@ -239,9 +293,9 @@ func (s *Service) UpdateStatus(ctx context.Context, id string, req *UpdateOrderS
 	return s.GetByID(ctx, id)
 }
 
-// Count returns total order count
-func (s *Service) Count(ctx context.Context) (int, error) {
-	return s.repo.Count(ctx)
+// Count returns the number of orders matching the filter
+func (s *Service) Count(ctx context.Context, f *ListFilter) (int, error) {
+	return s.repo.Count(ctx, f)
 }
 
 // CountByUserID returns order count for a user
@@ -306,3 +360,24 @@ func (s *Service) GetByIDs(ctx context.Context, ids []string) ([]*OrderResponse,
 
 	return responses, nil
 }
+
+// GetByUserIDs gets the same page of orders for each of several users
+func (s *Service) GetByUserIDs(ctx context.Context, userIDs []string, limit, offset int) ([]*OrderResponse, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	orders, err := s.repo.GetByUserIDs(ctx, userIDs, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*OrderResponse, len(orders))
+	for i, order := range orders {
+		responses[i] = order.ToResponse()
+	}
+	return responses, nil
+}
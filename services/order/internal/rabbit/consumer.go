@@ -31,6 +31,9 @@ func (c *Consumer) Start(client *rabbitmq.Client) error {
 	if err := client.BindQueue(queue.Name, "microservices.events", "user-service.user.created"); err != nil {
 		return err
 	}
+	if err := client.BindQueue(queue.Name, "microservices.events", "user-service.user.erasure_requested"); err != nil {
+		return err
+	}
 	if err := client.BindQueue(queue.Name, "microservices.events", "payment-service.payment.success"); err != nil {
 		return err
 	}
@@ -43,6 +46,7 @@ func (c *Consumer) Start(client *rabbitmq.Client) error {
 
 	// Register handlers
 	consumer.RegisterHandler("user.created", c.handleUserCreated)
+	consumer.RegisterHandler("user.erasure_requested", c.handleUserErasureRequested)
 	consumer.RegisterHandler("payment.success", c.handlePaymentSuccess)
 	consumer.RegisterHandler("payment.failed", c.handlePaymentFailed)
 
@@ -63,6 +67,21 @@ func (c *Consumer) handleUserCreated(ctx context.Context, event *rabbitmq.Event)
 	return c.service.HandleUserCreated(ctx, payload.UserID)
 }
 
+func (c *Consumer) handleUserErasureRequested(ctx context.Context, event *rabbitmq.Event) error {
+	log := logger.WithContext(ctx)
+	log.Info("Handling user.erasure_requested event")
+
+	var payload struct {
+		RequestID string `json:"request_id"`
+		UserID    string `json:"user_id"`
+	}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return err
+	}
+
+	return c.service.HandleUserErasureRequested(ctx, payload.RequestID, payload.UserID)
+}
+
 func (c *Consumer) handlePaymentSuccess(ctx context.Context, event *rabbitmq.Event) error {
 	log := logger.WithContext(ctx)
 	log.Info("Handling payment.success event")
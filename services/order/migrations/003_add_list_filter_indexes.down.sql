@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_orders_currency;
+DROP INDEX IF EXISTS idx_orders_total_amount;
+DROP INDEX IF EXISTS idx_orders_user_created_at;
+DROP INDEX IF EXISTS idx_orders_status_created_at;
+DROP INDEX IF EXISTS idx_orders_created_at_id;
@@ -0,0 +1,11 @@
+-- Keyset pagination walks (created_at, id) in both directions
+CREATE INDEX IF NOT EXISTS idx_orders_created_at_id ON orders (created_at DESC, id DESC);
+
+-- Indexes for list filters and sorting
+CREATE INDEX IF NOT EXISTS idx_orders_status_created_at ON orders (status, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_orders_user_created_at ON orders (user_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_orders_total_amount ON orders (total_amount);
+
+CREATE INDEX IF NOT EXISTS idx_orders_currency ON orders (currency);
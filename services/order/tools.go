@@ -0,0 +1,9 @@
+//go:build tools
+
+package tools
+
+import (
+	_ "github.com/99designs/gqlgen"
+	_ "github.com/99designs/gqlgen/codegen/config"
+	_ "github.com/vektah/gqlparser/v2"
+)
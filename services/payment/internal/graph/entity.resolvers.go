@@ -0,0 +1,84 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.86
+
+import (
+	"context"
+
+	"github.com/microservices-go/services/payment/internal/graph/generated"
+	"github.com/microservices-go/services/payment/internal/graph/model"
+	"github.com/microservices-go/services/payment/internal/payment"
+	"github.com/microservices-go/shared/subgraph"
+)
+
+// FindManyOrderByIDs is the resolver for the findManyOrderByIDs field.
+func (r *entityResolver) FindManyOrderByIDs(ctx context.Context, reps []*model.OrderByIDsInput) ([]*model.Order, error) {
+	if _, err := subgraph.CurrentUser(ctx); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(reps))
+	for i, rep := range reps {
+		ids[i] = rep.ID
+	}
+	// Order.payment is the only field here, so load it for every order at once
+	payments, err := r.Service.GetByOrderIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byOrder := make(map[string]*payment.PaymentResponse, len(payments))
+	for _, p := range payment.Readable(ctx, payments) {
+		byOrder[p.OrderID] = p
+	}
+	orders := make([]*model.Order, len(ids))
+	for i, id := range ids {
+		orders[i] = &model.Order{ID: id, Payment: byOrder[id]}
+	}
+	return orders, nil
+}
+
+// FindManyPaymentByIDs is the resolver for the findManyPaymentByIDs field.
+func (r *entityResolver) FindManyPaymentByIDs(ctx context.Context, reps []*model.PaymentByIDsInput) ([]*payment.PaymentResponse, error) {
+	if _, err := subgraph.CurrentUser(ctx); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(reps))
+	for i, rep := range reps {
+		ids[i] = rep.ID
+	}
+	payments, err := r.Service.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*payment.PaymentResponse, len(payments))
+	for _, p := range payment.Readable(ctx, payments) {
+		byID[p.ID] = p
+	}
+	// Entities must line up with the representations; unknown ids resolve to null
+	result := make([]*payment.PaymentResponse, len(ids))
+	for i, id := range ids {
+		result[i] = byID[id]
+	}
+	return result, nil
+}
+
+// FindManyUserByIDs is the resolver for the findManyUserByIDs field.
+func (r *entityResolver) FindManyUserByIDs(ctx context.Context, reps []*model.UserByIDsInput) ([]*model.User, error) {
+	// Users live in the user subgraph; a reference is all User.payments needs
+	users := make([]*model.User, len(reps))
+	for i, rep := range reps {
+		users[i] = &model.User{ID: rep.ID}
+	}
+	return users, nil
+}
+
+// Entity returns generated.EntityResolver implementation.
+func (r *Resolver) Entity() generated.EntityResolver { return &entityResolver{r} }
+
+type entityResolver struct{ *Resolver }
@@ -0,0 +1,27 @@
+package graph
+
+import (
+	"github.com/microservices-go/services/payment/internal/payment"
+	"github.com/microservices-go/shared/errors"
+)
+
+// Resolver resolves the payment subgraph from the payment service
+type Resolver struct {
+	Service *payment.Service
+}
+
+// intValue dereferences an optional Int argument; services apply their defaults to 0
+func intValue(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// nilIfNotFound turns a not-found error into a null result for nullable fields
+func nilIfNotFound(p *payment.PaymentResponse, err error) (*payment.PaymentResponse, error) {
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	return p, err
+}
@@ -0,0 +1,159 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.86
+
+import (
+	"context"
+
+	"github.com/microservices-go/services/payment/internal/graph/generated"
+	"github.com/microservices-go/services/payment/internal/graph/model"
+	"github.com/microservices-go/services/payment/internal/payment"
+	"github.com/microservices-go/shared/subgraph"
+)
+
+// CreatePayment is the resolver for the createPayment field.
+func (r *mutationResolver) CreatePayment(ctx context.Context, input payment.CreatePaymentRequest) (*payment.PaymentResponse, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	input.UserID = claims.UserID
+	if err := payment.CanCreate.Authorize(ctx, input.UserID); err != nil {
+		return nil, err
+	}
+	return r.Service.Create(ctx, &input)
+}
+
+// ProcessPayment is the resolver for the processPayment field.
+func (r *mutationResolver) ProcessPayment(ctx context.Context, id string) (*payment.PaymentResponse, error) {
+	if _, err := subgraph.CurrentUser(ctx); err != nil {
+		return nil, err
+	}
+
+	p, err := r.Service.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := payment.CanProcess.Authorize(ctx, p.UserID); err != nil {
+		return nil, err
+	}
+	return r.Service.Process(ctx, id, &payment.ProcessPaymentRequest{})
+}
+
+// RefundPayment is the resolver for the refundPayment field.
+func (r *mutationResolver) RefundPayment(ctx context.Context, id string, amount *float64, reason *string) (*payment.PaymentResponse, error) {
+	if err := payment.CanRefund.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	req := &payment.RefundRequest{}
+	if amount != nil {
+		req.Amount = *amount
+	}
+	if reason != nil {
+		req.Reason = *reason
+	}
+	return r.Service.Refund(ctx, id, req)
+}
+
+// Order is the resolver for the order field.
+func (r *paymentResolver) Order(ctx context.Context, obj *payment.PaymentResponse) (*model.Order, error) {
+	return &model.Order{ID: obj.OrderID}, nil
+}
+
+// User is the resolver for the user field.
+func (r *paymentResolver) User(ctx context.Context, obj *payment.PaymentResponse) (*model.User, error) {
+	return &model.User{ID: obj.UserID}, nil
+}
+
+// Status is the resolver for the status field.
+func (r *paymentResolver) Status(ctx context.Context, obj *payment.PaymentResponse) (string, error) {
+	return string(obj.Status), nil
+}
+
+// Method is the resolver for the method field.
+func (r *paymentResolver) Method(ctx context.Context, obj *payment.PaymentResponse) (string, error) {
+	return string(obj.Method), nil
+}
+
+// Payment is the resolver for the payment field.
+func (r *queryResolver) Payment(ctx context.Context, id string) (*payment.PaymentResponse, error) {
+	if _, err := subgraph.CurrentUser(ctx); err != nil {
+		return nil, err
+	}
+
+	p, err := r.Service.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := payment.CanRead.Authorize(ctx, p.UserID); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// PaymentByOrder is the resolver for the paymentByOrder field.
+func (r *queryResolver) PaymentByOrder(ctx context.Context, orderID string) (*payment.PaymentResponse, error) {
+	if _, err := subgraph.CurrentUser(ctx); err != nil {
+		return nil, err
+	}
+
+	p, err := nilIfNotFound(r.Service.GetByOrderID(ctx, orderID))
+	if err != nil || p == nil {
+		return nil, err
+	}
+	if err := payment.CanRead.Authorize(ctx, p.UserID); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// MyPayments is the resolver for the myPayments field.
+func (r *queryResolver) MyPayments(ctx context.Context, limit *int, offset *int) ([]*payment.PaymentResponse, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Service.GetByUserID(ctx, claims.UserID, intValue(limit), intValue(offset))
+}
+
+// Payments is the resolver for the payments field.
+func (r *userResolver) Payments(ctx context.Context, obj *model.User, limit *int, offset *int) ([]*payment.PaymentResponse, error) {
+	if err := payment.CanRead.Authorize(ctx, obj.ID); err != nil {
+		return nil, err
+	}
+	return r.Service.GetByUserID(ctx, obj.ID, intValue(limit), intValue(offset))
+}
+
+// Method is the resolver for the method field.
+func (r *createPaymentInputResolver) Method(ctx context.Context, obj *payment.CreatePaymentRequest, data string) error {
+	obj.Method = payment.PaymentMethod(data)
+	return nil
+}
+
+// Mutation returns generated.MutationResolver implementation.
+func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
+
+// Payment returns generated.PaymentResolver implementation.
+func (r *Resolver) Payment() generated.PaymentResolver { return &paymentResolver{r} }
+
+// Query returns generated.QueryResolver implementation.
+func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
+
+// User returns generated.UserResolver implementation.
+func (r *Resolver) User() generated.UserResolver { return &userResolver{r} }
+
+// CreatePaymentInput returns generated.CreatePaymentInputResolver implementation.
+func (r *Resolver) CreatePaymentInput() generated.CreatePaymentInputResolver {
+	return &createPaymentInputResolver{r}
+}
+
+type mutationResolver struct{ *Resolver }
+type paymentResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
+type createPaymentInputResolver struct{ *Resolver }
@@ -0,0 +1,113 @@
+package payment
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/microservices-go/shared/filter"
+	"gorm.io/gorm"
+)
+
+// ListFilter narrows and sorts the payment list
+type ListFilter struct {
+	Status      []string
+	Method      []string
+	UserID      string
+	OrderID     string
+	Currency    string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	MinAmount   *float64
+	MaxAmount   *float64
+	Sort        []filter.Sort
+}
+
+var paymentStatuses = []string{
+	string(PaymentStatusPending),
+	string(PaymentStatusProcessing),
+	string(PaymentStatusSuccess),
+	string(PaymentStatusFailed),
+	string(PaymentStatusRefunded),
+	string(PaymentStatusCancelled),
+}
+
+var paymentMethods = []string{
+	string(PaymentMethodCard),
+	string(PaymentMethodBankTransfer),
+	string(PaymentMethodEWallet),
+	string(PaymentMethodCash),
+}
+
+var paymentSortColumns = map[string]string{
+	"created_at": "created_at",
+	"amount":     "amount",
+	"status":     "status",
+	"paid_at":    "paid_at",
+}
+
+// ParseListFilter parses and validates the list filter query parameters
+func ParseListFilter(values url.Values) (*ListFilter, error) {
+	q := filter.FromQuery(values)
+	f := &ListFilter{
+		Status:      q.Enum("status", paymentStatuses...),
+		Method:      q.Enum("method", paymentMethods...),
+		UserID:      q.String("user_id", 36),
+		OrderID:     q.String("order_id", 255),
+		Currency:    strings.ToUpper(q.String("currency", 3)),
+		CreatedFrom: q.Time("created_from"),
+		CreatedTo:   q.Time("created_to"),
+		MinAmount:   q.Float("min_amount"),
+		MaxAmount:   q.Float("max_amount"),
+		Sort:        q.Sort("sort", paymentSortColumns),
+	}
+
+	if f.UserID != "" {
+		if _, err := uuid.Parse(f.UserID); err != nil {
+			q.Fail("user_id", "must be a valid UUID")
+		}
+	}
+	q.TimeRange("created_from", f.CreatedFrom, f.CreatedTo)
+	q.FloatRange("min_amount", f.MinAmount, f.MaxAmount)
+
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Apply adds the filter conditions to a query
+func (f *ListFilter) Apply(db *gorm.DB) *gorm.DB {
+	if f == nil {
+		return db
+	}
+	if len(f.Status) > 0 {
+		db = db.Where("status IN ?", f.Status)
+	}
+	if len(f.Method) > 0 {
+		db = db.Where("method IN ?", f.Method)
+	}
+	if f.UserID != "" {
+		db = db.Where("user_id = ?", f.UserID)
+	}
+	if f.OrderID != "" {
+		db = db.Where("order_id = ?", f.OrderID)
+	}
+	if f.Currency != "" {
+		db = db.Where("currency = ?", f.Currency)
+	}
+	if f.CreatedFrom != nil {
+		db = db.Where("created_at >= ?", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		db = db.Where("created_at <= ?", *f.CreatedTo)
+	}
+	if f.MinAmount != nil {
+		db = db.Where("amount >= ?", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		db = db.Where("amount <= ?", *f.MaxAmount)
+	}
+	return db
+}
@@ -0,0 +1,296 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/filter"
+	"github.com/microservices-go/shared/middleware"
+	commonv1 "github.com/microservices-go/shared/proto/common/v1"
+	paymentv1 "github.com/microservices-go/shared/proto/payment/v1"
+	"github.com/microservices-go/shared/response"
+	"github.com/microservices-go/shared/rpc"
+)
+
+// GRPCServer serves the payment service over gRPC
+type GRPCServer struct {
+	paymentv1.UnimplementedPaymentServiceServer
+	service *Service
+}
+
+// NewGRPCServer creates a new payment gRPC server
+func NewGRPCServer(service *Service) *GRPCServer {
+	return &GRPCServer{service: service}
+}
+
+// CreatePayment creates a payment
+func (s *GRPCServer) CreatePayment(ctx context.Context, req *paymentv1.CreatePaymentRequest) (*paymentv1.Payment, error) {
+	if err := CanCreate.Authorize(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	payment, err := s.service.Create(ctx, &CreatePaymentRequest{
+		OrderID:     req.OrderId,
+		UserID:      req.UserId,
+		Amount:      req.Amount,
+		Currency:    req.GetCurrency(),
+		Method:      PaymentMethod(req.Method),
+		Description: req.GetDescription(),
+		Token:       req.GetToken(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment.ToProto(), nil
+}
+
+// GetPayment gets a payment by ID
+func (s *GRPCServer) GetPayment(ctx context.Context, req *paymentv1.GetPaymentRequest) (*paymentv1.Payment, error) {
+	payment, err := s.service.GetByID(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := CanRead.Authorize(ctx, payment.UserID); err != nil {
+		return nil, err
+	}
+	return payment.ToProto(), nil
+}
+
+// GetPaymentByOrder gets the payment of an order
+func (s *GRPCServer) GetPaymentByOrder(ctx context.Context, req *paymentv1.GetPaymentByOrderRequest) (*paymentv1.Payment, error) {
+	payment, err := s.service.GetByOrderID(ctx, req.OrderId)
+	if err != nil {
+		return nil, err
+	}
+	if err := CanRead.Authorize(ctx, payment.UserID); err != nil {
+		return nil, err
+	}
+	return payment.ToProto(), nil
+}
+
+// ListPayments lists payments matching a filter
+func (s *GRPCServer) ListPayments(ctx context.Context, req *paymentv1.ListPaymentsRequest) (*paymentv1.ListPaymentsResponse, error) {
+	if err := CanList.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	q := rpc.NewQuery(req.Page)
+	if f := req.Filter; f != nil {
+		q.List("status", f.Status)
+		q.List("method", f.Method)
+		q.String("user_id", f.UserId)
+		q.String("order_id", f.OrderId)
+		q.String("currency", f.Currency)
+		q.Time("created_from", f.CreatedFrom)
+		q.Time("created_to", f.CreatedTo)
+		q.Float("min_amount", f.MinAmount)
+		q.Float("max_amount", f.MaxAmount)
+	}
+
+	f, err := ParseListFilter(q.Values())
+	if err != nil {
+		return nil, err
+	}
+	page, err := q.Page()
+	if err != nil {
+		return nil, err
+	}
+
+	var payments []*PaymentResponse
+	var meta response.Meta
+	if page != nil {
+		if err := filter.CheckPage(page, f.Sort); err != nil {
+			return nil, err
+		}
+		var info response.PageInfo
+		if payments, info, err = s.service.ListPage(ctx, f, page); err != nil {
+			return nil, err
+		}
+		count, _ := s.service.Count(ctx, f)
+		meta = response.NewMeta(count, page.Size(), 0).WithPageInfo(info)
+	} else {
+		limit, offset := q.Offset()
+		if payments, err = s.service.List(ctx, f, limit, offset); err != nil {
+			return nil, err
+		}
+		count, _ := s.service.Count(ctx, f)
+		meta = response.NewMeta(count, limit, offset)
+	}
+	return &paymentv1.ListPaymentsResponse{Payments: toProtoList(payments), Meta: rpc.PageMeta(meta)}, nil
+}
+
+// ExportMyData returns every payment of the calling user as JSON
+func (s *GRPCServer) ExportMyData(ctx context.Context, req *commonv1.ExportMyDataRequest) (*commonv1.DataExport, error) {
+	export, err := s.service.Export(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(export)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to encode export")
+	}
+	return &commonv1.DataExport{Data: data}, nil
+}
+
+// ListMyPayments lists the payments of the calling user
+func (s *GRPCServer) ListMyPayments(ctx context.Context, req *paymentv1.ListMyPaymentsRequest) (*paymentv1.ListPaymentsResponse, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+
+	q := rpc.NewQuery(req.Page)
+	page, err := q.Page()
+	if err != nil {
+		return nil, err
+	}
+
+	var payments []*PaymentResponse
+	var meta response.Meta
+	if page != nil {
+		var info response.PageInfo
+		if payments, info, err = s.service.GetByUserIDPage(ctx, claims.UserID, page); err != nil {
+			return nil, err
+		}
+		count, _ := s.service.CountByUserID(ctx, claims.UserID)
+		meta = response.NewMeta(count, page.Size(), 0).WithPageInfo(info)
+	} else {
+		limit, offset := q.Offset()
+		if payments, err = s.service.GetByUserID(ctx, claims.UserID, limit, offset); err != nil {
+			return nil, err
+		}
+		count, _ := s.service.CountByUserID(ctx, claims.UserID)
+		meta = response.NewMeta(count, limit, offset)
+	}
+	return &paymentv1.ListPaymentsResponse{Payments: toProtoList(payments), Meta: rpc.PageMeta(meta)}, nil
+}
+
+// ProcessPayment charges a pending payment
+func (s *GRPCServer) ProcessPayment(ctx context.Context, req *paymentv1.ProcessPaymentRequest) (*paymentv1.Payment, error) {
+	existing, err := s.service.GetByID(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := CanProcess.Authorize(ctx, existing.UserID); err != nil {
+		return nil, err
+	}
+
+	payment, err := s.service.Process(ctx, req.Id, &ProcessPaymentRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return payment.ToProto(), nil
+}
+
+// RefundPayment refunds a payment in full or in part
+func (s *GRPCServer) RefundPayment(ctx context.Context, req *paymentv1.RefundPaymentRequest) (*paymentv1.Payment, error) {
+	if err := CanRefund.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	payment, err := s.service.Refund(ctx, req.Id, &RefundRequest{Amount: req.GetAmount(), Reason: req.GetReason()})
+	if err != nil {
+		return nil, err
+	}
+	return payment.ToProto(), nil
+}
+
+// BatchGetPayments streams the payments found for the requested IDs
+func (s *GRPCServer) BatchGetPayments(req *paymentv1.BatchGetPaymentsRequest, stream paymentv1.PaymentService_BatchGetPaymentsServer) error {
+	if len(req.Ids) == 0 {
+		return errors.New(errors.ErrInvalidInput, "IDs array is required")
+	}
+
+	payments, err := s.service.GetByIDs(stream.Context(), req.Ids)
+	if err != nil {
+		return err
+	}
+	return sendAll(stream, Readable(stream.Context(), payments))
+}
+
+// BatchGetPaymentsByOrder streams the payment of each requested order
+func (s *GRPCServer) BatchGetPaymentsByOrder(req *paymentv1.BatchGetPaymentsByOrderRequest, stream paymentv1.PaymentService_BatchGetPaymentsByOrderServer) error {
+	if len(req.OrderIds) == 0 {
+		return errors.New(errors.ErrInvalidInput, "OrderIDs array is required")
+	}
+
+	payments, err := s.service.GetByOrderIDs(stream.Context(), req.OrderIds)
+	if err != nil {
+		return err
+	}
+	return sendAll(stream, Readable(stream.Context(), payments))
+}
+
+// BatchGetPaymentsByUser streams one page of payments per requested user
+func (s *GRPCServer) BatchGetPaymentsByUser(req *paymentv1.BatchGetPaymentsByUserRequest, stream paymentv1.PaymentService_BatchGetPaymentsByUserServer) error {
+	if len(req.UserIds) == 0 {
+		return errors.New(errors.ErrInvalidInput, "UserIDs array is required")
+	}
+
+	payments, err := s.service.GetByUserIDs(stream.Context(), req.UserIds, int(req.Limit), int(req.Offset))
+	if err != nil {
+		return err
+	}
+
+	byUser := make(map[string][]*paymentv1.Payment, len(req.UserIds))
+	for _, payment := range Readable(stream.Context(), payments) {
+		byUser[payment.UserID] = append(byUser[payment.UserID], payment.ToProto())
+	}
+	for _, userID := range req.UserIds {
+		if err := stream.Send(&paymentv1.UserPayments{UserId: userID, Payments: byUser[userID]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ToProto converts PaymentResponse to its gRPC message
+func (p *PaymentResponse) ToProto() *paymentv1.Payment {
+	payment := &paymentv1.Payment{
+		Id:            p.ID,
+		OrderId:       p.OrderID,
+		UserId:        p.UserID,
+		Amount:        p.Amount,
+		Currency:      p.Currency,
+		Status:        string(p.Status),
+		Method:        string(p.Method),
+		TransactionId: optional(p.TransactionID),
+		Provider:      optional(p.Provider),
+		Description:   optional(p.Description),
+		FailureReason: optional(p.FailureReason),
+		CreatedAt:     rpc.Timestamp(p.CreatedAt),
+		UpdatedAt:     rpc.Timestamp(p.UpdatedAt),
+	}
+	if p.PaidAt != nil {
+		payment.PaidAt = rpc.Timestamp(*p.PaidAt)
+	}
+	return payment
+}
+
+func toProtoList(payments []*PaymentResponse) []*paymentv1.Payment {
+	list := make([]*paymentv1.Payment, len(payments))
+	for i, payment := range payments {
+		list[i] = payment.ToProto()
+	}
+	return list
+}
+
+func sendAll(stream interface {
+	Send(*paymentv1.Payment) error
+}, payments []*PaymentResponse) error {
+	for _, payment := range payments {
+		if err := stream.Send(payment.ToProto()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// optional returns nil for empty strings, which responses omit
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,64 @@
+package payment
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/dbtest"
+	"github.com/microservices-go/shared/middleware"
+	paymentv1 "github.com/microservices-go/shared/proto/payment/v1"
+	"github.com/microservices-go/shared/policy"
+	"github.com/microservices-go/shared/rpc"
+	"github.com/microservices-go/shared/rpc/rpctest"
+)
+
+func TestBatchGetPaymentsStream(t *testing.T) {
+	db := dbtest.Open(t, dbtest.Tables{"payments": {
+		{"id": "p1", "order_id": "o1", "user_id": "owner", "amount": 10.0, "currency": "USD", "status": "success", "method": "card"},
+		{"id": "p2", "order_id": "o2", "user_id": "other", "amount": 20.0, "currency": "USD", "status": "success", "method": "card"},
+		{"id": "p3", "order_id": "o3", "user_id": "owner", "amount": 30.0, "currency": "EUR", "status": "pending", "method": "card"},
+	}})
+	srv := rpc.NewServer("payment-service", middleware.NewAuthMiddleware(&config.JWTConfig{Secret: "test"}), rpctest.ServiceAuth(t, "payment-service"), rpc.Methods{
+		Internal: []string{paymentv1.PaymentService_BatchGetPayments_FullMethodName},
+	})
+	paymentv1.RegisterPaymentServiceServer(srv, NewGRPCServer(NewService(NewRepository(db), nil, nil, nil, nil)))
+	client := paymentv1.NewPaymentServiceClient(rpctest.Dial(t, srv))
+
+	tests := []struct {
+		name string
+		user *middleware.UserClaims
+		want []string
+	}{
+		{"owner", &middleware.UserClaims{UserID: "owner", Role: policy.RoleUser}, []string{"p1", "p3"}},
+		{"finance", &middleware.UserClaims{UserID: "f1", Role: policy.RoleFinance, Permissions: policy.DefaultRoles[policy.RoleFinance]}, []string{"p1", "p2", "p3"}},
+		{"other user", &middleware.UserClaims{UserID: "stranger", Role: policy.RoleUser}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := rpc.OutgoingContext(context.Background(), rpctest.ServiceToken(t, "payment-service", tt.user))
+			stream, err := client.BatchGetPayments(ctx, &paymentv1.BatchGetPaymentsRequest{Ids: []string{"p1", "p2", "p3", "missing"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for {
+				payment, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, payment.Id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got payments %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	"github.com/microservices-go/shared/errors"
-	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/filter"
 	"github.com/microservices-go/shared/middleware"
+	"github.com/microservices-go/shared/pagination"
+	"github.com/microservices-go/shared/policy"
 	"github.com/microservices-go/shared/response"
 )
 
@@ -24,21 +26,31 @@ func NewHandler(service *Service) *Handler {
 }
 
 // RegisterRoutes registers all routes
-func (h *Handler) RegisterRoutes(r chi.Router, authMiddleware *middleware.AuthMiddleware) {
+func (h *Handler) RegisterRoutes(r chi.Router, authMiddleware *middleware.AuthMiddleware, serviceAuth *middleware.ServiceAuth) {
 	r.Route("/api/v1/payments", func(r chi.Router) {
+		// Internal routes: batch lookups for the gateway's dataloaders take
+		// service tokens only and drop what the on-behalf-of user may not read
+		r.Group(func(r chi.Router) {
+			r.Use(serviceAuth.Authenticate)
+
+			r.Post("/batch", h.GetBatch)
+			r.Post("/batch-by-order", h.GetBatchByOrderID)
+			r.Post("/batch-by-user", h.GetBatchByUserID)
+		})
+
 		// Protected routes
 		r.Group(func(r chi.Router) {
 			r.Use(authMiddleware.Authenticate)
 
+			// Create checks CanCreate against the body
 			r.Post("/", h.Create)
-			r.Get("/", h.List)
-			r.Post("/batch", h.GetBatch)
-			r.Post("/batch-by-order", h.GetBatchByOrderID)
+			r.With(policy.Require(CanList, nil)).Get("/", h.List)
 			r.Get("/my-payments", h.GetMyPayments)
-			r.Get("/{id}", h.GetByID)
-			r.Get("/order/{orderId}", h.GetByOrderID)
-			r.Post("/{id}/process", h.Process)
-			r.Post("/{id}/refund", h.Refund)
+			r.Get("/my-data", h.ExportMyData)
+			r.With(policy.Require(CanRead, h.paymentOwner)).Get("/{id}", h.GetByID)
+			r.With(policy.Require(CanRead, h.orderPaymentOwner)).Get("/order/{orderId}", h.GetByOrderID)
+			r.With(policy.Require(CanProcess, h.paymentOwner)).Post("/{id}/process", h.Process)
+			r.With(policy.Require(CanRefund, nil)).Post("/{id}/refund", h.Refund)
 		})
 	})
 }
@@ -53,6 +65,11 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := CanCreate.Authorize(ctx, req.UserID); err != nil {
+		writeError(w, err, "Failed to create payment")
+		return
+	}
+
 	payment, err := h.service.Create(ctx, &req)
 	if err != nil {
 		if appErr, ok := err.(*errors.AppError); ok {
@@ -102,6 +119,16 @@ func (h *Handler) GetByOrderID(w http.ResponseWriter, r *http.Request) {
 	response.OK(w, payment)
 }
 
+// ExportMyData returns every payment of the current user
+func (h *Handler) ExportMyData(w http.ResponseWriter, r *http.Request) {
+	export, err := h.service.Export(r.Context())
+	if err != nil {
+		writeError(w, err, "Failed to export payments")
+		return
+	}
+	response.OK(w, export)
+}
+
 // GetMyPayments gets payments for current user
 func (h *Handler) GetMyPayments(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -112,6 +139,22 @@ func (h *Handler) GetMyPayments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	page, err := pagination.FromQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, err, "Invalid pagination parameters")
+		return
+	}
+	if page != nil {
+		payments, info, err := h.service.GetByUserIDPage(ctx, claims.UserID, page)
+		if err != nil {
+			writeError(w, err, "Failed to get payments")
+			return
+		}
+		count, _ := h.service.CountByUserID(ctx, claims.UserID)
+		response.List(w, payments, response.NewMeta(count, page.Size(), 0).WithPageInfo(info))
+		return
+	}
+
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
 
@@ -131,14 +174,40 @@ func (h *Handler) GetMyPayments(w http.ResponseWriter, r *http.Request) {
 	response.List(w, payments, meta)
 }
 
-// List lists all payments (admin only)
+// List lists all payments (staff only)
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	f, err := ParseListFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, err, "Invalid filter parameters")
+		return
+	}
+
+	page, err := pagination.FromQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, err, "Invalid pagination parameters")
+		return
+	}
+	if page != nil {
+		if err := filter.CheckPage(page, f.Sort); err != nil {
+			writeError(w, err, "Invalid pagination parameters")
+			return
+		}
+		payments, info, err := h.service.ListPage(ctx, f, page)
+		if err != nil {
+			writeError(w, err, "Failed to list payments")
+			return
+		}
+		count, _ := h.service.Count(ctx, f)
+		response.List(w, payments, response.NewMeta(count, page.Size(), 0).WithPageInfo(info))
+		return
+	}
+
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
 
-	payments, err := h.service.List(ctx, limit, offset)
+	payments, err := h.service.List(ctx, f, limit, offset)
 	if err != nil {
 		if appErr, ok := err.(*errors.AppError); ok {
 			appErr.WriteHTTPResponse(w)
@@ -148,7 +217,7 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	count, _ := h.service.Count(ctx)
+	count, _ := h.service.Count(ctx, f)
 	meta := response.NewMeta(count, limit, offset)
 
 	response.List(w, payments, meta)
@@ -229,7 +298,35 @@ func (h *Handler) GetBatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response.Batch(w, payments)
+	response.Batch(w, Readable(ctx, payments))
+}
+
+// GetBatchByUserID gets a page of payments for each of multiple users
+func (h *Handler) GetBatchByUserID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req struct {
+		UserIDs []string `json:"user_ids"`
+		Limit   int      `json:"limit"`
+		Offset  int      `json:"offset"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.New(errors.ErrInvalidInput, "Invalid request body").WriteHTTPResponse(w)
+		return
+	}
+
+	if len(req.UserIDs) == 0 {
+		errors.New(errors.ErrInvalidInput, "UserIDs array is required").WriteHTTPResponse(w)
+		return
+	}
+
+	payments, err := h.service.GetByUserIDs(ctx, req.UserIDs, req.Limit, req.Offset)
+	if err != nil {
+		writeError(w, err, "Failed to get payments")
+		return
+	}
+
+	response.Batch(w, Readable(ctx, payments))
 }
 
 // GetBatchByOrderID gets multiple payments by order IDs
@@ -259,15 +356,14 @@ func (h *Handler) GetBatchByOrderID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response.Batch(w, payments)
+	response.Batch(w, Readable(ctx, payments))
 }
 
-// HealthCheck handles health check
-func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	logger.Info("Health check called")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status":  "healthy",
-		"service": "payment-service",
-	})
+// writeError writes an AppError as-is and anything else as an internal error
+func writeError(w http.ResponseWriter, err error, message string) {
+	if appErr, ok := err.(*errors.AppError); ok {
+		appErr.WriteHTTPResponse(w)
+		return
+	}
+	errors.New(errors.ErrInternalServer, message).WriteHTTPResponse(w)
 }
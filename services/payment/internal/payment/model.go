@@ -132,6 +132,9 @@ type PaymentIntentResponse struct {
 	PaymentID    string `json:"payment_id"`
 }
 
+// IsEntity marks PaymentResponse as the Payment entity of the payment subgraph
+func (PaymentResponse) IsEntity() {}
+
 // ToResponse converts Payment to PaymentResponse
 func (p *Payment) ToResponse() *PaymentResponse {
 	return &PaymentResponse{
@@ -152,6 +155,17 @@ func (p *Payment) ToResponse() *PaymentResponse {
 	}
 }
 
+// PaymentCreatedEvent represents payment created event
+type PaymentCreatedEvent struct {
+	PaymentID string        `json:"payment_id"`
+	OrderID   string        `json:"order_id"`
+	UserID    string        `json:"user_id"`
+	Amount    float64       `json:"amount"`
+	Currency  string        `json:"currency"`
+	Method    PaymentMethod `json:"method"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
 // PaymentSuccessEvent represents payment success event
 type PaymentSuccessEvent struct {
 	PaymentID     string    `json:"payment_id"`
@@ -173,3 +187,14 @@ type PaymentFailedEvent struct {
 	FailureReason string    `json:"failure_reason"`
 	FailedAt      time.Time `json:"failed_at"`
 }
+
+// PaymentRefundedEvent represents payment refunded event
+type PaymentRefundedEvent struct {
+	PaymentID  string    `json:"payment_id"`
+	OrderID    string    `json:"order_id"`
+	UserID     string    `json:"user_id"`
+	Amount     float64   `json:"amount"`
+	Currency   string    `json:"currency"`
+	Reason     string    `json:"reason"`
+	RefundedAt time.Time `json:"refunded_at"`
+}
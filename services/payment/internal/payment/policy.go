@@ -0,0 +1,44 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/microservices-go/shared/policy"
+)
+
+// Authorization rules of the payment service, checked by the HTTP routes,
+// the gRPC methods and the subgraph resolvers
+var (
+	CanCreate  = policy.OwnerOr("payment.create", policy.PermPaymentsWrite)
+	CanRead    = policy.OwnerOr("payment.read", policy.PermPaymentsRead)
+	CanList    = policy.Permission("payment.list", policy.PermPaymentsRead)
+	CanProcess = policy.OwnerOr("payment.process", policy.PermPaymentsWrite)
+	CanRefund  = policy.Permission("payment.refund", policy.PermPaymentsRefund)
+)
+
+// Readable keeps the payments the caller may read
+func Readable(ctx context.Context, payments []*PaymentResponse) []*PaymentResponse {
+	return policy.Filter(ctx, CanRead, payments, func(p *PaymentResponse) string { return p.UserID })
+}
+
+// paymentOwner resolves the owner of the payment in the id route parameter
+func (h *Handler) paymentOwner(r *http.Request) (string, error) {
+	payment, err := h.service.GetByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		return "", err
+	}
+	return payment.UserID, nil
+}
+
+// orderPaymentOwner resolves the owner of the payment of the order in the
+// orderId route parameter
+func (h *Handler) orderPaymentOwner(r *http.Request) (string, error) {
+	payment, err := h.service.GetByOrderID(r.Context(), chi.URLParam(r, "orderId"))
+	if err != nil {
+		return "", err
+	}
+	return payment.UserID, nil
+}
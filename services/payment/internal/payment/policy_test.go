@@ -0,0 +1,132 @@
+package payment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/microservices-go/shared/audit"
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/dbtest"
+	"github.com/microservices-go/shared/middleware"
+	paymentv1 "github.com/microservices-go/shared/proto/payment/v1"
+	"github.com/microservices-go/shared/policy/policytest"
+	"github.com/microservices-go/shared/rpc"
+	"github.com/microservices-go/shared/rpc/rpctest"
+)
+
+// TestEndpointRules checks the role matrix of the payment endpoints through
+// the HTTP routes and the gRPC methods: whether admin, support, finance, the
+// owning user and another user may call each one. my-payments is scoped to
+// the caller and needs no rule.
+func TestEndpointRules(t *testing.T) {
+	// p1 can be processed and p2 refunded
+	db := dbtest.Open(t, dbtest.Tables{"payments": {
+		{"id": "p1", "order_id": "o1", "user_id": policytest.OwnerID, "amount": 10.0, "currency": "USD", "status": "pending", "method": "card"},
+		{"id": "p2", "order_id": "o2", "user_id": policytest.OwnerID, "amount": 20.0, "currency": "USD", "status": "success", "method": "card"},
+	}})
+	jwtConfig := &config.JWTConfig{Secret: "test", Issuer: "test"}
+	service := NewService(NewRepository(db), nil, nil, nil, audit.NewLog(db))
+	auth := middleware.NewAuthMiddleware(jwtConfig)
+	serviceAuth := rpctest.ServiceAuth(t, "payment-service")
+
+	router := chi.NewRouter()
+	NewHandler(service).RegisterRoutes(router, auth, serviceAuth)
+
+	srv := rpc.NewServer("payment-service", auth, serviceAuth, rpc.Methods{
+		Internal: []string{
+			paymentv1.PaymentService_BatchGetPayments_FullMethodName,
+			paymentv1.PaymentService_BatchGetPaymentsByOrder_FullMethodName,
+			paymentv1.PaymentService_BatchGetPaymentsByUser_FullMethodName,
+		},
+	})
+	paymentv1.RegisterPaymentServiceServer(srv, NewGRPCServer(service))
+	client := paymentv1.NewPaymentServiceClient(rpctest.Dial(t, srv))
+
+	isP1 := func(p *paymentv1.Payment) bool { return p.Id == "p1" }
+	matrix := &policytest.Matrix{Service: "payment-service", JWT: jwtConfig, Router: router}
+	matrix.Run(t, []policytest.Endpoint{
+		{
+			Name: "createPayment", Admin: true, Owner: true,
+			HTTP: &policytest.Request{Method: "POST", Path: "/api/v1/payments", Body: `{"order_id":"o3","user_id":"` + policytest.OwnerID + `","amount":30,"method":"card"}`},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.CreatePayment(ctx, &paymentv1.CreatePaymentRequest{OrderId: "o3", UserId: policytest.OwnerID, Amount: 30, Method: "card"})
+				return err
+			},
+		},
+		{
+			Name: "payments", Admin: true, Support: true, Finance: true,
+			HTTP: &policytest.Request{Method: "GET", Path: "/api/v1/payments"},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.ListPayments(ctx, &paymentv1.ListPaymentsRequest{})
+				return err
+			},
+		},
+		{
+			Name: "_entities", Admin: true, Support: true, Finance: true, Owner: true, Internal: true,
+			HTTP: &policytest.Request{Method: "POST", Path: "/api/v1/payments/batch", Body: `{"ids":["p1"]}`, Returns: `"p1"`},
+			GRPC: func(ctx context.Context) error {
+				stream, err := client.BatchGetPayments(ctx, &paymentv1.BatchGetPaymentsRequest{Ids: []string{"p1"}})
+				if err != nil {
+					return err
+				}
+				return policytest.Drain(stream.Recv, isP1)
+			},
+		},
+		{
+			Name: "Order.payment", Admin: true, Support: true, Finance: true, Owner: true, Internal: true,
+			HTTP: &policytest.Request{Method: "POST", Path: "/api/v1/payments/batch-by-order", Body: `{"order_ids":["o1"]}`, Returns: `"p1"`},
+			GRPC: func(ctx context.Context) error {
+				stream, err := client.BatchGetPaymentsByOrder(ctx, &paymentv1.BatchGetPaymentsByOrderRequest{OrderIds: []string{"o1"}})
+				if err != nil {
+					return err
+				}
+				return policytest.Drain(stream.Recv, isP1)
+			},
+		},
+		{
+			Name: "User.payments", Admin: true, Support: true, Finance: true, Owner: true, Internal: true,
+			HTTP: &policytest.Request{Method: "POST", Path: "/api/v1/payments/batch-by-user", Body: `{"user_ids":["` + policytest.OwnerID + `"]}`, Returns: `"p1"`},
+			GRPC: func(ctx context.Context) error {
+				stream, err := client.BatchGetPaymentsByUser(ctx, &paymentv1.BatchGetPaymentsByUserRequest{UserIds: []string{policytest.OwnerID}})
+				if err != nil {
+					return err
+				}
+				return policytest.Drain(stream.Recv, func(u *paymentv1.UserPayments) bool { return len(u.Payments) > 0 })
+			},
+		},
+		{
+			Name: "payment", Admin: true, Support: true, Finance: true, Owner: true,
+			HTTP: &policytest.Request{Method: "GET", Path: "/api/v1/payments/p1"},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.GetPayment(ctx, &paymentv1.GetPaymentRequest{Id: "p1"})
+				return err
+			},
+		},
+		{
+			Name: "paymentByOrder", Admin: true, Support: true, Finance: true, Owner: true,
+			HTTP: &policytest.Request{Method: "GET", Path: "/api/v1/payments/order/o1"},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.GetPaymentByOrder(ctx, &paymentv1.GetPaymentByOrderRequest{OrderId: "o1"})
+				return err
+			},
+		},
+		{
+			Name: "processPayment", Admin: true, Owner: true,
+			HTTP: &policytest.Request{Method: "POST", Path: "/api/v1/payments/p1/process", Body: `{}`},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.ProcessPayment(ctx, &paymentv1.ProcessPaymentRequest{Id: "p1"})
+				return err
+			},
+		},
+		{
+			Name: "refundPayment", Admin: true, Finance: true,
+			HTTP: &policytest.Request{Method: "POST", Path: "/api/v1/payments/p2/refund", Body: `{}`},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.RefundPayment(ctx, &paymentv1.RefundPaymentRequest{Id: "p2"})
+				return err
+			},
+		},
+	})
+}
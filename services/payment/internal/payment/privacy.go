@@ -0,0 +1,144 @@
+package payment
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/microservices-go/shared/audit"
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/middleware"
+)
+
+// DataExport is everything the payment service holds about a user
+type DataExport struct {
+	Payments []*PaymentResponse `json:"payments"`
+}
+
+// UserDataErasedEvent reports that a service erased a user's personal data
+// for an erasure request
+type UserDataErasedEvent struct {
+	RequestID string    `json:"request_id"`
+	UserID    string    `json:"user_id"`
+	ErasedAt  time.Time `json:"erased_at"`
+}
+
+// ListAllByUserID lists every payment of a user
+func (r *Repository) ListAllByUserID(ctx context.Context, userID string) ([]*Payment, error) {
+	var payments []*Payment
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&payments).Error
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to get payments")
+	}
+	return payments, nil
+}
+
+// EraseUserDataWithDB blanks the description of every payment of a user and
+// returns the payments changed
+func (r *Repository) EraseUserDataWithDB(ctx context.Context, db *gorm.DB, userID string) ([]*Payment, error) {
+	var payments []*Payment
+	err := db.WithContext(ctx).
+		Select("id", "order_id").
+		Where("user_id = ? AND COALESCE(description, '') <> ''", userID).
+		Find(&payments).Error
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to get payments")
+	}
+	if len(payments) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(payments))
+	for i, payment := range payments {
+		ids[i] = payment.ID
+	}
+	err = db.WithContext(ctx).Model(&Payment{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"description": "",
+			"updated_at":  time.Now(),
+		}).Error
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to erase payment data")
+	}
+	return payments, nil
+}
+
+// Export returns every payment of the calling user
+func (s *Service) Export(ctx context.Context) (*DataExport, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+	if claims.FromAPIKey() {
+		return nil, errors.New(errors.ErrForbidden, "API keys cannot export personal data")
+	}
+
+	payments, err := s.repo.ListAllByUserID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &DataExport{Payments: toResponses(payments)}, nil
+}
+
+// HandleUserErasureRequested erases the personal data in a user's payments.
+// Amounts, methods, statuses and provider transaction IDs stay for
+// accounting. Running it again for the same request is harmless.
+func (s *Service) HandleUserErasureRequested(ctx context.Context, requestID, userID string) error {
+	var payments []*Payment
+	err := s.repo.WithTransaction(ctx, func(tx *gorm.DB) error {
+		var err error
+		if payments, err = s.repo.EraseUserDataWithDB(ctx, tx, userID); err != nil {
+			return err
+		}
+		return s.auditLog.RecordWithDB(ctx, tx, &audit.Event{
+			Action:     "payment.erase_user_data",
+			TargetType: "user",
+			TargetID:   userID,
+			Metadata: map[string]string{
+				"request_id": requestID,
+				"payments":   strconv.Itoa(len(payments)),
+			},
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	log := logger.WithContext(ctx)
+	if s.cache != nil {
+		for _, payment := range payments {
+			if err := s.cache.Delete(ctx, "payment:id:"+payment.ID); err != nil {
+				log.WithError(err).Warn("Failed to invalidate payment cache")
+			}
+			if err := s.cache.Delete(ctx, "payment:order:"+payment.OrderID); err != nil {
+				log.WithError(err).Warn("Failed to invalidate order payment cache")
+			}
+		}
+		if err := s.cache.DeletePattern(ctx, "payments:user:"+userID+":*"); err != nil {
+			log.WithError(err).Warn("Failed to invalidate user payments cache")
+		}
+		if err := s.cache.DeletePattern(ctx, "payments:list:*"); err != nil {
+			log.WithError(err).Warn("Failed to invalidate payments list cache")
+		}
+	}
+
+	if s.publisher != nil {
+		event := &UserDataErasedEvent{RequestID: requestID, UserID: userID, ErasedAt: time.Now()}
+		if err := s.publisher.PublishEvent(ctx, "user.erased", event); err != nil {
+			return err
+		}
+	}
+
+	log.WithField("audit", true).
+		WithField("user_id", userID).
+		WithField("payments", len(payments)).
+		Info("User data erased from payments")
+	return nil
+}
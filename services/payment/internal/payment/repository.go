@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/filter"
 	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/pagination"
 	"gorm.io/gorm"
 )
 
@@ -98,13 +100,27 @@ func (r *Repository) GetByUserID(ctx context.Context, userID string, limit, offs
 	return payments, nil
 }
 
-// List lists all payments with pagination
-func (r *Repository) List(ctx context.Context, limit, offset int) ([]*Payment, error) {
+// GetByUserIDPage gets a keyset page of payments for a user
+func (r *Repository) GetByUserIDPage(ctx context.Context, userID string, page *pagination.Page) ([]*Payment, error) {
 	log := logger.WithContext(ctx)
 
 	var payments []*Payment
-	err := r.db.WithContext(ctx).
-		Order("created_at DESC").
+	err := page.Scope(r.db.WithContext(ctx).Where("user_id = ?", userID)).Find(&payments).Error
+
+	if err != nil {
+		log.WithError(err).Error("Failed to get payments by user")
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to get payments")
+	}
+
+	return payments, nil
+}
+
+// List lists payments matching the filter with pagination
+func (r *Repository) List(ctx context.Context, f *ListFilter, limit, offset int) ([]*Payment, error) {
+	log := logger.WithContext(ctx)
+
+	var payments []*Payment
+	err := filter.OrderBy(f.Apply(r.db.WithContext(ctx)), f.Sort).
 		Limit(limit).
 		Offset(offset).
 		Find(&payments).Error
@@ -117,6 +133,21 @@ func (r *Repository) List(ctx context.Context, limit, offset int) ([]*Payment, e
 	return payments, nil
 }
 
+// ListPage lists a keyset page of payments matching the filter
+func (r *Repository) ListPage(ctx context.Context, f *ListFilter, page *pagination.Page) ([]*Payment, error) {
+	log := logger.WithContext(ctx)
+
+	var payments []*Payment
+	err := page.Scope(f.Apply(r.db.WithContext(ctx))).Find(&payments).Error
+
+	if err != nil {
+		log.WithError(err).Error("Failed to list payments")
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to list payments")
+	}
+
+	return payments, nil
+}
+
 // UpdateStatus updates payment status
 func (r *Repository) UpdateStatus(ctx context.Context, id string, status PaymentStatus, failureReason string) error {
 	return r.UpdateStatusWithDB(ctx, r.db, id, status, failureReason)
@@ -182,10 +213,10 @@ func (r *Repository) UpdateTransactionIDWithDB(ctx context.Context, db *gorm.DB,
 	return nil
 }
 
-// Count returns total payment count
-func (r *Repository) Count(ctx context.Context) (int, error) {
+// Count returns the number of payments matching the filter
+func (r *Repository) Count(ctx context.Context, f *ListFilter) (int, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(&Payment{}).Count(&count).Error
+	err := f.Apply(r.db.WithContext(ctx).Model(&Payment{})).Count(&count).Error
 	if err != nil {
 		return 0, errors.Wrap(err, errors.ErrDatabaseError, "Failed to count payments")
 	}
@@ -220,6 +251,33 @@ func (r *Repository) GetByIDs(ctx context.Context, ids []string) ([]*Payment, er
 	return payments, nil
 }
 
+// GetByUserIDs gets one page of payments for each of several users, newest first
+func (r *Repository) GetByUserIDs(ctx context.Context, userIDs []string, limit, offset int) ([]*Payment, error) {
+	log := logger.WithContext(ctx)
+
+	if len(userIDs) == 0 {
+		return []*Payment{}, nil
+	}
+
+	// Number each user's payments so the page applies per user, not to the whole result
+	ranked := r.db.WithContext(ctx).Model(&Payment{}).
+		Select("payments.*, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at DESC, id DESC) AS row_num").
+		Where("user_id IN ?", userIDs)
+
+	var payments []*Payment
+	err := r.db.WithContext(ctx).
+		Table("(?) AS payments", ranked).
+		Where("row_num > ? AND row_num <= ?", offset, offset+limit).
+		Order("user_id, row_num").
+		Find(&payments).Error
+	if err != nil {
+		log.WithError(err).Error("Failed to get payments by user IDs")
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to get payments by user IDs")
+	}
+
+	return payments, nil
+}
+
 // GetByOrderIDs gets multiple payments by order IDs
 func (r *Repository) GetByOrderIDs(ctx context.Context, orderIDs []string) ([]*Payment, error) {
 	log := logger.WithContext(ctx)
@@ -3,11 +3,16 @@ package payment
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/microservices-go/shared/audit"
 	"github.com/microservices-go/shared/cache"
 	"github.com/microservices-go/shared/errors"
 	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/pagination"
+	"github.com/microservices-go/shared/rabbitmq"
+	"github.com/microservices-go/shared/response"
 	"github.com/microservices-go/shared/validator"
 	"gorm.io/gorm"
 )
@@ -46,6 +51,7 @@ type Service struct {
 	validator *validator.Validator
 	provider  PaymentProvider
 	publisher EventPublisher
+	auditLog  *audit.Log
 	cacheTTL  time.Duration
 }
 
@@ -55,13 +61,14 @@ type EventPublisher interface {
 }
 
 // NewService creates a new payment service
-func NewService(repo *Repository, provider PaymentProvider, publisher EventPublisher, cacheClient *cache.Cache) *Service {
+func NewService(repo *Repository, provider PaymentProvider, publisher EventPublisher, cacheClient *cache.Cache, auditLog *audit.Log) *Service {
 	return &Service{
 		repo:      repo,
 		cache:     cacheClient,
 		validator: validator.New(),
 		provider:  provider,
 		publisher: publisher,
+		auditLog:  auditLog,
 		cacheTTL:  3 * time.Minute,
 	}
 }
@@ -132,6 +139,22 @@ func (s *Service) Create(ctx context.Context, req *CreatePaymentRequest) (*Payme
 		}
 	}
 
+	// Publish created event
+	if s.publisher != nil {
+		event := &PaymentCreatedEvent{
+			PaymentID: payment.ID,
+			OrderID:   payment.OrderID,
+			UserID:    payment.UserID,
+			Amount:    payment.Amount,
+			Currency:  payment.Currency,
+			Method:    payment.Method,
+			CreatedAt: payment.CreatedAt,
+		}
+		if err := s.publisher.PublishEvent(ctx, rabbitmq.EventPaymentCreated, event); err != nil {
+			log.WithError(err).Warn("Failed to publish payment created event")
+		}
+	}
+
 	return payment.ToResponse(), nil
 }
 
@@ -235,8 +258,19 @@ func (s *Service) GetByUserID(ctx context.Context, userID string, limit, offset
 	return responses, nil
 }
 
-// List lists all payments
-func (s *Service) List(ctx context.Context, limit, offset int) ([]*PaymentResponse, error) {
+// GetByUserIDPage gets a cursor page of payments for a user
+func (s *Service) GetByUserIDPage(ctx context.Context, userID string, page *pagination.Page) ([]*PaymentResponse, response.PageInfo, error) {
+	payments, err := s.repo.GetByUserIDPage(ctx, userID, page)
+	if err != nil {
+		return nil, response.PageInfo{}, err
+	}
+
+	payments, info := pagination.Trim(payments, page, paymentCursor)
+	return toResponses(payments), info, nil
+}
+
+// List lists payments matching the filter
+func (s *Service) List(ctx context.Context, f *ListFilter, limit, offset int) ([]*PaymentResponse, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 10
 	}
@@ -244,7 +278,7 @@ func (s *Service) List(ctx context.Context, limit, offset int) ([]*PaymentRespon
 		offset = 0
 	}
 
-	payments, err := s.repo.List(ctx, limit, offset)
+	payments, err := s.repo.List(ctx, f, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -256,6 +290,29 @@ func (s *Service) List(ctx context.Context, limit, offset int) ([]*PaymentRespon
 	return responses, nil
 }
 
+// ListPage lists a cursor page of payments matching the filter
+func (s *Service) ListPage(ctx context.Context, f *ListFilter, page *pagination.Page) ([]*PaymentResponse, response.PageInfo, error) {
+	payments, err := s.repo.ListPage(ctx, f, page)
+	if err != nil {
+		return nil, response.PageInfo{}, err
+	}
+
+	payments, info := pagination.Trim(payments, page, paymentCursor)
+	return toResponses(payments), info, nil
+}
+
+func paymentCursor(p *Payment) pagination.Cursor {
+	return pagination.NewCursor(p.CreatedAt, p.ID)
+}
+
+func toResponses(payments []*Payment) []*PaymentResponse {
+	responses := make([]*PaymentResponse, len(payments))
+	for i, payment := range payments {
+		responses[i] = payment.ToResponse()
+	}
+	return responses
+}
+
 // Process processes a payment
 func (s *Service) Process(ctx context.Context, id string, req *ProcessPaymentRequest) (*PaymentResponse, error) {
 	log := logger.WithContext(ctx)
@@ -303,7 +360,7 @@ func (s *Service) Process(ctx context.Context, id string, req *ProcessPaymentReq
 func (s *Service) completePayment(ctx context.Context, payment *Payment) error {
 	log := logger.WithContext(ctx)
 
-	if err := s.repo.UpdateStatus(ctx, payment.ID, PaymentStatusSuccess, ""); err != nil {
+	if err := s.updateStatus(ctx, payment, PaymentStatusSuccess, "", "payment.process", nil); err != nil {
 		return err
 	}
 
@@ -344,7 +401,8 @@ func (s *Service) completePayment(ctx context.Context, payment *Payment) error {
 func (s *Service) failPayment(ctx context.Context, payment *Payment, reason string) {
 	log := logger.WithContext(ctx)
 
-	if err := s.repo.UpdateStatus(ctx, payment.ID, PaymentStatusFailed, reason); err != nil {
+	metadata := map[string]string{"reason": reason}
+	if err := s.updateStatus(ctx, payment, PaymentStatusFailed, reason, "payment.process", metadata); err != nil {
 		log.WithError(err).Error("Failed to update payment status to failed")
 	}
 
@@ -385,13 +443,13 @@ func (s *Service) Refund(ctx context.Context, id string, req *RefundRequest) (*P
 		return nil, errors.New(errors.ErrConflict, "Only successful payments can be refunded")
 	}
 
+	refundAmount := req.Amount
+	if refundAmount == 0 {
+		refundAmount = payment.Amount
+	}
+
 	// Process refund with provider if available
 	if s.provider != nil && payment.TransactionID != "" {
-		refundAmount := req.Amount
-		if refundAmount == 0 {
-			refundAmount = payment.Amount
-		}
-
 		result, err := s.provider.Refund(ctx, payment.TransactionID, refundAmount)
 		if err != nil {
 			log.WithError(err).Error("Refund failed")
@@ -404,10 +462,31 @@ func (s *Service) Refund(ctx context.Context, id string, req *RefundRequest) (*P
 	}
 
 	// Update payment status
-	if err := s.repo.UpdateStatus(ctx, payment.ID, PaymentStatusRefunded, req.Reason); err != nil {
+	metadata := map[string]string{
+		"amount":   strconv.FormatFloat(refundAmount, 'f', -1, 64),
+		"currency": payment.Currency,
+		"reason":   req.Reason,
+	}
+	if err := s.updateStatus(ctx, payment, PaymentStatusRefunded, req.Reason, "payment.refund", metadata); err != nil {
 		return nil, err
 	}
 
+	// Publish refunded event
+	if s.publisher != nil {
+		event := &PaymentRefundedEvent{
+			PaymentID:  payment.ID,
+			OrderID:    payment.OrderID,
+			UserID:     payment.UserID,
+			Amount:     refundAmount,
+			Currency:   payment.Currency,
+			Reason:     req.Reason,
+			RefundedAt: time.Now(),
+		}
+		if err := s.publisher.PublishEvent(ctx, rabbitmq.EventPaymentRefunded, event); err != nil {
+			log.WithError(err).Warn("Failed to publish payment refunded event")
+		}
+	}
+
 	// Invalidate payment caches
 	if s.cache != nil {
 		if err := s.cache.Delete(ctx, "payment:id:"+payment.ID); err != nil {
@@ -424,9 +503,32 @@ func (s *Service) Refund(ctx context.Context, id string, req *RefundRequest) (*P
 	return s.GetByID(ctx, id)
 }
 
-// Count returns total payment count
-func (s *Service) Count(ctx context.Context) (int, error) {
-	return s.repo.Count(ctx)
+// updateStatus changes the status of a payment and records action in the
+// audit log in the same transaction
+func (s *Service) updateStatus(ctx context.Context, payment *Payment, status PaymentStatus, reason, action string, metadata map[string]string) error {
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metadata["order_id"] = payment.OrderID
+	metadata["user_id"] = payment.UserID
+
+	return s.repo.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := s.repo.UpdateStatusWithDB(ctx, tx, payment.ID, status, reason); err != nil {
+			return err
+		}
+		return s.auditLog.RecordWithDB(ctx, tx, &audit.Event{
+			Action:     action,
+			TargetType: "payment",
+			TargetID:   payment.ID,
+			Changes:    audit.Changes{"status": {From: payment.Status, To: status}},
+			Metadata:   metadata,
+		})
+	})
+}
+
+// Count returns the number of payments matching the filter
+func (s *Service) Count(ctx context.Context, f *ListFilter) (int, error) {
+	return s.repo.Count(ctx, f)
 }
 
 // CountByUserID returns payment count for a user
@@ -481,6 +583,27 @@ func (s *Service) GetByIDs(ctx context.Context, ids []string) ([]*PaymentRespons
 	return responses, nil
 }
 
+// GetByUserIDs gets the same page of payments for each of several users
+func (s *Service) GetByUserIDs(ctx context.Context, userIDs []string, limit, offset int) ([]*PaymentResponse, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	payments, err := s.repo.GetByUserIDs(ctx, userIDs, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*PaymentResponse, len(payments))
+	for i, payment := range payments {
+		responses[i] = payment.ToResponse()
+	}
+	return responses, nil
+}
+
 // GetByOrderIDs gets multiple payments by order IDs with caching
 func (s *Service) GetByOrderIDs(ctx context.Context, orderIDs []string) ([]*PaymentResponse, error) {
 	if len(orderIDs) == 0 {
@@ -31,12 +31,16 @@ func (c *Consumer) Start(client *rabbitmq.Client) error {
 	if err := client.BindQueue(queue.Name, "microservices.events", "order-service.order.created"); err != nil {
 		return err
 	}
+	if err := client.BindQueue(queue.Name, "microservices.events", "user-service.user.erasure_requested"); err != nil {
+		return err
+	}
 
 	// Create consumer
 	consumer := rabbitmq.NewConsumer(client)
 
 	// Register handlers
 	consumer.RegisterHandler("order.created", c.handleOrderCreated)
+	consumer.RegisterHandler("user.erasure_requested", c.handleUserErasureRequested)
 
 	return consumer.Start(queue.Name)
 }
@@ -58,3 +62,18 @@ func (c *Consumer) handleOrderCreated(ctx context.Context, event *rabbitmq.Event
 
 	return c.service.HandleOrderCreated(ctx, payload.OrderID, payload.UserID, payload.TotalAmount, payload.Currency, payload.Notes)
 }
+
+func (c *Consumer) handleUserErasureRequested(ctx context.Context, event *rabbitmq.Event) error {
+	log := logger.WithContext(ctx)
+	log.Info("Handling user.erasure_requested event")
+
+	var payload struct {
+		RequestID string `json:"request_id"`
+		UserID    string `json:"user_id"`
+	}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return err
+	}
+
+	return c.service.HandleUserErasureRequested(ctx, payload.RequestID, payload.UserID)
+}
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_payments_paid_at;
+DROP INDEX IF EXISTS idx_payments_currency;
+DROP INDEX IF EXISTS idx_payments_amount;
+DROP INDEX IF EXISTS idx_payments_method;
+DROP INDEX IF EXISTS idx_payments_user_created_at;
+DROP INDEX IF EXISTS idx_payments_status_created_at;
+DROP INDEX IF EXISTS idx_payments_created_at_id;
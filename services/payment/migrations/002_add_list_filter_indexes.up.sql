@@ -0,0 +1,15 @@
+-- Keyset pagination walks (created_at, id) in both directions
+CREATE INDEX IF NOT EXISTS idx_payments_created_at_id ON payments (created_at DESC, id DESC);
+
+-- Indexes for list filters and sorting
+CREATE INDEX IF NOT EXISTS idx_payments_status_created_at ON payments (status, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_payments_user_created_at ON payments (user_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_payments_method ON payments (method);
+
+CREATE INDEX IF NOT EXISTS idx_payments_amount ON payments (amount);
+
+CREATE INDEX IF NOT EXISTS idx_payments_currency ON payments (currency);
+
+CREATE INDEX IF NOT EXISTS idx_payments_paid_at ON payments (paid_at);
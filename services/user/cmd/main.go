@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -15,14 +16,25 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
+	"github.com/microservices-go/shared/audit"
 	"github.com/microservices-go/shared/cache"
 	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/encryption"
+	"github.com/microservices-go/shared/health"
 	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/metrics"
 	sharedMiddleware "github.com/microservices-go/shared/middleware"
 	"github.com/microservices-go/shared/migrate"
+	auditv1 "github.com/microservices-go/shared/proto/audit/v1"
+	userv1 "github.com/microservices-go/shared/proto/user/v1"
 	"github.com/microservices-go/shared/rabbitmq"
 	"github.com/microservices-go/shared/redis"
+	"github.com/microservices-go/shared/rpc"
+	"github.com/microservices-go/shared/subgraph"
+	"github.com/microservices-go/shared/tracing"
 
+	"github.com/microservices-go/services/user/internal/graph"
+	"github.com/microservices-go/services/user/internal/graph/generated"
 	"github.com/microservices-go/services/user/internal/rabbit"
 	"github.com/microservices-go/services/user/internal/user"
 )
@@ -36,8 +48,23 @@ func main() {
 	dbConfig := config.LoadDatabaseConfig("user")
 	serverConfig := config.LoadServerConfig("user")
 	jwtConfig := config.LoadJWTConfig()
+	serviceAuthConfig := config.LoadServiceAuthConfig("user-service")
 	rabbitConfig := config.LoadRabbitMQConfig()
 	redisConfig := config.LoadRedisConfig()
+	tracingConfig := config.LoadTracingConfig()
+	subgraphConfig := config.LoadSubgraphConfig("user")
+	apiKeyConfig := config.LoadAPIKeyConfig()
+
+	// Initialize tracing
+	shutdownTracer, err := tracing.InitTracer("user-service", tracingConfig)
+	if err != nil {
+		log.Warn("Failed to initialize tracing: " + err.Error())
+	} else {
+		defer shutdownTracer(context.Background())
+		if tracingConfig.Enabled {
+			log.Infof("Tracing enabled, exporting to %s", tracingConfig.Endpoint)
+		}
+	}
 
 	// Connect to Redis
 	redisClient, err := redis.NewClient(redisConfig)
@@ -50,10 +77,12 @@ func main() {
 		log.Info("Connected to Redis")
 	}
 
-	// Create cache client
+	// Create cache client and the rate limiter of API keys
 	var cacheClient *cache.Cache
+	var rateLimiter *sharedMiddleware.RateLimiter
 	if redisClient != nil {
 		cacheClient = cache.NewCache(redisClient.GetClient(), "user")
+		rateLimiter = sharedMiddleware.NewRateLimiter(redisClient, config.LoadRateLimitConfig("user"), "user")
 		log.Info("Caching enabled")
 	}
 
@@ -76,6 +105,16 @@ func main() {
 	}
 	log.Info("Connected to database via GORM")
 
+	// Export connection pool stats
+	if err := metrics.RegisterDBStats(sqlDB, "user"); err != nil {
+		log.Warn("Failed to register DB stats collector: " + err.Error())
+	}
+
+	// Trace GORM queries
+	if err := db.Use(tracing.NewGormPlugin("user")); err != nil {
+		log.Warn("Failed to register GORM tracing plugin: " + err.Error())
+	}
+
 	// Run database migrations (if enabled)
 	if shouldAutoMigrate() {
 		if err := runMigrations(sqlDB, log); err != nil {
@@ -101,8 +140,24 @@ func main() {
 		}
 	}
 
+	// Initialize field encryption of emails, names and MFA secrets
+	encryptionConfig := config.LoadEncryptionConfig("user")
+	encryptor, err := encryption.NewFromConfig(encryptionConfig)
+	if err != nil {
+		log.Fatal("Failed to initialize encryption: " + err.Error())
+	}
+	if err := db.Use(encryption.NewGormPlugin(encryptor)); err != nil {
+		log.Fatal("Failed to register field encryption: " + err.Error())
+	}
+
+	// Re-encrypt values under older keys, or written before encryption
+	reencryptCtx, stopReencryption := context.WithCancel(context.Background())
+	defer stopReencryption()
+	go encryptor.RunReencryption(reencryptCtx, db, user.EncryptedTargets, encryptionConfig.ReencryptBatchSize,
+		time.Duration(encryptionConfig.ReencryptInterval)*time.Second)
+
 	// Initialize repository
-	userRepo := user.NewRepository(db)
+	userRepo := user.NewRepository(db, encryptor)
 
 	// Initialize publisher
 	var publisher user.EventPublisher
@@ -110,14 +165,33 @@ func main() {
 		publisher = rabbit.NewPublisher(rabbitClient)
 	}
 
+	// Initialize audit log
+	auditLog := audit.NewLog(db)
+
 	// Initialize service
-	userService := user.NewService(userRepo, jwtConfig, publisher, cacheClient)
+	userService := user.NewService(userRepo, jwtConfig, config.LoadMFAConfig(), config.LoadOIDCConfig(), apiKeyConfig, publisher, cacheClient, auditLog)
+
+	// Initialize consumer of the other services' erasure reports
+	if rabbitClient != nil {
+		consumer := rabbit.NewConsumer(userService)
+		if err := consumer.Start(rabbitClient); err != nil {
+			log.Warn("Failed to start consumer: " + err.Error())
+		} else {
+			log.Info("Started RabbitMQ consumer")
+		}
+	}
 
 	// Initialize handler
 	userHandler := user.NewHandler(userService)
 
-	// Initialize auth middleware
-	authMiddleware := sharedMiddleware.NewAuthMiddleware(jwtConfig)
+	// Initialize auth middleware; API keys and sessions are checked locally
+	authMiddleware := sharedMiddleware.NewAuthMiddleware(jwtConfig).
+		WithAPIKeys(userService, rateLimiter).
+		WithSessions(userService)
+	serviceAuth, err := sharedMiddleware.NewServiceAuth(serviceAuthConfig, "user-service")
+	if err != nil {
+		log.Fatal("Failed to load service keys: " + err.Error())
+	}
 
 	// Setup router
 	r := chi.NewRouter()
@@ -125,16 +199,65 @@ func main() {
 	// Middleware
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(sharedMiddleware.ClientInfoMiddleware)
+	r.Use(sharedMiddleware.TracingMiddleware)
+	r.Use(sharedMiddleware.DeadlineMiddleware)
 	r.Use(sharedMiddleware.LoggingMiddleware)
 	r.Use(sharedMiddleware.RecoveryMiddleware)
 	r.Use(sharedMiddleware.SecurityHeadersMiddleware)
 	r.Use(sharedMiddleware.CORSMiddleware([]string{"*"}))
+	r.Use(sharedMiddleware.MetricsMiddleware("user-service"))
 
-	// Health check
-	r.Get("/health", userHandler.HealthCheck)
+	// Health checks: /livez for the process, /readyz (and legacy /health) for dependencies
+	healthRegistry := health.NewRegistry("user-service")
+	healthRegistry.Register("database", health.SQLCheck(sqlDB))
+	healthRegistry.RegisterOptional("redis", health.RedisCheck(redisClient.GetClient()))
+	healthRegistry.RegisterOptional("rabbitmq", health.AMQPCheck(rabbitClient))
+	r.Get("/livez", healthRegistry.LivenessHandler)
+	r.Get("/readyz", healthRegistry.ReadinessHandler)
+	r.Get("/health", healthRegistry.ReadinessHandler)
+
+	// Prometheus metrics
+	r.Handle("/metrics", metrics.Handler())
 
 	// API routes
-	userHandler.RegisterRoutes(r, authMiddleware)
+	userHandler.RegisterRoutes(r, authMiddleware, serviceAuth)
+
+	// Federated GraphQL subgraph, composed by the router (see federation/)
+	if subgraphConfig.Enabled {
+		schema := generated.NewExecutableSchema(generated.Config{Resolvers: &graph.Resolver{Service: userService}})
+		r.With(authMiddleware.Optional).Handle("/graphql", subgraph.NewHandler(schema))
+		log.Info("GraphQL subgraph enabled at /graphql")
+	}
+
+	// gRPC server for internal callers such as the gateway
+	grpcServer := rpc.NewServer("user-service", authMiddleware, serviceAuth, rpc.Methods{
+		Public: []string{
+			userv1.UserService_Register_FullMethodName,
+			userv1.UserService_Login_FullMethodName,
+			userv1.UserService_VerifyMFA_FullMethodName,
+			userv1.UserService_ListOIDCProviders_FullMethodName,
+			userv1.UserService_StartOIDCLogin_FullMethodName,
+			userv1.UserService_CompleteOIDCLogin_FullMethodName,
+		},
+		Internal: []string{
+			userv1.UserService_BatchGetUsers_FullMethodName,
+			userv1.UserService_VerifyAPIKey_FullMethodName,
+			userv1.UserService_CheckSession_FullMethodName,
+		},
+	})
+	userv1.RegisterUserServiceServer(grpcServer, user.NewGRPCServer(userService))
+	auditv1.RegisterAuditServiceServer(grpcServer, audit.NewGRPCServer(auditLog))
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%d", serverConfig.GRPCPort))
+	if err != nil {
+		log.Fatal("Failed to listen for gRPC: " + err.Error())
+	}
+	go func() {
+		log.Infof("gRPC server starting on port %d", serverConfig.GRPCPort)
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatal("gRPC server failed: " + err.Error())
+		}
+	}()
 
 	// Create server
 	srv := &http.Server{
@@ -174,6 +297,7 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	grpcServer.GracefulStop()
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Error("Server forced to shutdown: " + err.Error())
 	}
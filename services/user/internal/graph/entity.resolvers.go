@@ -0,0 +1,47 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.86
+
+import (
+	"context"
+
+	"github.com/microservices-go/services/user/internal/graph/generated"
+	"github.com/microservices-go/services/user/internal/graph/model"
+	"github.com/microservices-go/services/user/internal/user"
+	"github.com/microservices-go/shared/subgraph"
+)
+
+// FindManyUserByIDs is the resolver for the findManyUserByIDs field.
+func (r *entityResolver) FindManyUserByIDs(ctx context.Context, reps []*model.UserByIDsInput) ([]*user.UserResponse, error) {
+	if _, err := subgraph.CurrentUser(ctx); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(reps))
+	for i, rep := range reps {
+		ids[i] = rep.ID
+	}
+	users, err := r.Service.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*user.UserResponse, len(users))
+	for _, u := range user.Readable(ctx, users) {
+		byID[u.ID] = u
+	}
+	// Entities must line up with the representations; unknown ids resolve to null
+	result := make([]*user.UserResponse, len(ids))
+	for i, id := range ids {
+		result[i] = byID[id]
+	}
+	return result, nil
+}
+
+// Entity returns generated.EntityResolver implementation.
+func (r *Resolver) Entity() generated.EntityResolver { return &entityResolver{r} }
+
+type entityResolver struct{ *Resolver }
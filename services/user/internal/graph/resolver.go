@@ -0,0 +1,18 @@
+package graph
+
+import (
+	"github.com/microservices-go/services/user/internal/user"
+)
+
+// Resolver resolves the user subgraph from the user service
+type Resolver struct {
+	Service *user.Service
+}
+
+// intValue dereferences an optional Int argument; services apply their defaults to 0
+func intValue(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -0,0 +1,299 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.86
+
+import (
+	"context"
+
+	"github.com/microservices-go/services/user/internal/graph/generated"
+	"github.com/microservices-go/services/user/internal/user"
+	"github.com/microservices-go/shared/middleware"
+	"github.com/microservices-go/shared/subgraph"
+)
+
+// Scopes is the resolver for the scopes field.
+func (r *apiKeyResolver) Scopes(ctx context.Context, obj *user.APIKey) ([]string, error) {
+	return obj.Scopes, nil
+}
+
+// User is the resolver for the user field.
+func (r *authResponseResolver) User(ctx context.Context, obj *user.LoginResponse) (*user.UserResponse, error) {
+	if obj.User == nil {
+		return nil, nil
+	}
+	return obj.User.ToResponse(), nil
+}
+
+// Register is the resolver for the register field.
+func (r *mutationResolver) Register(ctx context.Context, input user.CreateUserRequest) (*user.LoginResponse, error) {
+	return r.Service.Create(ctx, &input)
+}
+
+// Login is the resolver for the login field.
+func (r *mutationResolver) Login(ctx context.Context, input user.LoginRequest) (*user.LoginResponse, error) {
+	return r.Service.Login(ctx, &input)
+}
+
+// VerifyMfa is the resolver for the verifyMfa field.
+func (r *mutationResolver) VerifyMfa(ctx context.Context, input user.MFAVerifyRequest) (*user.LoginResponse, error) {
+	return r.Service.VerifyMFA(ctx, &input)
+}
+
+// StartOidcLogin is the resolver for the startOidcLogin field.
+func (r *mutationResolver) StartOidcLogin(ctx context.Context, provider string) (*user.OIDCAuthorization, error) {
+	return r.Service.StartOIDCLogin(ctx, provider)
+}
+
+// CompleteOidcLogin is the resolver for the completeOidcLogin field.
+func (r *mutationResolver) CompleteOidcLogin(ctx context.Context, input user.OIDCCallbackRequest) (*user.LoginResponse, error) {
+	return r.Service.CompleteOIDCLogin(ctx, &input)
+}
+
+// UpdateUser is the resolver for the updateUser field.
+func (r *mutationResolver) UpdateUser(ctx context.Context, id string, firstName *string, lastName *string, isActive *bool) (*user.UserResponse, error) {
+	req := &user.UpdateUserRequest{IsActive: isActive}
+	if firstName != nil {
+		req.FirstName = *firstName
+	}
+	if lastName != nil {
+		req.LastName = *lastName
+	}
+	if err := user.AuthorizeUpdate(ctx, id, req); err != nil {
+		return nil, err
+	}
+	return r.Service.Update(ctx, id, req)
+}
+
+// DeleteUser is the resolver for the deleteUser field.
+func (r *mutationResolver) DeleteUser(ctx context.Context, id string) (bool, error) {
+	if err := user.CanDelete.Authorize(ctx, id); err != nil {
+		return false, err
+	}
+
+	if err := r.Service.Delete(ctx, id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// AssignRole is the resolver for the assignRole field.
+func (r *mutationResolver) AssignRole(ctx context.Context, userID string, role string) (*user.UserResponse, error) {
+	if err := user.CanManageRoles.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	return r.Service.AssignRole(ctx, userID, claims.UserID, &user.RoleRequest{Role: role})
+}
+
+// RevokeRole is the resolver for the revokeRole field.
+func (r *mutationResolver) RevokeRole(ctx context.Context, userID string, role string) (*user.UserResponse, error) {
+	if err := user.CanManageRoles.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	return r.Service.RevokeRole(ctx, userID, claims.UserID, &user.RoleRequest{Role: role})
+}
+
+// SetRoleMfaRequired is the resolver for the setRoleMfaRequired field.
+func (r *mutationResolver) SetRoleMfaRequired(ctx context.Context, role string, required bool) (*user.Role, error) {
+	if err := user.CanManageRoles.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+	return r.Service.SetRoleMFARequired(ctx, role, &user.RoleMFARequest{Required: &required})
+}
+
+// EnrollMfa is the resolver for the enrollMfa field.
+func (r *mutationResolver) EnrollMfa(ctx context.Context) (*user.MFAEnrollment, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Service.EnrollMFA(ctx, claims.UserID)
+}
+
+// ConfirmMfa is the resolver for the confirmMfa field.
+func (r *mutationResolver) ConfirmMfa(ctx context.Context, code string) (*user.MFABackupCodes, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Service.ConfirmMFA(ctx, claims.UserID, &user.MFACodeRequest{Code: code})
+}
+
+// DisableMfa is the resolver for the disableMfa field.
+func (r *mutationResolver) DisableMfa(ctx context.Context, code string) (bool, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := r.Service.DisableMFA(ctx, claims.UserID, &user.MFACodeRequest{Code: code}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RegenerateMfaBackupCodes is the resolver for the regenerateMfaBackupCodes field.
+func (r *mutationResolver) RegenerateMfaBackupCodes(ctx context.Context, code string) (*user.MFABackupCodes, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Service.RegenerateBackupCodes(ctx, claims.UserID, &user.MFACodeRequest{Code: code})
+}
+
+// StartIdentityLink is the resolver for the startIdentityLink field.
+func (r *mutationResolver) StartIdentityLink(ctx context.Context, provider string) (*user.OIDCAuthorization, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Service.StartIdentityLink(ctx, claims.UserID, provider)
+}
+
+// LinkIdentity is the resolver for the linkIdentity field.
+func (r *mutationResolver) LinkIdentity(ctx context.Context, input user.OIDCCallbackRequest) (*user.Identity, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Service.LinkIdentity(ctx, claims.UserID, &input)
+}
+
+// UnlinkIdentity is the resolver for the unlinkIdentity field.
+func (r *mutationResolver) UnlinkIdentity(ctx context.Context, provider string) (bool, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := r.Service.UnlinkIdentity(ctx, claims.UserID, provider); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CreateAPIKey is the resolver for the createApiKey field.
+func (r *mutationResolver) CreateAPIKey(ctx context.Context, input user.CreateAPIKeyRequest) (*user.CreatedAPIKey, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Service.CreateAPIKey(ctx, claims.UserID, &input)
+}
+
+// RevokeAPIKey is the resolver for the revokeApiKey field.
+func (r *mutationResolver) RevokeAPIKey(ctx context.Context, id string) (*user.APIKey, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Service.RevokeAPIKey(ctx, claims.UserID, id)
+}
+
+// RevokeSession is the resolver for the revokeSession field.
+func (r *mutationResolver) RevokeSession(ctx context.Context, id string) (bool, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return false, err
+	}
+	if _, err := r.Service.RevokeSession(ctx, claims.UserID, id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RevokeAllSessions is the resolver for the revokeAllSessions field.
+func (r *mutationResolver) RevokeAllSessions(ctx context.Context) (int, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return r.Service.RevokeAllSessions(ctx, claims.UserID)
+}
+
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*user.UserResponse, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Service.GetByID(ctx, claims.UserID)
+}
+
+// User is the resolver for the user field.
+func (r *queryResolver) User(ctx context.Context, id string) (*user.UserResponse, error) {
+	if err := user.CanRead.Authorize(ctx, id); err != nil {
+		return nil, err
+	}
+	return r.Service.GetByID(ctx, id)
+}
+
+// Users is the resolver for the users field.
+func (r *queryResolver) Users(ctx context.Context, limit *int, offset *int) ([]*user.UserResponse, error) {
+	if err := user.CanList.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+	return r.Service.List(ctx, &user.ListFilter{}, intValue(limit), intValue(offset))
+}
+
+// OidcProviders is the resolver for the oidcProviders field.
+func (r *queryResolver) OidcProviders(ctx context.Context) ([]string, error) {
+	return r.Service.OIDCProviders(), nil
+}
+
+// MyIdentities is the resolver for the myIdentities field.
+func (r *queryResolver) MyIdentities(ctx context.Context) ([]*user.Identity, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Service.ListIdentities(ctx, claims.UserID)
+}
+
+// MyAPIKeys is the resolver for the myApiKeys field.
+func (r *queryResolver) MyAPIKeys(ctx context.Context) ([]*user.APIKey, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Service.ListAPIKeys(ctx, claims.UserID)
+}
+
+// MySessions is the resolver for the mySessions field.
+func (r *queryResolver) MySessions(ctx context.Context) ([]*user.Session, error) {
+	claims, err := subgraph.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Service.ListSessions(ctx, claims.UserID)
+}
+
+// FullName is the resolver for the fullName field.
+func (r *userResolver) FullName(ctx context.Context, obj *user.UserResponse) (string, error) {
+	return obj.FirstName + " " + obj.LastName, nil
+}
+
+// ApiKey returns generated.ApiKeyResolver implementation.
+func (r *Resolver) ApiKey() generated.ApiKeyResolver { return &apiKeyResolver{r} }
+
+// AuthResponse returns generated.AuthResponseResolver implementation.
+func (r *Resolver) AuthResponse() generated.AuthResponseResolver { return &authResponseResolver{r} }
+
+// Mutation returns generated.MutationResolver implementation.
+func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
+
+// Query returns generated.QueryResolver implementation.
+func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
+
+// User returns generated.UserResolver implementation.
+func (r *Resolver) User() generated.UserResolver { return &userResolver{r} }
+
+type apiKeyResolver struct{ *Resolver }
+type authResponseResolver struct{ *Resolver }
+type mutationResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
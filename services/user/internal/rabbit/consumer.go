@@ -0,0 +1,60 @@
+package rabbit
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/microservices-go/services/user/internal/user"
+	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/rabbitmq"
+)
+
+// Consumer handles RabbitMQ events
+type Consumer struct {
+	service *user.Service
+}
+
+// NewConsumer creates a new consumer
+func NewConsumer(service *user.Service) *Consumer {
+	return &Consumer{service: service}
+}
+
+// Start starts consuming events
+func (c *Consumer) Start(client *rabbitmq.Client) error {
+	// Declare queue
+	queue, err := client.DeclareQueue("user-service-queue")
+	if err != nil {
+		return err
+	}
+
+	// Bind to exchange with routing keys
+	if err := client.BindQueue(queue.Name, "microservices.events", "order-service.user.erased"); err != nil {
+		return err
+	}
+	if err := client.BindQueue(queue.Name, "microservices.events", "payment-service.user.erased"); err != nil {
+		return err
+	}
+
+	// Create consumer
+	consumer := rabbitmq.NewConsumer(client)
+
+	// Register handlers
+	consumer.RegisterHandler("user.erased", c.handleUserErased)
+
+	return consumer.Start(queue.Name)
+}
+
+func (c *Consumer) handleUserErased(ctx context.Context, event *rabbitmq.Event) error {
+	log := logger.WithContext(ctx)
+	log.Infof("Handling user.erased event from %s", event.Service)
+
+	var payload struct {
+		RequestID string `json:"request_id"`
+		UserID    string `json:"user_id"`
+	}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return err
+	}
+
+	return c.service.HandleUserDataErased(ctx, payload.RequestID, event.Service)
+}
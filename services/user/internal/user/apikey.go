@@ -0,0 +1,332 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/middleware"
+	"github.com/microservices-go/shared/policy"
+)
+
+const (
+	// apiKeyPrefixLength is the length of the public, hex encoded part of a
+	// key that identifies it in lists and lookups
+	apiKeyPrefixLength = 16
+
+	// apiKeyTouchInterval throttles last-used updates of busy keys
+	apiKeyTouchInterval = time.Minute
+)
+
+// errInvalidAPIKey is returned for every unusable key alike
+var errInvalidAPIKey = errors.New(errors.ErrUnauthorized, "Invalid, expired or revoked API key")
+
+// APIKey is a personal API key of a user. The key reads
+// mgo_<prefix>_<secret>; only a hash of the secret is stored.
+type APIKey struct {
+	ID         string         `json:"id" gorm:"primaryKey;column:id"`
+	UserID     string         `json:"user_id" gorm:"column:user_id"`
+	Name       string         `json:"name" gorm:"column:name"`
+	Prefix     string         `json:"prefix" gorm:"column:prefix"`
+	SecretHash string         `json:"-" gorm:"column:secret_hash"`
+	Scopes     pq.StringArray `json:"scopes" gorm:"column:scopes;type:text[]"`
+	RateLimit  int            `json:"rate_limit,omitempty" gorm:"column:rate_limit"`
+	ExpiresAt  time.Time      `json:"expires_at" gorm:"column:expires_at"`
+	LastUsedAt *time.Time     `json:"last_used_at,omitempty" gorm:"column:last_used_at"`
+	RevokedAt  *time.Time     `json:"revoked_at,omitempty" gorm:"column:revoked_at"`
+	CreatedAt  time.Time      `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns the table name
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// Usable reports whether the key is neither revoked nor expired at now
+func (k *APIKey) Usable(now time.Time) bool {
+	return k.RevokedAt == nil && now.Before(k.ExpiresAt)
+}
+
+// CreateAPIKeyRequest names a new key and limits what it may do. Scopes are
+// permissions, e.g. orders:read; ExpiresInDays and RateLimit fall back to
+// the configured defaults.
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,max=20,dive,required"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty" validate:"omitempty,min=1"`
+	RateLimit     int      `json:"rate_limit,omitempty" validate:"omitempty,min=1"`
+}
+
+// CreatedAPIKey is a new key together with its secret, shown only once
+type CreatedAPIKey struct {
+	APIKey *APIKey `json:"api_key"`
+	Key    string  `json:"key"`
+}
+
+// CreateAPIKey stores a new API key
+func (r *Repository) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	if key.ID == "" {
+		key.ID = uuid.New().String()
+	}
+	key.CreatedAt = time.Now()
+	if err := r.db.WithContext(ctx).Create(key).Error; err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to create API key")
+	}
+	return nil
+}
+
+// GetAPIKey gets an API key of a user
+func (r *Repository) GetAPIKey(ctx context.Context, userID, id string) (*APIKey, error) {
+	return r.getAPIKey(ctx, "id = ? AND user_id = ?", id, userID)
+}
+
+// GetAPIKeyByPrefix gets the API key with the given prefix
+func (r *Repository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*APIKey, error) {
+	return r.getAPIKey(ctx, "prefix = ?", prefix)
+}
+
+func (r *Repository) getAPIKey(ctx context.Context, query string, args ...interface{}) (*APIKey, error) {
+	var key APIKey
+	err := r.db.WithContext(ctx).First(&key, append([]interface{}{query}, args...)...).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "API key not found")
+		}
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to get API key")
+	}
+	return &key, nil
+}
+
+// ListAPIKeys lists the API keys of a user, newest first
+func (r *Repository) ListAPIKeys(ctx context.Context, userID string) ([]*APIKey, error) {
+	var keys []*APIKey
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to list API keys")
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey marks an API key revoked
+func (r *Repository) RevokeAPIKey(ctx context.Context, key *APIKey) error {
+	now := time.Now()
+	if err := r.db.WithContext(ctx).Model(key).Update("revoked_at", now).Error; err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to revoke API key")
+	}
+	key.RevokedAt = &now
+	return nil
+}
+
+// TouchAPIKey records a use of an API key
+func (r *Repository) TouchAPIKey(ctx context.Context, id string) error {
+	err := r.db.WithContext(ctx).Model(&APIKey{}).Where("id = ?", id).Update("last_used_at", time.Now()).Error
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to update API key")
+	}
+	return nil
+}
+
+// CreateAPIKey creates an API key for a user. Its scopes may name any
+// permission; requests made with it still get no more than the user holds.
+func (s *Service) CreateAPIKey(ctx context.Context, userID string, req *CreateAPIKeyRequest) (*CreatedAPIKey, error) {
+	if err := requireInteractive(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.validator.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !policy.IsPermission(scope) {
+			return nil, errors.New(errors.ErrInvalidInput, "Unknown scope: "+scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = s.apiKeyConfig.DefaultTTLDays
+	}
+	if days > s.apiKeyConfig.MaxTTLDays {
+		return nil, errors.New(errors.ErrInvalidInput, "expires_in_days must be at most "+strconv.Itoa(s.apiKeyConfig.MaxTTLDays))
+	}
+	if req.RateLimit > s.apiKeyConfig.MaxRateLimit {
+		return nil, errors.New(errors.ErrInvalidInput, "rate_limit must be at most "+strconv.Itoa(s.apiKeyConfig.MaxRateLimit))
+	}
+
+	prefix, secret, err := newAPIKeySecret()
+	if err != nil {
+		return nil, err
+	}
+	key := &APIKey{
+		UserID:     userID,
+		Name:       req.Name,
+		Prefix:     prefix,
+		SecretHash: hashAPIKeySecret(secret),
+		Scopes:     scopes,
+		RateLimit:  req.RateLimit,
+		ExpiresAt:  time.Now().AddDate(0, 0, days),
+	}
+	if err := s.repo.CreateAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+
+	logger.WithContext(ctx).
+		WithField("audit", true).
+		WithField("user_id", userID).
+		WithField("api_key_id", key.ID).
+		WithField("scopes", strings.Join(scopes, " ")).
+		Info("API key created")
+	return &CreatedAPIKey{APIKey: key, Key: middleware.APIKeyPrefix + prefix + "_" + secret}, nil
+}
+
+// ListAPIKeys lists the API keys of a user, revoked and expired ones included
+func (s *Service) ListAPIKeys(ctx context.Context, userID string) ([]*APIKey, error) {
+	return s.repo.ListAPIKeys(ctx, userID)
+}
+
+// RevokeAPIKey revokes an API key of a user; revoking it again is a no-op
+func (s *Service) RevokeAPIKey(ctx context.Context, userID, id string) (*APIKey, error) {
+	if err := requireInteractive(ctx); err != nil {
+		return nil, err
+	}
+
+	key, err := s.repo.GetAPIKey(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return key, nil
+	}
+	if err := s.repo.RevokeAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+
+	logger.WithContext(ctx).
+		WithField("audit", true).
+		WithField("user_id", userID).
+		WithField("api_key_id", key.ID).
+		Info("API key revoked")
+	return key, nil
+}
+
+// VerifyAPIKey resolves a key to the claims requests made with it carry:
+// the user's permissions within the key's scopes, and the key's rate limit.
+// Keys of deactivated or deleted users stop working.
+func (s *Service) VerifyAPIKey(ctx context.Context, key string) (*middleware.UserClaims, error) {
+	prefix, secret, ok := parseAPIKey(key)
+	if !ok {
+		return nil, errInvalidAPIKey
+	}
+
+	apiKey, err := s.repo.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, errInvalidAPIKey
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if subtle.ConstantTimeCompare([]byte(hashAPIKeySecret(secret)), []byte(apiKey.SecretHash)) != 1 || !apiKey.Usable(now) {
+		return nil, errInvalidAPIKey
+	}
+
+	user, err := s.repo.GetByID(ctx, apiKey.UserID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, errInvalidAPIKey
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, errInvalidAPIKey
+	}
+
+	permissions, _, err := s.grantedPermissions(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	scoped := []string{}
+	for _, p := range permissions {
+		if slices.Contains(apiKey.Scopes, p) {
+			scoped = append(scoped, p)
+		}
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyTouchInterval {
+		if err := s.repo.TouchAPIKey(ctx, apiKey.ID); err != nil {
+			logger.WithContext(ctx).WithError(err).Warn("Failed to record API key use")
+		}
+	}
+
+	rateLimit := apiKey.RateLimit
+	if rateLimit == 0 {
+		rateLimit = s.apiKeyConfig.DefaultRateLimit
+	}
+	return &middleware.UserClaims{
+		UserID:      user.ID,
+		Email:       user.Email,
+		Role:        user.Role,
+		Permissions: scoped,
+		APIKeyID:    apiKey.ID,
+		Scopes:      apiKey.Scopes,
+		RateLimit:   rateLimit,
+	}, nil
+}
+
+// requireInteractive refuses callers using an API key, which may not
+// manage credentials: API keys, MFA and linked identities
+func requireInteractive(ctx context.Context) error {
+	if claims, ok := middleware.GetUserFromContext(ctx); ok && claims.FromAPIKey() {
+		return errors.New(errors.ErrForbidden, "API keys cannot manage credentials")
+	}
+	return nil
+}
+
+// newAPIKeySecret returns a random prefix and secret for a new key
+func newAPIKeySecret() (string, string, error) {
+	b := make([]byte, apiKeyPrefixLength/2+32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", errors.Wrap(err, errors.ErrInternalServer, "Failed to generate API key")
+	}
+	return hex.EncodeToString(b[:apiKeyPrefixLength/2]), base64.RawURLEncoding.EncodeToString(b[apiKeyPrefixLength/2:]), nil
+}
+
+// parseAPIKey splits a key into its prefix and secret
+func parseAPIKey(key string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(key, middleware.APIKeyPrefix)
+	if !ok {
+		return "", "", false
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(prefix) != apiKeyPrefixLength || secret == "" {
+		return "", "", false
+	}
+	if _, err := hex.DecodeString(prefix); err != nil {
+		return "", "", false
+	}
+	return prefix, secret, true
+}
+
+// hashAPIKeySecret hashes the secret of a key for storage; secrets are
+// random, so a fast hash suffices
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,66 @@
+package user
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/microservices-go/shared/middleware"
+)
+
+func TestAPIKeyFormat(t *testing.T) {
+	prefix, secret, err := newAPIKeySecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := middleware.APIKeyPrefix + prefix + "_" + secret
+	if !middleware.IsAPIKey(key) {
+		t.Fatalf("%q is not recognised as an API key", key)
+	}
+
+	gotPrefix, gotSecret, ok := parseAPIKey(key)
+	if !ok || gotPrefix != prefix || gotSecret != secret {
+		t.Errorf("parseAPIKey(%q) = %q, %q, %v", key, gotPrefix, gotSecret, ok)
+	}
+
+	for _, bad := range []string{
+		"",
+		prefix + "_" + secret,
+		middleware.APIKeyPrefix + prefix,
+		middleware.APIKeyPrefix + prefix + "_",
+		middleware.APIKeyPrefix + "short_" + secret,
+		middleware.APIKeyPrefix + strings.Repeat("z", apiKeyPrefixLength) + "_" + secret,
+	} {
+		if _, _, ok := parseAPIKey(bad); ok {
+			t.Errorf("parseAPIKey(%q) accepted a malformed key", bad)
+		}
+	}
+}
+
+func TestAPIKeyUsable(t *testing.T) {
+	now := time.Now()
+	revoked := now.Add(-time.Minute)
+
+	if !(&APIKey{ExpiresAt: now.Add(time.Hour)}).Usable(now) {
+		t.Error("an unexpired key must be usable")
+	}
+	if (&APIKey{ExpiresAt: now}).Usable(now) {
+		t.Error("an expired key must not be usable")
+	}
+	if (&APIKey{ExpiresAt: now.Add(time.Hour), RevokedAt: &revoked}).Usable(now) {
+		t.Error("a revoked key must not be usable")
+	}
+}
+
+func TestAPIKeysCannotManageCredentials(t *testing.T) {
+	jwtCaller := context.WithValue(context.Background(), middleware.UserContextKey, &middleware.UserClaims{UserID: "u"})
+	keyCaller := context.WithValue(context.Background(), middleware.UserContextKey, &middleware.UserClaims{UserID: "u", APIKeyID: "k"})
+
+	if err := requireInteractive(jwtCaller); err != nil {
+		t.Errorf("token caller: got %v, want nil", err)
+	}
+	if err := requireInteractive(keyCaller); err == nil {
+		t.Error("API key caller must be refused")
+	}
+}
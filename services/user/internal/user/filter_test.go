@@ -0,0 +1,40 @@
+package user
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/microservices-go/shared/errors"
+)
+
+func TestParseListFilterSort(t *testing.T) {
+	tests := []struct {
+		sort    string
+		wantErr string
+	}{
+		{"-created_at", ""},
+		{"email", "sort: cannot sort by email: emails and names are encrypted"},
+		{"created_at,-last_name", "sort: cannot sort by last_name: emails and names are encrypted"},
+		{"status", "sort: unknown sort field status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			_, err := ParseListFilter(url.Values{"sort": {tt.sort}})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			appErr, ok := err.(*errors.AppError)
+			if !ok || appErr.Code != errors.ErrValidationFailed {
+				t.Fatalf("got %v, want a validation error", err)
+			}
+			if appErr.Details != tt.wantErr {
+				t.Errorf("got details %q, want %q", appErr.Details, tt.wantErr)
+			}
+		})
+	}
+}
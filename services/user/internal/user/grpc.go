@@ -0,0 +1,653 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/filter"
+	"github.com/microservices-go/shared/middleware"
+	commonv1 "github.com/microservices-go/shared/proto/common/v1"
+	userv1 "github.com/microservices-go/shared/proto/user/v1"
+	"github.com/microservices-go/shared/response"
+	"github.com/microservices-go/shared/rpc"
+)
+
+// GRPCServer serves the user service over gRPC
+type GRPCServer struct {
+	userv1.UnimplementedUserServiceServer
+	service *Service
+}
+
+// NewGRPCServer creates a new user gRPC server
+func NewGRPCServer(service *Service) *GRPCServer {
+	return &GRPCServer{service: service}
+}
+
+// Register creates a user and returns a token for it
+func (s *GRPCServer) Register(ctx context.Context, req *userv1.RegisterRequest) (*userv1.AuthResponse, error) {
+	resp, err := s.service.Create(ctx, &CreateUserRequest{
+		Email:     req.Email,
+		Password:  req.Password,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.ToProto(), nil
+}
+
+// Login authenticates a user
+func (s *GRPCServer) Login(ctx context.Context, req *userv1.LoginRequest) (*userv1.AuthResponse, error) {
+	resp, err := s.service.Login(ctx, &LoginRequest{Email: req.Email, Password: req.Password})
+	if err != nil {
+		return nil, err
+	}
+	return resp.ToProto(), nil
+}
+
+// VerifyMFA completes a login that returned an MFA challenge
+func (s *GRPCServer) VerifyMFA(ctx context.Context, req *userv1.VerifyMFARequest) (*userv1.AuthResponse, error) {
+	resp, err := s.service.VerifyMFA(ctx, &MFAVerifyRequest{ChallengeToken: req.ChallengeToken, Code: req.Code})
+	if err != nil {
+		return nil, err
+	}
+	return resp.ToProto(), nil
+}
+
+// ListOIDCProviders lists the identity providers users can sign in with
+func (s *GRPCServer) ListOIDCProviders(ctx context.Context, req *userv1.ListOIDCProvidersRequest) (*userv1.ListOIDCProvidersResponse, error) {
+	return &userv1.ListOIDCProvidersResponse{Providers: s.service.OIDCProviders()}, nil
+}
+
+// StartOIDCLogin returns the URL to sign in at an identity provider
+func (s *GRPCServer) StartOIDCLogin(ctx context.Context, req *userv1.StartOIDCLoginRequest) (*userv1.OIDCAuthorization, error) {
+	authorization, err := s.service.StartOIDCLogin(ctx, req.Provider)
+	if err != nil {
+		return nil, err
+	}
+	return authorization.ToProto(), nil
+}
+
+// CompleteOIDCLogin signs in with the code and state from an identity provider
+func (s *GRPCServer) CompleteOIDCLogin(ctx context.Context, req *userv1.OIDCCallbackRequest) (*userv1.AuthResponse, error) {
+	resp, err := s.service.CompleteOIDCLogin(ctx, &OIDCCallbackRequest{Code: req.Code, State: req.State})
+	if err != nil {
+		return nil, err
+	}
+	return resp.ToProto(), nil
+}
+
+// GetMe gets the calling user
+func (s *GRPCServer) GetMe(ctx context.Context, req *userv1.GetMeRequest) (*userv1.User, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+
+	user, err := s.service.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return user.ToProto(), nil
+}
+
+// GetUser gets a user by ID
+func (s *GRPCServer) GetUser(ctx context.Context, req *userv1.GetUserRequest) (*userv1.User, error) {
+	if err := CanRead.Authorize(ctx, req.Id); err != nil {
+		return nil, err
+	}
+
+	user, err := s.service.GetByID(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return user.ToProto(), nil
+}
+
+// ListUsers lists users matching a filter
+func (s *GRPCServer) ListUsers(ctx context.Context, req *userv1.ListUsersRequest) (*userv1.ListUsersResponse, error) {
+	if err := CanList.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	q := rpc.NewQuery(req.Page)
+	if f := req.Filter; f != nil {
+		q.String("search", f.Search)
+		q.List("role", f.Role)
+		q.Bool("is_active", f.IsActive)
+		q.Time("created_from", f.CreatedFrom)
+		q.Time("created_to", f.CreatedTo)
+	}
+
+	f, err := ParseListFilter(q.Values())
+	if err != nil {
+		return nil, err
+	}
+	page, err := q.Page()
+	if err != nil {
+		return nil, err
+	}
+
+	var users []*UserResponse
+	var meta response.Meta
+	if page != nil {
+		if err := filter.CheckPage(page, f.Sort); err != nil {
+			return nil, err
+		}
+		var info response.PageInfo
+		if users, info, err = s.service.ListPage(ctx, f, page); err != nil {
+			return nil, err
+		}
+		count, _ := s.service.Count(ctx, f)
+		meta = response.NewMeta(count, page.Size(), 0).WithPageInfo(info)
+	} else {
+		limit, offset := q.Offset()
+		if users, err = s.service.List(ctx, f, limit, offset); err != nil {
+			return nil, err
+		}
+		count, _ := s.service.Count(ctx, f)
+		meta = response.NewMeta(count, limit, offset)
+	}
+
+	list := make([]*userv1.User, len(users))
+	for i, user := range users {
+		list[i] = user.ToProto()
+	}
+	return &userv1.ListUsersResponse{Users: list, Meta: rpc.PageMeta(meta)}, nil
+}
+
+// UpdateUser updates a user
+func (s *GRPCServer) UpdateUser(ctx context.Context, req *userv1.UpdateUserRequest) (*userv1.User, error) {
+	update := &UpdateUserRequest{
+		FirstName: req.GetFirstName(),
+		LastName:  req.GetLastName(),
+		IsActive:  req.IsActive,
+	}
+	if err := AuthorizeUpdate(ctx, req.Id, update); err != nil {
+		return nil, err
+	}
+
+	user, err := s.service.Update(ctx, req.Id, update)
+	if err != nil {
+		return nil, err
+	}
+	return user.ToProto(), nil
+}
+
+// DeleteUser deletes a user
+func (s *GRPCServer) DeleteUser(ctx context.Context, req *userv1.DeleteUserRequest) (*userv1.DeleteUserResponse, error) {
+	if err := CanDelete.Authorize(ctx, req.Id); err != nil {
+		return nil, err
+	}
+	if err := s.service.Delete(ctx, req.Id); err != nil {
+		return nil, err
+	}
+	return &userv1.DeleteUserResponse{}, nil
+}
+
+// ExportMyData returns everything held about the calling user as JSON
+func (s *GRPCServer) ExportMyData(ctx context.Context, req *commonv1.ExportMyDataRequest) (*commonv1.DataExport, error) {
+	export, err := s.service.Export(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(export)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to encode export")
+	}
+	return &commonv1.DataExport{Data: data}, nil
+}
+
+// RequestErasure erases a user's personal data across the services
+func (s *GRPCServer) RequestErasure(ctx context.Context, req *userv1.RequestErasureRequest) (*userv1.ErasureRequest, error) {
+	if err := CanErase.Authorize(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	request, err := s.service.RequestErasure(ctx, req.UserId, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return request.ToProto(), nil
+}
+
+// GetErasureRequest gets an erasure request
+func (s *GRPCServer) GetErasureRequest(ctx context.Context, req *userv1.GetErasureRequestRequest) (*userv1.ErasureRequest, error) {
+	if err := CanReadErasure.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	request, err := s.service.GetErasureRequest(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return request.ToProto(), nil
+}
+
+// ListErasureRequests lists erasure requests, optionally of one status
+func (s *GRPCServer) ListErasureRequests(ctx context.Context, req *userv1.ListErasureRequestsRequest) (*userv1.ListErasureRequestsResponse, error) {
+	if err := CanReadErasure.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	requests, err := s.service.ListErasureRequests(ctx, req.Status, int(req.Limit), int(req.Offset))
+	if err != nil {
+		return nil, err
+	}
+	resp := &userv1.ListErasureRequestsResponse{Requests: make([]*userv1.ErasureRequest, len(requests))}
+	for i, request := range requests {
+		resp.Requests[i] = request.ToProto()
+	}
+	return resp, nil
+}
+
+// AssignRole grants a role to a user
+func (s *GRPCServer) AssignRole(ctx context.Context, req *userv1.RoleRequest) (*userv1.User, error) {
+	if err := CanManageRoles.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	user, err := s.service.AssignRole(ctx, req.UserId, claims.UserID, &RoleRequest{Role: req.Role})
+	if err != nil {
+		return nil, err
+	}
+	return user.ToProto(), nil
+}
+
+// RevokeRole takes a role away from a user
+func (s *GRPCServer) RevokeRole(ctx context.Context, req *userv1.RoleRequest) (*userv1.User, error) {
+	if err := CanManageRoles.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	user, err := s.service.RevokeRole(ctx, req.UserId, claims.UserID, &RoleRequest{Role: req.Role})
+	if err != nil {
+		return nil, err
+	}
+	return user.ToProto(), nil
+}
+
+// SetRoleMFARequired sets whether holders of a role must use MFA
+func (s *GRPCServer) SetRoleMFARequired(ctx context.Context, req *userv1.SetRoleMFARequiredRequest) (*userv1.Role, error) {
+	if err := CanManageRoles.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	role, err := s.service.SetRoleMFARequired(ctx, req.Role, &RoleMFARequest{Required: &req.Required})
+	if err != nil {
+		return nil, err
+	}
+	return role.ToProto(), nil
+}
+
+// EnrollMFA starts TOTP enrolment for the calling user
+func (s *GRPCServer) EnrollMFA(ctx context.Context, req *userv1.EnrollMFARequest) (*userv1.MFAEnrollment, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+
+	enrollment, err := s.service.EnrollMFA(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &userv1.MFAEnrollment{Secret: enrollment.Secret, ProvisioningUri: enrollment.ProvisioningURI}, nil
+}
+
+// ConfirmMFA enables MFA for the calling user and returns backup codes
+func (s *GRPCServer) ConfirmMFA(ctx context.Context, req *userv1.MFACodeRequest) (*userv1.MFABackupCodes, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+
+	codes, err := s.service.ConfirmMFA(ctx, claims.UserID, &MFACodeRequest{Code: req.Code})
+	if err != nil {
+		return nil, err
+	}
+	return &userv1.MFABackupCodes{Codes: codes.Codes}, nil
+}
+
+// DisableMFA turns MFA off for the calling user
+func (s *GRPCServer) DisableMFA(ctx context.Context, req *userv1.MFACodeRequest) (*userv1.DisableMFAResponse, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+
+	if err := s.service.DisableMFA(ctx, claims.UserID, &MFACodeRequest{Code: req.Code}); err != nil {
+		return nil, err
+	}
+	return &userv1.DisableMFAResponse{}, nil
+}
+
+// RegenerateBackupCodes replaces the backup codes of the calling user
+func (s *GRPCServer) RegenerateBackupCodes(ctx context.Context, req *userv1.MFACodeRequest) (*userv1.MFABackupCodes, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+
+	codes, err := s.service.RegenerateBackupCodes(ctx, claims.UserID, &MFACodeRequest{Code: req.Code})
+	if err != nil {
+		return nil, err
+	}
+	return &userv1.MFABackupCodes{Codes: codes.Codes}, nil
+}
+
+// ListIdentities lists the external identities of the calling user
+func (s *GRPCServer) ListIdentities(ctx context.Context, req *userv1.ListIdentitiesRequest) (*userv1.ListIdentitiesResponse, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+
+	identities, err := s.service.ListIdentities(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	resp := &userv1.ListIdentitiesResponse{Identities: make([]*userv1.Identity, len(identities))}
+	for i, identity := range identities {
+		resp.Identities[i] = identity.ToProto()
+	}
+	return resp, nil
+}
+
+// StartIdentityLink returns the URL to sign in at an identity provider whose
+// account is to be linked to the calling user
+func (s *GRPCServer) StartIdentityLink(ctx context.Context, req *userv1.StartOIDCLoginRequest) (*userv1.OIDCAuthorization, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+
+	authorization, err := s.service.StartIdentityLink(ctx, claims.UserID, req.Provider)
+	if err != nil {
+		return nil, err
+	}
+	return authorization.ToProto(), nil
+}
+
+// LinkIdentity links an identity provider account to the calling user
+func (s *GRPCServer) LinkIdentity(ctx context.Context, req *userv1.OIDCCallbackRequest) (*userv1.Identity, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+
+	identity, err := s.service.LinkIdentity(ctx, claims.UserID, &OIDCCallbackRequest{Code: req.Code, State: req.State})
+	if err != nil {
+		return nil, err
+	}
+	return identity.ToProto(), nil
+}
+
+// UnlinkIdentity unlinks an identity provider account from the calling user
+func (s *GRPCServer) UnlinkIdentity(ctx context.Context, req *userv1.UnlinkIdentityRequest) (*userv1.UnlinkIdentityResponse, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+
+	if err := s.service.UnlinkIdentity(ctx, claims.UserID, req.Provider); err != nil {
+		return nil, err
+	}
+	return &userv1.UnlinkIdentityResponse{}, nil
+}
+
+// CreateAPIKey creates an API key for the calling user
+func (s *GRPCServer) CreateAPIKey(ctx context.Context, req *userv1.CreateAPIKeyRequest) (*userv1.CreatedAPIKey, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+
+	created, err := s.service.CreateAPIKey(ctx, claims.UserID, &CreateAPIKeyRequest{
+		Name:          req.Name,
+		Scopes:        req.Scopes,
+		ExpiresInDays: int(req.ExpiresInDays),
+		RateLimit:     int(req.RateLimit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &userv1.CreatedAPIKey{ApiKey: created.APIKey.ToProto(), Key: created.Key}, nil
+}
+
+// ListAPIKeys lists the API keys of the calling user
+func (s *GRPCServer) ListAPIKeys(ctx context.Context, req *userv1.ListAPIKeysRequest) (*userv1.ListAPIKeysResponse, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+
+	keys, err := s.service.ListAPIKeys(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	resp := &userv1.ListAPIKeysResponse{ApiKeys: make([]*userv1.APIKey, len(keys))}
+	for i, key := range keys {
+		resp.ApiKeys[i] = key.ToProto()
+	}
+	return resp, nil
+}
+
+// RevokeAPIKey revokes an API key of the calling user
+func (s *GRPCServer) RevokeAPIKey(ctx context.Context, req *userv1.RevokeAPIKeyRequest) (*userv1.APIKey, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+
+	key, err := s.service.RevokeAPIKey(ctx, claims.UserID, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return key.ToProto(), nil
+}
+
+// VerifyAPIKey resolves an API key for another service
+func (s *GRPCServer) VerifyAPIKey(ctx context.Context, req *userv1.VerifyAPIKeyRequest) (*userv1.APIKeyClaims, error) {
+	claims, err := s.service.VerifyAPIKey(ctx, req.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &userv1.APIKeyClaims{
+		UserId:      claims.UserID,
+		Email:       claims.Email,
+		Role:        claims.Role,
+		Permissions: claims.Permissions,
+		ApiKeyId:    claims.APIKeyID,
+		Scopes:      claims.Scopes,
+		RateLimit:   int32(claims.RateLimit),
+	}, nil
+}
+
+// ListSessions lists the active sessions of the calling user
+func (s *GRPCServer) ListSessions(ctx context.Context, req *userv1.ListSessionsRequest) (*userv1.ListSessionsResponse, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+
+	sessions, err := s.service.ListSessions(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	resp := &userv1.ListSessionsResponse{Sessions: make([]*userv1.Session, len(sessions))}
+	for i, session := range sessions {
+		resp.Sessions[i] = session.ToProto()
+	}
+	return resp, nil
+}
+
+// RevokeSession signs the calling user out of one session
+func (s *GRPCServer) RevokeSession(ctx context.Context, req *userv1.RevokeSessionRequest) (*userv1.Session, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+
+	session, err := s.service.RevokeSession(ctx, claims.UserID, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return session.ToProto(), nil
+}
+
+// RevokeAllSessions signs the calling user out of every session
+func (s *GRPCServer) RevokeAllSessions(ctx context.Context, req *userv1.RevokeAllSessionsRequest) (*userv1.RevokeAllSessionsResponse, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+
+	revoked, err := s.service.RevokeAllSessions(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &userv1.RevokeAllSessionsResponse{Revoked: int32(revoked)}, nil
+}
+
+// CheckSession checks a user's session for another service
+func (s *GRPCServer) CheckSession(ctx context.Context, req *userv1.CheckSessionRequest) (*userv1.CheckSessionResponse, error) {
+	if err := s.service.CheckSession(ctx, req.UserId, req.SessionId); err != nil {
+		return nil, err
+	}
+	return &userv1.CheckSessionResponse{}, nil
+}
+
+// BatchGetUsers streams the users found for the requested IDs
+func (s *GRPCServer) BatchGetUsers(req *userv1.BatchGetUsersRequest, stream userv1.UserService_BatchGetUsersServer) error {
+	if len(req.Ids) == 0 {
+		return errors.New(errors.ErrInvalidInput, "IDs array is required")
+	}
+
+	users, err := s.service.GetByIDs(stream.Context(), req.Ids)
+	if err != nil {
+		return err
+	}
+	for _, user := range Readable(stream.Context(), users) {
+		if err := stream.Send(user.ToProto()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ToProto converts UserResponse to its gRPC message
+func (u *UserResponse) ToProto() *userv1.User {
+	return &userv1.User{
+		Id:         u.ID,
+		Email:      u.Email,
+		FirstName:  u.FirstName,
+		LastName:   u.LastName,
+		Role:       u.Role,
+		IsActive:   u.IsActive,
+		MfaEnabled: u.MFAEnabled,
+		CreatedAt:  rpc.Timestamp(u.CreatedAt),
+		UpdatedAt:  rpc.Timestamp(u.UpdatedAt),
+	}
+}
+
+// ToProto converts LoginResponse to its gRPC message
+func (r *LoginResponse) ToProto() *userv1.AuthResponse {
+	resp := &userv1.AuthResponse{
+		Token:                 r.Token,
+		MfaRequired:           r.MFARequired,
+		ChallengeToken:        r.ChallengeToken,
+		MfaEnrollmentRequired: r.MFAEnrollmentRequired,
+	}
+	if r.User != nil {
+		resp.User = r.User.ToResponse().ToProto()
+	}
+	return resp
+}
+
+// ToProto converts Role to its gRPC message
+func (r *Role) ToProto() *userv1.Role {
+	return &userv1.Role{Name: r.Name, Description: r.Description, MfaRequired: r.MFARequired}
+}
+
+// ToProto converts OIDCAuthorization to its gRPC message
+func (a *OIDCAuthorization) ToProto() *userv1.OIDCAuthorization {
+	return &userv1.OIDCAuthorization{AuthorizationUrl: a.AuthorizationURL, State: a.State}
+}
+
+// ToProto converts Identity to its gRPC message
+func (i *Identity) ToProto() *userv1.Identity {
+	identity := &userv1.Identity{
+		Id:        i.ID,
+		Provider:  i.Provider,
+		Subject:   i.Subject,
+		Email:     i.Email,
+		CreatedAt: rpc.Timestamp(i.CreatedAt),
+	}
+	if i.LastLoginAt != nil {
+		identity.LastLoginAt = rpc.Timestamp(*i.LastLoginAt)
+	}
+	return identity
+}
+
+// ToProto converts APIKey to its gRPC message
+func (k *APIKey) ToProto() *userv1.APIKey {
+	key := &userv1.APIKey{
+		Id:        k.ID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Scopes:    k.Scopes,
+		RateLimit: int32(k.RateLimit),
+		ExpiresAt: rpc.Timestamp(k.ExpiresAt),
+		CreatedAt: rpc.Timestamp(k.CreatedAt),
+	}
+	if k.LastUsedAt != nil {
+		key.LastUsedAt = rpc.Timestamp(*k.LastUsedAt)
+	}
+	if k.RevokedAt != nil {
+		key.RevokedAt = rpc.Timestamp(*k.RevokedAt)
+	}
+	return key
+}
+
+// ToProto converts Session to its gRPC message
+func (s *Session) ToProto() *userv1.Session {
+	session := &userv1.Session{
+		Id:         s.ID,
+		IpAddress:  s.IPAddress,
+		UserAgent:  s.UserAgent,
+		CreatedAt:  rpc.Timestamp(s.CreatedAt),
+		LastSeenAt: rpc.Timestamp(s.LastSeenAt),
+		ExpiresAt:  rpc.Timestamp(s.ExpiresAt),
+		Current:    s.Current,
+	}
+	if s.RevokedAt != nil {
+		session.RevokedAt = rpc.Timestamp(*s.RevokedAt)
+	}
+	return session
+}
+
+// ToProto converts ErasureRequest to its gRPC message
+func (r *ErasureRequest) ToProto() *userv1.ErasureRequest {
+	request := &userv1.ErasureRequest{
+		Id:          r.ID,
+		UserId:      r.UserID,
+		RequestedBy: r.RequestedBy,
+		Status:      r.Status,
+		CreatedAt:   rpc.Timestamp(r.CreatedAt),
+	}
+	if r.OrdersErasedAt != nil {
+		request.OrdersErasedAt = rpc.Timestamp(*r.OrdersErasedAt)
+	}
+	if r.PaymentsErasedAt != nil {
+		request.PaymentsErasedAt = rpc.Timestamp(*r.PaymentsErasedAt)
+	}
+	if r.CompletedAt != nil {
+		request.CompletedAt = rpc.Timestamp(*r.CompletedAt)
+	}
+	return request
+}
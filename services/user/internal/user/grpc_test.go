@@ -0,0 +1,64 @@
+package user
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/dbtest"
+	"github.com/microservices-go/shared/middleware"
+	userv1 "github.com/microservices-go/shared/proto/user/v1"
+	"github.com/microservices-go/shared/policy"
+	"github.com/microservices-go/shared/rpc"
+	"github.com/microservices-go/shared/rpc/rpctest"
+)
+
+func TestBatchGetUsersStream(t *testing.T) {
+	db := dbtest.Open(t, dbtest.Tables{"users": {
+		{"id": "owner", "email": "owner@example.com", "first_name": "Olive", "last_name": "Owner", "role": "user", "is_active": true},
+		{"id": "other", "email": "other@example.com", "first_name": "Otto", "last_name": "Other", "role": "user", "is_active": true},
+	}})
+	srv := rpc.NewServer("user-service", middleware.NewAuthMiddleware(&config.JWTConfig{Secret: "test"}), rpctest.ServiceAuth(t, "user-service"), rpc.Methods{
+		Internal: []string{userv1.UserService_BatchGetUsers_FullMethodName},
+	})
+	service := NewService(NewRepository(db, nil), &config.JWTConfig{}, &config.MFAConfig{}, &config.OIDCConfig{}, &config.APIKeyConfig{}, nil, nil, nil)
+	userv1.RegisterUserServiceServer(srv, NewGRPCServer(service))
+	client := userv1.NewUserServiceClient(rpctest.Dial(t, srv))
+
+	tests := []struct {
+		name string
+		user *middleware.UserClaims
+		want []string
+	}{
+		{"owner", &middleware.UserClaims{UserID: "owner", Role: policy.RoleUser}, []string{"owner"}},
+		{"support", &middleware.UserClaims{UserID: "s1", Role: policy.RoleSupport, Permissions: policy.DefaultRoles[policy.RoleSupport]}, []string{"owner", "other"}},
+		{"other user", &middleware.UserClaims{UserID: "stranger", Role: policy.RoleUser}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := rpc.OutgoingContext(context.Background(), rpctest.ServiceToken(t, "user-service", tt.user))
+			stream, err := client.BatchGetUsers(ctx, &userv1.BatchGetUsersRequest{Ids: []string{"owner", "other", "missing"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for {
+				user, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, user.Id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got users %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	"github.com/microservices-go/shared/errors"
-	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/filter"
 	"github.com/microservices-go/shared/middleware"
+	"github.com/microservices-go/shared/pagination"
+	"github.com/microservices-go/shared/policy"
 	"github.com/microservices-go/shared/response"
 )
 
@@ -24,22 +26,63 @@ func NewHandler(service *Service) *Handler {
 }
 
 // RegisterRoutes registers all routes
-func (h *Handler) RegisterRoutes(r chi.Router, authMiddleware *middleware.AuthMiddleware) {
+func (h *Handler) RegisterRoutes(r chi.Router, authMiddleware *middleware.AuthMiddleware, serviceAuth *middleware.ServiceAuth) {
 	r.Route("/api/v1/users", func(r chi.Router) {
 		// Public routes
 		r.Post("/register", h.Register)
 		r.Post("/login", h.Login)
+		r.Post("/login/mfa", h.VerifyMFA)
+		r.Get("/oidc/providers", h.ListOIDCProviders)
+		r.Post("/oidc/{provider}/authorize", h.StartOIDCLogin)
+		r.Post("/oidc/callback", h.CompleteOIDCLogin)
+
+		// Internal routes: batch lookups for the gateway's dataloaders take
+		// service tokens only and drop what the on-behalf-of user may not read
+		r.Group(func(r chi.Router) {
+			r.Use(serviceAuth.Authenticate)
+
+			r.Post("/batch", h.GetBatch)
+		})
 
 		// Protected routes
 		r.Group(func(r chi.Router) {
 			r.Use(authMiddleware.Authenticate)
 
-			r.Get("/", h.List)
-			r.Post("/batch", h.GetBatch)
-			r.Get("/{id}", h.GetByID)
-			r.Put("/{id}", h.Update)
-			r.Delete("/{id}", h.Delete)
+			// Update also checks CanSetActive against the body
+			r.With(policy.Require(CanList, nil)).Get("/", h.List)
+			r.With(policy.Require(CanRead, policy.Param("id"))).Get("/{id}", h.GetByID)
+			r.With(policy.Require(CanUpdate, policy.Param("id"))).Put("/{id}", h.Update)
+			r.With(policy.Require(CanDelete, policy.Param("id"))).Delete("/{id}", h.Delete)
+			r.With(policy.Require(CanManageRoles, nil)).Post("/{id}/roles", h.AssignRole)
+			r.With(policy.Require(CanManageRoles, nil)).Delete("/{id}/roles/{role}", h.RevokeRole)
+			r.With(policy.Require(CanManageRoles, nil)).Put("/roles/{role}/mfa", h.SetRoleMFARequired)
 			r.Get("/me", h.GetMe)
+
+			// MFA of the calling user
+			r.Post("/me/mfa", h.EnrollMFA)
+			r.Post("/me/mfa/confirm", h.ConfirmMFA)
+			r.Post("/me/mfa/disable", h.DisableMFA)
+			r.Post("/me/mfa/backup-codes", h.RegenerateBackupCodes)
+
+			// External identities of the calling user
+			r.Get("/me/identities", h.ListIdentities)
+			r.Post("/me/identities/{provider}/authorize", h.StartIdentityLink)
+			r.Post("/me/identities", h.LinkIdentity)
+			r.Delete("/me/identities/{provider}", h.UnlinkIdentity)
+
+			// Personal API keys of the calling user
+			r.Get("/me/api-keys", h.ListAPIKeys)
+			r.Post("/me/api-keys", h.CreateAPIKey)
+			r.Delete("/me/api-keys/{id}", h.RevokeAPIKey)
+			r.Get("/me/sessions", h.ListSessions)
+			r.Delete("/me/sessions", h.RevokeAllSessions)
+			r.Delete("/me/sessions/{id}", h.RevokeSession)
+
+			// Personal data export and erasure
+			r.Get("/me/data", h.ExportMyData)
+			r.With(policy.Require(CanErase, policy.Param("id"))).Post("/{id}/erasure", h.RequestErasure)
+			r.With(policy.Require(CanReadErasure, nil)).Get("/erasure-requests", h.ListErasureRequests)
+			r.With(policy.Require(CanReadErasure, nil)).Get("/erasure-requests/{id}", h.GetErasureRequest)
 		})
 	})
 }
@@ -135,10 +178,36 @@ func (h *Handler) GetMe(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	f, err := ParseListFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, err, "Invalid filter parameters")
+		return
+	}
+
+	page, err := pagination.FromQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, err, "Invalid pagination parameters")
+		return
+	}
+	if page != nil {
+		if err := filter.CheckPage(page, f.Sort); err != nil {
+			writeError(w, err, "Invalid pagination parameters")
+			return
+		}
+		users, info, err := h.service.ListPage(ctx, f, page)
+		if err != nil {
+			writeError(w, err, "Failed to list users")
+			return
+		}
+		count, _ := h.service.Count(ctx, f)
+		response.List(w, users, response.NewMeta(count, page.Size(), 0).WithPageInfo(info))
+		return
+	}
+
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
 
-	users, err := h.service.List(ctx, limit, offset)
+	users, err := h.service.List(ctx, f, limit, offset)
 	if err != nil {
 		if appErr, ok := err.(*errors.AppError); ok {
 			appErr.WriteHTTPResponse(w)
@@ -148,7 +217,7 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	count, _ := h.service.Count(ctx)
+	count, _ := h.service.Count(ctx, f)
 	meta := response.NewMeta(count, limit, offset)
 
 	response.List(w, users, meta)
@@ -165,6 +234,11 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := AuthorizeUpdate(ctx, id, &req); err != nil {
+		writeError(w, err, "Failed to update user")
+		return
+	}
+
 	user, err := h.service.Update(ctx, id, &req)
 	if err != nil {
 		if appErr, ok := err.(*errors.AppError); ok {
@@ -195,6 +269,392 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	response.Deleted(w, "User deleted successfully")
 }
 
+// AssignRole grants a role to a user
+func (h *Handler) AssignRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	var req RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.New(errors.ErrInvalidInput, "Invalid request body").WriteHTTPResponse(w)
+		return
+	}
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	user, err := h.service.AssignRole(ctx, id, claims.UserID, &req)
+	if err != nil {
+		writeError(w, err, "Failed to assign role")
+		return
+	}
+
+	response.OK(w, user)
+}
+
+// RevokeRole takes a role away from a user
+func (h *Handler) RevokeRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	user, err := h.service.RevokeRole(ctx, id, claims.UserID, &RoleRequest{Role: chi.URLParam(r, "role")})
+	if err != nil {
+		writeError(w, err, "Failed to revoke role")
+		return
+	}
+
+	response.OK(w, user)
+}
+
+// VerifyMFA completes a login with an MFA challenge token and code
+func (h *Handler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req MFAVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.New(errors.ErrInvalidInput, "Invalid request body").WriteHTTPResponse(w)
+		return
+	}
+
+	resp, err := h.service.VerifyMFA(ctx, &req)
+	if err != nil {
+		writeError(w, err, "Login failed")
+		return
+	}
+
+	response.OK(w, resp)
+}
+
+// EnrollMFA starts TOTP enrolment for the current user
+func (h *Handler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	enrollment, err := h.service.EnrollMFA(ctx, claims.UserID)
+	if err != nil {
+		writeError(w, err, "Failed to start MFA enrolment")
+		return
+	}
+
+	response.OK(w, enrollment)
+}
+
+// ConfirmMFA enables MFA for the current user and returns backup codes
+func (h *Handler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.New(errors.ErrInvalidInput, "Invalid request body").WriteHTTPResponse(w)
+		return
+	}
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	codes, err := h.service.ConfirmMFA(ctx, claims.UserID, &req)
+	if err != nil {
+		writeError(w, err, "Failed to enable MFA")
+		return
+	}
+
+	response.OK(w, codes)
+}
+
+// DisableMFA turns MFA off for the current user
+func (h *Handler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.New(errors.ErrInvalidInput, "Invalid request body").WriteHTTPResponse(w)
+		return
+	}
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	if err := h.service.DisableMFA(ctx, claims.UserID, &req); err != nil {
+		writeError(w, err, "Failed to disable MFA")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// RegenerateBackupCodes replaces the backup codes of the current user
+func (h *Handler) RegenerateBackupCodes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.New(errors.ErrInvalidInput, "Invalid request body").WriteHTTPResponse(w)
+		return
+	}
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	codes, err := h.service.RegenerateBackupCodes(ctx, claims.UserID, &req)
+	if err != nil {
+		writeError(w, err, "Failed to regenerate backup codes")
+		return
+	}
+
+	response.OK(w, codes)
+}
+
+// SetRoleMFARequired sets whether holders of a role must use MFA
+func (h *Handler) SetRoleMFARequired(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req RoleMFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.New(errors.ErrInvalidInput, "Invalid request body").WriteHTTPResponse(w)
+		return
+	}
+
+	role, err := h.service.SetRoleMFARequired(ctx, chi.URLParam(r, "role"), &req)
+	if err != nil {
+		writeError(w, err, "Failed to update role")
+		return
+	}
+
+	response.OK(w, role)
+}
+
+// ListOIDCProviders lists the identity providers users can sign in with
+func (h *Handler) ListOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	response.OK(w, h.service.OIDCProviders())
+}
+
+// StartOIDCLogin returns the URL to sign in at an identity provider
+func (h *Handler) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authorization, err := h.service.StartOIDCLogin(ctx, chi.URLParam(r, "provider"))
+	if err != nil {
+		writeError(w, err, "Failed to start login")
+		return
+	}
+
+	response.OK(w, authorization)
+}
+
+// CompleteOIDCLogin signs in with the code and state from an identity provider
+func (h *Handler) CompleteOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req OIDCCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.New(errors.ErrInvalidInput, "Invalid request body").WriteHTTPResponse(w)
+		return
+	}
+
+	resp, err := h.service.CompleteOIDCLogin(ctx, &req)
+	if err != nil {
+		writeError(w, err, "Login failed")
+		return
+	}
+
+	response.OK(w, resp)
+}
+
+// ListIdentities lists the external identities of the current user
+func (h *Handler) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	identities, err := h.service.ListIdentities(ctx, claims.UserID)
+	if err != nil {
+		writeError(w, err, "Failed to list identities")
+		return
+	}
+
+	response.OK(w, identities)
+}
+
+// StartIdentityLink returns the URL to sign in at an identity provider whose
+// account is to be linked to the current user
+func (h *Handler) StartIdentityLink(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	authorization, err := h.service.StartIdentityLink(ctx, claims.UserID, chi.URLParam(r, "provider"))
+	if err != nil {
+		writeError(w, err, "Failed to start linking")
+		return
+	}
+
+	response.OK(w, authorization)
+}
+
+// LinkIdentity links an identity provider account to the current user
+func (h *Handler) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req OIDCCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.New(errors.ErrInvalidInput, "Invalid request body").WriteHTTPResponse(w)
+		return
+	}
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	identity, err := h.service.LinkIdentity(ctx, claims.UserID, &req)
+	if err != nil {
+		writeError(w, err, "Failed to link identity")
+		return
+	}
+
+	response.Created(w, identity)
+}
+
+// UnlinkIdentity unlinks an identity provider account from the current user
+func (h *Handler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	if err := h.service.UnlinkIdentity(ctx, claims.UserID, chi.URLParam(r, "provider")); err != nil {
+		writeError(w, err, "Failed to unlink identity")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// ListAPIKeys lists the API keys of the current user
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	keys, err := h.service.ListAPIKeys(ctx, claims.UserID)
+	if err != nil {
+		writeError(w, err, "Failed to list API keys")
+		return
+	}
+
+	response.OK(w, keys)
+}
+
+// CreateAPIKey creates an API key for the current user; the response holds
+// the only copy of the key
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.New(errors.ErrInvalidInput, "Invalid request body").WriteHTTPResponse(w)
+		return
+	}
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	created, err := h.service.CreateAPIKey(ctx, claims.UserID, &req)
+	if err != nil {
+		writeError(w, err, "Failed to create API key")
+		return
+	}
+
+	response.Created(w, created)
+}
+
+// RevokeAPIKey revokes an API key of the current user
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	if _, err := h.service.RevokeAPIKey(ctx, claims.UserID, chi.URLParam(r, "id")); err != nil {
+		writeError(w, err, "Failed to revoke API key")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// ListSessions lists the active sessions of the current user
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	sessions, err := h.service.ListSessions(ctx, claims.UserID)
+	if err != nil {
+		writeError(w, err, "Failed to list sessions")
+		return
+	}
+
+	response.OK(w, sessions)
+}
+
+// RevokeSession signs the current user out of one session
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	if _, err := h.service.RevokeSession(ctx, claims.UserID, chi.URLParam(r, "id")); err != nil {
+		writeError(w, err, "Failed to revoke session")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// RevokeAllSessions signs the current user out of every session
+func (h *Handler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	revoked, err := h.service.RevokeAllSessions(ctx, claims.UserID)
+	if err != nil {
+		writeError(w, err, "Failed to revoke sessions")
+		return
+	}
+
+	response.OK(w, map[string]int{"revoked": revoked})
+}
+
+// ExportMyData returns everything held about the current user
+func (h *Handler) ExportMyData(w http.ResponseWriter, r *http.Request) {
+	export, err := h.service.Export(r.Context())
+	if err != nil {
+		writeError(w, err, "Failed to export user data")
+		return
+	}
+	response.OK(w, export)
+}
+
+// RequestErasure erases a user's personal data across the services
+func (h *Handler) RequestErasure(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, _ := middleware.GetUserFromContext(ctx)
+	request, err := h.service.RequestErasure(ctx, chi.URLParam(r, "id"), claims.UserID)
+	if err != nil {
+		writeError(w, err, "Failed to request erasure")
+		return
+	}
+
+	response.JSON(w, request, http.StatusAccepted)
+}
+
+// ListErasureRequests lists erasure requests, optionally filtered by status
+func (h *Handler) ListErasureRequests(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	requests, err := h.service.ListErasureRequests(ctx, query.Get("status"), limit, offset)
+	if err != nil {
+		writeError(w, err, "Failed to list erasure requests")
+		return
+	}
+
+	response.OK(w, requests)
+}
+
+// GetErasureRequest gets an erasure request
+func (h *Handler) GetErasureRequest(w http.ResponseWriter, r *http.Request) {
+	request, err := h.service.GetErasureRequest(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, err, "Failed to get erasure request")
+		return
+	}
+
+	response.OK(w, request)
+}
+
 // GetBatch gets multiple users by IDs
 func (h *Handler) GetBatch(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -222,15 +682,14 @@ func (h *Handler) GetBatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response.Batch(w, users)
+	response.Batch(w, Readable(ctx, users))
 }
 
-// HealthCheck handles health check
-func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	logger.Info("Health check called")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status":  "healthy",
-		"service": "user-service",
-	})
+// writeError writes an AppError as-is and anything else as an internal error
+func writeError(w http.ResponseWriter, err error, message string) {
+	if appErr, ok := err.(*errors.AppError); ok {
+		appErr.WriteHTTPResponse(w)
+		return
+	}
+	errors.New(errors.ErrInternalServer, message).WriteHTTPResponse(w)
 }
@@ -0,0 +1,660 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/microservices-go/shared/audit"
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/middleware"
+	"github.com/microservices-go/shared/rabbitmq"
+)
+
+const (
+	// totpPeriod is the lifetime of a TOTP code in seconds; one period of
+	// clock skew is accepted either way
+	totpPeriod = 30
+
+	// backupCodeCount is how many backup codes a user holds at a time
+	backupCodeCount = 10
+
+	// backupCodeAlphabet leaves out characters that are easily confused
+	backupCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+	// mfaChallengeAudience marks challenge tokens, which are signed with a
+	// key derived from the JWT secret so they never pass as access tokens
+	mfaChallengeAudience = "mfa-challenge"
+)
+
+var totpOpts = totp.ValidateOpts{Period: totpPeriod, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+
+// UserMFA holds a user's TOTP secret; it is enabled once confirmed
+type UserMFA struct {
+	UserID         string     `gorm:"primaryKey;column:user_id"`
+	Secret         string     `gorm:"column:secret;serializer:encrypted"`
+	ConfirmedAt    *time.Time `gorm:"column:confirmed_at"`
+	LastUsedStep   int64      `gorm:"column:last_used_step"`
+	FailedAttempts int        `gorm:"column:failed_attempts"`
+	LockedUntil    *time.Time `gorm:"column:locked_until"`
+	CreatedAt      time.Time  `gorm:"column:created_at"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at"`
+}
+
+// TableName returns the table name
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
+
+// BackupCode is a hashed single-use MFA backup code
+type BackupCode struct {
+	ID        string     `gorm:"primaryKey;column:id"`
+	UserID    string     `gorm:"column:user_id"`
+	CodeHash  string     `gorm:"column:code_hash"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at"`
+}
+
+// TableName returns the table name
+func (BackupCode) TableName() string {
+	return "mfa_backup_codes"
+}
+
+// MFACodeRequest carries a TOTP code or, where accepted, a backup code
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required,max=20"`
+}
+
+// MFAVerifyRequest completes a login that returned an MFA challenge
+type MFAVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required,max=20"`
+}
+
+// RoleMFARequest sets whether holders of a role must use MFA
+type RoleMFARequest struct {
+	Required *bool `json:"required" validate:"required"`
+}
+
+// MFAEnrollment is the secret of a started enrolment; authenticator apps
+// read the provisioning URI from a QR code
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// MFABackupCodes are shown once, when generated
+type MFABackupCodes struct {
+	Codes []string `json:"codes"`
+}
+
+// mfaChallengeClaims identify a user who passed the password step
+type mfaChallengeClaims struct {
+	UserID string `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+// GetMFA gets the MFA settings of a user
+func (r *Repository) GetMFA(ctx context.Context, userID string) (*UserMFA, error) {
+	var mfa UserMFA
+	err := r.db.WithContext(ctx).First(&mfa, "user_id = ?", userID).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "MFA enrolment not started")
+		}
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to get MFA settings")
+	}
+	return &mfa, nil
+}
+
+// SaveMFA stores a new, unconfirmed TOTP secret, replacing any earlier one
+func (r *Repository) SaveMFA(ctx context.Context, mfa *UserMFA) error {
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(mfa).Error
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to save MFA settings")
+	}
+	return nil
+}
+
+// EnableMFAWithDB confirms the TOTP secret of a user and flags the user
+func (r *Repository) EnableMFAWithDB(ctx context.Context, db *gorm.DB, userID string) error {
+	now := time.Now()
+	err := db.WithContext(ctx).Model(&UserMFA{}).Where("user_id = ?", userID).
+		Updates(map[string]interface{}{"confirmed_at": now, "updated_at": now}).Error
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to enable MFA")
+	}
+	return r.setMFAEnabledWithDB(ctx, db, userID, true)
+}
+
+// DisableMFAWithDB drops the TOTP secret and backup codes of a user
+func (r *Repository) DisableMFAWithDB(ctx context.Context, db *gorm.DB, userID string) error {
+	if err := db.WithContext(ctx).Delete(&BackupCode{}, "user_id = ?", userID).Error; err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to delete backup codes")
+	}
+	if err := db.WithContext(ctx).Delete(&UserMFA{}, "user_id = ?", userID).Error; err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to disable MFA")
+	}
+	return r.setMFAEnabledWithDB(ctx, db, userID, false)
+}
+
+func (r *Repository) setMFAEnabledWithDB(ctx context.Context, db *gorm.DB, userID string, enabled bool) error {
+	err := db.WithContext(ctx).Model(&User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"mfa_enabled": enabled, "updated_at": time.Now()}).Error
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to update user")
+	}
+	return nil
+}
+
+// ReplaceBackupCodesWithDB replaces the backup codes of a user with new hashes
+func (r *Repository) ReplaceBackupCodesWithDB(ctx context.Context, db *gorm.DB, userID string, hashes []string) error {
+	if err := db.WithContext(ctx).Delete(&BackupCode{}, "user_id = ?", userID).Error; err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to delete backup codes")
+	}
+
+	now := time.Now()
+	codes := make([]*BackupCode, len(hashes))
+	for i, hash := range hashes {
+		codes[i] = &BackupCode{ID: uuid.New().String(), UserID: userID, CodeHash: hash, CreatedAt: now}
+	}
+	if err := db.WithContext(ctx).Create(&codes).Error; err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to store backup codes")
+	}
+	return nil
+}
+
+// UseBackupCode marks an unused backup code as used, reporting whether there was one
+func (r *Repository) UseBackupCode(ctx context.Context, userID, hash string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&BackupCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if err := result.Error; err != nil {
+		return false, errors.Wrap(err, errors.ErrDatabaseError, "Failed to use backup code")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// UseTOTPStep records the time step of an accepted TOTP code and clears
+// failed attempts, reporting false when the step was already used
+func (r *Repository) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&UserMFA{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Updates(map[string]interface{}{
+			"last_used_step":  step,
+			"failed_attempts": 0,
+			"locked_until":    nil,
+			"updated_at":      time.Now(),
+		})
+	if err := result.Error; err != nil {
+		return false, errors.Wrap(err, errors.ErrDatabaseError, "Failed to record MFA code")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RecordMFAFailure counts a rejected code, locking verification until
+// lockedUntil once maxAttempts is reached
+func (r *Repository) RecordMFAFailure(ctx context.Context, userID string, maxAttempts int, lockedUntil time.Time) error {
+	err := r.db.WithContext(ctx).Exec(`
+		UPDATE user_mfa SET
+			failed_attempts = CASE WHEN failed_attempts + 1 >= ? THEN 0 ELSE failed_attempts + 1 END,
+			locked_until = CASE WHEN failed_attempts + 1 >= ? THEN ? ELSE locked_until END,
+			updated_at = ?
+		WHERE user_id = ?`,
+		maxAttempts, maxAttempts, lockedUntil, time.Now(), userID).Error
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to record MFA failure")
+	}
+	return nil
+}
+
+// MFARequired reports whether any role of a user requires MFA
+func (r *Repository) MFARequired(ctx context.Context, userID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("user_roles").
+		Joins("JOIN roles ON roles.name = user_roles.role").
+		Where("user_roles.user_id = ? AND roles.mfa_required", userID).
+		Count(&count).Error
+	if err != nil {
+		return false, errors.Wrap(err, errors.ErrDatabaseError, "Failed to check MFA policy")
+	}
+	return count > 0, nil
+}
+
+// SetRoleMFARequired sets whether a role requires MFA and returns the role
+func (r *Repository) SetRoleMFARequired(ctx context.Context, name string, required bool) (*Role, error) {
+	result := r.db.WithContext(ctx).Model(&Role{}).Where("name = ?", name).Update("mfa_required", required)
+	if err := result.Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to update role")
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New(errors.ErrNotFound, "Role not found")
+	}
+
+	var role Role
+	if err := r.db.WithContext(ctx).First(&role, "name = ?", name).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to get role")
+	}
+	return &role, nil
+}
+
+// EnrollMFA starts TOTP enrolment with a new secret; MFA is enabled once a
+// code from it is confirmed
+func (s *Service) EnrollMFA(ctx context.Context, userID string) (*MFAEnrollment, error) {
+	if err := requireInteractive(ctx); err != nil {
+		return nil, err
+	}
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, errors.New(errors.ErrConflict, "MFA is already enabled")
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.mfaConfig.Issuer,
+		AccountName: user.Email,
+		Period:      totpPeriod,
+		Digits:      totpOpts.Digits,
+		Algorithm:   totpOpts.Algorithm,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to generate MFA secret")
+	}
+
+	now := time.Now()
+	if err := s.repo.SaveMFA(ctx, &UserMFA{UserID: userID, Secret: key.Secret(), CreatedAt: now, UpdatedAt: now}); err != nil {
+		return nil, err
+	}
+	return &MFAEnrollment{Secret: key.Secret(), ProvisioningURI: key.URL()}, nil
+}
+
+// ConfirmMFA enables MFA once the user proves they hold the enrolled secret
+// and returns their backup codes
+func (s *Service) ConfirmMFA(ctx context.Context, userID string, req *MFACodeRequest) (*MFABackupCodes, error) {
+	if err := requireInteractive(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.validator.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	mfa, err := s.repo.GetMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.ConfirmedAt != nil {
+		return nil, errors.New(errors.ErrConflict, "MFA is already enabled")
+	}
+	if err := s.verifyCode(ctx, mfa, req.Code, false); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newBackupCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := s.repo.EnableMFAWithDB(ctx, tx, userID); err != nil {
+			return err
+		}
+		return s.repo.ReplaceBackupCodesWithDB(ctx, tx, userID, hashes)
+	}); err != nil {
+		return nil, err
+	}
+
+	s.mfaChanged(ctx, user, true, "MFA enabled")
+	return &MFABackupCodes{Codes: codes}, nil
+}
+
+// DisableMFA turns MFA off with a valid TOTP or backup code, unless a role
+// of the user requires it
+func (s *Service) DisableMFA(ctx context.Context, userID string, req *MFACodeRequest) error {
+	if err := requireInteractive(ctx); err != nil {
+		return err
+	}
+	if err := s.validator.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return errors.New(errors.ErrConflict, "MFA is not enabled")
+	}
+	required, err := s.repo.MFARequired(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return errors.New(errors.ErrForbidden, "MFA is required for your role")
+	}
+
+	mfa, err := s.repo.GetMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.verifyCode(ctx, mfa, req.Code, true); err != nil {
+		return err
+	}
+
+	if err := s.repo.WithTransaction(ctx, func(tx *gorm.DB) error {
+		return s.repo.DisableMFAWithDB(ctx, tx, userID)
+	}); err != nil {
+		return err
+	}
+
+	s.mfaChanged(ctx, user, false, "MFA disabled")
+	return nil
+}
+
+// RegenerateBackupCodes replaces the backup codes of a user, given a TOTP code
+func (s *Service) RegenerateBackupCodes(ctx context.Context, userID string, req *MFACodeRequest) (*MFABackupCodes, error) {
+	if err := requireInteractive(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.validator.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled {
+		return nil, errors.New(errors.ErrConflict, "MFA is not enabled")
+	}
+	mfa, err := s.repo.GetMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyCode(ctx, mfa, req.Code, false); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newBackupCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.WithTransaction(ctx, func(tx *gorm.DB) error {
+		return s.repo.ReplaceBackupCodesWithDB(ctx, tx, userID, hashes)
+	}); err != nil {
+		return nil, err
+	}
+
+	s.mfaChanged(ctx, user, true, "MFA backup codes regenerated")
+	return &MFABackupCodes{Codes: codes}, nil
+}
+
+// VerifyMFA completes a login with the challenge token Login returned and a
+// TOTP or backup code
+func (s *Service) VerifyMFA(ctx context.Context, req *MFAVerifyRequest) (*LoginResponse, error) {
+	if err := s.validator.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	userID, ok := s.parseChallenge(req.ChallengeToken)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "Invalid or expired MFA challenge")
+	}
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, errors.New(errors.ErrUnauthorized, "Invalid or expired MFA challenge")
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		s.loginFailed(ctx, user, "account_deactivated")
+		return nil, errors.New(errors.ErrForbidden, "Account is deactivated")
+	}
+	if !user.MFAEnabled {
+		return nil, errors.New(errors.ErrUnauthorized, "Invalid or expired MFA challenge")
+	}
+
+	mfa, err := s.repo.GetMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyCode(ctx, mfa, req.Code, true); err != nil {
+		s.loginFailed(ctx, user, "invalid_mfa_code")
+		return nil, err
+	}
+	return s.signIn(ctx, user)
+}
+
+// SetRoleMFARequired sets whether holders of a role must use MFA. Holders
+// without MFA are signed out and get tokens without permissions until they
+// enrol.
+func (s *Service) SetRoleMFARequired(ctx context.Context, name string, req *RoleMFARequest) (*Role, error) {
+	if err := s.validator.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	role, err := s.repo.SetRoleMFARequired(ctx, name, *req.Required)
+	if err != nil {
+		return nil, err
+	}
+
+	log := logger.WithContext(ctx).WithField("audit", true).WithField("role", name)
+	log.WithField("mfa_required", role.MFARequired).Info("Role MFA policy changed")
+
+	// Holders without MFA now sign in with enrolment-only tokens; end the
+	// sessions whose tokens still carry the role's permissions
+	if role.MFARequired {
+		revoked, err := s.repo.RevokeSessionsWithoutMFA(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		log.WithField("sessions", revoked).Info("Sessions ended for role holders without MFA")
+	}
+	return role, nil
+}
+
+// signIn starts a session for a user who passed every factor they have and
+// issues its access token. Users whose roles require MFA but who have not
+// enrolled get a token without permissions, enough to enrol.
+func (s *Service) signIn(ctx context.Context, user *User) (*LoginResponse, error) {
+	permissions, enrollmentRequired, err := s.grantedPermissions(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	session, err := s.startSession(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.auditLog.Record(ctx, &audit.Event{
+		Action:     "user.login",
+		TargetType: "user",
+		TargetID:   user.ID,
+		Metadata:   map[string]string{"session_id": session.ID},
+		ActorType:  audit.ActorUser,
+		ActorID:    user.ID,
+		ActorRole:  user.Role,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &LoginResponse{User: user, MFAEnrollmentRequired: enrollmentRequired}
+	resp.Token, err = middleware.GenerateToken(user.ID, user.Email, user.Role, permissions, session.ID, session.ExpiresAt, s.jwtConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to generate token")
+	}
+	return resp, nil
+}
+
+// grantedPermissions returns the permissions of a user's roles, or none
+// while a role requires MFA the user has not enrolled in
+func (s *Service) grantedPermissions(ctx context.Context, user *User) ([]string, bool, error) {
+	permissions, err := s.repo.GetPermissions(ctx, user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if user.MFAEnabled {
+		return permissions, false, nil
+	}
+
+	required, err := s.repo.MFARequired(ctx, user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if required {
+		return nil, true, nil
+	}
+	return permissions, false, nil
+}
+
+// verifyCode checks a TOTP code, or a backup code when allowBackup is set.
+// Used codes are rejected, and too many failures lock verification for a while.
+func (s *Service) verifyCode(ctx context.Context, mfa *UserMFA, code string, allowBackup bool) error {
+	now := time.Now()
+	if mfa.LockedUntil != nil && now.Before(*mfa.LockedUntil) {
+		return errors.New(errors.ErrRateLimit, "Too many invalid MFA codes, try again later")
+	}
+
+	var ok bool
+	var err error
+	if step, valid := totpStep(mfa.Secret, strings.TrimSpace(code), now); valid {
+		ok, err = s.repo.UseTOTPStep(ctx, mfa.UserID, step)
+	} else if allowBackup && mfa.ConfirmedAt != nil {
+		ok, err = s.repo.UseBackupCode(ctx, mfa.UserID, hashBackupCode(code))
+	}
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+
+	lockedUntil := now.Add(time.Duration(s.mfaConfig.LockoutTime) * time.Second)
+	if err := s.repo.RecordMFAFailure(ctx, mfa.UserID, s.mfaConfig.MaxAttempts, lockedUntil); err != nil {
+		return err
+	}
+	logger.WithContext(ctx).
+		WithField("audit", true).
+		WithField("user_id", mfa.UserID).
+		Warn("MFA verification failed")
+	return errors.New(errors.ErrUnauthorized, "Invalid MFA code")
+}
+
+// mfaChanged drops cached copies of a user whose MFA settings changed,
+// announces the change and records it in the audit log
+func (s *Service) mfaChanged(ctx context.Context, user *User, enabled bool, message string) {
+	s.invalidateUser(ctx, user)
+	if s.publisher != nil {
+		event := &UserMFAChangedEvent{UserID: user.ID, MFAEnabled: enabled, ChangedAt: time.Now()}
+		if err := s.publisher.PublishEvent(ctx, rabbitmq.EventUserMFAChanged, event); err != nil {
+			logger.WithContext(ctx).WithError(err).Warn("Failed to publish user MFA changed event")
+		}
+	}
+	logger.WithContext(ctx).
+		WithField("audit", true).
+		WithField("user_id", user.ID).
+		Info(message)
+}
+
+// issueChallenge signs the token that carries a user from the password step
+// to the MFA step
+func (s *Service) issueChallenge(userID string) (string, error) {
+	now := time.Now()
+	claims := mfaChallengeClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.jwtConfig.Issuer,
+			Audience:  jwt.ClaimStrings{mfaChallengeAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(s.mfaConfig.ChallengeTTL) * time.Second)),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.challengeKey())
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrInternalServer, "Failed to generate MFA challenge")
+	}
+	return token, nil
+}
+
+// parseChallenge returns the user of a valid challenge token
+func (s *Service) parseChallenge(tokenString string) (string, bool) {
+	claims := &mfaChallengeClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return s.challengeKey(), nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(mfaChallengeAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !token.Valid || claims.UserID == "" {
+		return "", false
+	}
+	return claims.UserID, true
+}
+
+func (s *Service) challengeKey() []byte {
+	key := sha256.Sum256([]byte(mfaChallengeAudience + ":" + s.jwtConfig.Secret))
+	return key[:]
+}
+
+// totpStep returns the time step a TOTP code is valid for at now
+func totpStep(secret, code string, now time.Time) (int64, bool) {
+	for _, skew := range []int64{0, -1, 1} {
+		t := now.Add(time.Duration(skew*totpPeriod) * time.Second)
+		want, err := totp.GenerateCodeCustom(secret, t, totpOpts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return t.Unix() / totpPeriod, true
+		}
+	}
+	return 0, false
+}
+
+// newBackupCodes generates backup codes formatted as xxxxx-xxxxx, with the
+// hashes to store for them
+func newBackupCodes() ([]string, []string, error) {
+	codes := make([]string, backupCodeCount)
+	hashes := make([]string, backupCodeCount)
+	for i := range codes {
+		raw := make([]byte, 10)
+		for j := range raw {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(backupCodeAlphabet))))
+			if err != nil {
+				return nil, nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to generate backup codes")
+			}
+			raw[j] = backupCodeAlphabet[n.Int64()]
+		}
+		codes[i] = string(raw[:5]) + "-" + string(raw[5:])
+		hashes[i] = hashBackupCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashBackupCode hashes a backup code, ignoring case, spaces and dashes.
+// Codes are random enough that an unsalted SHA-256 is safe to store.
+func hashBackupCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,87 @@
+package user
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/middleware"
+)
+
+func TestTOTPStep(t *testing.T) {
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: "test", AccountName: "a@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+
+	for _, skew := range []int64{-1, 0, 1} {
+		code, err := totp.GenerateCodeCustom(key.Secret(), now.Add(time.Duration(skew*totpPeriod)*time.Second), totpOpts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		step, ok := totpStep(key.Secret(), code, now)
+		if !ok || step != now.Unix()/totpPeriod+skew {
+			t.Errorf("skew %d: got step %d, %v", skew, step, ok)
+		}
+	}
+
+	stale, _ := totp.GenerateCodeCustom(key.Secret(), now.Add(-2*totpPeriod*time.Second), totpOpts)
+	if _, ok := totpStep(key.Secret(), stale, now); ok {
+		t.Error("a code two periods old must be rejected")
+	}
+}
+
+func TestBackupCodes(t *testing.T) {
+	codes, hashes, err := newBackupCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != backupCodeCount || len(hashes) != backupCodeCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), backupCodeCount)
+	}
+
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("code %q is not formatted as xxxxx-xxxxx", code)
+		}
+		if seen[hashes[i]] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[hashes[i]] = true
+
+		typed := strings.ToUpper(strings.ReplaceAll(code, "-", " "))
+		if hashBackupCode(typed) != hashes[i] {
+			t.Errorf("%q does not match the hash of %q", typed, code)
+		}
+	}
+}
+
+func TestChallengeIsNotAnAccessToken(t *testing.T) {
+	jwtConfig := &config.JWTConfig{Secret: "secret", Issuer: "test"}
+	s := &Service{jwtConfig: jwtConfig, mfaConfig: &config.MFAConfig{ChallengeTTL: 60}}
+
+	challenge, err := s.issueChallenge("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID, ok := s.parseChallenge(challenge); !ok || userID != "u1" {
+		t.Errorf("got %q, %v, want the challenge for u1", userID, ok)
+	}
+	if _, err := middleware.NewAuthMiddleware(jwtConfig).Verify(context.Background(), "Bearer "+challenge); err == nil {
+		t.Error("a challenge token must not authenticate requests")
+	}
+
+	token, err := middleware.GenerateToken("u1", "a@example.com", "user", nil, "s1", time.Now().Add(time.Hour), jwtConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.parseChallenge(token); ok {
+		t.Error("an access token must not pass as a challenge")
+	}
+}
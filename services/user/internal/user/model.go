@@ -6,20 +6,36 @@ import (
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+
+	"github.com/microservices-go/shared/encryption"
 )
 
-// User represents a user entity
+// EncryptedTargets are the encrypted columns of the user service, which the
+// re-encryption job keeps under the primary key
+var EncryptedTargets = []encryption.Target{
+	{Table: "users", Key: "id", Columns: []string{"email", "first_name", "last_name"}, Indexes: map[string]string{"email": "email_index"}},
+	{Table: "identities", Key: "id", Columns: []string{"email"}},
+	{Table: "user_mfa", Key: "user_id", Columns: []string{"secret"}},
+}
+
+// personalFields are the encrypted fields of a user, redacted in the audit log
+var personalFields = []string{"email", "first_name", "last_name"}
+
+// User represents a user entity. Email and names are encrypted at rest;
+// EmailIndex, the blind index of the email, is unique and used for lookups.
 type User struct {
-	ID        string         `json:"id" gorm:"primaryKey;column:id"`
-	Email     string         `json:"email" gorm:"unique;column:email"`
-	Password  string         `json:"-" gorm:"column:password_hash"`
-	FirstName string         `json:"first_name" gorm:"column:first_name"`
-	LastName  string         `json:"last_name" gorm:"column:last_name"`
-	Role      string         `json:"role" gorm:"column:role"`
-	IsActive  bool           `json:"is_active" gorm:"column:is_active"`
-	CreatedAt time.Time      `json:"created_at" gorm:"column:created_at"`
-	UpdatedAt time.Time      `json:"updated_at" gorm:"column:updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index;column:deleted_at"`
+	ID         string         `json:"id" gorm:"primaryKey;column:id"`
+	Email      string         `json:"email" gorm:"column:email;serializer:encrypted"`
+	EmailIndex string         `json:"-" gorm:"column:email_index"`
+	Password   string         `json:"-" gorm:"column:password_hash"`
+	FirstName  string         `json:"first_name" gorm:"column:first_name;serializer:encrypted"`
+	LastName   string         `json:"last_name" gorm:"column:last_name;serializer:encrypted"`
+	Role       string         `json:"role" gorm:"column:role"`
+	IsActive   bool           `json:"is_active" gorm:"column:is_active"`
+	MFAEnabled bool           `json:"mfa_enabled" gorm:"column:mfa_enabled"`
+	CreatedAt  time.Time      `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt  time.Time      `json:"updated_at" gorm:"column:updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index;column:deleted_at"`
 }
 
 // TableName returns the table name
@@ -42,8 +58,17 @@ func (u *User) HashPassword() error {
 	return nil
 }
 
+// HasPassword reports whether the user can sign in with a password; users
+// signed up through an identity provider have none
+func (u *User) HasPassword() bool {
+	return u.Password != ""
+}
+
 // CheckPassword verifies the password
 func (u *User) CheckPassword(password string) bool {
+	if !u.HasPassword() {
+		return false
+	}
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 	return err == nil
 }
@@ -90,35 +115,45 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
-// LoginResponse represents login response
+// LoginResponse represents login response. Users with MFA get no token but
+// a challenge token to pass to VerifyMFA with their code; users whose role
+// requires MFA but who have not enrolled get a token without permissions.
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  *User  `json:"user"`
+	Token                 string `json:"token,omitempty"`
+	User                  *User  `json:"user,omitempty"`
+	MFARequired           bool   `json:"mfa_required,omitempty"`
+	ChallengeToken        string `json:"challenge_token,omitempty"`
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
 }
 
 // UserResponse represents user response (without sensitive data)
 type UserResponse struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Role      string    `json:"role"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         string    `json:"id"`
+	Email      string    `json:"email"`
+	FirstName  string    `json:"first_name"`
+	LastName   string    `json:"last_name"`
+	Role       string    `json:"role"`
+	IsActive   bool      `json:"is_active"`
+	MFAEnabled bool      `json:"mfa_enabled"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
 
+// IsEntity marks UserResponse as the User entity of the user subgraph
+func (UserResponse) IsEntity() {}
+
 // ToResponse converts User to UserResponse
 func (u *User) ToResponse() *UserResponse {
 	return &UserResponse{
-		ID:        u.ID,
-		Email:     u.Email,
-		FirstName: u.FirstName,
-		LastName:  u.LastName,
-		Role:      u.Role,
-		IsActive:  u.IsActive,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:         u.ID,
+		Email:      u.Email,
+		FirstName:  u.FirstName,
+		LastName:   u.LastName,
+		Role:       u.Role,
+		IsActive:   u.IsActive,
+		MFAEnabled: u.MFAEnabled,
+		CreatedAt:  u.CreatedAt,
+		UpdatedAt:  u.UpdatedAt,
 	}
 }
 
@@ -130,3 +165,26 @@ type UserCreatedEvent struct {
 	LastName  string    `json:"last_name"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// UserUpdatedEvent represents user updated event
+type UserUpdatedEvent struct {
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	IsActive  bool      `json:"is_active"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UserMFAChangedEvent represents a change to a user's MFA settings
+type UserMFAChangedEvent struct {
+	UserID     string    `json:"user_id"`
+	MFAEnabled bool      `json:"mfa_enabled"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+// UserDeletedEvent represents user deleted event
+type UserDeletedEvent struct {
+	UserID    string    `json:"user_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
@@ -0,0 +1,547 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/logger"
+)
+
+// oidcHTTPTimeout bounds discovery, key and token requests to a provider
+const oidcHTTPTimeout = 10 * time.Second
+
+// Identity links a subject at an OpenID provider to a user
+type Identity struct {
+	ID          string     `json:"id" gorm:"primaryKey;column:id"`
+	UserID      string     `json:"user_id" gorm:"column:user_id"`
+	Provider    string     `json:"provider" gorm:"column:provider"`
+	Subject     string     `json:"subject" gorm:"column:subject"`
+	Email       string     `json:"email,omitempty" gorm:"column:email;serializer:encrypted"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty" gorm:"column:last_login_at"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns the table name
+func (Identity) TableName() string {
+	return "identities"
+}
+
+// OIDCLogin is an authorization request in flight. Only the hash of its
+// state is stored; the PKCE verifier and nonce never leave the service.
+type OIDCLogin struct {
+	StateHash    string    `gorm:"primaryKey;column:state_hash"`
+	Provider     string    `gorm:"column:provider"`
+	CodeVerifier string    `gorm:"column:code_verifier"`
+	Nonce        string    `gorm:"column:nonce"`
+	UserID       *string   `gorm:"column:user_id"`
+	ExpiresAt    time.Time `gorm:"column:expires_at"`
+	CreatedAt    time.Time `gorm:"column:created_at"`
+}
+
+// TableName returns the table name
+func (OIDCLogin) TableName() string {
+	return "oidc_logins"
+}
+
+// OIDCCallbackRequest carries the code and state a provider redirected back with
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" validate:"required,max=2048"`
+	State string `json:"state" validate:"required,max=128"`
+}
+
+// OIDCAuthorization is where to send the browser to sign in at a provider;
+// the state comes back with the code
+type OIDCAuthorization struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+}
+
+// oidcClaims are the ID token claims used to find or create a user
+type oidcClaims struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+}
+
+// oidcClient is the relying party for one provider. Discovery runs on first
+// use, so the service starts while a provider is unreachable.
+type oidcClient struct {
+	cfg         config.OIDCProviderConfig
+	redirectURL string
+	httpClient  *http.Client
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+// newOIDCClients creates a client per configured provider
+func newOIDCClients(cfg *config.OIDCConfig) map[string]*oidcClient {
+	clients := make(map[string]*oidcClient)
+	if cfg == nil {
+		return clients
+	}
+	for _, p := range cfg.Providers {
+		clients[p.Name] = &oidcClient{
+			cfg:         p,
+			redirectURL: cfg.RedirectURL,
+			httpClient:  &http.Client{Timeout: oidcHTTPTimeout},
+		}
+	}
+	return clients
+}
+
+// discover returns the provider metadata and the OAuth2 client for it
+func (c *oidcClient) discover(ctx context.Context) (*oidc.Provider, *oauth2.Config, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.provider == nil {
+		provider, err := oidc.NewProvider(oidc.ClientContext(ctx, c.httpClient), c.cfg.Issuer)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.provider = provider
+	}
+
+	return c.provider, &oauth2.Config{
+		ClientID:     c.cfg.ClientID,
+		ClientSecret: c.cfg.ClientSecret,
+		Endpoint:     c.provider.Endpoint(),
+		RedirectURL:  c.redirectURL,
+		Scopes:       c.cfg.Scopes,
+	}, nil
+}
+
+// authCodeURL builds the authorization request with a PKCE (S256) challenge
+// for verifier and the nonce the ID token must carry
+func (c *oidcClient) authCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	_, oauth, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// exchange redeems an authorization code with its PKCE verifier and returns
+// the claims of the verified ID token
+func (c *oidcClient) exchange(ctx context.Context, code, verifier, nonce string) (*oidcClaims, error) {
+	provider, oauth, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = oidc.ClientContext(ctx, c.httpClient)
+	token, err := oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, err
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "Token response has no ID token")
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: c.cfg.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New(errors.ErrUnauthorized, "ID token nonce mismatch")
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// SaveOIDCLogin stores a started authorization request, dropping expired ones
+func (r *Repository) SaveOIDCLogin(ctx context.Context, login *OIDCLogin) error {
+	if err := r.db.WithContext(ctx).Delete(&OIDCLogin{}, "expires_at < ?", time.Now()).Error; err != nil {
+		logger.WithContext(ctx).WithError(err).Warn("Failed to delete expired OIDC logins")
+	}
+	if err := r.db.WithContext(ctx).Create(login).Error; err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to start login")
+	}
+	return nil
+}
+
+// TakeOIDCLogin deletes and returns the unexpired authorization request with
+// the given state hash, so each can be completed once
+func (r *Repository) TakeOIDCLogin(ctx context.Context, stateHash string) (*OIDCLogin, error) {
+	var logins []OIDCLogin
+	err := r.db.WithContext(ctx).Clauses(clause.Returning{}).
+		Where("state_hash = ? AND expires_at > ?", stateHash, time.Now()).
+		Delete(&logins).Error
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to complete login")
+	}
+	if len(logins) == 0 {
+		return nil, errors.New(errors.ErrUnauthorized, "Invalid or expired login state")
+	}
+	return &logins[0], nil
+}
+
+// GetIdentity gets the identity of a provider subject
+func (r *Repository) GetIdentity(ctx context.Context, provider, subject string) (*Identity, error) {
+	var identity Identity
+	err := r.db.WithContext(ctx).First(&identity, "provider = ? AND subject = ?", provider, subject).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Identity not found")
+		}
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to get identity")
+	}
+	return &identity, nil
+}
+
+// ListIdentities lists the identities linked to a user
+func (r *Repository) ListIdentities(ctx context.Context, userID string) ([]*Identity, error) {
+	var identities []*Identity
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("provider").Find(&identities).Error
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to list identities")
+	}
+	return identities, nil
+}
+
+// CreateIdentity links an identity to a user
+func (r *Repository) CreateIdentity(ctx context.Context, identity *Identity) error {
+	return r.CreateIdentityWithDB(ctx, r.db, identity)
+}
+
+// CreateIdentityWithDB links an identity to a user using the provided database connection
+func (r *Repository) CreateIdentityWithDB(ctx context.Context, db *gorm.DB, identity *Identity) error {
+	if identity.ID == "" {
+		identity.ID = uuid.New().String()
+	}
+	identity.CreatedAt = time.Now()
+	if err := db.WithContext(ctx).Create(identity).Error; err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to link identity")
+	}
+	return nil
+}
+
+// TouchIdentity records a sign-in with an identity
+func (r *Repository) TouchIdentity(ctx context.Context, id string) error {
+	err := r.db.WithContext(ctx).Model(&Identity{}).Where("id = ?", id).Update("last_login_at", time.Now()).Error
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to update identity")
+	}
+	return nil
+}
+
+// DeleteIdentity unlinks the identity of a provider from a user
+func (r *Repository) DeleteIdentity(ctx context.Context, userID, provider string) error {
+	result := r.db.WithContext(ctx).Delete(&Identity{}, "user_id = ? AND provider = ?", userID, provider)
+	if err := result.Error; err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to unlink identity")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Identity not found")
+	}
+	return nil
+}
+
+// OIDCProviders lists the names of the configured providers
+func (s *Service) OIDCProviders() []string {
+	providers := []string{}
+	if s.oidcConfig != nil {
+		for _, p := range s.oidcConfig.Providers {
+			providers = append(providers, p.Name)
+		}
+	}
+	return providers
+}
+
+// StartOIDCLogin starts signing in or signing up with a provider
+func (s *Service) StartOIDCLogin(ctx context.Context, provider string) (*OIDCAuthorization, error) {
+	return s.startOIDC(ctx, provider, nil)
+}
+
+// StartIdentityLink starts linking an account at a provider to a user
+func (s *Service) StartIdentityLink(ctx context.Context, userID, provider string) (*OIDCAuthorization, error) {
+	if err := requireInteractive(ctx); err != nil {
+		return nil, err
+	}
+	return s.startOIDC(ctx, provider, &userID)
+}
+
+// CompleteOIDCLogin signs in the user linked to the provider account. An
+// unknown account with a verified email signs up a new user, unless the
+// email is taken: then the owner must sign in and link the account.
+func (s *Service) CompleteOIDCLogin(ctx context.Context, req *OIDCCallbackRequest) (*LoginResponse, error) {
+	login, claims, err := s.finishOIDC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if login.UserID != nil {
+		return nil, errors.New(errors.ErrUnauthorized, "Invalid or expired login state")
+	}
+
+	identity, err := s.repo.GetIdentity(ctx, login.Provider, claims.Subject)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	var user *User
+	if identity != nil {
+		if user, err = s.repo.GetByID(ctx, identity.UserID); err != nil {
+			if errors.IsNotFound(err) {
+				return nil, errors.New(errors.ErrUnauthorized, "Account not found")
+			}
+			return nil, err
+		}
+		if err := s.repo.TouchIdentity(ctx, identity.ID); err != nil {
+			logger.WithContext(ctx).WithError(err).Warn("Failed to record identity sign-in")
+		}
+	} else if user, err = s.signUpWithIdentity(ctx, login.Provider, claims); err != nil {
+		return nil, err
+	}
+
+	return s.authenticated(ctx, user)
+}
+
+// LinkIdentity links the provider account of a completed authorization to
+// the user who started it
+func (s *Service) LinkIdentity(ctx context.Context, userID string, req *OIDCCallbackRequest) (*Identity, error) {
+	if err := requireInteractive(ctx); err != nil {
+		return nil, err
+	}
+	login, claims, err := s.finishOIDC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if login.UserID == nil || *login.UserID != userID {
+		return nil, errors.New(errors.ErrUnauthorized, "Invalid or expired login state")
+	}
+
+	existing, err := s.repo.GetIdentity(ctx, login.Provider, claims.Subject)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.UserID == userID {
+			return nil, errors.New(errors.ErrConflict, "Account is already linked")
+		}
+		return nil, errors.New(errors.ErrConflict, "Account is linked to another user")
+	}
+
+	identities, err := s.repo.ListIdentities(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, identity := range identities {
+		if identity.Provider == login.Provider {
+			return nil, errors.New(errors.ErrConflict, "Another account of this provider is already linked")
+		}
+	}
+
+	identity := &Identity{UserID: userID, Provider: login.Provider, Subject: claims.Subject, Email: claims.Email}
+	if err := s.repo.CreateIdentity(ctx, identity); err != nil {
+		return nil, err
+	}
+
+	logger.WithContext(ctx).
+		WithField("audit", true).
+		WithField("user_id", userID).
+		WithField("provider", login.Provider).
+		Info("Identity linked")
+	return identity, nil
+}
+
+// UnlinkIdentity unlinks the account of a provider from a user, unless it
+// is the user's only way to sign in
+func (s *Service) UnlinkIdentity(ctx context.Context, userID, provider string) error {
+	if err := requireInteractive(ctx); err != nil {
+		return err
+	}
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.HasPassword() {
+		identities, err := s.repo.ListIdentities(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if len(identities) == 1 && identities[0].Provider == provider {
+			return errors.New(errors.ErrConflict, "Cannot unlink the only way to sign in")
+		}
+	}
+
+	if err := s.repo.DeleteIdentity(ctx, userID, provider); err != nil {
+		return err
+	}
+
+	logger.WithContext(ctx).
+		WithField("audit", true).
+		WithField("user_id", userID).
+		WithField("provider", provider).
+		Info("Identity unlinked")
+	return nil
+}
+
+// ListIdentities lists the identities linked to a user
+func (s *Service) ListIdentities(ctx context.Context, userID string) ([]*Identity, error) {
+	return s.repo.ListIdentities(ctx, userID)
+}
+
+// startOIDC stores a new authorization request, for linking to userID when
+// not nil, and returns the provider URL to send the browser to
+func (s *Service) startOIDC(ctx context.Context, provider string, userID *string) (*OIDCAuthorization, error) {
+	client, ok := s.oidc[provider]
+	if !ok {
+		return nil, errors.New(errors.ErrNotFound, "Unknown identity provider")
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	authURL, err := client.authCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		logger.WithContext(ctx).WithError(err).WithField("provider", provider).Error("OIDC discovery failed")
+		return nil, errors.New(errors.ErrServiceUnavailable, "Identity provider unavailable")
+	}
+
+	now := time.Now()
+	if err := s.repo.SaveOIDCLogin(ctx, &OIDCLogin{
+		StateHash:    hashState(state),
+		Provider:     provider,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		UserID:       userID,
+		ExpiresAt:    now.Add(time.Duration(s.oidcConfig.StateTTL) * time.Second),
+		CreatedAt:    now,
+	}); err != nil {
+		return nil, err
+	}
+	return &OIDCAuthorization{AuthorizationURL: authURL, State: state}, nil
+}
+
+// finishOIDC redeems the code of a stored authorization request and returns
+// the request with the provider's verified claims
+func (s *Service) finishOIDC(ctx context.Context, req *OIDCCallbackRequest) (*OIDCLogin, *oidcClaims, error) {
+	if err := s.validator.ValidateStruct(req); err != nil {
+		return nil, nil, err
+	}
+
+	login, err := s.repo.TakeOIDCLogin(ctx, hashState(req.State))
+	if err != nil {
+		return nil, nil, err
+	}
+	client, ok := s.oidc[login.Provider]
+	if !ok {
+		return nil, nil, errors.New(errors.ErrUnauthorized, "Invalid or expired login state")
+	}
+
+	claims, err := client.exchange(ctx, req.Code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		logger.WithContext(ctx).
+			WithField("audit", true).
+			WithField("provider", login.Provider).
+			WithError(err).
+			Warn("OIDC sign-in rejected")
+		return nil, nil, errors.New(errors.ErrUnauthorized, "Sign-in with identity provider failed")
+	}
+	if claims.Subject == "" {
+		return nil, nil, errors.New(errors.ErrUnauthorized, "Sign-in with identity provider failed")
+	}
+	return login, claims, nil
+}
+
+// signUpWithIdentity creates a user without a password for a new provider
+// account and publishes user.created
+func (s *Service) signUpWithIdentity(ctx context.Context, provider string, claims *oidcClaims) (*User, error) {
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errors.New(errors.ErrForbidden, "Identity provider did not confirm an email address")
+	}
+
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName, lastName, _ = strings.Cut(claims.Name, " ")
+	}
+	if firstName == "" {
+		firstName, _, _ = strings.Cut(claims.Email, "@")
+	}
+	user := &User{
+		Email:     claims.Email,
+		FirstName: truncate(firstName, 100),
+		LastName:  truncate(lastName, 100),
+	}
+
+	if err := s.repo.WithTransaction(ctx, func(tx *gorm.DB) error {
+		exists, err := s.repo.ExistsByEmailWithDB(ctx, tx, user.Email)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return errors.New(errors.ErrConflict, "Email already registered; sign in and link the account instead")
+		}
+
+		if err := s.repo.CreateWithDB(ctx, tx, user); err != nil {
+			return err
+		}
+		now := time.Now()
+		return s.repo.CreateIdentityWithDB(ctx, tx, &Identity{
+			UserID:      user.ID,
+			Provider:    provider,
+			Subject:     claims.Subject,
+			Email:       claims.Email,
+			LastLoginAt: &now,
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	s.userCreated(ctx, user)
+	return user, nil
+}
+
+// randomToken returns 32 random bytes, base64url encoded
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, errors.ErrInternalServer, "Failed to generate login state")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashState hashes a login state for storage
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,215 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+
+	"github.com/microservices-go/shared/config"
+)
+
+// mockIssuer is a minimal OpenID provider: it authorizes every request for
+// its subject and redeems codes only with the matching PKCE verifier
+type mockIssuer struct {
+	*httptest.Server
+	key     *rsa.PrivateKey
+	subject string
+
+	mu    sync.Mutex
+	codes map[string]url.Values // authorization request by code
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIssuer{key: key, subject: "mock-subject", codes: make(map[string]url.Values)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("/authorize", m.authorize)
+	mux.HandleFunc("/token", m.token)
+	mux.HandleFunc("/jwks", m.jwks)
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *mockIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                m.URL,
+		"authorization_endpoint":                m.URL + "/authorize",
+		"token_endpoint":                        m.URL + "/token",
+		"jwks_uri":                              m.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (m *mockIssuer) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "PKCE required", http.StatusBadRequest)
+		return
+	}
+
+	code := base64.RawURLEncoding.EncodeToString([]byte(q.Get("state")))
+	m.mu.Lock()
+	m.codes[code] = q
+	m.mu.Unlock()
+
+	redirect, _ := url.Parse(q.Get("redirect_uri"))
+	redirect.RawQuery = url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (m *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	code := r.PostForm.Get("code")
+	m.mu.Lock()
+	authz, ok := m.codes[code]
+	delete(m.codes, code)
+	m.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(challenge[:]) != authz.Get("code_challenge") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            m.URL,
+		"sub":            m.subject,
+		"aud":            authz.Get("client_id"),
+		"nonce":          authz.Get("nonce"),
+		"email":          "jane@example.com",
+		"email_verified": true,
+		"given_name":     "Jane",
+		"family_name":    "Doe",
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Minute).Unix(),
+	})
+	idToken.Header["kid"] = "test"
+	signed, _ := idToken.SignedString(m.key)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     signed,
+	})
+}
+
+func (m *mockIssuer) jwks(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+		}},
+	})
+}
+
+// login follows an authorization URL and returns the code the issuer
+// redirects back with
+func (m *mockIssuer) login(t *testing.T, authURL, state string) string {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	location, err := resp.Location()
+	if err != nil {
+		t.Fatalf("no redirect from authorize: status %d", resp.StatusCode)
+	}
+	if got := location.Query().Get("state"); got != state {
+		t.Fatalf("got state %q, want %q", got, state)
+	}
+	return location.Query().Get("code")
+}
+
+func TestOIDCClient(t *testing.T) {
+	issuer := newMockIssuer(t)
+	clients := newOIDCClients(&config.OIDCConfig{
+		RedirectURL: "http://localhost:3000/auth/callback",
+		Providers: []config.OIDCProviderConfig{{
+			Name:         "mock",
+			Issuer:       issuer.URL,
+			ClientID:     "client",
+			ClientSecret: "secret",
+			Scopes:       []string{"openid", "email", "profile"},
+		}},
+	})
+	client := clients["mock"]
+	ctx := context.Background()
+
+	start := func(t *testing.T) (code, verifier, nonce string) {
+		state, _ := randomToken()
+		nonce, _ = randomToken()
+		verifier = oauth2.GenerateVerifier()
+		authURL, err := client.authCodeURL(ctx, state, nonce, verifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return issuer.login(t, authURL, state), verifier, nonce
+	}
+
+	t.Run("code with verifier and nonce", func(t *testing.T) {
+		code, verifier, nonce := start(t)
+		claims, err := client.exchange(ctx, code, verifier, nonce)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claims.Subject != issuer.subject || claims.Email != "jane@example.com" || !claims.EmailVerified || claims.GivenName != "Jane" {
+			t.Errorf("got claims %+v", claims)
+		}
+	})
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		code, _, nonce := start(t)
+		if _, err := client.exchange(ctx, code, "another-verifier", nonce); err == nil {
+			t.Error("a code must not be redeemed without its PKCE verifier")
+		}
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		code, verifier, _ := start(t)
+		if _, err := client.exchange(ctx, code, verifier, "another-nonce"); err == nil {
+			t.Error("an ID token for another nonce must be rejected")
+		}
+	})
+
+	t.Run("code used twice", func(t *testing.T) {
+		code, verifier, nonce := start(t)
+		if _, err := client.exchange(ctx, code, verifier, nonce); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.exchange(ctx, code, verifier, nonce); err == nil {
+			t.Error("a code must not be redeemed twice")
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package user
+
+import (
+	"context"
+
+	"github.com/microservices-go/shared/policy"
+)
+
+// Authorization rules of the user service, checked by the HTTP routes, the
+// gRPC methods and the subgraph resolvers. A user's ID is its owner.
+var (
+	CanRead        = policy.OwnerOr("user.read", policy.PermUsersRead)
+	CanList        = policy.Permission("user.list", policy.PermUsersRead)
+	CanUpdate      = policy.OwnerOr("user.update", policy.PermUsersWrite)
+	CanSetActive   = policy.Permission("user.set_active", policy.PermUsersWrite)
+	CanDelete      = policy.OwnerOr("user.delete", policy.PermUsersWrite)
+	CanManageRoles = policy.Permission("user.manage_roles", policy.PermRolesManage)
+	CanErase       = policy.OwnerOr("user.erase", policy.PermUsersWrite)
+	CanReadErasure = policy.Permission("user.read_erasure", policy.PermUsersRead)
+)
+
+// AuthorizeUpdate checks an update of user id; (de)activating an account,
+// one's own included, takes users:write
+func AuthorizeUpdate(ctx context.Context, id string, req *UpdateUserRequest) error {
+	if err := CanUpdate.Authorize(ctx, id); err != nil {
+		return err
+	}
+	if req.IsActive != nil {
+		return CanSetActive.Authorize(ctx, "")
+	}
+	return nil
+}
+
+// Readable keeps the users the caller may read
+func Readable(ctx context.Context, users []*UserResponse) []*UserResponse {
+	return policy.Filter(ctx, CanRead, users, func(u *UserResponse) string { return u.ID })
+}
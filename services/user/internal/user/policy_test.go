@@ -0,0 +1,243 @@
+package user
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/microservices-go/shared/audit"
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/dbtest"
+	"github.com/microservices-go/shared/middleware"
+	"github.com/microservices-go/shared/policy"
+	"github.com/microservices-go/shared/policy/policytest"
+	userv1 "github.com/microservices-go/shared/proto/user/v1"
+	"github.com/microservices-go/shared/rpc"
+	"github.com/microservices-go/shared/rpc/rpctest"
+)
+
+// TestEndpointRules checks the role matrix of the user endpoints through
+// the HTTP routes and the gRPC methods: whether admin, support, finance, the
+// user themselves and another user may call each one. register, login, OIDC
+// login, me, the caller's own MFA and identity endpoints and the export of
+// their data need no rule.
+func TestEndpointRules(t *testing.T) {
+	const erasureID = "9a3d8c1e-4b5f-4e6a-8d7c-2f1b0a9e8d7c"
+	db := dbtest.Open(t, dbtest.Tables{
+		"users": {
+			{"id": policytest.OwnerID, "email": "owner@example.com", "first_name": "Olive", "last_name": "Owner", "role": policy.RoleUser, "is_active": true},
+		},
+		"roles": {
+			{"name": policy.RoleSupport, "description": "Support"},
+		},
+		"erasure_requests": {
+			{"id": erasureID, "user_id": policytest.OtherID, "requested_by": policytest.OtherID, "status": "completed"},
+		},
+	})
+	jwtConfig := &config.JWTConfig{Secret: "test", Issuer: "test", ExpiresIn: 1}
+	service := NewService(NewRepository(db, dbtest.NewEncryptor(t)), jwtConfig, &config.MFAConfig{}, &config.OIDCConfig{}, &config.APIKeyConfig{}, nil, nil, audit.NewLog(db))
+	auth := middleware.NewAuthMiddleware(jwtConfig)
+	serviceAuth := rpctest.ServiceAuth(t, "user-service")
+
+	router := chi.NewRouter()
+	NewHandler(service).RegisterRoutes(router, auth, serviceAuth)
+
+	srv := rpc.NewServer("user-service", auth, serviceAuth, rpc.Methods{
+		Internal: []string{userv1.UserService_BatchGetUsers_FullMethodName},
+	})
+	userv1.RegisterUserServiceServer(srv, NewGRPCServer(service))
+	client := userv1.NewUserServiceClient(rpctest.Dial(t, srv))
+
+	userPath := "/api/v1/users/" + policytest.OwnerID
+	inactive := false
+	matrix := &policytest.Matrix{Service: "user-service", JWT: jwtConfig, Router: router}
+	matrix.Run(t, []policytest.Endpoint{
+		{
+			Name: "users", Admin: true, Support: true, Finance: true,
+			HTTP: &policytest.Request{Method: "GET", Path: "/api/v1/users"},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.ListUsers(ctx, &userv1.ListUsersRequest{})
+				return err
+			},
+		},
+		{
+			Name: "_entities", Admin: true, Support: true, Finance: true, Owner: true, Internal: true,
+			HTTP: &policytest.Request{Method: "POST", Path: "/api/v1/users/batch", Body: `{"ids":["` + policytest.OwnerID + `"]}`, Returns: policytest.OwnerID},
+			GRPC: func(ctx context.Context) error {
+				stream, err := client.BatchGetUsers(ctx, &userv1.BatchGetUsersRequest{Ids: []string{policytest.OwnerID}})
+				if err != nil {
+					return err
+				}
+				return policytest.Drain(stream.Recv, func(u *userv1.User) bool { return u.Id == policytest.OwnerID })
+			},
+		},
+		{
+			Name: "user", Admin: true, Support: true, Finance: true, Owner: true,
+			HTTP: &policytest.Request{Method: "GET", Path: userPath},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.GetUser(ctx, &userv1.GetUserRequest{Id: policytest.OwnerID})
+				return err
+			},
+		},
+		{
+			Name: "updateUser", Admin: true, Owner: true,
+			HTTP: &policytest.Request{Method: "PUT", Path: userPath, Body: `{"first_name":"Ann"}`},
+			GRPC: func(ctx context.Context) error {
+				firstName := "Ann"
+				_, err := client.UpdateUser(ctx, &userv1.UpdateUserRequest{Id: policytest.OwnerID, FirstName: &firstName})
+				return err
+			},
+		},
+		{
+			Name: "updateUser with isActive", Admin: true,
+			HTTP: &policytest.Request{Method: "PUT", Path: userPath, Body: `{"is_active":false}`},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.UpdateUser(ctx, &userv1.UpdateUserRequest{Id: policytest.OwnerID, IsActive: &inactive})
+				return err
+			},
+		},
+		{
+			Name: "deleteUser", Admin: true, Owner: true,
+			HTTP: &policytest.Request{Method: "DELETE", Path: userPath},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.DeleteUser(ctx, &userv1.DeleteUserRequest{Id: policytest.OwnerID})
+				return err
+			},
+		},
+		{
+			Name: "assignRole", Admin: true,
+			HTTP: &policytest.Request{Method: "POST", Path: userPath + "/roles", Body: `{"role":"support"}`},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.AssignRole(ctx, &userv1.RoleRequest{UserId: policytest.OwnerID, Role: policy.RoleSupport})
+				return err
+			},
+		},
+		{
+			Name: "revokeRole", Admin: true,
+			HTTP: &policytest.Request{Method: "DELETE", Path: userPath + "/roles/support"},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.RevokeRole(ctx, &userv1.RoleRequest{UserId: policytest.OwnerID, Role: policy.RoleSupport})
+				return err
+			},
+		},
+		{
+			Name: "setRoleMfaRequired", Admin: true,
+			HTTP: &policytest.Request{Method: "PUT", Path: "/api/v1/users/roles/support/mfa", Body: `{"required":true}`},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.SetRoleMFARequired(ctx, &userv1.SetRoleMFARequiredRequest{Role: policy.RoleSupport, Required: true})
+				return err
+			},
+		},
+		{
+			Name: "requestErasure", Admin: true, Owner: true,
+			HTTP: &policytest.Request{Method: "POST", Path: userPath + "/erasure"},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.RequestErasure(ctx, &userv1.RequestErasureRequest{UserId: policytest.OwnerID})
+				return err
+			},
+		},
+		{
+			Name: "erasureRequests", Admin: true, Support: true, Finance: true,
+			HTTP: &policytest.Request{Method: "GET", Path: "/api/v1/users/erasure-requests"},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.ListErasureRequests(ctx, &userv1.ListErasureRequestsRequest{})
+				return err
+			},
+		},
+		{
+			Name: "erasureRequest", Admin: true, Support: true, Finance: true,
+			HTTP: &policytest.Request{Method: "GET", Path: "/api/v1/users/erasure-requests/" + erasureID},
+			GRPC: func(ctx context.Context) error {
+				_, err := client.GetErasureRequest(ctx, &userv1.GetErasureRequestRequest{Id: erasureID})
+				return err
+			},
+		},
+	})
+}
+
+// staff returns the claims of a user holding one of the seeded roles
+func staff(role string) *middleware.UserClaims {
+	return &middleware.UserClaims{UserID: role, Role: role, Permissions: policy.DefaultRoles[role]}
+}
+
+func TestAuthorizeUpdate(t *testing.T) {
+	active := false
+	owner := &middleware.UserClaims{UserID: "owner", Role: policy.RoleUser}
+	admin := staff(policy.RoleAdmin)
+
+	tests := []struct {
+		name    string
+		claims  *middleware.UserClaims
+		req     *UpdateUserRequest
+		allowed bool
+	}{
+		{"owner renames", owner, &UpdateUserRequest{FirstName: "Ann"}, true},
+		{"owner deactivates", owner, &UpdateUserRequest{IsActive: &active}, false},
+		{"admin deactivates", admin, &UpdateUserRequest{IsActive: &active}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), middleware.UserContextKey, tt.claims)
+			if err := AuthorizeUpdate(ctx, "owner", tt.req); (err == nil) != tt.allowed {
+				t.Errorf("got %v, want allowed = %v", err, tt.allowed)
+			}
+		})
+	}
+}
+
+// TestSeedMatchesDefaultRoles fails when the seed migrations and
+// policy.DefaultRoles grant different permissions
+func TestSeedMatchesDefaultRoles(t *testing.T) {
+	files, err := filepath.Glob("../../migrations/*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pair := regexp.MustCompile(`\('(\w+)', '(\w+:\w+)'\)`)
+	seeded := map[string][]string{}
+	for _, file := range files {
+		sql, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range pair.FindAllStringSubmatch(string(sql), -1) {
+			seeded[m[1]] = append(seeded[m[1]], m[2])
+		}
+	}
+
+	for role, want := range policy.DefaultRoles {
+		got := slices.Sorted(slices.Values(seeded[role]))
+		if !slices.Equal(got, slices.Sorted(slices.Values(want))) {
+			t.Errorf("%s: seeded %v, want %v", role, got, want)
+		}
+		delete(seeded, role)
+	}
+	for role := range seeded {
+		t.Errorf("%s: seeded but missing from policy.DefaultRoles", role)
+	}
+}
+
+func TestPrimaryRole(t *testing.T) {
+	tests := []struct {
+		roles []string
+		want  string
+	}{
+		{nil, policy.RoleUser},
+		{[]string{policy.RoleSupport}, policy.RoleSupport},
+		{[]string{policy.RoleFinance, policy.RoleSupport}, policy.RoleFinance},
+		{[]string{policy.RoleAdmin, policy.RoleFinance}, policy.RoleAdmin},
+		{[]string{"auditor"}, "auditor"},
+	}
+
+	for _, tt := range tests {
+		if got := primaryRole(tt.roles); got != tt.want {
+			t.Errorf("primaryRole(%v) = %q, want %q", tt.roles, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/microservices-go/shared/audit"
+	"github.com/microservices-go/shared/encryption"
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/middleware"
+	"github.com/microservices-go/shared/pagination"
+)
+
+// Erasure request statuses
+const (
+	ErasureStatusPending   = "pending"   // waiting for the other services
+	ErasureStatusCompleted = "completed" // every service erased its data
+)
+
+// erasedColumns maps the services that take part in an erasure to the
+// column recording when they finished
+var erasedColumns = map[string]string{
+	"order-service":   "orders_erased_at",
+	"payment-service": "payments_erased_at",
+}
+
+// userTables are the tables holding personal data of a user besides users
+// itself; erasing a user deletes their rows
+var userTables = []string{"user_mfa", "mfa_backup_codes", "identities", "oidc_logins", "api_keys", "sessions"}
+
+// DataExport is everything the user service holds about a user. API key
+// secrets and MFA secrets are not part of it.
+type DataExport struct {
+	User       *UserResponse `json:"user"`
+	Roles      []string      `json:"roles"`
+	Identities []*Identity   `json:"identities"`
+	APIKeys    []*APIKey     `json:"api_keys"`
+	Sessions   []*Session    `json:"sessions"`
+}
+
+// ErasureRequest tracks the erasure of a user's personal data across the
+// services
+type ErasureRequest struct {
+	ID               string     `json:"id" gorm:"primaryKey;column:id"`
+	UserID           string     `json:"user_id" gorm:"column:user_id"`
+	RequestedBy      string     `json:"requested_by" gorm:"column:requested_by"`
+	Status           string     `json:"status" gorm:"column:status"`
+	OrdersErasedAt   *time.Time `json:"orders_erased_at,omitempty" gorm:"column:orders_erased_at"`
+	PaymentsErasedAt *time.Time `json:"payments_erased_at,omitempty" gorm:"column:payments_erased_at"`
+	CreatedAt        time.Time  `json:"created_at" gorm:"column:created_at"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty" gorm:"column:completed_at"`
+}
+
+// TableName returns the table name
+func (ErasureRequest) TableName() string {
+	return "erasure_requests"
+}
+
+// UserErasureRequestedEvent asks the other services to erase a user's
+// personal data
+type UserErasureRequestedEvent struct {
+	RequestID   string    `json:"request_id"`
+	UserID      string    `json:"user_id"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// erasedEmail is the address an erased user keeps, unique but meaningless
+func erasedEmail(userID string) string {
+	return "erased-" + userID + "@erased.invalid"
+}
+
+// ListAllSessions lists every session of a user, revoked and expired ones
+// included, newest first
+func (r *Repository) ListAllSessions(ctx context.Context, userID string) ([]*Session, error) {
+	var sessions []*Session
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&sessions).Error
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to list sessions")
+	}
+	return sessions, nil
+}
+
+// GetByIDUnscoped gets a user by ID, deleted users included
+func (r *Repository) GetByIDUnscoped(ctx context.Context, id string) (*User, error) {
+	var user User
+	err := r.db.WithContext(ctx).Unscoped().First(&user, "id = ?", id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.ErrUserNotFound
+		}
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to get user")
+	}
+	return &user, nil
+}
+
+// EraseUserWithDB anonymizes a user in place and deletes their credentials,
+// identities and sessions. The row stays, soft-deleted, so that the user's
+// ID still resolves in orders and payments.
+func (r *Repository) EraseUserWithDB(ctx context.Context, db *gorm.DB, userID string) error {
+	for _, table := range userTables {
+		if err := db.WithContext(ctx).Exec("DELETE FROM "+table+" WHERE user_id = ?", userID).Error; err != nil {
+			return errors.Wrap(err, errors.ErrDatabaseError, "Failed to erase user data")
+		}
+	}
+
+	// Updates from a map bypass the encrypting serializer
+	email, err := r.enc.Encrypt(ctx, encryption.Cell{Table: "users", Column: "email", Row: userID}, erasedEmail(userID))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = db.WithContext(ctx).Unscoped().Model(&User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"email":         email,
+			"email_index":   r.enc.BlindIndex(erasedEmail(userID)),
+			"password_hash": "",
+			"first_name":    "",
+			"last_name":     "",
+			"is_active":     false,
+			"mfa_enabled":   false,
+			"updated_at":    now,
+			"deleted_at":    gorm.Expr("COALESCE(deleted_at, ?)", now),
+		}).Error
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to erase user")
+	}
+	return nil
+}
+
+// CreateErasureRequestWithDB stores a new erasure request
+func (r *Repository) CreateErasureRequestWithDB(ctx context.Context, db *gorm.DB, request *ErasureRequest) error {
+	if request.ID == "" {
+		request.ID = uuid.New().String()
+	}
+	if err := db.WithContext(ctx).Create(request).Error; err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to create erasure request")
+	}
+	return nil
+}
+
+// GetErasureRequest gets an erasure request by ID
+func (r *Repository) GetErasureRequest(ctx context.Context, id string) (*ErasureRequest, error) {
+	return r.getErasureRequest(ctx, r.db, "id = ?", id)
+}
+
+// GetErasureRequestByUser gets the erasure request of a user
+func (r *Repository) GetErasureRequestByUser(ctx context.Context, userID string) (*ErasureRequest, error) {
+	return r.getErasureRequest(ctx, r.db, "user_id = ?", userID)
+}
+
+func (r *Repository) getErasureRequest(ctx context.Context, db *gorm.DB, query string, args ...interface{}) (*ErasureRequest, error) {
+	var request ErasureRequest
+	if err := db.WithContext(ctx).Where(query, args...).First(&request).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Erasure request not found")
+		}
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to get erasure request")
+	}
+	return &request, nil
+}
+
+// ListErasureRequests lists erasure requests, optionally of one status,
+// newest first
+func (r *Repository) ListErasureRequests(ctx context.Context, status string, limit, offset int) ([]*ErasureRequest, error) {
+	query := r.db.WithContext(ctx)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var requests []*ErasureRequest
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&requests).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to list erasure requests")
+	}
+	return requests, nil
+}
+
+// MarkErasedWithDB records that a service finished an erasure request and
+// completes the request once every service has. It returns the request and
+// whether this call completed it.
+func (r *Repository) MarkErasedWithDB(ctx context.Context, db *gorm.DB, id, column string) (*ErasureRequest, bool, error) {
+	now := time.Now()
+	err := db.WithContext(ctx).Model(&ErasureRequest{}).
+		Where("id = ? AND "+column+" IS NULL", id).
+		Update(column, now).Error
+	if err != nil {
+		return nil, false, errors.Wrap(err, errors.ErrDatabaseError, "Failed to update erasure request")
+	}
+
+	query := db.WithContext(ctx).Model(&ErasureRequest{}).Where("id = ? AND status = ?", id, ErasureStatusPending)
+	for _, c := range erasedColumns {
+		query = query.Where(c + " IS NOT NULL")
+	}
+	result := query.Updates(map[string]interface{}{"status": ErasureStatusCompleted, "completed_at": now})
+	if result.Error != nil {
+		return nil, false, errors.Wrap(result.Error, errors.ErrDatabaseError, "Failed to update erasure request")
+	}
+
+	request, err := r.getErasureRequest(ctx, db, "id = ?", id)
+	if err != nil {
+		return nil, false, err
+	}
+	return request, result.RowsAffected > 0, nil
+}
+
+// Export returns everything held about the calling user
+func (s *Service) Export(ctx context.Context) (*DataExport, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+	if claims.FromAPIKey() {
+		return nil, errors.New(errors.ErrForbidden, "API keys cannot export personal data")
+	}
+
+	user, err := s.repo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	export := &DataExport{User: user.ToResponse()}
+	if export.Roles, err = s.repo.GetRolesWithDB(ctx, s.repo.db, user.ID); err != nil {
+		return nil, err
+	}
+	if export.Identities, err = s.repo.ListIdentities(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if export.APIKeys, err = s.repo.ListAPIKeys(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if export.Sessions, err = s.repo.ListAllSessions(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// RequestErasure erases a user's personal data: the account is anonymized
+// and signed out at once, and the other services are asked to erase theirs.
+// Requesting it again returns the existing request, re-sending it to the
+// services while it is pending.
+func (s *Service) RequestErasure(ctx context.Context, userID, actorID string) (*ErasureRequest, error) {
+	if err := requireInteractive(ctx); err != nil {
+		return nil, err
+	}
+
+	request, err := s.repo.GetErasureRequestByUser(ctx, userID)
+	if err == nil {
+		if request.Status == ErasureStatusPending {
+			s.publishErasure(ctx, request)
+		}
+		return request, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	user, err := s.repo.GetByIDUnscoped(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	request = &ErasureRequest{
+		UserID:      userID,
+		RequestedBy: actorID,
+		Status:      ErasureStatusPending,
+		CreatedAt:   time.Now(),
+	}
+	err = s.repo.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := s.repo.EraseUserWithDB(ctx, tx, userID); err != nil {
+			return err
+		}
+		if err := s.repo.CreateErasureRequestWithDB(ctx, tx, request); err != nil {
+			return err
+		}
+		return s.auditLog.RecordWithDB(ctx, tx, &audit.Event{
+			Action:     "user.erase",
+			TargetType: "user",
+			TargetID:   userID,
+			Metadata:   map[string]string{"request_id": request.ID},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateUser(ctx, user)
+	s.publishErasure(ctx, request)
+
+	logger.WithContext(ctx).
+		WithField("audit", true).
+		WithField("user_id", userID).
+		WithField("request_id", request.ID).
+		Info("User erased")
+	return request, nil
+}
+
+// GetErasureRequest gets an erasure request
+func (s *Service) GetErasureRequest(ctx context.Context, id string) (*ErasureRequest, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, errors.New(errors.ErrNotFound, "Erasure request not found")
+	}
+	return s.repo.GetErasureRequest(ctx, id)
+}
+
+// ListErasureRequests lists erasure requests, optionally of one status
+func (s *Service) ListErasureRequests(ctx context.Context, status string, limit, offset int) ([]*ErasureRequest, error) {
+	if status != "" && status != ErasureStatusPending && status != ErasureStatusCompleted {
+		return nil, errors.New(errors.ErrInvalidInput, "Unknown erasure status: "+status)
+	}
+	if limit <= 0 || limit > pagination.MaxSize {
+		limit = pagination.DefaultSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.repo.ListErasureRequests(ctx, status, limit, offset)
+}
+
+// HandleUserDataErased records that a service erased its data for an
+// erasure request
+func (s *Service) HandleUserDataErased(ctx context.Context, requestID, service string) error {
+	log := logger.WithContext(ctx)
+
+	column, ok := erasedColumns[service]
+	if !ok {
+		log.Warnf("Ignoring erasure report of unknown service: %s", service)
+		return nil
+	}
+	if _, err := uuid.Parse(requestID); err != nil {
+		log.Warnf("Ignoring erasure report of invalid request: %s", requestID)
+		return nil
+	}
+
+	return s.repo.WithTransaction(ctx, func(tx *gorm.DB) error {
+		request, completed, err := s.repo.MarkErasedWithDB(ctx, tx, requestID, column)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				log.Warnf("Ignoring erasure report of unknown request: %s", requestID)
+				return nil
+			}
+			return err
+		}
+		if !completed {
+			return nil
+		}
+
+		log.WithField("audit", true).
+			WithField("user_id", request.UserID).
+			WithField("request_id", request.ID).
+			Info("Erasure request completed")
+		return s.auditLog.RecordWithDB(ctx, tx, &audit.Event{
+			Action:     "user.erasure_completed",
+			TargetType: "user",
+			TargetID:   request.UserID,
+			Changes:    audit.Changes{"status": {From: ErasureStatusPending, To: ErasureStatusCompleted}},
+			Metadata:   map[string]string{"request_id": request.ID},
+		})
+	})
+}
+
+// publishErasure sends an erasure request to the other services; a request
+// that could not be sent stays pending and is sent again when requested again
+func (s *Service) publishErasure(ctx context.Context, request *ErasureRequest) {
+	log := logger.WithContext(ctx)
+	if s.publisher == nil {
+		log.Warn("No event publisher; erasure request stays pending")
+		return
+	}
+
+	event := &UserErasureRequestedEvent{
+		RequestID:   request.ID,
+		UserID:      request.UserID,
+		RequestedAt: request.CreatedAt,
+	}
+	if err := s.publisher.PublishEvent(ctx, "user.erasure_requested", event); err != nil {
+		log.WithError(err).Warn("Failed to publish user erasure requested event")
+	}
+}
@@ -3,19 +3,24 @@ package user
 import (
 	"context"
 
+	"github.com/microservices-go/shared/encryption"
 	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/filter"
 	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/pagination"
 	"gorm.io/gorm"
 )
 
 // Repository handles user data access
 type Repository struct {
-	db *gorm.DB
+	db  *gorm.DB
+	enc *encryption.Encryptor
 }
 
-// NewRepository creates a new user repository
-func NewRepository(db *gorm.DB) *Repository {
-	return &Repository{db: db}
+// NewRepository creates a new user repository; enc computes the blind
+// indexes of emails, whose columns it also encrypts
+func NewRepository(db *gorm.DB, enc *encryption.Encryptor) *Repository {
+	return &Repository{db: db, enc: enc}
 }
 
 // WithTransaction executes a function within a database transaction
@@ -34,12 +39,15 @@ func (r *Repository) Create(ctx context.Context, user *User) error {
 func (r *Repository) CreateWithDB(ctx context.Context, db *gorm.DB, user *User) error {
 	log := logger.WithContext(ctx)
 
-	// Hash password before saving
-	if err := user.HashPassword(); err != nil {
-		log.WithError(err).Error("Failed to hash password")
-		return errors.Wrap(err, errors.ErrInternalServer, "Failed to process password")
+	// Hash password before saving; users from identity providers have none
+	if user.HasPassword() {
+		if err := user.HashPassword(); err != nil {
+			log.WithError(err).Error("Failed to hash password")
+			return errors.Wrap(err, errors.ErrInternalServer, "Failed to process password")
+		}
 	}
 
+	user.EmailIndex = r.enc.BlindIndex(user.Email)
 	if err := db.WithContext(ctx).Create(user).Error; err != nil {
 		log.WithError(err).Error("Failed to create user")
 		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to create user")
@@ -71,7 +79,7 @@ func (r *Repository) GetByEmail(ctx context.Context, email string) (*User, error
 	log := logger.WithContext(ctx)
 
 	var user User
-	err := r.db.WithContext(ctx).First(&user, "email = ?", email).Error
+	err := r.byEmail(r.db.WithContext(ctx), email).First(&user).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, errors.ErrUserNotFound
@@ -93,7 +101,7 @@ func (r *Repository) ExistsByEmailWithDB(ctx context.Context, db *gorm.DB, email
 	log := logger.WithContext(ctx)
 
 	var count int64
-	err := db.WithContext(ctx).Model(&User{}).Where("email = ?", email).Count(&count).Error
+	err := r.byEmail(db.WithContext(ctx).Model(&User{}), email).Count(&count).Error
 	if err != nil {
 		log.WithError(err).Error("Failed to check email existence")
 		return false, errors.Wrap(err, errors.ErrDatabaseError, "Failed to check email existence")
@@ -102,13 +110,19 @@ func (r *Repository) ExistsByEmailWithDB(ctx context.Context, db *gorm.DB, email
 	return count > 0, nil
 }
 
-// List lists all users with pagination
-func (r *Repository) List(ctx context.Context, limit, offset int) ([]*User, error) {
+// byEmail matches the user with an email through its blind index. Rows
+// written before emails were encrypted match on the email itself until the
+// re-encryption job has indexed them.
+func (r *Repository) byEmail(db *gorm.DB, email string) *gorm.DB {
+	return db.Where("email_index = ? OR (email_index IS NULL AND email = ?)", r.enc.BlindIndex(email), email)
+}
+
+// List lists users matching the filter with pagination
+func (r *Repository) List(ctx context.Context, f *ListFilter, limit, offset int) ([]*User, error) {
 	log := logger.WithContext(ctx)
 
 	var users []*User
-	err := r.db.WithContext(ctx).
-		Order("created_at DESC").
+	err := filter.OrderBy(f.Apply(r.db.WithContext(ctx), r.enc), f.Sort).
 		Limit(limit).
 		Offset(offset).
 		Find(&users).Error
@@ -121,6 +135,21 @@ func (r *Repository) List(ctx context.Context, limit, offset int) ([]*User, erro
 	return users, nil
 }
 
+// ListPage lists a keyset page of users matching the filter
+func (r *Repository) ListPage(ctx context.Context, f *ListFilter, page *pagination.Page) ([]*User, error) {
+	log := logger.WithContext(ctx)
+
+	var users []*User
+	err := page.Scope(f.Apply(r.db.WithContext(ctx), r.enc)).Find(&users).Error
+
+	if err != nil {
+		log.WithError(err).Error("Failed to list users")
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to list users")
+	}
+
+	return users, nil
+}
+
 // Update updates user
 func (r *Repository) Update(ctx context.Context, user *User) error {
 	return r.UpdateWithDB(ctx, r.db, user)
@@ -167,10 +196,10 @@ func (r *Repository) DeleteWithDB(ctx context.Context, db *gorm.DB, id string) e
 	return nil
 }
 
-// Count returns total user count
-func (r *Repository) Count(ctx context.Context) (int, error) {
+// Count returns the number of users matching the filter
+func (r *Repository) Count(ctx context.Context, f *ListFilter) (int, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(&User{}).Count(&count).Error
+	err := f.Apply(r.db.WithContext(ctx).Model(&User{}), r.enc).Count(&count).Error
 	if err != nil {
 		return 0, errors.Wrap(err, errors.ErrDatabaseError, "Failed to count users")
 	}
@@ -0,0 +1,269 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/microservices-go/shared/audit"
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/policy"
+)
+
+// rolePriority orders roles when picking a user's primary role, the one kept
+// in users.role and the role claim; other roles rank after these
+var rolePriority = []string{policy.RoleAdmin, policy.RoleFinance, policy.RoleSupport}
+
+// Role is a named set of permissions
+type Role struct {
+	Name        string    `json:"name" gorm:"primaryKey;column:name"`
+	Description string    `json:"description" gorm:"column:description"`
+	MFARequired bool      `json:"mfa_required" gorm:"column:mfa_required"`
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// TableName returns the table name
+func (Role) TableName() string {
+	return "roles"
+}
+
+// UserRole assigns a role to a user
+type UserRole struct {
+	UserID     string    `gorm:"primaryKey;column:user_id"`
+	Role       string    `gorm:"primaryKey;column:role"`
+	AssignedBy *string   `gorm:"column:assigned_by"`
+	CreatedAt  time.Time `gorm:"column:created_at"`
+}
+
+// TableName returns the table name
+func (UserRole) TableName() string {
+	return "user_roles"
+}
+
+// RoleRequest represents a role assignment or revocation request
+type RoleRequest struct {
+	Role string `json:"role" validate:"required,max=50"`
+}
+
+// RoleExistsWithDB checks if a role exists using the provided database connection
+func (r *Repository) RoleExistsWithDB(ctx context.Context, db *gorm.DB, name string) (bool, error) {
+	var count int64
+	err := db.WithContext(ctx).Model(&Role{}).Where("name = ?", name).Count(&count).Error
+	if err != nil {
+		return false, errors.Wrap(err, errors.ErrDatabaseError, "Failed to check role existence")
+	}
+	return count > 0, nil
+}
+
+// GetRolesWithDB lists the roles of a user using the provided database connection
+func (r *Repository) GetRolesWithDB(ctx context.Context, db *gorm.DB, userID string) ([]string, error) {
+	var roles []string
+	err := db.WithContext(ctx).Model(&UserRole{}).
+		Where("user_id = ?", userID).
+		Order("role").
+		Pluck("role", &roles).Error
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to get user roles")
+	}
+	return roles, nil
+}
+
+// GetPermissions lists the permissions granted by all roles of a user
+func (r *Repository) GetPermissions(ctx context.Context, userID string) ([]string, error) {
+	var permissions []string
+	err := r.db.WithContext(ctx).Table("user_roles").
+		Joins("JOIN role_permissions ON role_permissions.role = user_roles.role").
+		Where("user_roles.user_id = ?", userID).
+		Distinct().
+		Order("role_permissions.permission").
+		Pluck("role_permissions.permission", &permissions).Error
+	if err != nil {
+		logger.WithContext(ctx).WithError(err).Error("Failed to get user permissions")
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to get user permissions")
+	}
+	return permissions, nil
+}
+
+// AssignRoleWithDB adds a role to a user, doing nothing if they hold it
+func (r *Repository) AssignRoleWithDB(ctx context.Context, db *gorm.DB, userRole *UserRole) error {
+	err := db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(userRole).Error
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to assign role")
+	}
+	return nil
+}
+
+// RevokeRoleWithDB removes a role from a user, reporting whether they held it
+func (r *Repository) RevokeRoleWithDB(ctx context.Context, db *gorm.DB, userID, role string) (bool, error) {
+	result := db.WithContext(ctx).Delete(&UserRole{}, "user_id = ? AND role = ?", userID, role)
+	if err := result.Error; err != nil {
+		return false, errors.Wrap(err, errors.ErrDatabaseError, "Failed to revoke role")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SetPrimaryRoleWithDB stores the primary role of a user
+func (r *Repository) SetPrimaryRoleWithDB(ctx context.Context, db *gorm.DB, userID, role string) error {
+	err := db.WithContext(ctx).Model(&User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"role": role, "updated_at": time.Now()}).Error
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to update user role")
+	}
+	return nil
+}
+
+// AssignRole grants a role to a user; actorID is the admin assigning it
+func (s *Service) AssignRole(ctx context.Context, userID, actorID string, req *RoleRequest) (*UserResponse, error) {
+	if err := s.validator.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	return s.changeRoles(ctx, userID, req.Role, "user.assign_role", func(tx *gorm.DB) error {
+		return s.repo.AssignRoleWithDB(ctx, tx, &UserRole{
+			UserID:     userID,
+			Role:       req.Role,
+			AssignedBy: &actorID,
+			CreatedAt:  time.Now(),
+		})
+	})
+}
+
+// RevokeRole takes a role away from a user. Admins cannot revoke their own
+// admin role, so there is always someone left to manage roles.
+func (s *Service) RevokeRole(ctx context.Context, userID, actorID string, req *RoleRequest) (*UserResponse, error) {
+	if err := s.validator.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+	if userID == actorID && req.Role == policy.RoleAdmin {
+		return nil, errors.New(errors.ErrForbidden, "Admins cannot revoke their own admin role")
+	}
+
+	return s.changeRoles(ctx, userID, req.Role, "user.revoke_role", func(tx *gorm.DB) error {
+		held, err := s.repo.RevokeRoleWithDB(ctx, tx, userID, req.Role)
+		if err != nil {
+			return err
+		}
+		if !held {
+			return errors.New(errors.ErrNotFound, "User does not have this role")
+		}
+		return nil
+	})
+}
+
+// changeRoles applies a role change to an existing user and role, then
+// recomputes the user's primary role and records action in the audit log.
+// Tokens carry the permissions of their sign-in, so a user who lost a role
+// is signed out everywhere; one who gained a role gets it on the next
+// sign-in.
+func (s *Service) changeRoles(ctx context.Context, userID, role, action string, change func(tx *gorm.DB) error) (*UserResponse, error) {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	lostRole := false
+	if err := s.repo.WithTransaction(ctx, func(tx *gorm.DB) error {
+		exists, err := s.repo.RoleExistsWithDB(ctx, tx, role)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return errors.New(errors.ErrNotFound, "Role not found")
+		}
+
+		before, err := s.repo.GetRolesWithDB(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		if err := change(tx); err != nil {
+			return err
+		}
+
+		roles, err := s.repo.GetRolesWithDB(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		user.Role = primaryRole(roles)
+		if err := s.repo.SetPrimaryRoleWithDB(ctx, tx, userID, user.Role); err != nil {
+			return err
+		}
+		if err := s.auditLog.RecordWithDB(ctx, tx, &audit.Event{
+			Action:     action,
+			TargetType: "user",
+			TargetID:   userID,
+			Changes:    audit.Changes{"roles": {From: before, To: roles}},
+			Metadata:   map[string]string{"role": role},
+		}); err != nil {
+			return err
+		}
+		lostRole = len(roles) < len(before)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if lostRole {
+		if _, err := s.endSessions(ctx, userID, "Sessions ended for user who lost a role"); err != nil {
+			return nil, err
+		}
+	}
+
+	s.invalidateUser(ctx, user)
+	if s.publisher != nil {
+		event := &UserUpdatedEvent{
+			UserID:    user.ID,
+			Email:     user.Email,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			IsActive:  user.IsActive,
+			UpdatedAt: time.Now(),
+		}
+		if err := s.publisher.PublishEvent(ctx, "user.updated", event); err != nil {
+			logger.WithContext(ctx).WithError(err).Warn("Failed to publish user updated event")
+		}
+	}
+
+	logger.WithContext(ctx).
+		WithField("audit", true).
+		WithField("user_id", userID).
+		WithField("role", role).
+		WithField("primary_role", user.Role).
+		Info("User roles changed")
+	return user.ToResponse(), nil
+}
+
+// invalidateUser drops the cached copies of a user
+func (s *Service) invalidateUser(ctx context.Context, user *User) {
+	if s.cache == nil {
+		return
+	}
+
+	log := logger.WithContext(ctx)
+	if err := s.cache.Delete(ctx, "user:id:"+user.ID); err != nil {
+		log.WithError(err).Warn("Failed to invalidate user cache")
+	}
+	if err := s.cache.Delete(ctx, "user:email:"+user.Email); err != nil {
+		log.WithError(err).Warn("Failed to invalidate user email cache")
+	}
+	if err := s.cache.DeletePattern(ctx, "users:list:*"); err != nil {
+		log.WithError(err).Warn("Failed to invalidate users list cache")
+	}
+}
+
+// primaryRole picks the highest-ranked of roles, or RoleUser for none
+func primaryRole(roles []string) string {
+	for _, role := range rolePriority {
+		for _, held := range roles {
+			if held == role {
+				return role
+			}
+		}
+	}
+	if len(roles) > 0 {
+		return roles[0]
+	}
+	return policy.RoleUser
+}
@@ -4,23 +4,30 @@ import (
 	"context"
 	"time"
 
+	"github.com/microservices-go/shared/audit"
 	"github.com/microservices-go/shared/cache"
 	"github.com/microservices-go/shared/config"
 	"github.com/microservices-go/shared/errors"
 	"github.com/microservices-go/shared/logger"
-	"github.com/microservices-go/shared/middleware"
+	"github.com/microservices-go/shared/pagination"
+	"github.com/microservices-go/shared/response"
 	"github.com/microservices-go/shared/validator"
 	"gorm.io/gorm"
 )
 
 // Service handles user business logic
 type Service struct {
-	repo      *Repository
-	cache     *cache.Cache
-	validator *validator.Validator
-	jwtConfig *config.JWTConfig
-	publisher EventPublisher
-	cacheTTL  time.Duration
+	repo         *Repository
+	cache        *cache.Cache
+	validator    *validator.Validator
+	jwtConfig    *config.JWTConfig
+	mfaConfig    *config.MFAConfig
+	oidcConfig   *config.OIDCConfig
+	oidc         map[string]*oidcClient
+	apiKeyConfig *config.APIKeyConfig
+	publisher    EventPublisher
+	auditLog     *audit.Log
+	cacheTTL     time.Duration
 }
 
 // EventPublisher interface for publishing events
@@ -29,14 +36,19 @@ type EventPublisher interface {
 }
 
 // NewService creates a new user service
-func NewService(repo *Repository, jwtConfig *config.JWTConfig, publisher EventPublisher, cacheClient *cache.Cache) *Service {
+func NewService(repo *Repository, jwtConfig *config.JWTConfig, mfaConfig *config.MFAConfig, oidcConfig *config.OIDCConfig, apiKeyConfig *config.APIKeyConfig, publisher EventPublisher, cacheClient *cache.Cache, auditLog *audit.Log) *Service {
 	return &Service{
-		repo:      repo,
-		cache:     cacheClient,
-		validator: validator.New(),
-		jwtConfig: jwtConfig,
-		publisher: publisher,
-		cacheTTL:  5 * time.Minute,
+		repo:         repo,
+		cache:        cacheClient,
+		validator:    validator.New(),
+		jwtConfig:    jwtConfig,
+		mfaConfig:    mfaConfig,
+		oidcConfig:   oidcConfig,
+		oidc:         newOIDCClients(oidcConfig),
+		apiKeyConfig: apiKeyConfig,
+		publisher:    publisher,
+		auditLog:     auditLog,
+		cacheTTL:     5 * time.Minute,
 	}
 }
 
@@ -77,6 +89,22 @@ func (s *Service) Create(ctx context.Context, req *CreateUserRequest) (*LoginRes
 		return nil, err
 	}
 
+	s.userCreated(ctx, user)
+
+	// Generate JWT token
+	resp, err := s.signIn(ctx, user)
+	if err != nil {
+		log.WithError(err).Warn("Failed to generate token for new user")
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// userCreated invalidates list caches and publishes user.created for a new user
+func (s *Service) userCreated(ctx context.Context, user *User) {
+	log := logger.WithContext(ctx)
+
 	// Invalidate list caches
 	if s.cache != nil {
 		if err := s.cache.DeletePattern(ctx, "users:list:*"); err != nil {
@@ -97,18 +125,6 @@ func (s *Service) Create(ctx context.Context, req *CreateUserRequest) (*LoginRes
 			log.WithError(err).Warn("Failed to publish user created event")
 		}
 	}
-
-	// Generate JWT token
-	token, err := middleware.GenerateToken(user.ID, user.Email, user.Role, s.jwtConfig)
-	if err != nil {
-		log.WithError(err).Warn("Failed to generate token for new user")
-		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to generate token")
-	}
-
-	return &LoginResponse{
-		Token: token,
-		User:  user,
-	}, nil
 }
 
 // GetByID gets user by ID with caching
@@ -171,8 +187,8 @@ func (s *Service) GetByEmail(ctx context.Context, email string) (*UserResponse,
 	return response, nil
 }
 
-// List lists users with pagination and caching
-func (s *Service) List(ctx context.Context, limit, offset int) ([]*UserResponse, error) {
+// List lists users matching the filter; unfiltered pages are cached
+func (s *Service) List(ctx context.Context, f *ListFilter, limit, offset int) ([]*UserResponse, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 10
 	}
@@ -181,9 +197,10 @@ func (s *Service) List(ctx context.Context, limit, offset int) ([]*UserResponse,
 	}
 
 	cacheKey := "users:list:limit:" + string(rune(limit)) + ":offset:" + string(rune(offset))
+	useCache := s.cache != nil && f.IsEmpty()
 
 	// Try to get from cache
-	if s.cache != nil {
+	if useCache {
 		var cachedUsers []*UserResponse
 		if err := s.cache.Get(ctx, cacheKey, &cachedUsers); err == nil {
 			return cachedUsers, nil
@@ -191,7 +208,7 @@ func (s *Service) List(ctx context.Context, limit, offset int) ([]*UserResponse,
 	}
 
 	// Get from database
-	users, err := s.repo.List(ctx, limit, offset)
+	users, err := s.repo.List(ctx, f, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -202,7 +219,7 @@ func (s *Service) List(ctx context.Context, limit, offset int) ([]*UserResponse,
 	}
 
 	// Store in cache
-	if s.cache != nil {
+	if useCache {
 		if err := s.cache.Set(ctx, cacheKey, responses, s.cacheTTL); err != nil {
 			logger.WithContext(ctx).WithError(err).Warn("Failed to cache users list")
 		}
@@ -211,6 +228,24 @@ func (s *Service) List(ctx context.Context, limit, offset int) ([]*UserResponse,
 	return responses, nil
 }
 
+// ListPage lists a cursor page of users matching the filter
+func (s *Service) ListPage(ctx context.Context, f *ListFilter, page *pagination.Page) ([]*UserResponse, response.PageInfo, error) {
+	users, err := s.repo.ListPage(ctx, f, page)
+	if err != nil {
+		return nil, response.PageInfo{}, err
+	}
+
+	users, info := pagination.Trim(users, page, func(u *User) pagination.Cursor {
+		return pagination.NewCursor(u.CreatedAt, u.ID)
+	})
+
+	responses := make([]*UserResponse, len(users))
+	for i, user := range users {
+		responses[i] = user.ToResponse()
+	}
+	return responses, info, nil
+}
+
 // Update updates user
 func (s *Service) Update(ctx context.Context, id string, req *UpdateUserRequest) (*UserResponse, error) {
 	// Validate request
@@ -225,6 +260,8 @@ func (s *Service) Update(ctx context.Context, id string, req *UpdateUserRequest)
 	}
 
 	// Update fields
+	before := user.ToResponse()
+	deactivated := user.IsActive && req.IsActive != nil && !*req.IsActive
 	if req.FirstName != "" {
 		user.FirstName = req.FirstName
 	}
@@ -235,10 +272,31 @@ func (s *Service) Update(ctx context.Context, id string, req *UpdateUserRequest)
 		user.IsActive = *req.IsActive
 	}
 
-	if err := s.repo.Update(ctx, user); err != nil {
+	action := "user.update"
+	if deactivated {
+		action = "user.deactivate"
+	}
+	if err := s.repo.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := s.repo.UpdateWithDB(ctx, tx, user); err != nil {
+			return err
+		}
+		return s.auditLog.RecordWithDB(ctx, tx, &audit.Event{
+			Action:     action,
+			TargetType: "user",
+			TargetID:   id,
+			Changes:    audit.Diff(before, user.ToResponse(), "updated_at").Redact(personalFields...),
+		})
+	}); err != nil {
 		return nil, err
 	}
 
+	// Sign a deactivated user out everywhere
+	if deactivated {
+		if _, err := s.endSessions(ctx, id, "Sessions ended for deactivated user"); err != nil {
+			return nil, err
+		}
+	}
+
 	// Invalidate caches
 	if s.cache != nil {
 		log := logger.WithContext(ctx)
@@ -253,6 +311,21 @@ func (s *Service) Update(ctx context.Context, id string, req *UpdateUserRequest)
 		}
 	}
 
+	// Publish event
+	if s.publisher != nil {
+		event := &UserUpdatedEvent{
+			UserID:    user.ID,
+			Email:     user.Email,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			IsActive:  user.IsActive,
+			UpdatedAt: user.UpdatedAt,
+		}
+		if err := s.publisher.PublishEvent(ctx, "user.updated", event); err != nil {
+			logger.WithContext(ctx).WithError(err).Warn("Failed to publish user updated event")
+		}
+	}
+
 	return user.ToResponse(), nil
 }
 
@@ -264,7 +337,22 @@ func (s *Service) Delete(ctx context.Context, id string) error {
 		return err
 	}
 
-	if err := s.repo.Delete(ctx, id); err != nil {
+	if err := s.repo.WithTransaction(ctx, func(tx *gorm.DB) error {
+		if err := s.repo.DeleteWithDB(ctx, tx, id); err != nil {
+			return err
+		}
+		return s.auditLog.RecordWithDB(ctx, tx, &audit.Event{
+			Action:     "user.delete",
+			TargetType: "user",
+			TargetID:   id,
+			Changes:    audit.Diff(user.ToResponse(), nil).Redact(personalFields...),
+		})
+	}); err != nil {
+		return err
+	}
+
+	// Sign a deleted user out everywhere
+	if _, err := s.endSessions(ctx, id, "Sessions ended for deleted user"); err != nil {
 		return err
 	}
 
@@ -282,10 +370,22 @@ func (s *Service) Delete(ctx context.Context, id string) error {
 		}
 	}
 
+	// Publish event
+	if s.publisher != nil {
+		event := &UserDeletedEvent{
+			UserID:    id,
+			DeletedAt: time.Now(),
+		}
+		if err := s.publisher.PublishEvent(ctx, "user.deleted", event); err != nil {
+			logger.WithContext(ctx).WithError(err).Warn("Failed to publish user deleted event")
+		}
+	}
+
 	return nil
 }
 
-// Login authenticates user and returns token
+// Login authenticates user and returns token, or an MFA challenge for
+// users with MFA enabled
 func (s *Service) Login(ctx context.Context, req *LoginRequest) (*LoginResponse, error) {
 	// Validate request
 	if err := s.validator.ValidateStruct(req); err != nil {
@@ -303,29 +403,54 @@ func (s *Service) Login(ctx context.Context, req *LoginRequest) (*LoginResponse,
 
 	// Check password
 	if !user.CheckPassword(req.Password) {
+		s.loginFailed(ctx, user, "invalid_password")
 		return nil, errors.New(errors.ErrUnauthorized, "Invalid email or password")
 	}
 
+	return s.authenticated(ctx, user)
+}
+
+// authenticated continues a login whose first factor, a password or an
+// identity provider, passed: deactivated users are refused, users with MFA
+// get a challenge and everyone else a token
+func (s *Service) authenticated(ctx context.Context, user *User) (*LoginResponse, error) {
 	// Check if user is active
 	if !user.IsActive {
+		s.loginFailed(ctx, user, "account_deactivated")
 		return nil, errors.New(errors.ErrForbidden, "Account is deactivated")
 	}
 
+	// With MFA the token is only issued by VerifyMFA
+	if user.MFAEnabled {
+		challenge, err := s.issueChallenge(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResponse{MFARequired: true, ChallengeToken: challenge}, nil
+	}
+
 	// Generate JWT token
-	token, err := middleware.GenerateToken(user.ID, user.Email, user.Role, s.jwtConfig)
+	return s.signIn(ctx, user)
+}
+
+// loginFailed records a refused login of an existing user. The attempt is
+// refused either way, so a failure to record it is only logged.
+func (s *Service) loginFailed(ctx context.Context, user *User, reason string) {
+	err := s.auditLog.Record(ctx, &audit.Event{
+		Action:     "user.login_failed",
+		TargetType: "user",
+		TargetID:   user.ID,
+		Metadata:   map[string]string{"reason": reason},
+		ActorType:  audit.ActorAnonymous,
+	})
 	if err != nil {
-		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to generate token")
+		logger.WithContext(ctx).WithError(err).Warn("Failed to record failed login")
 	}
-
-	return &LoginResponse{
-		Token: token,
-		User:  user,
-	}, nil
 }
 
-// Count returns total user count
-func (s *Service) Count(ctx context.Context) (int, error) {
-	return s.repo.Count(ctx)
+// Count returns the number of users matching the filter
+func (s *Service) Count(ctx context.Context, f *ListFilter) (int, error) {
+	return s.repo.Count(ctx, f)
 }
 
 // GetByIDs gets multiple users by IDs with caching
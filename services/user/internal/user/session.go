@@ -0,0 +1,246 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/middleware"
+)
+
+const (
+	// sessionTouchInterval throttles last-seen updates of busy sessions
+	sessionTouchInterval = time.Minute
+
+	// maxIPAddressLength and maxUserAgentLength bound the client info
+	// stored with a session
+	maxIPAddressLength = 45
+	maxUserAgentLength = 512
+)
+
+// errInactiveSession is returned for every session that is not active alike
+var errInactiveSession = errors.New(errors.ErrUnauthorized, "Session has been revoked or has expired")
+
+// Session is a login of a user on one device. Every token issued at login
+// belongs to a session and stops working once it is revoked or expires.
+type Session struct {
+	ID         string     `json:"id" gorm:"primaryKey;column:id"`
+	UserID     string     `json:"user_id" gorm:"column:user_id"`
+	IPAddress  string     `json:"ip_address" gorm:"column:ip_address"`
+	UserAgent  string     `json:"user_agent" gorm:"column:user_agent"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"column:created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at" gorm:"column:last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"column:expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" gorm:"column:revoked_at"`
+	Current    bool       `json:"current" gorm:"-"` // whether the caller's token belongs to it
+}
+
+// TableName returns the table name
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// Active reports whether the session is neither revoked nor expired at now
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// CreateSession stores a new session
+func (r *Repository) CreateSession(ctx context.Context, session *Session) error {
+	if session.ID == "" {
+		session.ID = uuid.New().String()
+	}
+	if err := r.db.WithContext(ctx).Create(session).Error; err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to create session")
+	}
+	return nil
+}
+
+// GetSession gets a session of a user
+func (r *Repository) GetSession(ctx context.Context, userID, id string) (*Session, error) {
+	var session Session
+	err := r.db.WithContext(ctx).First(&session, "id = ? AND user_id = ?", id, userID).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Session not found")
+		}
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to get session")
+	}
+	return &session, nil
+}
+
+// ListActiveSessions lists the active sessions of a user, most recently
+// seen first
+func (r *Repository) ListActiveSessions(ctx context.Context, userID string) ([]*Session, error) {
+	var sessions []*Session
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to list sessions")
+	}
+	return sessions, nil
+}
+
+// RevokeSession marks a session revoked
+func (r *Repository) RevokeSession(ctx context.Context, session *Session) error {
+	now := time.Now()
+	if err := r.db.WithContext(ctx).Model(session).Update("revoked_at", now).Error; err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to revoke session")
+	}
+	session.RevokedAt = &now
+	return nil
+}
+
+// RevokeSessions marks every active session of a user revoked and returns
+// how many there were
+func (r *Repository) RevokeSessions(ctx context.Context, userID string) (int, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&Session{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Update("revoked_at", now)
+	if result.Error != nil {
+		return 0, errors.Wrap(result.Error, errors.ErrDatabaseError, "Failed to revoke sessions")
+	}
+	return int(result.RowsAffected), nil
+}
+
+// RevokeSessionsWithoutMFA revokes the active sessions of the holders of a
+// role who have not enabled MFA
+func (r *Repository) RevokeSessionsWithoutMFA(ctx context.Context, role string) (int, error) {
+	now := time.Now()
+	holders := r.db.Table("user_roles").
+		Select("user_roles.user_id").
+		Joins("JOIN users ON users.id = user_roles.user_id").
+		Where("user_roles.role = ? AND NOT users.mfa_enabled", role)
+	result := r.db.WithContext(ctx).Model(&Session{}).
+		Where("user_id IN (?) AND revoked_at IS NULL AND expires_at > ?", holders, now).
+		Update("revoked_at", now)
+	if result.Error != nil {
+		return 0, errors.Wrap(result.Error, errors.ErrDatabaseError, "Failed to revoke sessions")
+	}
+	return int(result.RowsAffected), nil
+}
+
+// TouchSession records activity in a session
+func (r *Repository) TouchSession(ctx context.Context, id string) error {
+	err := r.db.WithContext(ctx).Model(&Session{}).Where("id = ?", id).Update("last_seen_at", time.Now()).Error
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to update session")
+	}
+	return nil
+}
+
+// ListSessions lists the active sessions of a user, marking the caller's
+func (s *Service) ListSessions(ctx context.Context, userID string) ([]*Session, error) {
+	sessions, err := s.repo.ListActiveSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if claims, ok := middleware.GetUserFromContext(ctx); ok {
+		for _, session := range sessions {
+			session.Current = session.ID == claims.SessionID
+		}
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs a user out of one session; revoking it again is a no-op
+func (s *Service) RevokeSession(ctx context.Context, userID, id string) (*Session, error) {
+	if err := requireInteractive(ctx); err != nil {
+		return nil, err
+	}
+
+	session, err := s.repo.GetSession(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if session.RevokedAt != nil {
+		return session, nil
+	}
+	if err := s.repo.RevokeSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	logger.WithContext(ctx).
+		WithField("audit", true).
+		WithField("user_id", userID).
+		WithField("session_id", session.ID).
+		Info("Session revoked")
+	return session, nil
+}
+
+// RevokeAllSessions signs a user out everywhere, the caller's own session
+// included, and returns how many sessions were active
+func (s *Service) RevokeAllSessions(ctx context.Context, userID string) (int, error) {
+	if err := requireInteractive(ctx); err != nil {
+		return 0, err
+	}
+	return s.endSessions(ctx, userID, "Sessions revoked")
+}
+
+// CheckSession fails unless a session of a user is active, and records the
+// activity
+func (s *Service) CheckSession(ctx context.Context, userID, sessionID string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return errInactiveSession
+	}
+
+	session, err := s.repo.GetSession(ctx, userID, sessionID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return errInactiveSession
+		}
+		return err
+	}
+	now := time.Now()
+	if !session.Active(now) {
+		return errInactiveSession
+	}
+
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		if err := s.repo.TouchSession(ctx, session.ID); err != nil {
+			logger.WithContext(ctx).WithError(err).Warn("Failed to record session activity")
+		}
+	}
+	return nil
+}
+
+// startSession records a new login of a user from the caller's device
+func (s *Service) startSession(ctx context.Context, userID string) (*Session, error) {
+	now := time.Now()
+	session := &Session{
+		UserID:     userID,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(time.Duration(s.jwtConfig.ExpiresIn) * time.Hour),
+	}
+	if info, ok := middleware.GetClientInfo(ctx); ok {
+		session.IPAddress = truncate(info.IP, maxIPAddressLength)
+		session.UserAgent = truncate(info.UserAgent, maxUserAgentLength)
+	}
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// endSessions revokes every active session of a user, logging why
+func (s *Service) endSessions(ctx context.Context, userID, message string) (int, error) {
+	revoked, err := s.repo.RevokeSessions(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	logger.WithContext(ctx).
+		WithField("audit", true).
+		WithField("user_id", userID).
+		WithField("sessions", revoked).
+		Info(message)
+	return revoked, nil
+}
@@ -0,0 +1,29 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSessionActive(t *testing.T) {
+	now := time.Now()
+	revoked := now.Add(-time.Minute)
+
+	if !(&Session{ExpiresAt: now.Add(time.Hour)}).Active(now) {
+		t.Error("an unexpired session must be active")
+	}
+	if (&Session{ExpiresAt: now}).Active(now) {
+		t.Error("an expired session must not be active")
+	}
+	if (&Session{ExpiresAt: now.Add(time.Hour), RevokedAt: &revoked}).Active(now) {
+		t.Error("a revoked session must not be active")
+	}
+}
+
+func TestCheckSessionRejectsMalformedID(t *testing.T) {
+	s := &Service{}
+	if err := s.CheckSession(context.Background(), "u1", "not-a-session"); err != errInactiveSession {
+		t.Errorf("got %v, want %v", err, errInactiveSession)
+	}
+}
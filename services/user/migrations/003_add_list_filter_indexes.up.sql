@@ -0,0 +1,11 @@
+-- Keyset pagination walks (created_at, id) in both directions
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users (created_at DESC, id DESC);
+
+-- Trigram index for case-insensitive substring search on email and name
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_users_search_trgm ON users
+    USING gin ((email || ' ' || first_name || ' ' || last_name) gin_trgm_ops);
+
+-- Indexes for sorting
+CREATE INDEX IF NOT EXISTS idx_users_last_name ON users (last_name);
@@ -0,0 +1,69 @@
+-- Roles group permissions; users hold any number of roles
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    name VARCHAR(100) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(50) NOT NULL REFERENCES roles (name) ON DELETE CASCADE,
+    permission VARCHAR(100) NOT NULL REFERENCES permissions (name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL REFERENCES roles (name) ON DELETE CASCADE,
+    assigned_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, role)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_roles_role ON user_roles (role);
+
+-- Seed roles and permissions (mirrors policy.DefaultRoles)
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Full access to every resource'),
+    ('support', 'Read-only access to users, orders and payments'),
+    ('finance', 'Reads users, orders and payments and issues refunds')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (name, description) VALUES
+    ('users:read', 'Read any user'),
+    ('users:write', 'Update, deactivate and delete any user'),
+    ('roles:manage', 'Assign and revoke roles'),
+    ('orders:read', 'Read any order'),
+    ('orders:write', 'Create orders for and update orders of any user'),
+    ('payments:read', 'Read any payment'),
+    ('payments:write', 'Create and process payments of any user'),
+    ('payments:refund', 'Refund payments')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'users:read'),
+    ('admin', 'users:write'),
+    ('admin', 'roles:manage'),
+    ('admin', 'orders:read'),
+    ('admin', 'orders:write'),
+    ('admin', 'payments:read'),
+    ('admin', 'payments:write'),
+    ('admin', 'payments:refund'),
+    ('support', 'users:read'),
+    ('support', 'orders:read'),
+    ('support', 'payments:read'),
+    ('finance', 'users:read'),
+    ('finance', 'orders:read'),
+    ('finance', 'payments:read'),
+    ('finance', 'payments:refund')
+ON CONFLICT DO NOTHING;
+
+-- Existing users keep the seeded role they were given through users.role
+INSERT INTO user_roles (user_id, role)
+SELECT u.id, u.role FROM users u JOIN roles r ON r.name = u.role
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+ALTER TABLE roles DROP COLUMN IF EXISTS mfa_required;
+DROP TABLE IF EXISTS mfa_backup_codes;
+DROP TABLE IF EXISTS user_mfa;
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_enabled;
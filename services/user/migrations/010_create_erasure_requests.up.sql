@@ -0,0 +1,14 @@
+-- Right-to-erasure requests. The user service anonymizes the user at once;
+-- the order and payment services report when they erased their data.
+CREATE TABLE IF NOT EXISTS erasure_requests (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL UNIQUE,
+    requested_by UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    orders_erased_at TIMESTAMP WITH TIME ZONE,
+    payments_erased_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_erasure_requests_status_created_at ON erasure_requests (status, created_at DESC);
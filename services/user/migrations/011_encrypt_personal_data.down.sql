@@ -0,0 +1,18 @@
+-- Values must be decrypted before rolling back, or they will not fit
+ALTER TABLE user_mfa ALTER COLUMN secret TYPE VARCHAR(64);
+
+ALTER TABLE identities ALTER COLUMN email TYPE VARCHAR(255);
+
+DROP INDEX IF EXISTS idx_users_email_index;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS email_index,
+    ALTER COLUMN email TYPE VARCHAR(255),
+    ALTER COLUMN first_name TYPE VARCHAR(100),
+    ALTER COLUMN last_name TYPE VARCHAR(100),
+    ADD CONSTRAINT users_email_key UNIQUE (email);
+
+CREATE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE INDEX IF NOT EXISTS idx_users_last_name ON users (last_name);
+CREATE INDEX IF NOT EXISTS idx_users_search_trgm ON users
+    USING gin ((email || ' ' || first_name || ' ' || last_name) gin_trgm_ops);
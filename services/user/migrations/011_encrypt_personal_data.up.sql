@@ -0,0 +1,19 @@
+-- Email, names and MFA secrets are stored encrypted. Ciphertexts are longer
+-- than the values and differ for equal values, so uniqueness and lookups by
+-- email move to a blind index; the application fills it for existing rows.
+DROP INDEX IF EXISTS idx_users_search_trgm;
+DROP INDEX IF EXISTS idx_users_last_name;
+DROP INDEX IF EXISTS idx_users_email;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+
+ALTER TABLE users
+    ALTER COLUMN email TYPE TEXT,
+    ALTER COLUMN first_name TYPE TEXT,
+    ALTER COLUMN last_name TYPE TEXT,
+    ADD COLUMN IF NOT EXISTS email_index VARCHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_index ON users (email_index);
+
+ALTER TABLE identities ALTER COLUMN email TYPE TEXT;
+
+ALTER TABLE user_mfa ALTER COLUMN secret TYPE TEXT;
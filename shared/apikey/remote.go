@@ -0,0 +1,110 @@
+// Package apikey verifies personal API keys in the services that do not
+// store them, by asking the user service.
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/middleware"
+	userv1 "github.com/microservices-go/shared/proto/user/v1"
+	"github.com/microservices-go/shared/rpc"
+)
+
+// userService is the audience of the service tokens sent with verification calls
+const userService = "user-service"
+
+// Remote is a middleware.APIKeyVerifier calling the user service's internal
+// VerifyAPIKey method with a service token. Verified keys are trusted for
+// the cache TTL, so a revoked key may keep working for that long.
+type Remote struct {
+	client      userv1.UserServiceClient
+	serviceAuth *config.ServiceAuthConfig
+	ttl         time.Duration
+
+	mu     sync.Mutex
+	cached map[[sha256.Size]byte]cachedClaims
+}
+
+// cachedClaims are the claims of a verified key and when to verify it again
+type cachedClaims struct {
+	claims  *middleware.UserClaims
+	expires time.Time
+}
+
+// NewRemote creates a verifier that calls the user service over conn as the
+// caller of serviceAuth
+func NewRemote(conn grpc.ClientConnInterface, serviceAuth *config.ServiceAuthConfig, cfg *config.APIKeyConfig) *Remote {
+	return &Remote{
+		client:      userv1.NewUserServiceClient(conn),
+		serviceAuth: serviceAuth,
+		ttl:         time.Duration(cfg.CacheTTL) * time.Second,
+		cached:      make(map[[sha256.Size]byte]cachedClaims),
+	}
+}
+
+// VerifyAPIKey resolves key through the cache or the user service
+func (r *Remote) VerifyAPIKey(ctx context.Context, key string) (*middleware.UserClaims, error) {
+	sum := sha256.Sum256([]byte(key))
+	if claims, ok := r.get(sum); ok {
+		return claims, nil
+	}
+
+	token, err := middleware.GenerateServiceToken(userService, nil, r.serviceAuth)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to sign service token")
+	}
+
+	resp, err := r.client.VerifyAPIKey(rpc.OutgoingContext(ctx, "Bearer "+token), &userv1.VerifyAPIKeyRequest{Key: key})
+	if err != nil {
+		return nil, rpc.FromStatus(err, userService)
+	}
+
+	claims := &middleware.UserClaims{
+		UserID:      resp.UserId,
+		Email:       resp.Email,
+		Role:        resp.Role,
+		Permissions: resp.Permissions,
+		APIKeyID:    resp.ApiKeyId,
+		Scopes:      resp.Scopes,
+		RateLimit:   int(resp.RateLimit),
+	}
+	r.put(sum, claims)
+	return claims, nil
+}
+
+// get returns the unexpired claims cached for a key hash
+func (r *Remote) get(sum [sha256.Size]byte) (*middleware.UserClaims, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.cached[sum]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.claims, true
+}
+
+// put caches the claims of a key hash, first dropping expired entries
+func (r *Remote) put(sum [sha256.Size]byte, claims *middleware.UserClaims) {
+	if r.ttl <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range r.cached {
+		if now.After(entry.expires) {
+			delete(r.cached, k)
+		}
+	}
+	r.cached[sum] = cachedClaims{claims: claims, expires: now.Add(r.ttl)}
+}
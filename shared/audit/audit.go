@@ -0,0 +1,368 @@
+// Package audit keeps the append-only log of security- and money-relevant
+// actions of a service. Every entry holds the hash of the previous one, so
+// editing or removing an entry breaks the chain and shows up in Verify.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/middleware"
+)
+
+// Actor types of an entry
+const (
+	ActorUser      = "user"      // a signed-in user
+	ActorAPIKey    = "api_key"   // a user's API key
+	ActorService   = "service"   // another service, on no user's behalf
+	ActorSystem    = "system"    // the service itself, e.g. handling an event
+	ActorAnonymous = "anonymous" // nobody signed in, e.g. a failed login
+)
+
+// verifyBatchSize is how many entries Verify reads at a time
+const verifyBatchSize = 500
+
+// Entry is one action in the audit log. Changes and Metadata hold JSON
+// exactly as hashed.
+type Entry struct {
+	ID         int64     `json:"id" gorm:"primaryKey;column:id"`
+	ActorType  string    `json:"actor_type" gorm:"column:actor_type"`
+	ActorID    string    `json:"actor_id" gorm:"column:actor_id"`
+	ActorRole  string    `json:"actor_role" gorm:"column:actor_role"`
+	Action     string    `json:"action" gorm:"column:action"`
+	TargetType string    `json:"target_type" gorm:"column:target_type"`
+	TargetID   string    `json:"target_id" gorm:"column:target_id"`
+	Changes    string    `json:"changes" gorm:"column:changes;type:json"`
+	Metadata   string    `json:"metadata" gorm:"column:metadata;type:json"`
+	RequestID  string    `json:"request_id" gorm:"column:request_id"`
+	IPAddress  string    `json:"ip_address" gorm:"column:ip_address"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at"`
+	PrevHash   string    `json:"prev_hash" gorm:"column:prev_hash"`
+	Hash       string    `json:"hash" gorm:"column:hash"`
+}
+
+// TableName returns the table name
+func (Entry) TableName() string {
+	return "audit_log"
+}
+
+// Change is the value of a field before and after an action
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Changes maps field names to how they changed
+type Changes map[string]Change
+
+// Event is an action to record. The request ID and IP address are taken
+// from the context, and so is the actor unless ActorType is set.
+type Event struct {
+	Action     string
+	TargetType string
+	TargetID   string
+	Changes    Changes
+	Metadata   map[string]string
+	ActorType  string
+	ActorID    string
+	ActorRole  string
+}
+
+// Filter narrows a listing; empty fields match everything
+type Filter struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	Since      *time.Time
+	Until      *time.Time
+}
+
+// Verification is the outcome of checking the hash chain
+type Verification struct {
+	Valid    bool
+	Checked  int64
+	BrokenAt int64 // ID of the first entry that does not match, when not valid
+	Reason   string
+}
+
+// Log appends to and reads the audit_log table of a service
+type Log struct {
+	db *gorm.DB
+}
+
+// NewLog creates an audit log on a service's database
+func NewLog(db *gorm.DB) *Log {
+	return &Log{db: db}
+}
+
+// Record appends an event in a transaction of its own
+func (l *Log) Record(ctx context.Context, event *Event) error {
+	return l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return l.RecordWithDB(ctx, tx, event)
+	})
+}
+
+// RecordWithDB appends an event within the caller's transaction, so that it
+// is only kept if the action is. Appends are serialized by a transaction
+// level lock while the previous hash is read.
+func (l *Log) RecordWithDB(ctx context.Context, tx *gorm.DB, event *Event) error {
+	entry, err := newEntry(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	db := tx.WithContext(ctx)
+	if err := db.Exec("SELECT pg_advisory_xact_lock(hashtext('audit_log'))").Error; err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to lock audit log")
+	}
+
+	var last Entry
+	err = db.Select("hash").Order("id DESC").Limit(1).Find(&last).Error
+	if err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to read audit log")
+	}
+	entry.PrevHash = last.Hash
+	entry.Hash = entry.computeHash()
+
+	if err := db.Create(entry).Error; err != nil {
+		return errors.Wrap(err, errors.ErrDatabaseError, "Failed to write audit log")
+	}
+	return nil
+}
+
+// List returns the entries matching f, newest first
+func (l *Log) List(ctx context.Context, f *Filter, limit, offset int) ([]*Entry, error) {
+	query := l.db.WithContext(ctx).Model(&Entry{})
+	if f != nil {
+		if f.ActorID != "" {
+			query = query.Where("actor_id = ?", f.ActorID)
+		}
+		if f.Action != "" {
+			query = query.Where("action = ?", f.Action)
+		}
+		if f.TargetType != "" {
+			query = query.Where("target_type = ?", f.TargetType)
+		}
+		if f.TargetID != "" {
+			query = query.Where("target_id = ?", f.TargetID)
+		}
+		if f.Since != nil {
+			query = query.Where("created_at >= ?", *f.Since)
+		}
+		if f.Until != nil {
+			query = query.Where("created_at < ?", *f.Until)
+		}
+	}
+
+	var entries []*Entry
+	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&entries).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to list audit log")
+	}
+	return entries, nil
+}
+
+// Verify walks the whole chain, oldest first, and reports the first entry
+// whose hash or link to its predecessor does not match
+func (l *Log) Verify(ctx context.Context) (*Verification, error) {
+	result := &Verification{Valid: true}
+	prevHash := ""
+	var lastID int64
+	for {
+		var batch []*Entry
+		err := l.db.WithContext(ctx).
+			Where("id > ?", lastID).
+			Order("id ASC").
+			Limit(verifyBatchSize).
+			Find(&batch).Error
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to read audit log")
+		}
+
+		checked, brokenAt, reason := verifyChain(batch, prevHash)
+		result.Checked += checked
+		if brokenAt != 0 {
+			result.Valid, result.BrokenAt, result.Reason = false, brokenAt, reason
+			logger.WithContext(ctx).
+				WithField("audit", true).
+				WithField("entry_id", brokenAt).
+				WithField("reason", reason).
+				Error("Audit log chain broken")
+			return result, nil
+		}
+		if len(batch) < verifyBatchSize {
+			return result, nil
+		}
+		prevHash = batch[len(batch)-1].Hash
+		lastID = batch[len(batch)-1].ID
+	}
+}
+
+// verifyChain checks consecutive entries following one with prevHash and
+// returns how many matched and, for the first that does not, its ID and why
+func verifyChain(entries []*Entry, prevHash string) (int64, int64, string) {
+	var checked int64
+	for _, entry := range entries {
+		if entry.PrevHash != prevHash {
+			return checked, entry.ID, "previous hash does not match the preceding entry"
+		}
+		if entry.Hash != entry.computeHash() {
+			return checked, entry.ID, "hash does not match the entry's contents"
+		}
+		prevHash = entry.Hash
+		checked++
+	}
+	return checked, 0, ""
+}
+
+// computeHash hashes the entry's contents together with the previous hash
+func (e *Entry) computeHash() string {
+	// Fields in a fixed order; the ID is left out as it is only known after
+	// the insert, and the chain already fixes the order
+	content, _ := json.Marshal([]string{
+		e.PrevHash,
+		e.ActorType,
+		e.ActorID,
+		e.ActorRole,
+		e.Action,
+		e.TargetType,
+		e.TargetID,
+		e.Changes,
+		e.Metadata,
+		e.RequestID,
+		e.IPAddress,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// newEntry builds the entry of an event, with the actor, request ID and IP
+// address of ctx
+func newEntry(ctx context.Context, event *Event) (*Entry, error) {
+	changes := event.Changes
+	if changes == nil {
+		changes = Changes{}
+	}
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to encode audit changes")
+	}
+	metadata := event.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+
+	entry := &Entry{
+		ActorType:  event.ActorType,
+		ActorID:    event.ActorID,
+		ActorRole:  event.ActorRole,
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		Changes:    string(changesJSON),
+		RequestID:  logger.GetTraceID(ctx),
+		// Postgres keeps microseconds; hash what will be read back
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if entry.ActorType == "" {
+		entry.ActorType, entry.ActorID, entry.ActorRole = actor(ctx, metadata)
+	}
+	if info, ok := middleware.GetClientInfo(ctx); ok {
+		entry.IPAddress = info.IP
+	}
+
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to encode audit metadata")
+	}
+	entry.Metadata = string(metadataJSON)
+	return entry, nil
+}
+
+// actor describes who acts in ctx, adding the API key or calling service
+// to metadata
+func actor(ctx context.Context, metadata map[string]string) (string, string, string) {
+	if service, ok := middleware.GetServiceFromContext(ctx); ok {
+		metadata["via_service"] = service.Subject
+		if service.OnBehalfOf == nil {
+			return ActorService, service.Subject, ""
+		}
+	}
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return ActorSystem, "", ""
+	}
+	if claims.FromAPIKey() {
+		metadata["api_key_id"] = claims.APIKeyID
+		return ActorAPIKey, claims.UserID, claims.Role
+	}
+	return ActorUser, claims.UserID, claims.Role
+}
+
+// Diff returns the fields that differ between two values of the same
+// struct type, by their JSON names, except those named in ignore. Fields
+// hidden from JSON are skipped.
+func Diff(before, after interface{}, ignore ...string) Changes {
+	from, to := fields(before), fields(after)
+	for _, name := range ignore {
+		delete(from, name)
+		delete(to, name)
+	}
+	changes := Changes{}
+	for name, value := range to {
+		if old, ok := from[name]; !ok || !reflect.DeepEqual(old, value) {
+			changes[name] = Change{From: from[name], To: value}
+		}
+	}
+	for name, value := range from {
+		if _, ok := to[name]; !ok {
+			changes[name] = Change{From: value, To: nil}
+		}
+	}
+	return changes
+}
+
+// Redacted stands in for the values of redacted fields
+const Redacted = "[redacted]"
+
+// Redact keeps that fields changed but not their values, for personal data
+// that is encrypted at rest and must not end up in the log in plaintext
+func (c Changes) Redact(fields ...string) Changes {
+	for _, name := range fields {
+		change, ok := c[name]
+		if !ok {
+			continue
+		}
+		if change.From != nil {
+			change.From = Redacted
+		}
+		if change.To != nil {
+			change.To = Redacted
+		}
+		c[name] = change
+	}
+	return c
+}
+
+// fields decodes the JSON form of v into a map
+func fields(v interface{}) map[string]interface{} {
+	m := map[string]interface{}{}
+	if v == nil {
+		return m
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return m
+	}
+	_ = json.Unmarshal(b, &m)
+	return m
+}
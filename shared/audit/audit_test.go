@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"context"
+	"testing"
+)
+
+// chain builds a valid chain of n entries
+func chain(t *testing.T, n int) []*Entry {
+	t.Helper()
+
+	var entries []*Entry
+	prevHash := ""
+	for i := 0; i < n; i++ {
+		entry, err := newEntry(context.Background(), &Event{
+			Action:     "order.update_status",
+			TargetType: "order",
+			TargetID:   "order-1",
+			Changes:    Changes{"status": {From: "pending", To: "confirmed"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry.ID = int64(i + 1)
+		entry.PrevHash = prevHash
+		entry.Hash = entry.computeHash()
+		prevHash = entry.Hash
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestVerifyChain(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func([]*Entry) []*Entry
+		checked int64
+		broken  int64
+	}{
+		{"intact", func(e []*Entry) []*Entry { return e }, 3, 0},
+		{"edited", func(e []*Entry) []*Entry {
+			e[1].Changes = `{"status":{"from":"pending","to":"cancelled"}}`
+			return e
+		}, 1, 2},
+		{"edited and rehashed", func(e []*Entry) []*Entry {
+			e[1].ActorID = "someone-else"
+			e[1].Hash = e[1].computeHash()
+			return e
+		}, 2, 3},
+		{"removed", func(e []*Entry) []*Entry { return append(e[:1], e[2:]...) }, 1, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checked, broken, _ := verifyChain(tt.tamper(chain(t, 3)), "")
+			if checked != tt.checked || broken != tt.broken {
+				t.Errorf("got checked %d, broken at %d; want %d, %d", checked, broken, tt.checked, tt.broken)
+			}
+		})
+	}
+}
+
+func TestNewEntryActor(t *testing.T) {
+	entry, err := newEntry(context.Background(), &Event{Action: "user.login_failed", ActorType: ActorAnonymous})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.ActorType != ActorAnonymous || entry.Changes != "{}" || entry.Metadata != "{}" {
+		t.Errorf("got %+v", entry)
+	}
+
+	entry, err = newEntry(context.Background(), &Event{Action: "payment.process"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.ActorType != ActorSystem {
+		t.Errorf("actor type = %q, want %q", entry.ActorType, ActorSystem)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	type user struct {
+		Name     string `json:"name"`
+		Active   bool   `json:"active"`
+		Password string `json:"-"`
+		Updated  int    `json:"updated"`
+	}
+
+	changes := Diff(
+		user{Name: "Ann", Active: true, Password: "a", Updated: 1},
+		user{Name: "Ann", Active: false, Password: "b", Updated: 2},
+		"updated",
+	)
+	if len(changes) != 1 || changes["active"] != (Change{From: true, To: false}) {
+		t.Errorf("got %v", changes)
+	}
+
+	if changes := Diff(user{Name: "Ann"}, nil); changes["name"] != (Change{From: "Ann", To: nil}) {
+		t.Errorf("got %v", changes)
+	}
+
+	changes = Diff(user{Name: "Ann"}, nil).Redact("name")
+	if changes["name"] != (Change{From: Redacted, To: nil}) || changes["active"] != (Change{From: false, To: nil}) {
+		t.Errorf("got %v", changes)
+	}
+}
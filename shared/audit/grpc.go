@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/microservices-go/shared/pagination"
+	"github.com/microservices-go/shared/policy"
+	auditv1 "github.com/microservices-go/shared/proto/audit/v1"
+	"github.com/microservices-go/shared/rpc"
+)
+
+// CanRead is the rule for reading and verifying the audit log
+var CanRead = policy.Permission("audit.read", policy.PermAuditRead)
+
+// GRPCServer implements auditv1.AuditServiceServer on a service's log
+type GRPCServer struct {
+	auditv1.UnimplementedAuditServiceServer
+	log *Log
+}
+
+// NewGRPCServer creates the audit gRPC server of a service
+func NewGRPCServer(log *Log) *GRPCServer {
+	return &GRPCServer{log: log}
+}
+
+// ListEntries returns the entries matching the request, newest first
+func (s *GRPCServer) ListEntries(ctx context.Context, req *auditv1.ListEntriesRequest) (*auditv1.ListEntriesResponse, error) {
+	if err := CanRead.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	f := &Filter{
+		ActorID:    req.ActorId,
+		Action:     req.Action,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetId,
+	}
+	if req.Since != nil {
+		since := req.Since.AsTime()
+		f.Since = &since
+	}
+	if req.Until != nil {
+		until := req.Until.AsTime()
+		f.Until = &until
+	}
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = pagination.DefaultSize
+	}
+	if limit > pagination.MaxSize {
+		limit = pagination.MaxSize
+	}
+
+	entries, err := s.log.List(ctx, f, limit, int(req.Offset))
+	if err != nil {
+		return nil, err
+	}
+	resp := &auditv1.ListEntriesResponse{Entries: make([]*auditv1.Entry, len(entries))}
+	for i, entry := range entries {
+		resp.Entries[i] = entry.ToProto()
+	}
+	return resp, nil
+}
+
+// VerifyChain checks the hash chain of the log
+func (s *GRPCServer) VerifyChain(ctx context.Context, req *auditv1.VerifyChainRequest) (*auditv1.ChainVerification, error) {
+	if err := CanRead.Authorize(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	result, err := s.log.Verify(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &auditv1.ChainVerification{
+		Valid:    result.Valid,
+		Checked:  result.Checked,
+		BrokenAt: result.BrokenAt,
+		Reason:   result.Reason,
+	}, nil
+}
+
+// ToProto converts Entry to its gRPC message
+func (e *Entry) ToProto() *auditv1.Entry {
+	entry := &auditv1.Entry{
+		Id:         e.ID,
+		ActorType:  e.ActorType,
+		ActorId:    e.ActorID,
+		ActorRole:  e.ActorRole,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetId:   e.TargetID,
+		RequestId:  e.RequestID,
+		IpAddress:  e.IPAddress,
+		CreatedAt:  rpc.Timestamp(e.CreatedAt),
+		PrevHash:   e.PrevHash,
+		Hash:       e.Hash,
+	}
+
+	var changes map[string]json.RawMessage
+	_ = json.Unmarshal([]byte(e.Changes), &changes)
+	for field, raw := range changes {
+		var change struct {
+			From json.RawMessage `json:"from"`
+			To   json.RawMessage `json:"to"`
+		}
+		_ = json.Unmarshal(raw, &change)
+		entry.Changes = append(entry.Changes, &auditv1.Change{Field: field, From: string(change.From), To: string(change.To)})
+	}
+	sort.Slice(entry.Changes, func(i, j int) bool { return entry.Changes[i].Field < entry.Changes[j].Field })
+
+	_ = json.Unmarshal([]byte(e.Metadata), &entry.Metadata)
+	return entry
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/go-redis/redis/v8"
 	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/metrics"
 )
 
 // Cache provides caching operations using Redis
@@ -37,16 +38,20 @@ func (c *Cache) Get(ctx context.Context, key string, dest interface{}) error {
 
 	data, err := c.client.Get(ctx, c.key(key)).Result()
 	if err == redis.Nil {
+		metrics.CacheRequestsTotal.WithLabelValues(c.prefix, metrics.CacheMiss).Inc()
 		return fmt.Errorf("key not found")
 	}
 	if err != nil {
+		metrics.CacheRequestsTotal.WithLabelValues(c.prefix, metrics.CacheError).Inc()
 		return fmt.Errorf("failed to get from cache: %w", err)
 	}
 
 	if err := json.Unmarshal([]byte(data), dest); err != nil {
+		metrics.CacheRequestsTotal.WithLabelValues(c.prefix, metrics.CacheError).Inc()
 		return fmt.Errorf("failed to unmarshal cache data: %w", err)
 	}
 
+	metrics.CacheRequestsTotal.WithLabelValues(c.prefix, metrics.CacheHit).Inc()
 	return nil
 }
 
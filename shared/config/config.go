@@ -1,6 +1,10 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -47,6 +51,7 @@ func (c *DatabaseConfig) DSN() string {
 // ServerConfig holds server configuration
 type ServerConfig struct {
 	Port         int
+	GRPCPort     int
 	ReadTimeout  int
 	WriteTimeout int
 }
@@ -73,6 +78,64 @@ type JWTConfig struct {
 	Issuer    string
 }
 
+// ServiceAuthConfig holds the keys and lifetime of the service tokens the
+// gateway and services use on internal-only calls. Every caller signs with
+// its own Ed25519 key, so a service can only mint tokens in its own name.
+type ServiceAuthConfig struct {
+	Service    string            // the caller this process signs as, e.g. "order-service"
+	SigningKey string            // base64 PKCS #8 private key of Service
+	PublicKeys map[string]string // base64 PKIX public keys by caller
+	TTL        int               // seconds
+}
+
+// MFAConfig holds the user service's TOTP multi-factor settings
+type MFAConfig struct {
+	Issuer       string // shown by authenticator apps
+	ChallengeTTL int    // seconds between the password and the second factor
+	MaxAttempts  int    // failed codes before verification is locked
+	LockoutTime  int    // seconds
+}
+
+// OIDCConfig holds the user service's OpenID Connect login settings
+type OIDCConfig struct {
+	Providers   []OIDCProviderConfig
+	RedirectURL string // where providers send the browser back with code and state
+	StateTTL    int    // seconds a started login may take
+}
+
+// OIDCProviderConfig holds the client registration at one OpenID provider
+type OIDCProviderConfig struct {
+	Name         string // as used in the API, e.g. "google"
+	Issuer       string // discovery URL base
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+// APIKeyConfig holds the settings of users' personal API keys
+type APIKeyConfig struct {
+	DefaultTTLDays   int
+	MaxTTLDays       int
+	DefaultRateLimit int // requests per rate limit window for keys without their own limit
+	MaxRateLimit     int
+	CacheTTL         int // seconds other services trust a verified key
+}
+
+// SessionConfig holds the settings of users' login sessions, which last as
+// long as their JWT (JWTConfig.ExpiresIn)
+type SessionConfig struct {
+	CacheTTL int // seconds other services trust an active session
+}
+
+// EncryptionConfig holds the settings of a service's field-level encryption
+type EncryptionConfig struct {
+	KMS                string // key provider; "local" reads a key set from Keys or KeyFile
+	Keys               string // key set JSON, e.g. from a secret; takes precedence over KeyFile
+	KeyFile            string // key set file, created with fresh keys when missing outside production
+	ReencryptInterval  int    // seconds between re-encryption runs; 0 runs once at startup
+	ReencryptBatchSize int
+}
+
 // RedisConfig holds Redis configuration for rate limiting
 type RedisConfig struct {
 	Host     string
@@ -92,6 +155,55 @@ type RateLimitConfig struct {
 	WindowSeconds     int
 }
 
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	Enabled     bool
+	Endpoint    string // OTLP/HTTP collector URL, e.g. http://localhost:4318
+	SampleRatio float64
+}
+
+// UpstreamConfig holds the gateway's gRPC client settings for downstream services
+type UpstreamConfig struct {
+	RequestTimeout   int // per-attempt timeout, milliseconds
+	MaxRetries       int
+	RetryBackoffBase int // milliseconds
+	RetryBackoffMax  int // milliseconds
+	RetryBudget      int // retries and hedges allowed per 100 requests
+	HedgeDelay       int // milliseconds before a lookup is sent again in parallel; 0 disables hedging
+	BreakerThreshold int // consecutive failures before opening
+	BreakerCooldown  int // seconds before a half-open probe
+}
+
+// GraphQLConfig holds the gateway's GraphQL server settings
+type GraphQLConfig struct {
+	Introspection    bool
+	Playground       bool
+	APQCacheTTL      int // seconds
+	AllowlistEnabled bool
+	AllowlistPath    string // persisted operation manifest
+	MaxDepth         int
+	CostBudgets      map[string]int // per role; "" is the anonymous budget
+	DefaultBudget    int            // authenticated roles without their own budget
+	ResponseCache    bool
+	ResponseCacheTTL int // seconds; upper bound on @cacheControl maxAge
+	LoaderStats      bool
+}
+
+// SubgraphConfig holds the settings of a service's federated GraphQL subgraph
+type SubgraphConfig struct {
+	Enabled bool
+}
+
+// Environment returns the deployment environment (development, staging, production)
+func Environment() string {
+	return getEnv("ENV", "development")
+}
+
+// IsProduction reports whether the process runs in production mode
+func IsProduction() bool {
+	return Environment() == "production"
+}
+
 // LoadDatabaseConfig loads database config from environment
 func LoadDatabaseConfig(service string) *DatabaseConfig {
 	prefix := strings.ToUpper(service)
@@ -110,6 +222,7 @@ func LoadServerConfig(service string) *ServerConfig {
 	prefix := strings.ToUpper(service)
 	return &ServerConfig{
 		Port:         getEnvAsInt(fmt.Sprintf("%s_PORT", prefix), 8080),
+		GRPCPort:     getEnvAsInt(fmt.Sprintf("%s_GRPC_PORT", prefix), 9090),
 		ReadTimeout:  getEnvAsInt(fmt.Sprintf("%s_READ_TIMEOUT", prefix), 10),
 		WriteTimeout: getEnvAsInt(fmt.Sprintf("%s_WRITE_TIMEOUT", prefix), 10),
 	}
@@ -135,6 +248,119 @@ func LoadJWTConfig() *JWTConfig {
 	}
 }
 
+// serviceCallers are the processes that sign service tokens
+var serviceCallers = []string{"gateway", "user-service", "order-service", "payment-service"}
+
+// LoadServiceAuthConfig loads the service token config of the caller named
+// service from environment. Its private key is read from <SERVICE>_SIGNING_KEY,
+// e.g. ORDER_SERVICE_SIGNING_KEY, and the public keys of all callers from
+// SERVICE_PUBLIC_KEYS as comma-separated name=key pairs. Outside production,
+// missing keys default to well-known development keys.
+func LoadServiceAuthConfig(service string) *ServiceAuthConfig {
+	prefix := strings.ToUpper(strings.ReplaceAll(service, "-", "_"))
+	cfg := &ServiceAuthConfig{
+		Service:    service,
+		SigningKey: getEnv(prefix+"_SIGNING_KEY", ""),
+		PublicKeys: make(map[string]string),
+		TTL:        getEnvAsInt("SERVICE_JWT_TTL", 60),
+	}
+	for _, pair := range strings.Split(getEnv("SERVICE_PUBLIC_KEYS", ""), ",") {
+		// Keys are base64 and may end in '=', so only the first one separates
+		name, key, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && name != "" && key != "" {
+			cfg.PublicKeys[strings.TrimSpace(name)] = strings.TrimSpace(key)
+		}
+	}
+
+	if !IsProduction() {
+		if cfg.SigningKey == "" {
+			cfg.SigningKey, _ = developmentServiceKey(service)
+		}
+		for _, caller := range serviceCallers {
+			if _, ok := cfg.PublicKeys[caller]; !ok {
+				_, cfg.PublicKeys[caller] = developmentServiceKey(caller)
+			}
+		}
+	}
+	return cfg
+}
+
+// developmentServiceKey derives a key pair from the caller's name, so local
+// runs work without configured keys. Anyone can derive it: never use it in
+// production.
+func developmentServiceKey(caller string) (private, public string) {
+	seed := sha256.Sum256([]byte("microservices-go development service key: " + caller))
+	key := ed25519.NewKeyFromSeed(seed[:])
+	privateDER, _ := x509.MarshalPKCS8PrivateKey(key)
+	publicDER, _ := x509.MarshalPKIXPublicKey(key.Public())
+	return base64.StdEncoding.EncodeToString(privateDER), base64.StdEncoding.EncodeToString(publicDER)
+}
+
+// LoadMFAConfig loads MFA config from environment
+func LoadMFAConfig() *MFAConfig {
+	return &MFAConfig{
+		Issuer:       getEnv("MFA_ISSUER", "microservices-go"),
+		ChallengeTTL: getEnvAsInt("MFA_CHALLENGE_TTL", 300),
+		MaxAttempts:  getEnvAsInt("MFA_MAX_ATTEMPTS", 5),
+		LockoutTime:  getEnvAsInt("MFA_LOCKOUT_TIME", 900),
+	}
+}
+
+// LoadOIDCConfig loads OIDC config from environment. OIDC_PROVIDERS lists
+// the provider names; each is configured by OIDC_<NAME>_ISSUER,
+// OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET and OIDC_<NAME>_SCOPES.
+func LoadOIDCConfig() *OIDCConfig {
+	cfg := &OIDCConfig{
+		RedirectURL: getEnv("OIDC_REDIRECT_URL", "http://localhost:3000/auth/callback"),
+		StateTTL:    getEnvAsInt("OIDC_STATE_TTL", 600),
+	}
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name)
+		cfg.Providers = append(cfg.Providers, OIDCProviderConfig{
+			Name:         name,
+			Issuer:       getEnv(prefix+"_ISSUER", ""),
+			ClientID:     getEnv(prefix+"_CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"_CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"_SCOPES", "openid email profile")),
+		})
+	}
+	return cfg
+}
+
+// LoadAPIKeyConfig loads API key config from environment
+func LoadAPIKeyConfig() *APIKeyConfig {
+	return &APIKeyConfig{
+		DefaultTTLDays:   getEnvAsInt("API_KEY_DEFAULT_TTL_DAYS", 90),
+		MaxTTLDays:       getEnvAsInt("API_KEY_MAX_TTL_DAYS", 365),
+		DefaultRateLimit: getEnvAsInt("API_KEY_RATE_LIMIT", 60),
+		MaxRateLimit:     getEnvAsInt("API_KEY_MAX_RATE_LIMIT", 1000),
+		CacheTTL:         getEnvAsInt("API_KEY_CACHE_TTL", 30),
+	}
+}
+
+// LoadEncryptionConfig loads a service's encryption config from environment
+func LoadEncryptionConfig(service string) *EncryptionConfig {
+	prefix := strings.ToUpper(service)
+	return &EncryptionConfig{
+		KMS:                getEnv(fmt.Sprintf("%s_ENCRYPTION_KMS", prefix), "local"),
+		Keys:               getEnv(fmt.Sprintf("%s_ENCRYPTION_KEYS", prefix), ""),
+		KeyFile:            getEnv(fmt.Sprintf("%s_ENCRYPTION_KEY_FILE", prefix), fmt.Sprintf("keys/%s.json", service)),
+		ReencryptInterval:  getEnvAsInt(fmt.Sprintf("%s_REENCRYPT_INTERVAL", prefix), 3600),
+		ReencryptBatchSize: getEnvAsInt(fmt.Sprintf("%s_REENCRYPT_BATCH_SIZE", prefix), 500),
+	}
+}
+
+// LoadSessionConfig loads session config from environment
+func LoadSessionConfig() *SessionConfig {
+	return &SessionConfig{
+		CacheTTL: getEnvAsInt("SESSION_CACHE_TTL", 10),
+	}
+}
+
 // LoadRedisConfig loads Redis config from environment
 func LoadRedisConfig() *RedisConfig {
 	return &RedisConfig{
@@ -165,6 +391,61 @@ func LoadRateLimitConfig(service string) *RateLimitConfig {
 	}
 }
 
+// LoadUpstreamConfig loads gateway upstream client config from environment
+func LoadUpstreamConfig() *UpstreamConfig {
+	return &UpstreamConfig{
+		RequestTimeout:   getEnvAsInt("UPSTREAM_REQUEST_TIMEOUT_MS", 5000),
+		MaxRetries:       getEnvAsInt("UPSTREAM_MAX_RETRIES", 2),
+		RetryBackoffBase: getEnvAsInt("UPSTREAM_RETRY_BACKOFF_BASE_MS", 50),
+		RetryBackoffMax:  getEnvAsInt("UPSTREAM_RETRY_BACKOFF_MAX_MS", 1000),
+		RetryBudget:      getEnvAsInt("UPSTREAM_RETRY_BUDGET", 20),
+		HedgeDelay:       getEnvAsInt("UPSTREAM_HEDGE_DELAY_MS", 200),
+		BreakerThreshold: getEnvAsInt("UPSTREAM_BREAKER_THRESHOLD", 5),
+		BreakerCooldown:  getEnvAsInt("UPSTREAM_BREAKER_COOLDOWN", 30),
+	}
+}
+
+// LoadGraphQLConfig loads gateway GraphQL config from environment.
+// Introspection and the playground default to on only in development;
+// the operation allowlist defaults to on in production.
+func LoadGraphQLConfig() *GraphQLConfig {
+	dev := Environment() == "development"
+	return &GraphQLConfig{
+		Introspection:    getEnvAsBool("GRAPHQL_INTROSPECTION", dev),
+		Playground:       getEnvAsBool("GRAPHQL_PLAYGROUND", dev),
+		APQCacheTTL:      getEnvAsInt("GRAPHQL_APQ_CACHE_TTL", 86400),
+		AllowlistEnabled: getEnvAsBool("GRAPHQL_ALLOWLIST_ENABLED", IsProduction()),
+		AllowlistPath:    getEnv("GRAPHQL_ALLOWLIST_PATH", "persisted-operations.json"),
+		MaxDepth:         getEnvAsInt("GRAPHQL_MAX_DEPTH", 10),
+		CostBudgets: map[string]int{
+			"":      getEnvAsInt("GRAPHQL_COST_BUDGET_ANONYMOUS", 200),
+			"admin": getEnvAsInt("GRAPHQL_COST_BUDGET_ADMIN", 20000),
+		},
+		DefaultBudget:    getEnvAsInt("GRAPHQL_COST_BUDGET_USER", 5000),
+		ResponseCache:    getEnvAsBool("GRAPHQL_RESPONSE_CACHE_ENABLED", true),
+		ResponseCacheTTL: getEnvAsInt("GRAPHQL_RESPONSE_CACHE_MAX_TTL", 300),
+		LoaderStats:      getEnvAsBool("GRAPHQL_LOADER_STATS", dev),
+	}
+}
+
+// LoadSubgraphConfig loads subgraph config for a service from environment;
+// the subgraph is off unless enabled
+func LoadSubgraphConfig(service string) *SubgraphConfig {
+	prefix := strings.ToUpper(service)
+	return &SubgraphConfig{
+		Enabled: getEnvAsBool(fmt.Sprintf("%s_SUBGRAPH_ENABLED", prefix), false),
+	}
+}
+
+// LoadTracingConfig loads tracing config from environment
+func LoadTracingConfig() *TracingConfig {
+	return &TracingConfig{
+		Enabled:     getEnvAsBool("ENABLE_TRACING", false),
+		Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318"),
+		SampleRatio: getEnvAsFloat("OTEL_TRACES_SAMPLER_RATIO", 1.0),
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -189,3 +470,12 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,326 @@
+// Package dbtest provides a fake PostgreSQL database for tests of the
+// services' handlers. GORM talks to a database/sql driver that answers every
+// SELECT with the fixture rows of the table it reads, filtered by the
+// `column = $n` and `column IN (...)` conditions of its WHERE clause. Other
+// conditions, ordering and limits are ignored, and writes succeed without
+// changing the fixtures.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/microservices-go/shared/encryption"
+)
+
+// Row is a fixture row by column name
+type Row map[string]interface{}
+
+// Tables holds the fixture rows by table name
+type Tables map[string][]Row
+
+// Open returns a GORM database answering queries from tables. Fields tagged
+// serializer:encrypted are sealed with a fresh key set; fixtures may hold
+// them in plaintext.
+func Open(t testing.TB, tables Tables) *gorm.DB {
+	t.Helper()
+
+	sqlDB := sql.OpenDB(connector{tables: tables})
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(encryption.NewGormPlugin(NewEncryptor(t))); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// NewEncryptor returns an encryptor with a fresh key set
+func NewEncryptor(t testing.TB) *encryption.Encryptor {
+	t.Helper()
+
+	set, err := encryption.GenerateKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := encryption.NewLocalKeyProvider(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := encryption.New(provider, provider.IndexKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enc
+}
+
+var (
+	fromTable  = regexp.MustCompile(`(?i)\bFROM\s+"?(\w+)"?`)
+	countQuery = regexp.MustCompile(`(?i)^\s*SELECT\s+count\(`)
+	equalsArg  = regexp.MustCompile(`(?:"?\w+"?\.)?"?(\w+)"?\s*=\s*\$(\d+)`)
+	inArgs     = regexp.MustCompile(`(?i)(?:"?\w+"?\.)?"?(\w+)"?\s+IN\s+\(((?:\s*\$\d+\s*,?)+)\)`)
+	argRef     = regexp.MustCompile(`\$(\d+)`)
+)
+
+// connector opens connections to the fixtures
+type connector struct {
+	tables Tables
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{tables: c.tables}, nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return fakeDriver{c}
+}
+
+// fakeDriver only exists to satisfy driver.Connector; connections come from
+// the connector
+type fakeDriver struct {
+	c connector
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return d.c.Connect(context.Background())
+}
+
+// conn runs queries against the fixtures. Transactions are accepted and do
+// nothing.
+type conn struct {
+	tables Tables
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return tx{}, nil
+}
+
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return tx{}, nil
+}
+
+// CheckNamedValue accepts arguments of any type; they are only compared as
+// text
+func (c *conn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c *conn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(query)), "SELECT") {
+		return &rows{}, nil
+	}
+
+	match := fromTable.FindStringSubmatch(query)
+	if match == nil {
+		return &rows{}, nil
+	}
+	found := filter(c.tables[match[1]], query, args)
+	if countQuery.MatchString(query) {
+		return &rows{columns: []string{"count"}, values: [][]driver.Value{{int64(len(found))}}}, nil
+	}
+	return newRows(found), nil
+}
+
+// filter keeps the rows matching the equality and IN conditions of the
+// query's WHERE clause on columns the rows have
+func filter(table []Row, query string, args []driver.NamedValue) []Row {
+	where := ""
+	if i := strings.Index(strings.ToUpper(query), " WHERE "); i >= 0 {
+		where = query[i:]
+	}
+
+	conditions := map[string][]string{}
+	for _, m := range equalsArg.FindAllStringSubmatch(where, -1) {
+		conditions[m[1]] = append(conditions[m[1]], arg(args, m[2]))
+	}
+	for _, m := range inArgs.FindAllStringSubmatch(where, -1) {
+		var values []string
+		for _, ref := range argRef.FindAllStringSubmatch(m[2], -1) {
+			values = append(values, arg(args, ref[1]))
+		}
+		conditions[m[1]] = append(conditions[m[1]], strings.Join(values, "\x00"))
+	}
+
+	var found []Row
+rows:
+	for _, row := range table {
+		for column, wants := range conditions {
+			value, ok := row[column]
+			if !ok {
+				continue
+			}
+			for _, want := range wants {
+				if !contains(strings.Split(want, "\x00"), fmt.Sprint(value)) {
+					continue rows
+				}
+			}
+		}
+		found = append(found, row)
+	}
+	return found
+}
+
+// arg returns the text of the argument with the 1-based number n
+func arg(args []driver.NamedValue, n string) string {
+	i, _ := strconv.Atoi(n)
+	if i < 1 || i > len(args) {
+		return ""
+	}
+	return fmt.Sprint(args[i-1].Value)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// stmt runs a prepared query through its connection
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, named(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, named(args))
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	values := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		values[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return values
+}
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+// rows iterates over fixture rows
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+// newRows returns the rows with the union of their columns; columns a row
+// lacks are NULL
+func newRows(found []Row) *rows {
+	seen := map[string]bool{}
+	r := &rows{}
+	for _, row := range found {
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				r.columns = append(r.columns, column)
+			}
+		}
+	}
+	sort.Strings(r.columns)
+
+	for _, row := range found {
+		values := make([]driver.Value, len(r.columns))
+		for i, column := range r.columns {
+			values[i] = value(row[column])
+		}
+		r.values = append(r.values, values)
+	}
+	return r
+}
+
+// value converts a fixture value to one of the types a driver may return
+func value(v interface{}) driver.Value {
+	if v == nil {
+		return nil
+	}
+	if t, ok := v.(time.Time); ok {
+		return t
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+		return value(rv.Elem().Interface())
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Slice:
+		if b, ok := v.([]byte); ok {
+			return b
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -0,0 +1,50 @@
+// Command rotate-key adds a fresh key to a local encryption key file and
+// makes it primary. Services pick it up on restart; their re-encryption job
+// then moves every value to the new key, after which older keys may be
+// removed from the file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/microservices-go/shared/encryption"
+)
+
+func main() {
+	path := flag.String("file", "", "key file to rotate, e.g. services/user/keys/user.json")
+	create := flag.Bool("create", false, "create the key file instead when it does not exist")
+	flag.Parse()
+
+	if *path == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if _, err := os.Stat(*path); os.IsNotExist(err) && *create {
+		set, err := encryption.LoadKeyFile(*path, true)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Created %s with primary key %s\n", *path, set.Primary)
+		return
+	}
+
+	set, err := encryption.LoadKeyFile(*path, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	id, err := set.Rotate()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := encryption.WriteKeyFile(*path, set); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("New primary key %s (%d keys in %s)\n", id, len(set.Keys), *path)
+}
@@ -0,0 +1,254 @@
+// Package encryption encrypts selected database fields at rest with envelope
+// encryption: values are sealed with AES-256-GCM under a data key, and the
+// data key is stored next to them wrapped by a key encryption key held by a
+// KeyProvider, such as a KMS. Blind indexes keep encrypted fields searchable
+// by exact value.
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"github.com/microservices-go/shared/errors"
+)
+
+// prefix marks encrypted values; values without it were written before
+// their field was encrypted and are read as they are. v1 values are only
+// bound to their key ID; the re-encryption job rewrites them as v2.
+const (
+	prefix   = "enc:v2:"
+	prefixV1 = "enc:v1:"
+)
+
+// maxCachedKeys bounds the unwrapped data keys kept in memory; every process
+// writes with its own data key, so readers meet many
+const maxCachedKeys = 1024
+
+// KeyProvider holds the key encryption keys. New data keys are wrapped with
+// the primary key; older keys stay available to unwrap what they wrapped
+// until everything is re-encrypted. A KMS client implements it;
+// LocalKeyProvider keeps the keys in a file for development and tests.
+type KeyProvider interface {
+	// PrimaryKeyID returns the ID of the key that wraps new data keys;
+	// IDs must not contain ':'
+	PrimaryKeyID() string
+	// WrapKey encrypts a data key with the key encryption key keyID
+	WrapKey(ctx context.Context, keyID string, dataKey []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key wrapped by WrapKey
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// dataKey is an unwrapped data key and how it is stored
+type dataKey struct {
+	keyID   string
+	wrapped string
+	aead    cipher.AEAD
+}
+
+// Encryptor encrypts and decrypts field values and computes their blind
+// indexes. It is safe for concurrent use.
+type Encryptor struct {
+	provider KeyProvider
+	indexKey []byte
+
+	mu      sync.Mutex
+	current *dataKey
+	keys    map[string]*dataKey // by keyID:wrapped
+}
+
+// New creates an encryptor; indexKey keys the blind indexes and must stay
+// the same for as long as the indexes are kept
+func New(provider KeyProvider, indexKey []byte) (*Encryptor, error) {
+	if len(indexKey) < 32 {
+		return nil, errors.New(errors.ErrInternalServer, "Blind index key must be at least 32 bytes")
+	}
+	return &Encryptor{provider: provider, indexKey: indexKey, keys: map[string]*dataKey{}}, nil
+}
+
+// Cell is where a value is stored. It is bound into the associated data, so
+// a value copied to another column or row no longer decrypts.
+type Cell struct {
+	Table  string
+	Column string
+	Row    string // primary key of the row
+}
+
+// associatedData binds a v2 value to its key ID and cell
+func (c Cell) associatedData(keyID string) []byte {
+	return []byte(strings.Join([]string{prefix + keyID, c.Table, c.Column, c.Row}, "\x00"))
+}
+
+// Encrypt seals a value stored in cell; the empty string stays empty
+func (e *Encryptor) Encrypt(ctx context.Context, cell Cell, plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	key, err := e.currentKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.Wrap(err, errors.ErrInternalServer, "Failed to encrypt value")
+	}
+	sealed := key.aead.Seal(nonce, nonce, []byte(plaintext), cell.associatedData(key.keyID))
+	return prefix + key.keyID + ":" + key.wrapped + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value sealed by Encrypt for the same cell; values that
+// were never encrypted are returned as they are
+func (e *Encryptor) Decrypt(ctx context.Context, cell Cell, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	v1 := strings.HasPrefix(value, prefixV1)
+	parts := strings.SplitN(strings.TrimPrefix(strings.TrimPrefix(value, prefix), prefixV1), ":", 3)
+	if len(parts) != 3 {
+		return "", errors.New(errors.ErrInternalServer, "Malformed encrypted value")
+	}
+	key, err := e.dataKey(ctx, parts[0], parts[1])
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil || len(sealed) < key.aead.NonceSize() {
+		return "", errors.New(errors.ErrInternalServer, "Malformed encrypted value")
+	}
+	nonce, ciphertext := sealed[:key.aead.NonceSize()], sealed[key.aead.NonceSize():]
+	ad := cell.associatedData(key.keyID)
+	if v1 {
+		ad = []byte(key.keyID)
+	}
+	plaintext, err := key.aead.Open(nil, nonce, ciphertext, ad)
+	if err != nil {
+		return "", errors.Wrap(err, errors.ErrInternalServer, "Failed to decrypt value")
+	}
+	return string(plaintext), nil
+}
+
+// BlindIndex returns the HMAC-SHA256 of a value, trimmed and lowercased, so
+// that an encrypted field can be looked up by exact, case-insensitive value
+func (e *Encryptor) BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, e.indexKey)
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(value))))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NeedsReencryption reports whether a stored value is plaintext, a v1 value
+// or its data key is wrapped by a key other than the primary one
+func (e *Encryptor) NeedsReencryption(value string) bool {
+	if value == "" {
+		return false
+	}
+	return !strings.HasPrefix(value, e.primaryPrefix())
+}
+
+// primaryPrefix is how values encrypted under the primary key start
+func (e *Encryptor) primaryPrefix() string {
+	return prefix + e.provider.PrimaryKeyID() + ":"
+}
+
+// IsEncrypted reports whether a stored value was written by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix) || strings.HasPrefix(value, prefixV1)
+}
+
+// currentKey returns the data key new values are sealed with, creating one
+// when there is none for the primary key yet
+func (e *Encryptor) currentKey(ctx context.Context) (*dataKey, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	keyID := e.provider.PrimaryKeyID()
+	if e.current != nil && e.current.keyID == keyID {
+		return e.current, nil
+	}
+
+	plain := make([]byte, 32)
+	if _, err := rand.Read(plain); err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to generate data key")
+	}
+	wrapped, err := e.provider.WrapKey(ctx, keyID, plain)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to wrap data key")
+	}
+	key, err := newDataKey(keyID, base64.RawStdEncoding.EncodeToString(wrapped), plain)
+	if err != nil {
+		return nil, err
+	}
+	e.current = key
+	e.cache(key)
+	return key, nil
+}
+
+// dataKey returns the unwrapped data key of a stored value
+func (e *Encryptor) dataKey(ctx context.Context, keyID, wrapped string) (*dataKey, error) {
+	e.mu.Lock()
+	key, ok := e.keys[keyID+":"+wrapped]
+	e.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	raw, err := base64.RawStdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, errors.New(errors.ErrInternalServer, "Malformed encrypted value")
+	}
+	plain, err := e.provider.UnwrapKey(ctx, keyID, raw)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to unwrap data key")
+	}
+	if key, err = newDataKey(keyID, wrapped, plain); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	e.cache(key)
+	e.mu.Unlock()
+	return key, nil
+}
+
+// cache keeps an unwrapped data key; the caller holds e.mu
+func (e *Encryptor) cache(key *dataKey) {
+	if len(e.keys) >= maxCachedKeys {
+		e.keys = map[string]*dataKey{}
+		if e.current != nil {
+			e.keys[e.current.keyID+":"+e.current.wrapped] = e.current
+		}
+	}
+	e.keys[key.keyID+":"+key.wrapped] = key
+}
+
+func newDataKey(keyID, wrapped string, plain []byte) (*dataKey, error) {
+	aead, err := newAEAD(plain)
+	if err != nil {
+		return nil, err
+	}
+	return &dataKey{keyID: keyID, wrapped: wrapped, aead: aead}, nil
+}
+
+// newAEAD creates an AES-GCM cipher from a 256-bit key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New(errors.ErrInternalServer, "Encryption keys must be 256 bits")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to create cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to create cipher")
+	}
+	return aead, nil
+}
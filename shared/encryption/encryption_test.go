@@ -0,0 +1,169 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func newTestEncryptor(t *testing.T, set *KeySet) *Encryptor {
+	t.Helper()
+	provider, err := NewLocalKeyProvider(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := New(provider, provider.IndexKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enc
+}
+
+// Cells the tests encrypt into
+var (
+	email   = Cell{Table: "users", Column: "email", Row: "u1"}
+	address = Cell{Table: "orders", Column: "shipping_address", Row: "o1"}
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	ctx := context.Background()
+	set, err := GenerateKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := newTestEncryptor(t, set)
+
+	sealed, err := enc.Encrypt(ctx, email, "jane@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(sealed) || strings.Contains(sealed, "jane") {
+		t.Fatalf("Encrypt = %q", sealed)
+	}
+	again, _ := enc.Encrypt(ctx, email, "jane@example.com")
+	if again == sealed {
+		t.Error("equal plaintexts encrypted to equal values")
+	}
+	if got, err := enc.Decrypt(ctx, email, sealed); err != nil || got != "jane@example.com" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+
+	// Values from before encryption are read as they are
+	if got, err := enc.Decrypt(ctx, email, "plain@example.com"); err != nil || got != "plain@example.com" {
+		t.Errorf("Decrypt(plaintext) = %q, %v", got, err)
+	}
+	if got, _ := enc.Encrypt(ctx, email, ""); got != "" {
+		t.Errorf("Encrypt(\"\") = %q", got)
+	}
+
+	tampered := sealed[:len(sealed)-2] + "AA"
+	if _, err := enc.Decrypt(ctx, email, tampered); err == nil {
+		t.Error("tampered value decrypted")
+	}
+}
+
+func TestCellBinding(t *testing.T) {
+	ctx := context.Background()
+	set, err := GenerateKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := newTestEncryptor(t, set)
+
+	sealed, err := enc.Encrypt(ctx, email, "jane@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cell := range []Cell{
+		{Table: "users", Column: "email", Row: "u2"},
+		{Table: "users", Column: "first_name", Row: "u1"},
+		{Table: "identities", Column: "email", Row: "u1"},
+	} {
+		if _, err := enc.Decrypt(ctx, cell, sealed); err == nil {
+			t.Errorf("value sealed for %v decrypted for %v", email, cell)
+		}
+	}
+}
+
+func TestV1Values(t *testing.T) {
+	ctx := context.Background()
+	set, err := GenerateKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := newTestEncryptor(t, set)
+
+	// v1 values are only bound to their key ID
+	key, err := enc.currentKey(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, key.aead.NonceSize())
+	sealed := key.aead.Seal(nonce, nonce, []byte("jane@example.com"), []byte(key.keyID))
+	v1 := prefixV1 + key.keyID + ":" + key.wrapped + ":" + base64.RawStdEncoding.EncodeToString(sealed)
+
+	if !IsEncrypted(v1) || !enc.NeedsReencryption(v1) {
+		t.Error("v1 value not recognized as encrypted and due for re-encryption")
+	}
+	if got, err := enc.Decrypt(ctx, email, v1); err != nil || got != "jane@example.com" {
+		t.Errorf("Decrypt(v1) = %q, %v", got, err)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	ctx := context.Background()
+	set, err := GenerateKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := newTestEncryptor(t, set)
+	sealed, err := old.Encrypt(ctx, address, "1 Main St")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := set.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	enc := newTestEncryptor(t, set)
+
+	if !enc.NeedsReencryption(sealed) || !enc.NeedsReencryption("1 Main St") {
+		t.Error("value under the old key or in plaintext needs no re-encryption")
+	}
+	if got, err := enc.Decrypt(ctx, address, sealed); err != nil || got != "1 Main St" {
+		t.Errorf("Decrypt under old key = %q, %v", got, err)
+	}
+	resealed, err := enc.Encrypt(ctx, address, "1 Main St")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc.NeedsReencryption(resealed) || enc.NeedsReencryption("") {
+		t.Error("value under the primary key needs re-encryption")
+	}
+
+	delete(set.Keys, strings.SplitN(strings.TrimPrefix(sealed, prefix), ":", 2)[0])
+	if _, err := newTestEncryptor(t, set).Decrypt(ctx, address, sealed); err == nil {
+		t.Error("value decrypted after its key was removed")
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	set, err := GenerateKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := newTestEncryptor(t, set)
+
+	if enc.BlindIndex("Jane@Example.com ") != enc.BlindIndex("jane@example.com") {
+		t.Error("blind index depends on case or surrounding space")
+	}
+	if enc.BlindIndex("jane@example.com") == enc.BlindIndex("john@example.com") {
+		t.Error("different values share a blind index")
+	}
+
+	other, _ := GenerateKeySet()
+	if newTestEncryptor(t, other).BlindIndex("jane@example.com") == enc.BlindIndex("jane@example.com") {
+		t.Error("blind index does not depend on the index key")
+	}
+}
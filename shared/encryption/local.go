@@ -0,0 +1,204 @@
+package encryption
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/errors"
+)
+
+// KeySet is the content of a local key file: base64 256-bit key encryption
+// keys by ID, the primary one among them and the blind index key
+type KeySet struct {
+	Primary  string            `json:"primary"`
+	Keys     map[string]string `json:"keys"`
+	IndexKey string            `json:"index_key"`
+}
+
+// GenerateKeySet creates a key set with one fresh key and index key
+func GenerateKeySet() (*KeySet, error) {
+	index, err := randomKey()
+	if err != nil {
+		return nil, err
+	}
+	set := &KeySet{Keys: map[string]string{}, IndexKey: index}
+	if _, err := set.Rotate(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// Rotate adds a fresh key and makes it primary, returning its ID. The older
+// keys stay so that values they protect can still be read and re-encrypted.
+func (s *KeySet) Rotate() (string, error) {
+	key, err := randomKey()
+	if err != nil {
+		return "", err
+	}
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return "", errors.Wrap(err, errors.ErrInternalServer, "Failed to generate key ID")
+	}
+	s.Primary = "key-" + hex.EncodeToString(id)
+	s.Keys[s.Primary] = key
+	return s.Primary, nil
+}
+
+// ParseKeySet parses the JSON of a key set
+func ParseKeySet(data []byte) (*KeySet, error) {
+	var set KeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Invalid encryption key set")
+	}
+	return &set, nil
+}
+
+// LoadKeyFile reads a key file, creating it with a fresh key set when it
+// does not exist and create is set
+func LoadKeyFile(path string, create bool) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && create {
+		set, err := GenerateKeySet()
+		if err != nil {
+			return nil, err
+		}
+		return set, WriteKeyFile(path, set)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to read encryption key file")
+	}
+	return ParseKeySet(data)
+}
+
+// WriteKeyFile writes a key set readable by its owner only
+func WriteKeyFile(path string, set *KeySet) error {
+	data, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, errors.ErrInternalServer, "Failed to encode encryption key set")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return errors.Wrap(err, errors.ErrInternalServer, "Failed to write encryption key file")
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return errors.Wrap(err, errors.ErrInternalServer, "Failed to write encryption key file")
+	}
+	return nil
+}
+
+// LocalKeyProvider wraps data keys with the keys of a KeySet. It stands in
+// for a KMS in development and tests.
+type LocalKeyProvider struct {
+	primary  string
+	keys     map[string]cipher.AEAD
+	indexKey []byte
+}
+
+// NewLocalKeyProvider creates a key provider from a key set
+func NewLocalKeyProvider(set *KeySet) (*LocalKeyProvider, error) {
+	if _, ok := set.Keys[set.Primary]; !ok {
+		return nil, errors.New(errors.ErrInternalServer, "Primary encryption key not found in key set")
+	}
+
+	p := &LocalKeyProvider{primary: set.Primary, keys: make(map[string]cipher.AEAD, len(set.Keys))}
+	for id, encoded := range set.Keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, errors.New(errors.ErrInternalServer, "Invalid encryption key ID: "+id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrInternalServer, "Invalid encryption key "+id)
+		}
+		if p.keys[id], err = newAEAD(key); err != nil {
+			return nil, err
+		}
+	}
+
+	indexKey, err := base64.StdEncoding.DecodeString(set.IndexKey)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Invalid blind index key")
+	}
+	p.indexKey = indexKey
+	return p, nil
+}
+
+// PrimaryKeyID returns the ID of the key that wraps new data keys
+func (p *LocalKeyProvider) PrimaryKeyID() string {
+	return p.primary
+}
+
+// IndexKey returns the blind index key of the key set
+func (p *LocalKeyProvider) IndexKey() []byte {
+	return p.indexKey
+}
+
+// WrapKey encrypts a data key with the key keyID
+func (p *LocalKeyProvider) WrapKey(ctx context.Context, keyID string, dataKey []byte) ([]byte, error) {
+	kek, ok := p.keys[keyID]
+	if !ok {
+		return nil, errors.New(errors.ErrInternalServer, "Unknown encryption key: "+keyID)
+	}
+	nonce := make([]byte, kek.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to wrap data key")
+	}
+	return kek.Seal(nonce, nonce, dataKey, []byte(keyID)), nil
+}
+
+// UnwrapKey decrypts a data key wrapped by WrapKey
+func (p *LocalKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	kek, ok := p.keys[keyID]
+	if !ok {
+		return nil, errors.New(errors.ErrInternalServer, "Unknown encryption key: "+keyID)
+	}
+	if len(wrapped) < kek.NonceSize() {
+		return nil, errors.New(errors.ErrInternalServer, "Malformed wrapped data key")
+	}
+	nonce, ciphertext := wrapped[:kek.NonceSize()], wrapped[kek.NonceSize():]
+	dataKey, err := kek.Open(nil, nonce, ciphertext, []byte(keyID))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternalServer, "Failed to unwrap data key")
+	}
+	return dataKey, nil
+}
+
+// randomKey returns a fresh base64 256-bit key
+func randomKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", errors.Wrap(err, errors.ErrInternalServer, "Failed to generate encryption key")
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// NewFromConfig creates a service's encryptor. Only the local key provider
+// is built in; a KMS-backed KeyProvider is passed to New instead.
+func NewFromConfig(cfg *config.EncryptionConfig) (*Encryptor, error) {
+	if cfg.KMS != "local" {
+		return nil, errors.New(errors.ErrInternalServer, "Unsupported KMS: "+cfg.KMS)
+	}
+
+	var set *KeySet
+	var err error
+	if cfg.Keys != "" {
+		set, err = ParseKeySet([]byte(cfg.Keys))
+	} else {
+		set, err = LoadKeyFile(cfg.KeyFile, !config.IsProduction())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := NewLocalKeyProvider(set)
+	if err != nil {
+		return nil, err
+	}
+	return New(provider, provider.IndexKey())
+}
@@ -0,0 +1,168 @@
+package encryption
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/logger"
+)
+
+// Target names the encrypted columns of a table
+type Target struct {
+	Table   string
+	Key     string            // primary key column
+	Columns []string          // encrypted columns
+	Indexes map[string]string // blind index column by the column it indexes
+}
+
+// Reencrypt rewrites the values of a target that are plaintext, v1 values or
+// whose data key is wrapped by a key other than the primary one, and fills missing
+// blind indexes, batchSize rows at a time. A row changed meanwhile is left
+// to its writer, who already used the primary key. It returns how many rows
+// it rewrote.
+func (e *Encryptor) Reencrypt(ctx context.Context, db *gorm.DB, target Target, batchSize int) (int, error) {
+	log := logger.WithContext(ctx).WithField("table", target.Table)
+
+	columns := append([]string{target.Key}, target.Columns...)
+	var stale []string
+	var args []interface{}
+	for _, column := range target.Columns {
+		stale = append(stale, "("+column+" <> '' AND "+column+" NOT LIKE ?)")
+		args = append(args, e.primaryPrefix()+"%")
+	}
+	for column, index := range target.Indexes {
+		stale = append(stale, "("+index+" IS NULL AND "+column+" <> '')")
+	}
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM " + target.Table +
+		" WHERE (" + strings.Join(stale, " OR ") + ")"
+
+	rewritten := 0
+	var after *string
+	for {
+		q, qargs := query, args
+		if after != nil {
+			q, qargs = q+" AND "+target.Key+" > ?", append(append([]interface{}{}, args...), *after)
+		}
+		q += " ORDER BY " + target.Key + " LIMIT ?"
+		qargs = append(qargs, batchSize)
+
+		rows, err := e.readBatch(ctx, db, q, qargs, len(columns))
+		if err != nil {
+			return rewritten, err
+		}
+		for _, row := range rows {
+			ok, err := e.reencryptRow(ctx, db, target, row)
+			if err != nil {
+				log.WithError(err).WithField("key", row[0].String).Warn("Failed to re-encrypt row")
+				continue
+			}
+			if ok {
+				rewritten++
+			}
+		}
+		if len(rows) < batchSize {
+			return rewritten, nil
+		}
+		after = &rows[len(rows)-1][0].String
+	}
+}
+
+// readBatch reads a batch of rows as strings
+func (e *Encryptor) readBatch(ctx context.Context, db *gorm.DB, query string, args []interface{}, width int) ([][]sql.NullString, error) {
+	rows, err := db.WithContext(ctx).Raw(query, args...).Rows()
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to read rows to re-encrypt")
+	}
+	defer rows.Close()
+
+	var batch [][]sql.NullString
+	for rows.Next() {
+		row := make([]sql.NullString, width)
+		dest := make([]interface{}, width)
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to read rows to re-encrypt")
+		}
+		batch = append(batch, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, errors.ErrDatabaseError, "Failed to read rows to re-encrypt")
+	}
+	return batch, nil
+}
+
+// reencryptRow rewrites one row read by Reencrypt, reporting whether it was
+// still unchanged
+func (e *Encryptor) reencryptRow(ctx context.Context, db *gorm.DB, target Target, row []sql.NullString) (bool, error) {
+	var set, where []string
+	var setArgs, whereArgs []interface{}
+	for i, column := range target.Columns {
+		stored := row[i+1]
+		if !stored.Valid || stored.String == "" {
+			continue
+		}
+		cell := Cell{Table: target.Table, Column: column, Row: row[0].String}
+		plaintext, err := e.Decrypt(ctx, cell, stored.String)
+		if err != nil {
+			return false, err
+		}
+		if e.NeedsReencryption(stored.String) {
+			encrypted, err := e.Encrypt(ctx, cell, plaintext)
+			if err != nil {
+				return false, err
+			}
+			set = append(set, column+" = ?")
+			setArgs = append(setArgs, encrypted)
+		}
+		if index, ok := target.Indexes[column]; ok {
+			set = append(set, index+" = ?")
+			setArgs = append(setArgs, e.BlindIndex(plaintext))
+		}
+		where = append(where, column+" = ?")
+		whereArgs = append(whereArgs, stored.String)
+	}
+	if len(set) == 0 {
+		return false, nil
+	}
+
+	args := append(append(setArgs, row[0].String), whereArgs...)
+	result := db.WithContext(ctx).Exec("UPDATE "+target.Table+" SET "+strings.Join(set, ", ")+
+		" WHERE "+target.Key+" = ? AND "+strings.Join(where, " AND "), args...)
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, errors.ErrDatabaseError, "Failed to re-encrypt row")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RunReencryption re-encrypts the targets now and then every interval until
+// ctx is done; an interval of zero runs once
+func (e *Encryptor) RunReencryption(ctx context.Context, db *gorm.DB, targets []Target, batchSize int, interval time.Duration) {
+	log := logger.WithContext(ctx)
+	for {
+		for _, target := range targets {
+			rewritten, err := e.Reencrypt(ctx, db, target, batchSize)
+			if err != nil {
+				log.WithError(err).WithField("table", target.Table).Error("Re-encryption failed")
+				continue
+			}
+			if rewritten > 0 {
+				log.WithField("table", target.Table).Infof("Re-encrypted %d rows", rewritten)
+			}
+		}
+		if interval <= 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
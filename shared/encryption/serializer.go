@@ -0,0 +1,146 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// SerializerName is the name of the GORM serializer; encrypt a string field
+// with the tag `gorm:"serializer:encrypted"`
+const SerializerName = "encrypted"
+
+// GormPlugin encrypts fields tagged serializer:encrypted on their way into
+// the database and decrypts them on their way out, bound to their table,
+// column and row. Updates from a map bypass it, so they must pass values
+// through Encrypt themselves.
+// Register with db.Use(encryption.NewGormPlugin(enc)) before the models are
+// first used.
+type GormPlugin struct {
+	enc *Encryptor
+}
+
+// NewGormPlugin creates the plugin encrypting fields with enc
+func NewGormPlugin(enc *Encryptor) *GormPlugin {
+	return &GormPlugin{enc: enc}
+}
+
+// Name returns the plugin name
+func (p *GormPlugin) Name() string {
+	return "field-encryption"
+}
+
+// Initialize registers the serializer and the callback decrypting what
+// queries read
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	schema.RegisterSerializer(SerializerName, Serializer{enc: p.enc})
+	return db.Callback().Query().After("gorm:query").Register("encryption:decrypt", p.decrypt)
+}
+
+// decrypt opens the encrypted fields of the rows a query read. It runs once
+// the rows are scanned, as a field can only be opened with its row's key.
+func (p *GormPlugin) decrypt(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil {
+		return
+	}
+	var fields []*schema.Field
+	for _, field := range stmt.Schema.Fields {
+		if field.TagSettings["SERIALIZER"] == SerializerName {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+
+	rows := reflect.Indirect(stmt.ReflectValue)
+	if rows.Kind() == reflect.Struct {
+		p.decryptRow(db, fields, rows)
+		return
+	}
+	if rows.Kind() == reflect.Slice || rows.Kind() == reflect.Array {
+		for i := 0; i < rows.Len(); i++ {
+			p.decryptRow(db, fields, reflect.Indirect(rows.Index(i)))
+		}
+	}
+}
+
+// decryptRow opens the encrypted fields of one row
+func (p *GormPlugin) decryptRow(db *gorm.DB, fields []*schema.Field, row reflect.Value) {
+	ctx := db.Statement.Context
+	if row.Kind() != reflect.Struct || row.Type() != db.Statement.Schema.ModelType {
+		return
+	}
+	for _, field := range fields {
+		value := field.ReflectValueOf(ctx, row)
+		if value.Kind() != reflect.String || !IsEncrypted(value.String()) {
+			continue
+		}
+		cell, err := cellOf(ctx, field, row)
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+		plaintext, err := p.enc.Decrypt(ctx, cell, value.String())
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+		value.SetString(plaintext)
+	}
+}
+
+// cellOf returns where the field of row is stored
+func cellOf(ctx context.Context, field *schema.Field, row reflect.Value) (Cell, error) {
+	key := field.Schema.PrioritizedPrimaryField
+	if key == nil {
+		return Cell{}, fmt.Errorf("encrypted field %s: %s has no primary key", field.Name, field.Schema.Name)
+	}
+	id, zero := key.ValueOf(ctx, row)
+	if zero {
+		return Cell{}, fmt.Errorf("encrypted field %s: row has no primary key", field.Name)
+	}
+	return Cell{Table: field.Schema.Table, Column: field.DBName, Row: fmt.Sprint(id)}, nil
+}
+
+// Serializer encrypts string fields on their way into the database. Read
+// values are left sealed for GormPlugin to open.
+type Serializer struct {
+	enc *Encryptor
+}
+
+// Scan reads a column value into the field as it is stored
+func (s Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("encrypted field %s: unsupported column value %T", field.Name, dbValue)
+	}
+	return field.Set(ctx, dst, value)
+}
+
+// Value encrypts the field value for its row, which must have its primary
+// key by now
+func (s Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("encrypted field %s: unsupported type %T", field.Name, fieldValue)
+	}
+	if value == "" {
+		return "", nil
+	}
+	cell, err := cellOf(ctx, field, dst)
+	if err != nil {
+		return nil, err
+	}
+	return s.enc.Encrypt(ctx, cell, value)
+}
@@ -25,14 +25,21 @@ const (
 	ErrDatabaseError      ErrorCode = "DATABASE_ERROR"
 )
 
+// FieldError describes a validation failure on a single input field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 // AppError represents a structured application error
 type AppError struct {
-	Code       ErrorCode `json:"code"`
-	Message    string    `json:"message"`
-	Details    string    `json:"details,omitempty"`
-	Service    string    `json:"service,omitempty"`
-	StatusCode int       `json:"-"`
-	Err        error     `json:"-"`
+	Code       ErrorCode    `json:"code"`
+	Message    string       `json:"message"`
+	Details    string       `json:"details,omitempty"`
+	Service    string       `json:"service,omitempty"`
+	Fields     []FieldError `json:"fields,omitempty"`
+	StatusCode int          `json:"-"`
+	Err        error        `json:"-"`
 }
 
 func (e *AppError) Error() string {
@@ -86,6 +93,17 @@ func (e *AppError) WithDetails(details string) *AppError {
 	return e
 }
 
+// WithFields adds per-field validation errors
+func (e *AppError) WithFields(fields []FieldError) *AppError {
+	e.Fields = fields
+	return e
+}
+
+// IsServerError reports whether the error maps to a 5xx status
+func (e *AppError) IsServerError() bool {
+	return e.StatusCode >= http.StatusInternalServerError
+}
+
 // IsNotFound checks if error is not found
 func IsNotFound(err error) bool {
 	if appErr, ok := err.(*AppError); ok {
@@ -130,6 +148,28 @@ func getStatusCode(code ErrorCode) int {
 	}
 }
 
+// CodeFromStatus maps an HTTP status code to the closest ErrorCode
+func CodeFromStatus(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrInvalidInput
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusTooManyRequests:
+		return ErrRateLimit
+	case http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return ErrServiceUnavailable
+	default:
+		return ErrInternalServer
+	}
+}
+
 // Common errors
 var (
 	ErrUserNotFound     = New(ErrNotFound, "User not found")
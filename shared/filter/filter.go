@@ -0,0 +1,187 @@
+package filter
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/pagination"
+	"gorm.io/gorm"
+)
+
+// Query wraps list query parameters with typed accessors that collect
+// per-field errors instead of failing on the first one
+type Query struct {
+	values url.Values
+	fields []errors.FieldError
+}
+
+// FromQuery creates a new filter query from URL query parameters
+func FromQuery(values url.Values) *Query {
+	return &Query{values: values}
+}
+
+// Fail records a validation error for a parameter
+func (q *Query) Fail(field, message string) {
+	q.fields = append(q.fields, errors.FieldError{Field: field, Message: message})
+}
+
+// Err returns a VALIDATION_FAILED error listing every invalid parameter, or nil
+func (q *Query) Err() error {
+	if len(q.fields) == 0 {
+		return nil
+	}
+
+	details := make([]string, len(q.fields))
+	for i, f := range q.fields {
+		details[i] = f.Field + ": " + f.Message
+	}
+	return errors.New(errors.ErrValidationFailed, "Invalid query parameters").
+		WithDetails(strings.Join(details, "; ")).
+		WithFields(q.fields)
+}
+
+// String returns a trimmed parameter, failing when it exceeds maxLen
+func (q *Query) String(key string, maxLen int) string {
+	value := strings.TrimSpace(q.values.Get(key))
+	if len(value) > maxLen {
+		q.Fail(key, "must be at most "+strconv.Itoa(maxLen)+" characters")
+		return ""
+	}
+	return value
+}
+
+// List returns a comma-separated or repeated parameter
+func (q *Query) List(key string) []string {
+	var items []string
+	for _, raw := range q.values[key] {
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// Enum returns a list parameter whose items must all be in allowed
+func (q *Query) Enum(key string, allowed ...string) []string {
+	items := q.List(key)
+	for _, item := range items {
+		if !slices.Contains(allowed, item) {
+			q.Fail(key, "must be one of: "+strings.Join(allowed, ", "))
+			return nil
+		}
+	}
+	return items
+}
+
+// Time parses an RFC 3339 timestamp or a YYYY-MM-DD date
+func (q *Query) Time(key string) *time.Time {
+	raw := q.values.Get(key)
+	if raw == "" {
+		return nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return &t
+		}
+	}
+	q.Fail(key, "must be an RFC 3339 timestamp or YYYY-MM-DD date")
+	return nil
+}
+
+// Float parses a non-negative number
+func (q *Query) Float(key string) *float64 {
+	raw := q.values.Get(key)
+	if raw == "" {
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil || f < 0 {
+		q.Fail(key, "must be a non-negative number")
+		return nil
+	}
+	return &f
+}
+
+// Bool parses a boolean
+func (q *Query) Bool(key string) *bool {
+	raw := q.values.Get(key)
+	if raw == "" {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		q.Fail(key, "must be true or false")
+		return nil
+	}
+	return &b
+}
+
+// TimeRange fails when both bounds are set and from is after to
+func (q *Query) TimeRange(fromKey string, from, to *time.Time) {
+	if from != nil && to != nil && from.After(*to) {
+		q.Fail(fromKey, "must not be after the end of the range")
+	}
+}
+
+// FloatRange fails when both bounds are set and min is greater than max
+func (q *Query) FloatRange(minKey string, min, max *float64) {
+	if min != nil && max != nil && *min > *max {
+		q.Fail(minKey, "must not be greater than the upper bound")
+	}
+}
+
+// Sort is a single ORDER BY term
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// Sort parses "field,-other" style sort keys, mapping each field onto a column
+func (q *Query) Sort(key string, columns map[string]string) []Sort {
+	var sorts []Sort
+	for _, item := range q.List(key) {
+		desc := strings.HasPrefix(item, "-")
+		column, ok := columns[strings.TrimPrefix(item, "-")]
+		if !ok {
+			q.Fail(key, "unknown sort field "+strings.TrimPrefix(item, "-"))
+			return nil
+		}
+		sorts = append(sorts, Sort{Column: column, Desc: desc})
+	}
+	return sorts
+}
+
+// OrderBy applies the sort terms, defaulting to newest first, with id as the
+// final tiebreaker so offset pages are stable
+func OrderBy(db *gorm.DB, sorts []Sort) *gorm.DB {
+	if len(sorts) == 0 {
+		return db.Order("created_at DESC, id DESC")
+	}
+
+	for _, s := range sorts {
+		if s.Desc {
+			db = db.Order(s.Column + " DESC")
+		} else {
+			db = db.Order(s.Column + " ASC")
+		}
+	}
+	return db.Order("id DESC")
+}
+
+// CheckPage rejects sort terms on a cursor page, as cursors are positions in
+// the default (created_at, id) ordering
+func CheckPage(page *pagination.Page, sorts []Sort) error {
+	if page != nil && len(sorts) > 0 {
+		return errors.New(errors.ErrInvalidInput, "sort cannot be combined with cursor pagination")
+	}
+	return nil
+}
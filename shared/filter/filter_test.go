@@ -0,0 +1,132 @@
+package filter
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/pagination"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type row struct {
+	ID        string
+	CreatedAt time.Time
+}
+
+var sortColumns = map[string]string{"createdAt": "created_at", "total": "total_amount"}
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []Sort
+		valid bool
+	}{
+		{"none", "", nil, true},
+		{"ascending", "sort=total", []Sort{{Column: "total_amount"}}, true},
+		{"mixed", "sort=-total,createdAt", []Sort{{Column: "total_amount", Desc: true}, {Column: "created_at"}}, true},
+		{"repeated", "sort=-createdAt&sort=total", []Sort{{Column: "created_at", Desc: true}, {Column: "total_amount"}}, true},
+		{"unknown field", "sort=-password", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			q := FromQuery(values)
+			sorts := q.Sort("sort", sortColumns)
+			if !reflect.DeepEqual(sorts, tt.want) {
+				t.Errorf("got %+v, want %+v", sorts, tt.want)
+			}
+			if err := q.Err(); (err == nil) != tt.valid {
+				t.Errorf("got error %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		sorts []Sort
+		want  string
+	}{
+		{"default", nil, `SELECT * FROM "rows" ORDER BY created_at DESC, id DESC`},
+		{"single", []Sort{{Column: "total_amount"}}, `SELECT * FROM "rows" ORDER BY total_amount ASC,id DESC`},
+		{"multiple", []Sort{{Column: "status", Desc: true}, {Column: "created_at"}},
+			`SELECT * FROM "rows" ORDER BY status DESC,created_at ASC,id DESC`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []row
+			stmt := OrderBy(db.Model(&row{}), tt.sorts).Find(&rows).Statement
+			if got := stmt.SQL.String(); got != tt.want {
+				t.Errorf("got SQL %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPage(t *testing.T) {
+	sorts := []Sort{{Column: "total_amount"}}
+	page := &pagination.Page{First: 10}
+
+	tests := []struct {
+		name  string
+		page  *pagination.Page
+		sorts []Sort
+		valid bool
+	}{
+		{"offset with sort", nil, sorts, true},
+		{"cursor without sort", page, nil, true},
+		{"cursor with sort", page, sorts, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPage(tt.page, tt.sorts)
+			if tt.valid {
+				if err != nil {
+					t.Errorf("got %v, want nil", err)
+				}
+				return
+			}
+			if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrInvalidInput {
+				t.Errorf("got %v, want an %s error", err, errors.ErrInvalidInput)
+			}
+		})
+	}
+}
+
+func TestErr(t *testing.T) {
+	values, _ := url.ParseQuery("status=lost&from=yesterday&min=-1&max=5")
+	q := FromQuery(values)
+	q.Enum("status", "pending", "paid")
+	q.Time("from")
+	q.FloatRange("min", q.Float("min"), q.Float("max"))
+
+	err := q.Err()
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.Code != errors.ErrValidationFailed {
+		t.Fatalf("got %v, want a %s error", err, errors.ErrValidationFailed)
+	}
+	var fields []string
+	for _, f := range appErr.Fields {
+		fields = append(fields, f.Field)
+	}
+	if want := []string{"status", "from", "min"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("got failed fields %v, want %v", fields, want)
+	}
+}
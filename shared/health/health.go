@@ -0,0 +1,204 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/microservices-go/shared/rabbitmq"
+)
+
+// Check and report statuses
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFail     = "fail"
+)
+
+// CheckFunc pings a single dependency
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult is the outcome of a single dependency check
+type CheckResult struct {
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is the JSON body returned by the readiness endpoint
+type Report struct {
+	Status    string                 `json:"status"`
+	Service   string                 `json:"service"`
+	Checks    map[string]CheckResult `json:"checks"`
+	CheckedAt time.Time              `json:"checked_at"`
+}
+
+type check struct {
+	name     string
+	fn       CheckFunc
+	critical bool
+}
+
+// Registry runs dependency checks with a per-check timeout and caches the
+// result so frequent probes don't hammer the dependencies
+type Registry struct {
+	service  string
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu       sync.Mutex
+	checks   []check
+	cached   *Report
+	cachedAt time.Time
+}
+
+// NewRegistry creates a new health check registry
+func NewRegistry(service string) *Registry {
+	return &Registry{
+		service:  service,
+		timeout:  2 * time.Second,
+		cacheTTL: 5 * time.Second,
+	}
+}
+
+// Register adds a critical check; when it fails the service is not ready
+func (r *Registry) Register(name string, fn CheckFunc) {
+	r.add(name, fn, true)
+}
+
+// RegisterOptional adds a check whose failure only degrades the service
+func (r *Registry) RegisterOptional(name string, fn CheckFunc) {
+	r.add(name, fn, false)
+}
+
+func (r *Registry) add(name string, fn CheckFunc, critical bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks = append(r.checks, check{name: name, fn: fn, critical: critical})
+	r.cached = nil
+}
+
+// Run returns the cached report or runs all checks concurrently
+func (r *Registry) Run(ctx context.Context) *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cached != nil && time.Since(r.cachedAt) < r.cacheTTL {
+		return r.cached
+	}
+
+	results := make([]CheckResult, len(r.checks))
+	var wg sync.WaitGroup
+	for i, c := range r.checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = r.runCheck(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := &Report{
+		Status:    StatusOK,
+		Service:   r.service,
+		Checks:    make(map[string]CheckResult, len(r.checks)),
+		CheckedAt: time.Now().UTC(),
+	}
+	for i, c := range r.checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status == StatusDown {
+			if c.critical {
+				report.Status = StatusFail
+			} else if report.Status == StatusOK {
+				report.Status = StatusDegraded
+			}
+		}
+	}
+
+	r.cached = report
+	r.cachedAt = time.Now()
+	return report
+}
+
+func (r *Registry) runCheck(ctx context.Context, c check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.fn(ctx)
+	result := CheckResult{
+		Status:    StatusUp,
+		Critical:  c.critical,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LivenessHandler reports that the process is running; it never checks dependencies
+func (r *Registry) LivenessHandler(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status":  StatusOK,
+		"service": r.service,
+	})
+}
+
+// ReadinessHandler returns the per-dependency report, 503 when a critical check fails
+func (r *Registry) ReadinessHandler(w http.ResponseWriter, req *http.Request) {
+	// Detach from the request so a probe timeout doesn't poison the cached result
+	report := r.Run(context.WithoutCancel(req.Context()))
+
+	status := http.StatusOK
+	if report.Status == StatusFail {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// SQLCheck pings a database connection pool
+func SQLCheck(db *sql.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// RedisCheck pings a Redis client; a nil client, one that failed to connect
+// at startup, is reported down
+func RedisCheck(client *redis.Client) CheckFunc {
+	return func(ctx context.Context) error {
+		if client == nil {
+			return fmt.Errorf("not connected")
+		}
+		return client.Ping(ctx).Err()
+	}
+}
+
+// AMQPCheck verifies the RabbitMQ connection and channel are open; a nil
+// client is reported down
+func AMQPCheck(client *rabbitmq.Client) CheckFunc {
+	return func(ctx context.Context) error {
+		if client == nil {
+			return fmt.Errorf("not connected")
+		}
+		return client.Ping()
+	}
+}
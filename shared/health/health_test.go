@@ -0,0 +1,139 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	up   CheckFunc = func(ctx context.Context) error { return nil }
+	down CheckFunc = func(ctx context.Context) error { return fmt.Errorf("refused") }
+)
+
+func TestRunStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		critical CheckFunc
+		optional CheckFunc
+		want     string
+	}{
+		{"all up", up, up, StatusOK},
+		{"optional down", up, down, StatusDegraded},
+		{"critical down", down, up, StatusFail},
+		{"both down", down, down, StatusFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry("test")
+			r.Register("database", tt.critical)
+			r.RegisterOptional("redis", tt.optional)
+
+			report := r.Run(context.Background())
+			if report.Status != tt.want {
+				t.Errorf("got status %q, want %q", report.Status, tt.want)
+			}
+			if !report.Checks["database"].Critical || report.Checks["redis"].Critical {
+				t.Errorf("got critical flags %+v", report.Checks)
+			}
+		})
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	r := NewRegistry("test")
+	r.timeout = 10 * time.Millisecond
+	r.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	report := r.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("check ran for %s past its timeout", elapsed)
+	}
+	if got := report.Checks["slow"]; got.Status != StatusDown || got.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("got %+v, want down with %q", got, context.DeadlineExceeded)
+	}
+}
+
+func TestRunCache(t *testing.T) {
+	var calls atomic.Int32
+	r := NewRegistry("test")
+	r.Register("database", func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	})
+
+	first := r.Run(context.Background())
+	if second := r.Run(context.Background()); second != first || calls.Load() != 1 {
+		t.Errorf("got %d runs within the cache TTL, want 1", calls.Load())
+	}
+
+	r.cachedAt = time.Now().Add(-r.cacheTTL)
+	if third := r.Run(context.Background()); third == first || calls.Load() != 2 {
+		t.Errorf("got %d runs after the cache TTL, want 2", calls.Load())
+	}
+
+	r.RegisterOptional("redis", up)
+	if report := r.Run(context.Background()); len(report.Checks) != 2 || calls.Load() != 3 {
+		t.Errorf("got %d checks in %d runs after a register, want 2 in 3", len(report.Checks), calls.Load())
+	}
+}
+
+func TestReadinessHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		critical CheckFunc
+		optional CheckFunc
+		want     int
+	}{
+		{"ok", up, up, http.StatusOK},
+		{"degraded", up, down, http.StatusOK},
+		{"fail", down, up, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry("test")
+			r.Register("database", tt.critical)
+			r.RegisterOptional("redis", tt.optional)
+
+			rec := httptest.NewRecorder()
+			r.ReadinessHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != tt.want {
+				t.Errorf("got %d, want %d", rec.Code, tt.want)
+			}
+			var report Report
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatal(err)
+			}
+			if report.Service != "test" || len(report.Checks) != 2 {
+				t.Errorf("got report %+v", report)
+			}
+		})
+	}
+}
+
+func TestNilClientChecks(t *testing.T) {
+	r := NewRegistry("test")
+	r.RegisterOptional("redis", RedisCheck(nil))
+	r.RegisterOptional("rabbitmq", AMQPCheck(nil))
+
+	report := r.Run(context.Background())
+	if report.Status != StatusDegraded {
+		t.Errorf("got status %q, want %q", report.Status, StatusDegraded)
+	}
+	for name, result := range report.Checks {
+		if result.Status != StatusDown {
+			t.Errorf("%s: got %q, want %q", name, result.Status, StatusDown)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// HTTP metrics (RED: rate, errors, duration) labelled by chi route pattern
+var (
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total number of HTTP requests",
+	}, []string{"service", "method", "route", "status"})
+
+	HTTPRequestErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_request_errors_total",
+		Help: "Total number of HTTP requests that returned a 5xx status",
+	}, []string{"service", "method", "route"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "method", "route"})
+
+	HTTPRequestsInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Number of HTTP requests currently being served",
+	}, []string{"service"})
+)
+
+// gRPC metrics labelled by full method name and status code
+var (
+	GRPCRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "Total number of gRPC calls handled by the server",
+	}, []string{"service", "method", "code"})
+
+	GRPCRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "gRPC call latency in seconds",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "method"})
+
+	GRPCClientRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_client_handled_total",
+		Help: "Total number of gRPC calls made to upstream services",
+	}, []string{"upstream", "method", "code"})
+)
+
+// RabbitMQ metrics
+var (
+	RabbitMQPublishedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rabbitmq_messages_published_total",
+		Help: "Total number of events published to RabbitMQ",
+	}, []string{"service", "event_type", "status"})
+
+	RabbitMQConsumedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rabbitmq_messages_consumed_total",
+		Help: "Total number of events consumed from RabbitMQ by outcome",
+	}, []string{"queue", "event_type", "outcome"})
+
+	RabbitMQHandleDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rabbitmq_handler_duration_seconds",
+		Help:    "Time spent handling a consumed event in seconds",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"queue", "event_type"})
+)
+
+// Cache metrics
+var (
+	CacheRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_requests_total",
+		Help: "Total number of cache lookups by result (hit, miss, error)",
+	}, []string{"cache", "result"})
+)
+
+// Rate limiting metrics
+var (
+	RateLimitRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimit_rejections_total",
+		Help: "Total number of requests rejected by the rate limiter",
+	}, []string{"limiter", "scope"})
+)
+
+// Authorization metrics
+var (
+	AuthzDenialsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "authz_denials_total",
+		Help: "Total number of requests denied by an authorization policy",
+	}, []string{"action", "role"})
+)
+
+// Result labels for cache lookups
+const (
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+)
+
+// Handler returns the Prometheus scrape handler
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RegisterDBStats exports connection pool statistics for the given database
+func RegisterDBStats(db *sql.DB, dbName string) error {
+	if err := prometheus.Register(collectors.NewDBStatsCollector(db, dbName)); err != nil {
+		if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/logger"
+)
+
+// APIKeyPrefix starts every personal API key, telling them apart from JWTs
+const APIKeyPrefix = "mgo_"
+
+// APIKeyVerifier resolves an API key to the claims of the user it acts for,
+// failing for unknown, expired and revoked keys
+type APIKeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, key string) (*UserClaims, error)
+}
+
+// IsAPIKey reports whether a bearer token is an API key
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// WithAPIKeys makes the middleware accept API keys as bearer tokens. Each
+// request made with one counts against the key's rate limit in limiter,
+// when not nil.
+func (a *AuthMiddleware) WithAPIKeys(verifier APIKeyVerifier, limiter *RateLimiter) *AuthMiddleware {
+	a.apiKeys = verifier
+	a.limiter = limiter
+	return a
+}
+
+// verifyAPIKey resolves an API key and applies its rate limit
+func (a *AuthMiddleware) verifyAPIKey(ctx context.Context, key string) (*UserClaims, *errors.AppError) {
+	claims, err := a.apiKeys.VerifyAPIKey(ctx, key)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.IsServerError() {
+			logger.WithContext(ctx).WithError(err).Error("Failed to verify API key")
+			return nil, errors.New(errors.ErrServiceUnavailable, "Could not verify API key")
+		}
+		return nil, errors.New(errors.ErrUnauthorized, "Invalid, expired or revoked API key")
+	}
+
+	if a.limiter != nil {
+		if err := a.limiter.AllowAPIKey(claims); err != nil {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
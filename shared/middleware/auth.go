@@ -4,9 +4,11 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/errors"
 )
 
 // contextKey for auth context
@@ -14,17 +16,54 @@ type authContextKey string
 
 const UserContextKey authContextKey = "user"
 
-// UserClaims represents JWT claims
+// UserClaims represents JWT claims. Role is the user's primary role;
+// Permissions are everything granted by all of their roles. Callers using
+// an API key also carry its ID, scopes and rate limit, and their
+// Permissions are only those within the scopes. Tokens issued at login
+// carry the ID of their session.
 type UserClaims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID      string   `json:"user_id"`
+	Email       string   `json:"email"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions,omitempty"`
+	APIKeyID    string   `json:"api_key_id,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`
+	RateLimit   int      `json:"rate_limit,omitempty"`
+	SessionID   string   `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// HasPermission reports whether the claims grant permission
+func (c *UserClaims) HasPermission(permission string) bool {
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// FromAPIKey reports whether the caller authenticated with an API key
+func (c *UserClaims) FromAPIKey() bool {
+	return c.APIKeyID != ""
+}
+
+// HasScope reports whether an API key caller's scopes include permission
+func (c *UserClaims) HasScope(permission string) bool {
+	for _, s := range c.Scopes {
+		if s == permission {
+			return true
+		}
+	}
+	return false
+}
+
 // AuthMiddleware validates JWT token
 type AuthMiddleware struct {
 	jwtConfig *config.JWTConfig
+	apiKeys   APIKeyVerifier
+	limiter   *RateLimiter
+	sessions  SessionChecker
 }
 
 // NewAuthMiddleware creates new auth middleware
@@ -41,25 +80,31 @@ func (a *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			http.Error(w, `{"error":{"code":"UNAUTHORIZED","message":"Invalid authorization header format"}}`, http.StatusUnauthorized)
+		claims, err := a.parseHeader(r.Context(), authHeader)
+		if err != nil {
+			err.WriteHTTPResponse(w)
 			return
 		}
 
-		tokenString := parts[1]
-		token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(a.jwtConfig.Secret), nil
-		})
+		ctx := context.WithValue(r.Context(), UserContextKey, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		if err != nil || !token.Valid {
-			http.Error(w, `{"error":{"code":"UNAUTHORIZED","message":"Invalid or expired token"}}`, http.StatusUnauthorized)
+// Optional adds the user to context when a token is sent and lets anonymous
+// requests through; handlers decide what needs a user. Invalid tokens are
+// still rejected.
+func (a *AuthMiddleware) Optional(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			next.ServeHTTP(w, r)
 			return
 		}
 
-		claims, ok := token.Claims.(*UserClaims)
-		if !ok {
-			http.Error(w, `{"error":{"code":"UNAUTHORIZED","message":"Invalid token claims"}}`, http.StatusUnauthorized)
+		claims, err := a.parseHeader(r.Context(), authHeader)
+		if err != nil {
+			err.WriteHTTPResponse(w)
 			return
 		}
 
@@ -68,6 +113,50 @@ func (a *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 	})
 }
 
+// Verify validates a bearer Authorization value outside of HTTP, e.g. from
+// gRPC metadata
+func (a *AuthMiddleware) Verify(ctx context.Context, authHeader string) (*UserClaims, error) {
+	claims, err := a.parseHeader(ctx, authHeader)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// parseHeader validates a bearer Authorization header holding a JWT or an
+// API key, returning the claims or why it was rejected
+func (a *AuthMiddleware) parseHeader(ctx context.Context, authHeader string) (*UserClaims, *errors.AppError) {
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return nil, errors.New(errors.ErrUnauthorized, "Invalid authorization header format")
+	}
+
+	tokenString := parts[1]
+	if IsAPIKey(tokenString) && a.apiKeys != nil {
+		return a.verifyAPIKey(ctx, tokenString)
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return []byte(a.jwtConfig.Secret), nil
+	})
+
+	if err != nil || !token.Valid {
+		return nil, errors.New(errors.ErrUnauthorized, "Invalid or expired token")
+	}
+
+	claims, ok := token.Claims.(*UserClaims)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "Invalid token claims")
+	}
+
+	if a.sessions != nil {
+		if err := a.checkSession(ctx, claims); err != nil {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
+
 // GetUserFromContext extracts user claims from context
 func GetUserFromContext(ctx context.Context) (*UserClaims, bool) {
 	claims, ok := ctx.Value(UserContextKey).(*UserClaims)
@@ -96,14 +185,38 @@ func RequireRole(roles ...string) func(http.Handler) http.Handler {
 	}
 }
 
-// GenerateToken creates a new JWT token
-func GenerateToken(userID, email, role string, jwtConfig *config.JWTConfig) (string, error) {
+// RequirePermission checks if user has the required permission
+func RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := GetUserFromContext(r.Context())
+			if !ok {
+				http.Error(w, `{"error":{"code":"UNAUTHORIZED","message":"User not authenticated"}}`, http.StatusUnauthorized)
+				return
+			}
+
+			if !claims.HasPermission(permission) {
+				http.Error(w, `{"error":{"code":"FORBIDDEN","message":"Insufficient permissions"}}`, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GenerateToken creates a new JWT token for a login session, expiring with
+// it at expiresAt
+func GenerateToken(userID, email, role string, permissions []string, sessionID string, expiresAt time.Time, jwtConfig *config.JWTConfig) (string, error) {
 	claims := UserClaims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:      userID,
+		Email:       email,
+		Role:        role,
+		Permissions: permissions,
+		SessionID:   sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer: jwtConfig.Issuer,
+			Issuer:    jwtConfig.Issuer,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 		},
 	}
 
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// ClientInfoContextKey holds the ClientInfo of the request
+const ClientInfoContextKey authContextKey = "client"
+
+// ClientInfo describes the device a request came from, as recorded with
+// login sessions
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// WithClientInfo returns ctx carrying info
+func WithClientInfo(ctx context.Context, info *ClientInfo) context.Context {
+	return context.WithValue(ctx, ClientInfoContextKey, info)
+}
+
+// GetClientInfo extracts the client info from context
+func GetClientInfo(ctx context.Context) (*ClientInfo, bool) {
+	info, ok := ctx.Value(ClientInfoContextKey).(*ClientInfo)
+	return info, ok
+}
+
+// ClientInfoMiddleware adds the client's IP and user agent to the request
+// context. Use it after RealIP so proxied requests show the real client.
+func ClientInfoMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithClientInfo(r.Context(), &ClientInfo{
+			IP:        stripPort(r.RemoteAddr),
+			UserAgent: r.UserAgent(),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// stripPort returns the host of a host:port address, or addr unchanged
+func stripPort(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RequestTimeoutHeader carries the caller's remaining time budget in milliseconds
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+// DeadlineMiddleware applies the caller's remaining time budget to the request context
+// so handlers and queries stop once the gateway has stopped waiting
+func DeadlineMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ms, err := strconv.ParseInt(r.Header.Get(RequestTimeoutHeader), 10, 64)
+		if err != nil || ms <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), time.Duration(ms)*time.Millisecond)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/microservices-go/shared/metrics"
+)
+
+// MetricsMiddleware records request count, latency and errors per chi route pattern
+func MetricsMiddleware(service string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			inFlight := metrics.HTTPRequestsInFlight.WithLabelValues(service)
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			wrapped := newResponseWriter(w)
+			next.ServeHTTP(wrapped, r)
+
+			// Route pattern is only known after chi has matched the request.
+			// Use it instead of the raw path to keep label cardinality bounded.
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				if pattern := rctx.RoutePattern(); pattern != "" {
+					route = pattern
+				}
+			}
+
+			metrics.HTTPRequestsTotal.WithLabelValues(service, r.Method, route, strconv.Itoa(wrapped.statusCode)).Inc()
+			metrics.HTTPRequestDuration.WithLabelValues(service, r.Method, route).Observe(time.Since(start).Seconds())
+			if wrapped.statusCode >= 500 {
+				metrics.HTTPRequestErrorsTotal.WithLabelValues(service, r.Method, route).Inc()
+			}
+		})
+	}
+}
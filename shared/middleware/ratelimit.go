@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/errors"
 	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/metrics"
 	"github.com/microservices-go/shared/redis"
 )
 
@@ -28,8 +30,35 @@ func NewRateLimiter(redisClient *redis.Client, cfg *config.RateLimitConfig, pref
 
 // Allow checks if the request should be allowed
 func (rl *RateLimiter) Allow(key string) (bool, int, int, int) {
+	return rl.allow(key, rl.config.RequestsPerMinute)
+}
+
+// AllowAPIKey counts a request made with an API key against the key's own
+// limit, or the configured one for keys without, and returns a
+// RATE_LIMIT_EXCEEDED error once it is used up
+func (rl *RateLimiter) AllowAPIKey(claims *UserClaims) *errors.AppError {
+	limit := claims.RateLimit
+	if limit <= 0 {
+		limit = rl.config.RequestsPerMinute
+	}
+
+	allowed, current, limit, _ := rl.allow("apikey:"+claims.APIKeyID, limit)
+	if allowed {
+		return nil
+	}
+
+	metrics.RateLimitRejectionsTotal.WithLabelValues(rl.prefix, "api_key").Inc()
+	log := logger.New("rate-limiter")
+	log.WithField("api_key_id", claims.APIKeyID).
+		WithField("current", current).
+		WithField("limit", limit).
+		Warn("Rate limit exceeded for API key")
+	return errors.New(errors.ErrRateLimit, "API key rate limit exceeded, please try again later")
+}
+
+// allow counts a request under key against limit for the configured window
+func (rl *RateLimiter) allow(key string, limit int) (bool, int, int, int) {
 	window := time.Duration(rl.config.WindowSeconds) * time.Second
-	limit := rl.config.RequestsPerMinute
 
 	fullKey := fmt.Sprintf("ratelimit:%s:%s", rl.prefix, key)
 
@@ -70,6 +99,7 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusTooManyRequests)
 			w.Write([]byte(`{"error":{"code":"RATE_LIMIT_EXCEEDED","message":"Too many requests, please try again later"}}`))
+			metrics.RateLimitRejectionsTotal.WithLabelValues(rl.prefix, "ip").Inc()
 
 			log := logger.New("rate-limiter")
 			log.WithField("key", key).
@@ -102,6 +132,7 @@ func (rl *RateLimiter) PerUserMiddleware(next http.Handler) http.Handler {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusTooManyRequests)
 			w.Write([]byte(`{"error":{"code":"RATE_LIMIT_EXCEEDED","message":"Too many requests, please try again later"}}`))
+			metrics.RateLimitRejectionsTotal.WithLabelValues(rl.prefix, "user").Inc()
 
 			log := logger.New("rate-limiter")
 			log.WithField("user", key).
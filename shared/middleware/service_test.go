@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/microservices-go/shared/config"
+)
+
+// newServiceKey returns a fresh key pair in the encoding of ServiceAuthConfig
+func newServiceKey(t *testing.T) (private, public string) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(privateDER), base64.StdEncoding.EncodeToString(publicDER)
+}
+
+func TestServiceAuth(t *testing.T) {
+	gatewayKey, gatewayPublic := newServiceKey(t)
+	paymentKey, paymentPublic := newServiceKey(t)
+	strangerKey, _ := newServiceKey(t)
+	publicKeys := map[string]string{"gateway": gatewayPublic, "payment-service": paymentPublic}
+
+	serviceConfig := &config.ServiceAuthConfig{Service: "gateway", SigningKey: gatewayKey, PublicKeys: publicKeys, TTL: 60}
+	jwtConfig := &config.JWTConfig{Secret: "user-secret", Issuer: "test"}
+	user := &UserClaims{UserID: "u1", Role: "user"}
+
+	sign := func(t *testing.T, audience string, cfg *config.ServiceAuthConfig) string {
+		token, err := GenerateServiceToken(audience, user, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + token
+	}
+	userToken, err := GenerateToken("u1", "u1@example.com", "user", nil, "s1", time.Now().Add(time.Hour), jwtConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := *serviceConfig
+	expired.TTL = -60
+	// Another service's key in the gateway's name, and an unknown caller
+	impersonated := *serviceConfig
+	impersonated.SigningKey = paymentKey
+	stranger := &config.ServiceAuthConfig{Service: "stranger", SigningKey: strangerKey, TTL: 60}
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"service token", sign(t, "order-service", serviceConfig), http.StatusOK},
+		{"missing token", "", http.StatusUnauthorized},
+		{"end-user token", "Bearer " + userToken, http.StatusUnauthorized},
+		{"other audience", sign(t, "payment-service", serviceConfig), http.StatusUnauthorized},
+		{"expired", sign(t, "order-service", &expired), http.StatusUnauthorized},
+		{"signed with another caller's key", sign(t, "order-service", &impersonated), http.StatusUnauthorized},
+		{"unknown caller", sign(t, "order-service", stranger), http.StatusUnauthorized},
+	}
+
+	auth, err := NewServiceAuth(&config.ServiceAuthConfig{PublicKeys: publicKeys}, "order-service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := auth.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				service, _ := GetServiceFromContext(r.Context())
+				claims, _ := GetUserFromContext(r.Context())
+				if service == nil || service.Subject != "gateway" || claims == nil || claims.UserID != user.UserID {
+					t.Errorf("got service %+v and user %+v, want gateway on behalf of %s", service, claims, user.UserID)
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/batch", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("got status %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
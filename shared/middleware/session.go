@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/logger"
+)
+
+// SessionChecker reports whether the login session a token was issued for
+// is still active, failing once it was revoked or has expired
+type SessionChecker interface {
+	CheckSession(ctx context.Context, userID, sessionID string) error
+}
+
+// WithSessions makes the middleware reject tokens whose session is no longer
+// active, and tokens issued without a session
+func (a *AuthMiddleware) WithSessions(checker SessionChecker) *AuthMiddleware {
+	a.sessions = checker
+	return a
+}
+
+// checkSession rejects claims whose session is not active
+func (a *AuthMiddleware) checkSession(ctx context.Context, claims *UserClaims) *errors.AppError {
+	if claims.SessionID == "" {
+		return errors.New(errors.ErrUnauthorized, "Token has no session, please sign in again")
+	}
+
+	if err := a.sessions.CheckSession(ctx, claims.UserID, claims.SessionID); err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.IsServerError() {
+			logger.WithContext(ctx).WithError(err).Error("Failed to check session")
+			return errors.New(errors.ErrServiceUnavailable, "Could not check session")
+		}
+		return errors.New(errors.ErrUnauthorized, "Session has been revoked or has expired")
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/errors"
+)
+
+// fakeSessions reports the sessions in active as active and fails for the rest
+type fakeSessions struct {
+	active map[string]bool
+	err    error
+}
+
+func (f *fakeSessions) CheckSession(ctx context.Context, userID, sessionID string) error {
+	if f.err != nil {
+		return f.err
+	}
+	if !f.active[sessionID] {
+		return errors.New(errors.ErrUnauthorized, "Session has been revoked")
+	}
+	return nil
+}
+
+func TestAuthenticateChecksSession(t *testing.T) {
+	jwtConfig := &config.JWTConfig{Secret: "user-secret", Issuer: "test"}
+	token := func(t *testing.T, sessionID string, expiresAt time.Time) string {
+		signed, err := GenerateToken("u1", "u1@example.com", "user", nil, sessionID, expiresAt, jwtConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + signed
+	}
+	later := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		header   string
+		sessions *fakeSessions
+		want     int
+	}{
+		{"active session", token(t, "s1", later), &fakeSessions{active: map[string]bool{"s1": true}}, http.StatusOK},
+		{"revoked session", token(t, "s2", later), &fakeSessions{active: map[string]bool{"s1": true}}, http.StatusUnauthorized},
+		{"no session", token(t, "", later), &fakeSessions{active: map[string]bool{"": true}}, http.StatusUnauthorized},
+		{"expired token", token(t, "s1", time.Now().Add(-time.Minute)), &fakeSessions{active: map[string]bool{"s1": true}}, http.StatusUnauthorized},
+		{"checker down", token(t, "s1", later), &fakeSessions{err: errors.New(errors.ErrServiceUnavailable, "down")}, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := NewAuthMiddleware(jwtConfig).WithSessions(tt.sessions)
+			handler := auth.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", tt.header)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("got %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/response"
+	"gorm.io/gorm"
+)
+
+// Page size limits shared by offset and cursor paging
+const (
+	DefaultSize = 10
+	MaxSize     = 100
+)
+
+// Cursor is the keyset position of a row in the (created_at, id) ordering
+type Cursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        string    `json:"i"`
+}
+
+// NewCursor creates a cursor for a row
+func NewCursor(createdAt time.Time, id string) Cursor {
+	return Cursor{CreatedAt: createdAt, ID: id}
+}
+
+// Encode returns the opaque string form of the cursor
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses an opaque cursor string
+func Decode(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New(errors.ErrInvalidInput, "Invalid cursor")
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" || c.CreatedAt.IsZero() {
+		return nil, errors.New(errors.ErrInvalidInput, "Invalid cursor")
+	}
+	return &c, nil
+}
+
+// Page holds Relay-style cursor arguments. Rows are ordered newest first;
+// first/after walk forward (older), last/before walk backward (newer).
+type Page struct {
+	First  int
+	After  *Cursor
+	Last   int
+	Before *Cursor
+}
+
+// FromQuery parses first/after/last/before query parameters. It returns nil
+// when none are present so callers can fall back to limit/offset paging.
+func FromQuery(q url.Values) (*Page, error) {
+	first, after := q.Get("first"), q.Get("after")
+	last, before := q.Get("last"), q.Get("before")
+	if first == "" && after == "" && last == "" && before == "" {
+		return nil, nil
+	}
+
+	page := &Page{}
+	var err error
+	if page.First, err = parseSize(first, "first"); err != nil {
+		return nil, err
+	}
+	if page.Last, err = parseSize(last, "last"); err != nil {
+		return nil, err
+	}
+	if page.First > 0 && page.Last > 0 {
+		return nil, errors.New(errors.ErrInvalidInput, "first and last cannot be combined")
+	}
+	if after != "" {
+		if page.After, err = Decode(after); err != nil {
+			return nil, err
+		}
+	}
+	if before != "" {
+		if page.Before, err = Decode(before); err != nil {
+			return nil, err
+		}
+	}
+
+	if page.First == 0 && page.Last == 0 {
+		// Bare before cursor means "the page before this one"
+		if page.Before != nil && page.After == nil {
+			page.Last = DefaultSize
+		} else {
+			page.First = DefaultSize
+		}
+	}
+	return page, nil
+}
+
+func parseSize(value, name string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, errors.New(errors.ErrInvalidInput, name+" must be a non-negative integer")
+	}
+	if n > MaxSize {
+		n = MaxSize
+	}
+	return n, nil
+}
+
+// Backward reports whether the page is fetched with last/before
+func (p *Page) Backward() bool {
+	return p.Last > 0
+}
+
+// Size returns the requested number of rows
+func (p *Page) Size() int {
+	if p.Backward() {
+		return p.Last
+	}
+	return p.First
+}
+
+// Scope applies the keyset conditions, ordering and a one-row look-ahead limit
+func (p *Page) Scope(db *gorm.DB) *gorm.DB {
+	if p.After != nil {
+		db = db.Where("(created_at, id) < (?, ?)", p.After.CreatedAt, p.After.ID)
+	}
+	if p.Before != nil {
+		db = db.Where("(created_at, id) > (?, ?)", p.Before.CreatedAt, p.Before.ID)
+	}
+	if p.Backward() {
+		db = db.Order("created_at ASC, id ASC")
+	} else {
+		db = db.Order("created_at DESC, id DESC")
+	}
+	return db.Limit(p.Size() + 1)
+}
+
+// Trim drops the look-ahead row, restores newest-first order and builds the page info
+func Trim[T any](items []T, p *Page, cursor func(T) Cursor) ([]T, response.PageInfo) {
+	more := len(items) > p.Size()
+	if more {
+		items = items[:p.Size()]
+	}
+
+	info := response.PageInfo{}
+	if p.Backward() {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+		info.HasPreviousPage = more
+		info.HasNextPage = p.Before != nil
+	} else {
+		info.HasNextPage = more
+		info.HasPreviousPage = p.After != nil
+	}
+
+	if len(items) > 0 {
+		info.StartCursor = cursor(items[0]).Encode()
+		info.EndCursor = cursor(items[len(items)-1]).Encode()
+	}
+	return items, info
+}
@@ -0,0 +1,203 @@
+package pagination
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/microservices-go/shared/errors"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type row struct {
+	ID        string
+	CreatedAt time.Time
+}
+
+func rowCursor(r row) Cursor {
+	return NewCursor(r.CreatedAt, r.ID)
+}
+
+// dryRun opens a database that builds statements without running them
+func dryRun(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestDecode(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		cursor string
+		valid  bool
+	}{
+		{"encoded", NewCursor(at, "order-1").Encode(), true},
+		{"not base64", "not a cursor!", false},
+		{"not json", "bm90IGpzb24", false},
+		{"no id", NewCursor(at, "").Encode(), false},
+		{"no timestamp", NewCursor(time.Time{}, "order-1").Encode(), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Decode(tt.cursor)
+			if !tt.valid {
+				if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrInvalidInput {
+					t.Errorf("got %v, want an %s error", err, errors.ErrInvalidInput)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !c.CreatedAt.Equal(at) || c.ID != "order-1" {
+				t.Errorf("got %+v", c)
+			}
+		})
+	}
+}
+
+func TestFromQuery(t *testing.T) {
+	cursor := NewCursor(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), "order-1")
+
+	tests := []struct {
+		name      string
+		query     string
+		want      *Page
+		wantError bool
+	}{
+		{"none", "limit=5&offset=10", nil, false},
+		{"first", "first=5", &Page{First: 5}, false},
+		{"first after", "first=5&after=" + cursor.Encode(), &Page{First: 5, After: &cursor}, false},
+		{"bare after", "after=" + cursor.Encode(), &Page{First: DefaultSize, After: &cursor}, false},
+		{"last before", "last=5&before=" + cursor.Encode(), &Page{Last: 5, Before: &cursor}, false},
+		{"bare before", "before=" + cursor.Encode(), &Page{Last: DefaultSize, Before: &cursor}, false},
+		{"clamped", "first=1000", &Page{First: MaxSize}, false},
+		{"first and last", "first=5&last=5", nil, true},
+		{"negative", "first=-1", nil, true},
+		{"not a number", "last=ten", nil, true},
+		{"malformed cursor", "after=garbage", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			page, err := FromQuery(q)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("got error %v, want error %v", err, tt.wantError)
+			}
+			if !reflect.DeepEqual(page, tt.want) {
+				t.Errorf("got %+v, want %+v", page, tt.want)
+			}
+		})
+	}
+}
+
+func TestScope(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cursor := NewCursor(at, "order-1")
+
+	tests := []struct {
+		name string
+		page *Page
+		sql  string
+		vars []interface{}
+	}{
+		{"first", &Page{First: 5},
+			`SELECT * FROM "rows" ORDER BY created_at DESC, id DESC LIMIT $1`,
+			[]interface{}{6}},
+		{"first after", &Page{First: 5, After: &cursor},
+			`SELECT * FROM "rows" WHERE (created_at, id) < ($1, $2) ORDER BY created_at DESC, id DESC LIMIT $3`,
+			[]interface{}{at, "order-1", 6}},
+		{"last before", &Page{Last: 3, Before: &cursor},
+			`SELECT * FROM "rows" WHERE (created_at, id) > ($1, $2) ORDER BY created_at ASC, id ASC LIMIT $3`,
+			[]interface{}{at, "order-1", 4}},
+		{"between", &Page{First: 2, After: &cursor, Before: &cursor},
+			`SELECT * FROM "rows" WHERE (created_at, id) < ($1, $2) AND (created_at, id) > ($3, $4) ORDER BY created_at DESC, id DESC LIMIT $5`,
+			[]interface{}{at, "order-1", at, "order-1", 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []row
+			stmt := dryRun(t).Scopes(tt.page.Scope).Find(&rows).Statement
+			if got := stmt.SQL.String(); got != tt.sql {
+				t.Errorf("got SQL %s\nwant %s", got, tt.sql)
+			}
+			if !reflect.DeepEqual(stmt.Vars, tt.vars) {
+				t.Errorf("got vars %v, want %v", stmt.Vars, tt.vars)
+			}
+		})
+	}
+}
+
+func TestTrim(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	// rows returns n rows in the order Scope fetches them
+	rows := func(backward bool, n int) []row {
+		items := make([]row, n)
+		for i := range items {
+			offset := -i
+			if backward {
+				offset = i
+			}
+			items[i] = row{ID: string(rune('a' + i)), CreatedAt: at.Add(time.Duration(offset) * time.Minute)}
+		}
+		return items
+	}
+	cursor := NewCursor(at, "z")
+
+	tests := []struct {
+		name        string
+		page        *Page
+		items       []row
+		ids         string
+		hasNext     bool
+		hasPrevious bool
+		cursors     bool
+	}{
+		{"first page with more", &Page{First: 2}, rows(false, 3), "ab", true, false, true},
+		{"last forward page", &Page{First: 2, After: &cursor}, rows(false, 2), "ab", false, true, true},
+		{"backward page with more", &Page{Last: 2, Before: &cursor}, rows(true, 3), "ba", true, true, true},
+		{"first backward page", &Page{Last: 2}, rows(true, 2), "ba", false, false, true},
+		{"empty", &Page{First: 2, After: &cursor}, nil, "", false, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, info := Trim(tt.items, tt.page, rowCursor)
+
+			ids := ""
+			for _, item := range items {
+				ids += item.ID
+			}
+			if ids != tt.ids {
+				t.Errorf("got rows %q, want %q", ids, tt.ids)
+			}
+			if info.HasNextPage != tt.hasNext || info.HasPreviousPage != tt.hasPrevious {
+				t.Errorf("got hasNextPage %v, hasPreviousPage %v; want %v, %v",
+					info.HasNextPage, info.HasPreviousPage, tt.hasNext, tt.hasPrevious)
+			}
+			if !tt.cursors {
+				if info.StartCursor != "" || info.EndCursor != "" {
+					t.Errorf("got cursors %q, %q on an empty page", info.StartCursor, info.EndCursor)
+				}
+				return
+			}
+			if info.StartCursor != rowCursor(items[0]).Encode() || info.EndCursor != rowCursor(items[len(items)-1]).Encode() {
+				t.Errorf("got cursors %q, %q", info.StartCursor, info.EndCursor)
+			}
+		})
+	}
+}
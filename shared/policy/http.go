@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/microservices-go/shared/errors"
+)
+
+// Owner resolves the ID of the user owning the resource a request targets
+type Owner func(r *http.Request) (string, error)
+
+// Require enforces rule on a route. owner may be nil for rules without an
+// ownership clause; its errors, e.g. NOT_FOUND, are written as-is.
+func Require(rule Rule, owner Owner) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ownerID := ""
+			if owner != nil {
+				id, err := owner(r)
+				if err != nil {
+					writeError(w, err)
+					return
+				}
+				ownerID = id
+			}
+
+			if err := rule.Authorize(r.Context(), ownerID); err != nil {
+				writeError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Param resolves the owner from a route parameter holding a user ID
+func Param(name string) Owner {
+	return func(r *http.Request) (string, error) {
+		return chi.URLParam(r, name), nil
+	}
+}
+
+// writeError writes an AppError as-is and anything else as an internal error
+func writeError(w http.ResponseWriter, err error) {
+	if appErr, ok := err.(*errors.AppError); ok {
+		appErr.WriteHTTPResponse(w)
+		return
+	}
+	errors.New(errors.ErrInternalServer, "Failed to authorize request").WriteHTTPResponse(w)
+}
@@ -0,0 +1,144 @@
+package policy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/middleware"
+)
+
+const (
+	ownerID = "owner"
+	otherID = "other"
+
+	permRead  = "things:read"
+	permWrite = "things:write"
+)
+
+// callers are the subjects every rule is evaluated for
+var callers = map[string]*middleware.UserClaims{
+	"admin":  {UserID: "staff-admin", Role: RoleAdmin, Permissions: []string{permRead, permWrite}},
+	"reader": {UserID: "staff-reader", Role: RoleSupport, Permissions: []string{permRead}},
+	"owner":  {UserID: ownerID, Role: RoleUser},
+	"other":  {UserID: otherID, Role: RoleUser},
+
+	// API keys scoped to reading
+	"admin key": {UserID: "staff-admin", Role: RoleAdmin, Permissions: []string{permRead}, APIKeyID: "k1", Scopes: []string{permRead}},
+	"owner key": {UserID: ownerID, Role: RoleUser, APIKeyID: "k2", Scopes: []string{permRead}},
+}
+
+func TestRuleAllows(t *testing.T) {
+	tests := []struct {
+		rule Rule
+		want map[string]bool
+	}{
+		{Authenticated("a"), map[string]bool{"admin": true, "reader": true, "owner": true, "other": true, "admin key": true, "owner key": true}},
+		{Permission("a", permWrite), map[string]bool{"admin": true}},
+		{Permission("a", permRead), map[string]bool{"admin": true, "reader": true, "admin key": true}},
+		{OwnerOr("a", permWrite), map[string]bool{"admin": true, "owner": true}},
+		{OwnerOr("a", permRead), map[string]bool{"admin": true, "reader": true, "owner": true, "admin key": true, "owner key": true}},
+	}
+
+	for _, tt := range tests {
+		for name, claims := range callers {
+			if got := tt.rule.Allows(claims, ownerID); got != tt.want[name] {
+				t.Errorf("%+v allows %s = %v, want %v", tt.rule, name, got, tt.want[name])
+			}
+		}
+		if tt.rule.Allows(nil, ownerID) {
+			t.Errorf("%+v allows anonymous callers", tt.rule)
+		}
+	}
+}
+
+func TestOwnerClauseNeedsOwner(t *testing.T) {
+	if OwnerOr("a", permWrite).Allows(&middleware.UserClaims{Role: RoleUser}, "") {
+		t.Error("an empty owner ID must not match a caller without a user ID")
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	rule := OwnerOr("a", permWrite)
+
+	if err := rule.Authorize(context.Background(), ownerID); code(err) != errors.ErrUnauthorized {
+		t.Errorf("anonymous: got %v, want UNAUTHORIZED", err)
+	}
+	if err := rule.Authorize(withCaller("other"), ownerID); code(err) != errors.ErrForbidden {
+		t.Errorf("other: got %v, want FORBIDDEN", err)
+	}
+	if err := rule.Authorize(withCaller("owner"), ownerID); err != nil {
+		t.Errorf("owner: got %v, want nil", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	items := []string{ownerID, otherID, ownerID}
+	self := func(s string) string { return s }
+
+	if got := Filter(withCaller("owner"), OwnerOr("a", permRead), items, self); len(got) != 2 {
+		t.Errorf("owner: got %v, want only the owned items", got)
+	}
+	if got := Filter(withCaller("reader"), OwnerOr("a", permRead), items, self); len(got) != 3 {
+		t.Errorf("reader: got %v, want every item", got)
+	}
+	if got := Filter(context.Background(), OwnerOr("a", permRead), items, self); len(got) != 0 {
+		t.Errorf("anonymous: got %v, want none", got)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	notFound := func(r *http.Request) (string, error) {
+		return "", errors.New(errors.ErrNotFound, "Order not found")
+	}
+
+	tests := []struct {
+		name   string
+		rule   Rule
+		owner  Owner
+		caller string
+		want   int
+	}{
+		{"anonymous", Authenticated("a"), nil, "", http.StatusUnauthorized},
+		{"permission missing", Permission("a", permWrite), nil, "reader", http.StatusForbidden},
+		{"permission held", Permission("a", permWrite), nil, "admin", http.StatusOK},
+		{"owner from param", OwnerOr("a", permWrite), Param("id"), "owner", http.StatusOK},
+		{"not owner from param", OwnerOr("a", permWrite), Param("id"), "other", http.StatusForbidden},
+		{"owner lookup fails", OwnerOr("a", permWrite), notFound, "admin", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := chi.NewRouter()
+			r.With(Require(tt.rule, tt.owner)).Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/"+ownerID, nil)
+			if tt.caller != "" {
+				req = req.WithContext(withCaller(tt.caller))
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("got status %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func withCaller(name string) context.Context {
+	return context.WithValue(context.Background(), middleware.UserContextKey, callers[name])
+}
+
+func code(err error) errors.ErrorCode {
+	if appErr, ok := err.(*errors.AppError); ok {
+		return appErr.Code
+	}
+	return ""
+}
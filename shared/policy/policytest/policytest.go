@@ -0,0 +1,201 @@
+// Package policytest checks a service's role matrix against its real HTTP
+// routes and gRPC methods: every endpoint is called by admin, support,
+// finance, the owner of the fixtures, another user and an anonymous caller,
+// and whether the call got through is compared with the matrix.
+package policytest
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/middleware"
+	"github.com/microservices-go/shared/policy"
+	"github.com/microservices-go/shared/rpc"
+	"github.com/microservices-go/shared/rpc/rpctest"
+)
+
+// OwnerID is the user owning the fixtures the endpoints act on, and OtherID
+// another user
+const (
+	OwnerID = "6f0c1a52-3d4e-4b8a-9c61-0e2f5a7b8c90"
+	OtherID = "0b7e2d94-51a3-4c6f-8e20-9d1c3b4a5f67"
+)
+
+// ErrFiltered is returned by gRPC calls whose response left out the owner's
+// resource, which is how batch lookups deny it
+var ErrFiltered = stderrors.New("owner's resource filtered out")
+
+// Endpoint is one row of the matrix: whether each caller may call it, and
+// how to call it over HTTP and gRPC. Either call may be nil.
+type Endpoint struct {
+	Name                                  string
+	Admin, Support, Finance, Owner, Other bool
+
+	// Internal endpoints take a service token on behalf of the caller
+	// instead of the caller's own token
+	Internal bool
+
+	HTTP *Request
+	GRPC func(ctx context.Context) error
+}
+
+// Request is an HTTP call. When Returns is set, the call only counts as
+// allowed if the response body contains it, e.g. the owner's resource ID in
+// the result of a batch lookup.
+type Request struct {
+	Method  string
+	Path    string
+	Body    string
+	Returns string
+}
+
+// Matrix holds the service under test
+type Matrix struct {
+	Service string // audience of service tokens, e.g. "order-service"
+	JWT     *config.JWTConfig
+	Router  http.Handler
+}
+
+// caller is one column of the matrix
+type caller struct {
+	name   string
+	claims *middleware.UserClaims
+	want   func(Endpoint) bool
+}
+
+var callers = []caller{
+	{policy.RoleAdmin, staff(policy.RoleAdmin), func(e Endpoint) bool { return e.Admin }},
+	{policy.RoleSupport, staff(policy.RoleSupport), func(e Endpoint) bool { return e.Support }},
+	{policy.RoleFinance, staff(policy.RoleFinance), func(e Endpoint) bool { return e.Finance }},
+	{"owner", &middleware.UserClaims{UserID: OwnerID, Email: "owner@example.com", Role: policy.RoleUser}, func(e Endpoint) bool { return e.Owner }},
+	{"other", &middleware.UserClaims{UserID: OtherID, Email: "other@example.com", Role: policy.RoleUser}, func(e Endpoint) bool { return e.Other }},
+	{"anonymous", nil, func(Endpoint) bool { return false }},
+}
+
+// staff returns the claims of a user holding one of the seeded roles
+func staff(role string) *middleware.UserClaims {
+	return &middleware.UserClaims{UserID: role, Email: role + "@example.com", Role: role, Permissions: policy.DefaultRoles[role]}
+}
+
+// Run calls every endpoint as every caller and reports where the outcome
+// differs from the matrix. Calls must either succeed or be refused as
+// unauthenticated or forbidden; any other failure is reported too.
+func (m *Matrix) Run(t *testing.T, endpoints []Endpoint) {
+	for _, e := range endpoints {
+		t.Run(e.Name, func(t *testing.T) {
+			for _, c := range callers {
+				authHeader := m.authHeader(t, e, c.claims)
+				want := allowed
+				if !c.want(e) {
+					want = denied
+				}
+
+				if e.HTTP != nil {
+					if got, detail := m.serveHTTP(e.HTTP, authHeader); got != want {
+						t.Errorf("HTTP %s %s as %s: %s (%s), want %s", e.HTTP.Method, e.HTTP.Path, c.name, got, detail, want)
+					}
+				}
+				if e.GRPC != nil {
+					err := e.GRPC(rpc.OutgoingContext(context.Background(), authHeader))
+					if got := grpcOutcome(err); got != want {
+						t.Errorf("gRPC as %s: %s (%v), want %s", c.name, got, err, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+// outcome is how a call ended
+type outcome string
+
+const (
+	allowed outcome = "allowed"
+	denied  outcome = "denied"
+	failed  outcome = "failed"
+)
+
+// authHeader returns the Authorization value of a call by claims, or none
+// for an anonymous call to a user endpoint
+func (m *Matrix) authHeader(t *testing.T, e Endpoint, claims *middleware.UserClaims) string {
+	if e.Internal {
+		return rpctest.ServiceToken(t, m.Service, claims)
+	}
+	if claims == nil {
+		return ""
+	}
+
+	token, err := middleware.GenerateToken(claims.UserID, claims.Email, claims.Role, claims.Permissions, "s-"+claims.UserID, time.Now().Add(time.Hour), m.JWT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
+
+// serveHTTP returns the outcome of the request and its status and body
+func (m *Matrix) serveHTTP(r *Request, authHeader string) (outcome, string) {
+	req := httptest.NewRequest(r.Method, r.Path, strings.NewReader(r.Body))
+	req.Header.Set("Content-Type", "application/json")
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+	rec := httptest.NewRecorder()
+	m.Router.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	detail := http.StatusText(rec.Code) + ": " + strings.TrimSpace(body)
+	switch {
+	case rec.Code == http.StatusUnauthorized || rec.Code == http.StatusForbidden:
+		return denied, detail
+	case rec.Code >= 300:
+		return failed, detail
+	case r.Returns != "" && !strings.Contains(body, r.Returns):
+		return denied, detail
+	}
+	return allowed, detail
+}
+
+// grpcOutcome returns the outcome of a gRPC call that returned err
+func grpcOutcome(err error) outcome {
+	if err == nil {
+		return allowed
+	}
+	if stderrors.Is(err, ErrFiltered) {
+		return denied
+	}
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return denied
+	}
+	return failed
+}
+
+// Drain receives the messages of a stream until it ends, returning
+// ErrFiltered unless one of them matched
+func Drain[T any](recv func() (T, error), match func(T) bool) error {
+	found := false
+	for {
+		msg, err := recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		found = found || match(msg)
+	}
+	if !found {
+		return ErrFiltered
+	}
+	return nil
+}
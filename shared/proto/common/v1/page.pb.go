@@ -0,0 +1,339 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: common/v1/page.proto
+
+package commonv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// PageRequest selects a page of a list by offset or by Relay cursor.
+// Cursor fields take precedence over limit/offset, as in REST list queries.
+type PageRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Limit  int32                  `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset int32                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	First  *int32                 `protobuf:"varint,3,opt,name=first,proto3,oneof" json:"first,omitempty"`
+	After  *string                `protobuf:"bytes,4,opt,name=after,proto3,oneof" json:"after,omitempty"`
+	Last   *int32                 `protobuf:"varint,5,opt,name=last,proto3,oneof" json:"last,omitempty"`
+	Before *string                `protobuf:"bytes,6,opt,name=before,proto3,oneof" json:"before,omitempty"`
+	// Sort keys such as "created_at" or "-amount"; not combinable with cursors
+	Sort          []string `protobuf:"bytes,7,rep,name=sort,proto3" json:"sort,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PageRequest) Reset() {
+	*x = PageRequest{}
+	mi := &file_common_v1_page_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PageRequest) ProtoMessage() {}
+
+func (x *PageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_common_v1_page_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PageRequest.ProtoReflect.Descriptor instead.
+func (*PageRequest) Descriptor() ([]byte, []int) {
+	return file_common_v1_page_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PageRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *PageRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *PageRequest) GetFirst() int32 {
+	if x != nil && x.First != nil {
+		return *x.First
+	}
+	return 0
+}
+
+func (x *PageRequest) GetAfter() string {
+	if x != nil && x.After != nil {
+		return *x.After
+	}
+	return ""
+}
+
+func (x *PageRequest) GetLast() int32 {
+	if x != nil && x.Last != nil {
+		return *x.Last
+	}
+	return 0
+}
+
+func (x *PageRequest) GetBefore() string {
+	if x != nil && x.Before != nil {
+		return *x.Before
+	}
+	return ""
+}
+
+func (x *PageRequest) GetSort() []string {
+	if x != nil {
+		return x.Sort
+	}
+	return nil
+}
+
+// PageMeta describes a returned page, like the meta of REST list responses
+type PageMeta struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Total  int32                  `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	Limit  int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset int32                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	// Set when the list was fetched with cursor fields
+	CursorInfo    *CursorInfo `protobuf:"bytes,4,opt,name=cursor_info,json=cursorInfo,proto3" json:"cursor_info,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PageMeta) Reset() {
+	*x = PageMeta{}
+	mi := &file_common_v1_page_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PageMeta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PageMeta) ProtoMessage() {}
+
+func (x *PageMeta) ProtoReflect() protoreflect.Message {
+	mi := &file_common_v1_page_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PageMeta.ProtoReflect.Descriptor instead.
+func (*PageMeta) Descriptor() ([]byte, []int) {
+	return file_common_v1_page_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PageMeta) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *PageMeta) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *PageMeta) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *PageMeta) GetCursorInfo() *CursorInfo {
+	if x != nil {
+		return x.CursorInfo
+	}
+	return nil
+}
+
+// CursorInfo is the Relay page info of a cursor page
+type CursorInfo struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	StartCursor     string                 `protobuf:"bytes,1,opt,name=start_cursor,json=startCursor,proto3" json:"start_cursor,omitempty"`
+	EndCursor       string                 `protobuf:"bytes,2,opt,name=end_cursor,json=endCursor,proto3" json:"end_cursor,omitempty"`
+	HasNextPage     bool                   `protobuf:"varint,3,opt,name=has_next_page,json=hasNextPage,proto3" json:"has_next_page,omitempty"`
+	HasPreviousPage bool                   `protobuf:"varint,4,opt,name=has_previous_page,json=hasPreviousPage,proto3" json:"has_previous_page,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *CursorInfo) Reset() {
+	*x = CursorInfo{}
+	mi := &file_common_v1_page_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CursorInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CursorInfo) ProtoMessage() {}
+
+func (x *CursorInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_common_v1_page_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CursorInfo.ProtoReflect.Descriptor instead.
+func (*CursorInfo) Descriptor() ([]byte, []int) {
+	return file_common_v1_page_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CursorInfo) GetStartCursor() string {
+	if x != nil {
+		return x.StartCursor
+	}
+	return ""
+}
+
+func (x *CursorInfo) GetEndCursor() string {
+	if x != nil {
+		return x.EndCursor
+	}
+	return ""
+}
+
+func (x *CursorInfo) GetHasNextPage() bool {
+	if x != nil {
+		return x.HasNextPage
+	}
+	return false
+}
+
+func (x *CursorInfo) GetHasPreviousPage() bool {
+	if x != nil {
+		return x.HasPreviousPage
+	}
+	return false
+}
+
+var File_common_v1_page_proto protoreflect.FileDescriptor
+
+const file_common_v1_page_proto_rawDesc = "" +
+	"\n" +
+	"\x14common/v1/page.proto\x12\tcommon.v1\"\xe3\x01\n" +
+	"\vPageRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x05R\x06offset\x12\x19\n" +
+	"\x05first\x18\x03 \x01(\x05H\x00R\x05first\x88\x01\x01\x12\x19\n" +
+	"\x05after\x18\x04 \x01(\tH\x01R\x05after\x88\x01\x01\x12\x17\n" +
+	"\x04last\x18\x05 \x01(\x05H\x02R\x04last\x88\x01\x01\x12\x1b\n" +
+	"\x06before\x18\x06 \x01(\tH\x03R\x06before\x88\x01\x01\x12\x12\n" +
+	"\x04sort\x18\a \x03(\tR\x04sortB\b\n" +
+	"\x06_firstB\b\n" +
+	"\x06_afterB\a\n" +
+	"\x05_lastB\t\n" +
+	"\a_before\"\x86\x01\n" +
+	"\bPageMeta\x12\x14\n" +
+	"\x05total\x18\x01 \x01(\x05R\x05total\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\x126\n" +
+	"\vcursor_info\x18\x04 \x01(\v2\x15.common.v1.CursorInfoR\n" +
+	"cursorInfo\"\x9e\x01\n" +
+	"\n" +
+	"CursorInfo\x12!\n" +
+	"\fstart_cursor\x18\x01 \x01(\tR\vstartCursor\x12\x1d\n" +
+	"\n" +
+	"end_cursor\x18\x02 \x01(\tR\tendCursor\x12\"\n" +
+	"\rhas_next_page\x18\x03 \x01(\bR\vhasNextPage\x12*\n" +
+	"\x11has_previous_page\x18\x04 \x01(\bR\x0fhasPreviousPageB=Z;github.com/microservices-go/shared/proto/common/v1;commonv1b\x06proto3"
+
+var (
+	file_common_v1_page_proto_rawDescOnce sync.Once
+	file_common_v1_page_proto_rawDescData []byte
+)
+
+func file_common_v1_page_proto_rawDescGZIP() []byte {
+	file_common_v1_page_proto_rawDescOnce.Do(func() {
+		file_common_v1_page_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_common_v1_page_proto_rawDesc), len(file_common_v1_page_proto_rawDesc)))
+	})
+	return file_common_v1_page_proto_rawDescData
+}
+
+var file_common_v1_page_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_common_v1_page_proto_goTypes = []any{
+	(*PageRequest)(nil), // 0: common.v1.PageRequest
+	(*PageMeta)(nil),    // 1: common.v1.PageMeta
+	(*CursorInfo)(nil),  // 2: common.v1.CursorInfo
+}
+var file_common_v1_page_proto_depIdxs = []int32{
+	2, // 0: common.v1.PageMeta.cursor_info:type_name -> common.v1.CursorInfo
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_common_v1_page_proto_init() }
+func file_common_v1_page_proto_init() {
+	if File_common_v1_page_proto != nil {
+		return
+	}
+	file_common_v1_page_proto_msgTypes[0].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_common_v1_page_proto_rawDesc), len(file_common_v1_page_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_common_v1_page_proto_goTypes,
+		DependencyIndexes: file_common_v1_page_proto_depIdxs,
+		MessageInfos:      file_common_v1_page_proto_msgTypes,
+	}.Build()
+	File_common_v1_page_proto = out.File
+	file_common_v1_page_proto_goTypes = nil
+	file_common_v1_page_proto_depIdxs = nil
+}
@@ -7,7 +7,11 @@ import (
 	"time"
 
 	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/metrics"
+	"github.com/microservices-go/shared/tracing"
 	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Client wraps RabbitMQ connection
@@ -57,6 +61,17 @@ func (c *Client) Close() error {
 	return nil
 }
 
+// Ping reports an error when the connection or channel has been closed
+func (c *Client) Ping() error {
+	if c.conn == nil || c.conn.IsClosed() {
+		return fmt.Errorf("connection is closed")
+	}
+	if c.channel == nil || c.channel.IsClosed() {
+		return fmt.Errorf("channel is closed")
+	}
+	return nil
+}
+
 // DeclareExchange declares a topic exchange
 func (c *Client) DeclareExchange(name string) error {
 	return c.channel.ExchangeDeclare(
@@ -82,6 +97,19 @@ func (c *Client) DeclareQueue(name string) (amqp.Queue, error) {
 	)
 }
 
+// DeclareTemporaryQueue declares a server-named, exclusive queue that is
+// deleted when the connection closes, for per-instance fan-out consumers
+func (c *Client) DeclareTemporaryQueue() (amqp.Queue, error) {
+	return c.channel.QueueDeclare(
+		"",    // name (server-generated)
+		false, // durable
+		true,  // delete when unused
+		true,  // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+}
+
 // BindQueue binds queue to exchange with routing key
 func (c *Client) BindQueue(queue, exchange, routingKey string) error {
 	return c.channel.QueueBind(
@@ -100,6 +128,13 @@ func (c *Client) Publish(ctx context.Context, exchange, routingKey string, event
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
+	headers := amqp.Table{
+		"trace_id": event.TraceID,
+		"service":  event.Service,
+	}
+	// Propagate W3C trace context (traceparent/tracestate) to consumers
+	tracing.Inject(ctx, headerCarrier(headers))
+
 	return c.channel.PublishWithContext(
 		ctx,
 		exchange,   // exchange
@@ -110,10 +145,7 @@ func (c *Client) Publish(ctx context.Context, exchange, routingKey string, event
 			ContentType: "application/json",
 			Body:        body,
 			Timestamp:   time.Now(),
-			Headers: amqp.Table{
-				"trace_id": event.TraceID,
-				"service":  event.Service,
-			},
+			Headers:     headers,
 		},
 	)
 }
@@ -154,19 +186,41 @@ func (p *Publisher) PublishEvent(ctx context.Context, eventType string, payload
 		return fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
+	routingKey := fmt.Sprintf("%s.%s", p.serviceName, eventType)
+
+	ctx, span := tracing.StartSpan(ctx, routingKey+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "rabbitmq"),
+			attribute.String("messaging.destination.name", p.exchange),
+			attribute.String("messaging.rabbitmq.destination.routing_key", routingKey),
+		),
+	)
+	defer span.End()
+
+	traceID := logger.GetTraceID(ctx)
+	if traceID == "" {
+		traceID = tracing.TraceID(ctx)
+	}
+
 	event := &Event{
 		Type:      eventType,
 		Payload:   data,
 		Timestamp: time.Now(),
 		Service:   p.serviceName,
-		TraceID:   logger.GetTraceID(ctx),
+		TraceID:   traceID,
 	}
 
-	routingKey := fmt.Sprintf("%s.%s", p.serviceName, eventType)
-
 	logger.WithContext(ctx).Infof("Publishing event: %s", eventType)
 
-	return p.client.Publish(ctx, p.exchange, routingKey, event)
+	if err := p.client.Publish(ctx, p.exchange, routingKey, event); err != nil {
+		tracing.RecordError(ctx, err)
+		metrics.RabbitMQPublishedTotal.WithLabelValues(p.serviceName, eventType, "error").Inc()
+		return err
+	}
+
+	metrics.RabbitMQPublishedTotal.WithLabelValues(p.serviceName, eventType, "success").Inc()
+	return nil
 }
 
 // Consumer handles event consumption
@@ -200,40 +254,73 @@ func (c *Consumer) Start(queue string) error {
 			var event Event
 			if err := json.Unmarshal(msg.Body, &event); err != nil {
 				logger.Error("Failed to unmarshal event: " + err.Error())
+				metrics.RabbitMQConsumedTotal.WithLabelValues(queue, "unknown", "malformed").Inc()
 				msg.Nack(false, false)
 				continue
 			}
 
-			ctx := logger.SetTraceID(context.Background(), event.TraceID)
+			ctx := context.Background()
+			if msg.Headers != nil {
+				ctx = tracing.Extract(ctx, headerCarrier(msg.Headers))
+			}
+			ctx = logger.SetTraceID(ctx, event.TraceID)
 
 			handler, ok := c.handlers[event.Type]
 			if !ok {
 				logger.WithContext(ctx).Warnf("No handler for event type: %s", event.Type)
+				metrics.RabbitMQConsumedTotal.WithLabelValues(queue, event.Type, "unhandled").Inc()
 				msg.Ack(false)
 				continue
 			}
 
-			if err := handler(ctx, &event); err != nil {
-				logger.WithContext(ctx).WithError(err).Error("Failed to handle event")
-				msg.Nack(false, true) // requeue
-				continue
-			}
-
-			msg.Ack(false)
+			c.handle(ctx, queue, msg, &event, handler)
 		}
 	}()
 
 	return nil
 }
 
+// handle runs a handler inside a consumer span and acks or requeues the message
+func (c *Consumer) handle(ctx context.Context, queue string, msg amqp.Delivery, event *Event, handler func(context.Context, *Event) error) {
+	ctx, span := tracing.StartSpan(ctx, msg.RoutingKey+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "rabbitmq"),
+			attribute.String("messaging.source.name", queue),
+			attribute.String("messaging.rabbitmq.destination.routing_key", msg.RoutingKey),
+		),
+	)
+	defer span.End()
+
+	start := time.Now()
+	err := handler(ctx, event)
+	metrics.RabbitMQHandleDuration.WithLabelValues(queue, event.Type).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		tracing.RecordError(ctx, err)
+		logger.WithContext(ctx).WithError(err).Error("Failed to handle event")
+		metrics.RabbitMQConsumedTotal.WithLabelValues(queue, event.Type, "requeued").Inc()
+		msg.Nack(false, true) // requeue
+		return
+	}
+
+	metrics.RabbitMQConsumedTotal.WithLabelValues(queue, event.Type, "acked").Inc()
+	msg.Ack(false)
+}
+
 // Common event types
 const (
-	EventUserCreated    = "user.created"
-	EventUserUpdated    = "user.updated"
-	EventUserDeleted    = "user.deleted"
-	EventOrderCreated   = "order.created"
-	EventOrderUpdated   = "order.updated"
-	EventOrderCancelled = "order.cancelled"
-	EventPaymentSuccess = "payment.success"
-	EventPaymentFailed  = "payment.failed"
+	EventUserCreated          = "user.created"
+	EventUserUpdated          = "user.updated"
+	EventUserDeleted          = "user.deleted"
+	EventUserMFAChanged       = "user.mfa_changed"
+	EventUserErasureRequested = "user.erasure_requested"
+	EventUserErased           = "user.erased"
+	EventOrderCreated         = "order.created"
+	EventOrderUpdated         = "order.updated"
+	EventOrderCancelled       = "order.cancelled"
+	EventPaymentCreated       = "payment.created"
+	EventPaymentSuccess       = "payment.success"
+	EventPaymentFailed        = "payment.failed"
+	EventPaymentRefunded      = "payment.refunded"
 )
@@ -45,8 +45,11 @@ func (c *Client) Close() error {
 	return c.client.Close()
 }
 
-// GetClient returns the underlying redis client
+// GetClient returns the underlying redis client, or nil on a nil Client
 func (c *Client) GetClient() *redis.Client {
+	if c == nil {
+		return nil
+	}
 	return c.client
 }
 
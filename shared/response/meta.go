@@ -5,6 +5,17 @@ type Meta struct {
 	Total  int `json:"total"`
 	Limit  int `json:"limit"`
 	Offset int `json:"offset"`
+
+	// PageInfo is set when the list was fetched with cursor arguments
+	PageInfo *PageInfo `json:"page_info,omitempty"`
+}
+
+// PageInfo contains Relay-style cursor metadata for keyset-paginated lists
+type PageInfo struct {
+	StartCursor     string `json:"start_cursor,omitempty"`
+	EndCursor       string `json:"end_cursor,omitempty"`
+	HasNextPage     bool   `json:"has_next_page"`
+	HasPreviousPage bool   `json:"has_previous_page"`
 }
 
 // NewMeta creates a new Meta instance
@@ -33,3 +44,9 @@ func (m Meta) WithOffset(offset int) Meta {
 	m.Offset = offset
 	return m
 }
+
+// WithPageInfo returns a new Meta with cursor page info
+func (m Meta) WithPageInfo(info PageInfo) Meta {
+	m.PageInfo = &info
+	return m
+}
@@ -0,0 +1,31 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/microservices-go/shared/middleware"
+	"github.com/microservices-go/shared/tracing"
+)
+
+// OutgoingContext adds the caller's trace context and, when set, its bearer
+// Authorization value and client info to the metadata of calls made with ctx
+func OutgoingContext(ctx context.Context, authHeader string) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+
+	tracing.Inject(ctx, metadataCarrier(md))
+	if authHeader != "" {
+		md.Set(AuthorizationKey, authHeader)
+	}
+	if info, ok := middleware.GetClientInfo(ctx); ok {
+		md.Set(ClientIPKey, info.IP)
+		md.Set(ClientUserAgentKey, info.UserAgent)
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
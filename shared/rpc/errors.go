@@ -0,0 +1,140 @@
+package rpc
+
+import (
+	"context"
+	stderrors "errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+
+	"github.com/microservices-go/shared/errors"
+)
+
+// errorDomain marks ErrorInfo details that carry an AppError
+const errorDomain = "microservices-go"
+
+// statusCodes maps AppError codes to gRPC codes
+var statusCodes = map[errors.ErrorCode]codes.Code{
+	errors.ErrInvalidInput:       codes.InvalidArgument,
+	errors.ErrValidationFailed:   codes.InvalidArgument,
+	errors.ErrUnauthorized:       codes.Unauthenticated,
+	errors.ErrForbidden:          codes.PermissionDenied,
+	errors.ErrNotFound:           codes.NotFound,
+	errors.ErrConflict:           codes.AlreadyExists,
+	errors.ErrRateLimit:          codes.ResourceExhausted,
+	errors.ErrInternalServer:     codes.Internal,
+	errors.ErrServiceUnavailable: codes.Unavailable,
+	errors.ErrDatabaseError:      codes.Internal,
+}
+
+// errorCodes maps gRPC codes back to AppError codes for statuses without an ErrorInfo
+var errorCodes = map[codes.Code]errors.ErrorCode{
+	codes.InvalidArgument:   errors.ErrInvalidInput,
+	codes.Unauthenticated:   errors.ErrUnauthorized,
+	codes.PermissionDenied:  errors.ErrForbidden,
+	codes.NotFound:          errors.ErrNotFound,
+	codes.AlreadyExists:     errors.ErrConflict,
+	codes.ResourceExhausted: errors.ErrRateLimit,
+	codes.Unavailable:       errors.ErrServiceUnavailable,
+	codes.DeadlineExceeded:  errors.ErrServiceUnavailable,
+	codes.Canceled:          errors.ErrServiceUnavailable,
+}
+
+// ToStatus converts a service error into a gRPC status error. The AppError
+// code, service and details travel in an ErrorInfo and field errors in a
+// BadRequest so FromStatus can rebuild the same AppError on the client.
+func ToStatus(err error, service string) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if stderrors.Is(err, context.DeadlineExceeded) || stderrors.Is(err, context.Canceled) {
+		return status.FromContextError(err).Err()
+	}
+
+	var appErr *errors.AppError
+	if !stderrors.As(err, &appErr) {
+		appErr = errors.Wrap(err, errors.ErrInternalServer, "Internal server error")
+	}
+
+	code, ok := statusCodes[appErr.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+	if appErr.Service != "" {
+		service = appErr.Service
+	}
+
+	info := &errdetails.ErrorInfo{
+		Reason:   string(appErr.Code),
+		Domain:   errorDomain,
+		Metadata: map[string]string{"service": service},
+	}
+	if appErr.Details != "" {
+		info.Metadata["details"] = appErr.Details
+	}
+	details := []protoadapt.MessageV1{info}
+	if len(appErr.Fields) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, f := range appErr.Fields {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       f.Field,
+				Description: f.Message,
+			})
+		}
+		details = append(details, badRequest)
+	}
+
+	st := status.New(code, appErr.Message)
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// FromStatus converts an error returned by a gRPC call into an AppError,
+// attributing it to service when the status does not name one
+func FromStatus(err error, service string) *errors.AppError {
+	var appErr *errors.AppError
+	if stderrors.As(err, &appErr) {
+		return appErr
+	}
+
+	st, _ := status.FromError(err)
+	var fields []errors.FieldError
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			if d.Domain != errorDomain {
+				continue
+			}
+			appErr = errors.New(errors.ErrorCode(d.Reason), st.Message()).
+				WithService(d.Metadata["service"]).
+				WithDetails(d.Metadata["details"])
+		case *errdetails.BadRequest:
+			for _, v := range d.FieldViolations {
+				fields = append(fields, errors.FieldError{Field: v.Field, Message: v.Description})
+			}
+		}
+	}
+
+	if appErr == nil {
+		code, ok := errorCodes[st.Code()]
+		if !ok {
+			code = errors.ErrInternalServer
+		}
+		appErr = errors.New(code, st.Message())
+	}
+	if appErr.Service == "" {
+		appErr.Service = service
+	}
+	if len(fields) > 0 {
+		appErr.Fields = fields
+	}
+	appErr.Err = err
+	return appErr
+}
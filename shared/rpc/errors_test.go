@@ -0,0 +1,84 @@
+package rpc
+
+import (
+	"context"
+	stderrors "errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/microservices-go/shared/errors"
+)
+
+func TestStatusRoundTrip(t *testing.T) {
+	fields := []errors.FieldError{{Field: "email", Message: "is required"}}
+	tests := []struct {
+		name     string
+		err      error
+		wantCode codes.Code
+		want     *errors.AppError
+	}{
+		{
+			name:     "app error",
+			err:      errors.New(errors.ErrNotFound, "Order not found").WithDetails("id o1"),
+			wantCode: codes.NotFound,
+			want:     errors.New(errors.ErrNotFound, "Order not found").WithDetails("id o1").WithService("order-service"),
+		},
+		{
+			name:     "field errors",
+			err:      errors.New(errors.ErrValidationFailed, "Validation failed").WithFields(fields),
+			wantCode: codes.InvalidArgument,
+			want:     errors.New(errors.ErrValidationFailed, "Validation failed").WithFields(fields).WithService("order-service"),
+		},
+		{
+			name:     "service of a forwarded error",
+			err:      errors.New(errors.ErrForbidden, "Forbidden").WithService("user-service"),
+			wantCode: codes.PermissionDenied,
+			want:     errors.New(errors.ErrForbidden, "Forbidden").WithService("user-service"),
+		},
+		{
+			name:     "plain error",
+			err:      stderrors.New("connection reset"),
+			wantCode: codes.Internal,
+			want:     errors.New(errors.ErrInternalServer, "Internal server error").WithDetails("connection reset").WithService("order-service"),
+		},
+		{
+			name:     "deadline",
+			err:      context.DeadlineExceeded,
+			wantCode: codes.DeadlineExceeded,
+			want:     errors.New(errors.ErrServiceUnavailable, context.DeadlineExceeded.Error()).WithService("order-service"),
+		},
+		{
+			name:     "status without details",
+			err:      status.Error(codes.Unauthenticated, "no token"),
+			wantCode: codes.Unauthenticated,
+			want:     errors.New(errors.ErrUnauthorized, "no token").WithService("order-service"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := ToStatus(tt.err, "order-service")
+			if code := status.Code(st); code != tt.wantCode {
+				t.Fatalf("got code %s, want %s", code, tt.wantCode)
+			}
+
+			got := FromStatus(st, "order-service")
+			if got.Err != st {
+				t.Errorf("got wrapped error %v, want the status", got.Err)
+			}
+			got.Err = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToStatusNil(t *testing.T) {
+	if err := ToStatus(nil, "order-service"); err != nil {
+		t.Errorf("got %v, want nil", err)
+	}
+}
@@ -0,0 +1,125 @@
+package rpc
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/microservices-go/shared/pagination"
+	commonv1 "github.com/microservices-go/shared/proto/common/v1"
+	"github.com/microservices-go/shared/response"
+)
+
+// Query turns list request messages into the query parameters of the REST
+// list endpoints, so gRPC servers parse and validate filters and pages with
+// the same ParseListFilter and pagination.FromQuery as the HTTP handlers
+type Query url.Values
+
+// NewQuery creates a query holding the parameters of page
+func NewQuery(page *commonv1.PageRequest) Query {
+	q := Query{}
+	if page == nil {
+		return q
+	}
+	if page.Limit > 0 {
+		q.Int("limit", page.Limit)
+	}
+	if page.Offset > 0 {
+		q.Int("offset", page.Offset)
+	}
+	if page.First != nil {
+		q.Int("first", *page.First)
+	}
+	if page.Last != nil {
+		q.Int("last", *page.Last)
+	}
+	q.String("after", page.After)
+	q.String("before", page.Before)
+	q.List("sort", page.Sort)
+	return q
+}
+
+// Values returns the query parameters
+func (q Query) Values() url.Values {
+	return url.Values(q)
+}
+
+// String sets an optional string parameter
+func (q Query) String(key string, value *string) {
+	if value != nil {
+		url.Values(q).Set(key, *value)
+	}
+}
+
+// List sets a repeated parameter
+func (q Query) List(key string, values []string) {
+	for _, value := range values {
+		url.Values(q).Add(key, value)
+	}
+}
+
+// Int sets an integer parameter
+func (q Query) Int(key string, value int32) {
+	url.Values(q).Set(key, strconv.Itoa(int(value)))
+}
+
+// Float sets an optional number parameter
+func (q Query) Float(key string, value *float64) {
+	if value != nil {
+		url.Values(q).Set(key, strconv.FormatFloat(*value, 'f', -1, 64))
+	}
+}
+
+// Bool sets an optional boolean parameter
+func (q Query) Bool(key string, value *bool) {
+	if value != nil {
+		url.Values(q).Set(key, strconv.FormatBool(*value))
+	}
+}
+
+// Time sets an optional timestamp parameter in RFC 3339 format
+func (q Query) Time(key string, value *timestamppb.Timestamp) {
+	if value != nil {
+		url.Values(q).Set(key, value.AsTime().Format(time.RFC3339Nano))
+	}
+}
+
+// Offset returns the limit and offset parameters of an offset page
+func (q Query) Offset() (int, int) {
+	limit, _ := strconv.Atoi(url.Values(q).Get("limit"))
+	offset, _ := strconv.Atoi(url.Values(q).Get("offset"))
+	return limit, offset
+}
+
+// Page parses the cursor parameters; nil means offset paging
+func (q Query) Page() (*pagination.Page, error) {
+	return pagination.FromQuery(url.Values(q))
+}
+
+// PageMeta converts list metadata into its message form
+func PageMeta(meta response.Meta) *commonv1.PageMeta {
+	m := &commonv1.PageMeta{
+		Total:  int32(meta.Total),
+		Limit:  int32(meta.Limit),
+		Offset: int32(meta.Offset),
+	}
+	if meta.PageInfo != nil {
+		m.CursorInfo = &commonv1.CursorInfo{
+			StartCursor:     meta.PageInfo.StartCursor,
+			EndCursor:       meta.PageInfo.EndCursor,
+			HasNextPage:     meta.PageInfo.HasNextPage,
+			HasPreviousPage: meta.PageInfo.HasPreviousPage,
+		}
+	}
+	return m
+}
+
+// Timestamp converts a time into its message form, leaving zero times unset
+func Timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,228 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/metrics"
+	"github.com/microservices-go/shared/middleware"
+	"github.com/microservices-go/shared/tracing"
+)
+
+// AuthorizationKey is the metadata key carrying the caller's bearer token
+const AuthorizationKey = "authorization"
+
+// ClientIPKey and ClientUserAgentKey are the metadata keys carrying the
+// address and user agent of the end user's device, forwarded by the gateway
+const (
+	ClientIPKey        = "x-client-ip"
+	ClientUserAgentKey = "x-client-user-agent"
+)
+
+// Methods lists the methods of a service, by full name such as
+// /user.v1.UserService/Login, that are not called with end-user tokens
+type Methods struct {
+	Public   []string // accept anonymous callers
+	Internal []string // accept only service tokens, e.g. the gateway's batch lookups
+}
+
+// server holds what the interceptors need to serve one service
+type server struct {
+	service     string
+	auth        *middleware.AuthMiddleware
+	serviceAuth *middleware.ServiceAuth
+	public      map[string]bool
+	internal    map[string]bool
+}
+
+// NewServer creates a gRPC server with tracing, logging, metrics, panic
+// recovery and JWT auth from the authorization metadata. Public methods
+// accept anonymous callers; internal methods take service tokens instead of
+// user tokens and act for their on-behalf-of user. Handlers return
+// AppErrors, which are sent as gRPC statuses.
+func NewServer(service string, auth *middleware.AuthMiddleware, serviceAuth *middleware.ServiceAuth, methods Methods) *grpc.Server {
+	s := &server{
+		service:     service,
+		auth:        auth,
+		serviceAuth: serviceAuth,
+		public:      make(map[string]bool, len(methods.Public)),
+		internal:    make(map[string]bool, len(methods.Internal)),
+	}
+	for _, method := range methods.Public {
+		s.public[method] = true
+	}
+	for _, method := range methods.Internal {
+		s.internal[method] = true
+	}
+
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unary),
+		grpc.ChainStreamInterceptor(s.stream),
+	)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	return srv
+}
+
+func (s *server) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	var resp interface{}
+	err := s.serve(ctx, info.FullMethod, func(ctx context.Context) error {
+		var err error
+		resp, err = handler(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (s *server) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return s.serve(ss.Context(), info.FullMethod, func(ctx context.Context) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	})
+}
+
+// serve runs one call inside a server span with the caller authenticated and
+// converts its error into a gRPC status
+func (s *server) serve(ctx context.Context, method string, call func(context.Context) error) (err error) {
+	start := time.Now()
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	ctx = tracing.Extract(ctx, metadataCarrier(md))
+	ctx, span := tracing.StartSpan(ctx, method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(semconv.RPCSystemGRPC, semconv.RPCService(s.service)),
+	)
+	defer span.End()
+
+	traceID := tracing.TraceID(ctx)
+	if traceID == "" {
+		traceID = uuid.New().String()
+	}
+	ctx = logger.SetTraceID(ctx, traceID)
+	ctx = middleware.WithClientInfo(ctx, clientInfo(ctx, md))
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.WithContext(ctx).WithField("error", r).Error("Panic recovered")
+			err = errors.New(errors.ErrInternalServer, "Internal server error")
+		}
+		err = ToStatus(err, s.service)
+		code := status.Code(err)
+		duration := time.Since(start)
+
+		metrics.GRPCRequestsTotal.WithLabelValues(s.service, method, code.String()).Inc()
+		metrics.GRPCRequestDuration.WithLabelValues(s.service, method).Observe(duration.Seconds())
+		span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+
+		log := logger.WithContext(ctx).
+			WithField("method", method).
+			WithField("code", code.String()).
+			WithField("duration_ms", duration.Milliseconds())
+		switch code {
+		case codes.OK:
+			log.Info("gRPC call")
+		case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+			span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+			log.Error("gRPC call")
+		default:
+			log.Warn("gRPC call")
+		}
+	}()
+
+	ctx, err = s.authenticate(ctx, md, method)
+	if err != nil {
+		return err
+	}
+	return call(ctx)
+}
+
+// authenticate adds the claims of the authorization metadata to ctx.
+// Public methods accept calls without a token but still reject bad ones;
+// internal methods accept service tokens only.
+func (s *server) authenticate(ctx context.Context, md metadata.MD, method string) (context.Context, error) {
+	values := md.Get(AuthorizationKey)
+	if len(values) == 0 {
+		if s.public[method] {
+			return ctx, nil
+		}
+		return ctx, errors.New(errors.ErrUnauthorized, "Missing authorization metadata")
+	}
+
+	if s.internal[method] {
+		claims, err := s.serviceAuth.Verify(values[0])
+		if err != nil {
+			return ctx, err
+		}
+		return middleware.WithService(ctx, claims), nil
+	}
+
+	claims, err := s.auth.Verify(ctx, values[0])
+	if err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, middleware.UserContextKey, claims), nil
+}
+
+// clientInfo returns the forwarded client info of a call, or the peer's
+// address and user agent when it came directly from the client
+func clientInfo(ctx context.Context, md metadata.MD) *middleware.ClientInfo {
+	carrier := metadataCarrier(md)
+	if ip := carrier.Get(ClientIPKey); ip != "" {
+		return &middleware.ClientInfo{IP: ip, UserAgent: carrier.Get(ClientUserAgentKey)}
+	}
+
+	info := &middleware.ClientInfo{UserAgent: carrier.Get("user-agent")}
+	if p, ok := peer.FromContext(ctx); ok {
+		info.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(info.IP); err == nil {
+			info.IP = host
+		}
+	}
+	return info
+}
+
+// serverStream replaces the context of a stream with the interceptor's
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// metadataCarrier adapts gRPC metadata to an OpenTelemetry TextMapCarrier
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,117 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/middleware"
+	"github.com/microservices-go/shared/rpc/rpctest"
+)
+
+// Methods of the test service; each answers with who called it
+const (
+	publicMethod   = "/test.Echo/Public"
+	internalMethod = "/test.Echo/Internal"
+	userMethod     = "/test.Echo/User"
+)
+
+// whoami describes the caller in ctx as "anonymous", "user:<id>" or
+// "service:<name>/<on-behalf-of id>"
+func whoami(ctx context.Context) string {
+	who := "anonymous"
+	if claims, ok := middleware.GetUserFromContext(ctx); ok {
+		who = "user:" + claims.UserID
+	}
+	if service, ok := middleware.GetServiceFromContext(ctx); ok {
+		who = "service:" + service.Subject + "/" + who
+	}
+	return who
+}
+
+// echoService registers the test methods, which go through the interceptors
+// like generated handlers do
+func echoService() *grpc.ServiceDesc {
+	method := func(name string) grpc.MethodDesc {
+		return grpc.MethodDesc{
+			MethodName: name,
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := &wrapperspb.StringValue{}
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Echo/" + name}
+				return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return wrapperspb.String(whoami(ctx)), nil
+				})
+			},
+		}
+	}
+	return &grpc.ServiceDesc{
+		ServiceName: "test.Echo",
+		HandlerType: (*interface{})(nil),
+		Methods:     []grpc.MethodDesc{method("Public"), method("Internal"), method("User")},
+	}
+}
+
+func TestServerAuthentication(t *testing.T) {
+	jwtConfig := &config.JWTConfig{Secret: "user-secret", Issuer: "test"}
+	srv := NewServer("order-service", middleware.NewAuthMiddleware(jwtConfig), rpctest.ServiceAuth(t, "order-service"), Methods{
+		Public:   []string{publicMethod},
+		Internal: []string{internalMethod},
+	})
+	srv.RegisterService(echoService(), struct{}{})
+	conn := rpctest.Dial(t, srv)
+
+	user := &middleware.UserClaims{UserID: "u1", Role: "user"}
+	userToken, err := middleware.GenerateToken(user.UserID, "u1@example.com", user.Role, nil, "s1", time.Now().Add(time.Hour), jwtConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userToken = "Bearer " + userToken
+	serviceToken := rpctest.ServiceToken(t, "order-service", user)
+	otherAudience := rpctest.ServiceToken(t, "payment-service", user)
+
+	tests := []struct {
+		name     string
+		method   string
+		token    string
+		wantCode codes.Code
+		want     string
+	}{
+		{"public without token", publicMethod, "", codes.OK, "anonymous"},
+		{"public with user token", publicMethod, userToken, codes.OK, "user:u1"},
+		{"public with bad token", publicMethod, "Bearer not-a-token", codes.Unauthenticated, ""},
+		{"user method without token", userMethod, "", codes.Unauthenticated, ""},
+		{"user method with user token", userMethod, userToken, codes.OK, "user:u1"},
+		{"user method with service token", userMethod, serviceToken, codes.Unauthenticated, ""},
+		{"internal with service token", internalMethod, serviceToken, codes.OK, "service:gateway/user:u1"},
+		{"internal with user token", internalMethod, userToken, codes.Unauthenticated, ""},
+		{"internal for another service", internalMethod, otherAudience, codes.Unauthenticated, ""},
+		{"internal without token", internalMethod, "", codes.Unauthenticated, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.token != "" {
+				ctx = OutgoingContext(ctx, tt.token)
+			}
+
+			got := &wrapperspb.StringValue{}
+			err := conn.Invoke(ctx, tt.method, &wrapperspb.StringValue{}, got)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("got code %s (%v), want %s", code, err, tt.wantCode)
+			}
+			if err == nil && got.Value != tt.want {
+				t.Errorf("got caller %q, want %q", got.Value, tt.want)
+			}
+		})
+	}
+}
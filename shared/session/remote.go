@@ -0,0 +1,90 @@
+// Package session checks users' login sessions in the services that do not
+// store them, by asking the user service.
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/middleware"
+	userv1 "github.com/microservices-go/shared/proto/user/v1"
+	"github.com/microservices-go/shared/rpc"
+)
+
+// userService is the audience of the service tokens sent with check calls
+const userService = "user-service"
+
+// Remote is a middleware.SessionChecker calling the user service's internal
+// CheckSession method with a service token. Active sessions are trusted for
+// the cache TTL, so a revoked session may keep working for that long.
+type Remote struct {
+	client      userv1.UserServiceClient
+	serviceAuth *config.ServiceAuthConfig
+	ttl         time.Duration
+
+	mu     sync.Mutex
+	active map[string]time.Time // session ID to when to check it again
+}
+
+// NewRemote creates a checker that calls the user service over conn as the
+// caller of serviceAuth
+func NewRemote(conn grpc.ClientConnInterface, serviceAuth *config.ServiceAuthConfig, cfg *config.SessionConfig) *Remote {
+	return &Remote{
+		client:      userv1.NewUserServiceClient(conn),
+		serviceAuth: serviceAuth,
+		ttl:         time.Duration(cfg.CacheTTL) * time.Second,
+		active:      make(map[string]time.Time),
+	}
+}
+
+// CheckSession checks a session through the cache or the user service
+func (r *Remote) CheckSession(ctx context.Context, userID, sessionID string) error {
+	key := userID + ":" + sessionID
+	if r.cached(key) {
+		return nil
+	}
+
+	token, err := middleware.GenerateServiceToken(userService, nil, r.serviceAuth)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrInternalServer, "Failed to sign service token")
+	}
+
+	req := &userv1.CheckSessionRequest{UserId: userID, SessionId: sessionID}
+	if _, err := r.client.CheckSession(rpc.OutgoingContext(ctx, "Bearer "+token), req); err != nil {
+		return rpc.FromStatus(err, userService)
+	}
+	r.put(key)
+	return nil
+}
+
+// cached reports whether a session was found active within the TTL
+func (r *Remote) cached(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expires, ok := r.active[key]
+	return ok && time.Now().Before(expires)
+}
+
+// put caches an active session, first dropping expired entries
+func (r *Remote) put(key string) {
+	if r.ttl <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for k, expires := range r.active {
+		if now.After(expires) {
+			delete(r.active, k)
+		}
+	}
+	r.active[key] = now.Add(r.ttl)
+}
@@ -0,0 +1,86 @@
+// Package subgraph serves a service's federated GraphQL subgraph
+package subgraph
+
+import (
+	"context"
+	stderrors "errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/microservices-go/shared/config"
+	"github.com/microservices-go/shared/errors"
+	"github.com/microservices-go/shared/logger"
+	"github.com/microservices-go/shared/middleware"
+)
+
+const internalErrorMessage = "Internal server error"
+
+// NewHandler creates the HTTP handler of a subgraph schema. Introspection
+// stays on: composition reads _service { sdl }, which gqlgen only serves with
+// introspection enabled, and clients only reach subgraphs through the router.
+func NewHandler(schema graphql.ExecutableSchema) *handler.Server {
+	srv := handler.New(schema)
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+
+	srv.SetErrorPresenter(ErrorPresenter(config.IsProduction()))
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.Use(extension.Introspection{})
+	return srv
+}
+
+// ErrorPresenter maps AppErrors into GraphQL error extensions (code,
+// service, fields) the way the gateway does, so the router passes the same
+// errors on to clients
+func ErrorPresenter(hideInternal bool) graphql.ErrorPresenterFunc {
+	return func(ctx context.Context, err error) *gqlerror.Error {
+		gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+		if gqlErr.Extensions == nil {
+			gqlErr.Extensions = map[string]interface{}{}
+		}
+
+		var appErr *errors.AppError
+		if stderrors.As(err, &appErr) {
+			gqlErr.Message = appErr.Message
+			gqlErr.Extensions["code"] = appErr.Code
+			if appErr.Service != "" {
+				gqlErr.Extensions["service"] = appErr.Service
+			}
+			if len(appErr.Fields) > 0 {
+				gqlErr.Extensions["fields"] = appErr.Fields
+			}
+			if hideInternal && appErr.IsServerError() {
+				gqlErr.Message = internalErrorMessage
+			}
+			return gqlErr
+		}
+
+		if _, ok := gqlErr.Extensions["code"]; ok {
+			return gqlErr
+		}
+
+		gqlErr.Extensions["code"] = errors.ErrInternalServer
+		if hideInternal {
+			logger.WithContext(ctx).WithError(err).Error("Unhandled GraphQL error")
+			gqlErr.Message = internalErrorMessage
+		}
+		return gqlErr
+	}
+}
+
+// CurrentUser returns the authenticated caller, or UNAUTHORIZED for
+// anonymous requests
+func CurrentUser(ctx context.Context) (*middleware.UserClaims, error) {
+	claims, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errors.New(errors.ErrUnauthorized, "User not authenticated")
+	}
+	return claims, nil
+}
@@ -4,23 +4,39 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/microservices-go/shared/config"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
-var tracer trace.Tracer
+var tracer trace.Tracer = otel.Tracer("default")
 
-// InitTracer initializes the OpenTelemetry tracer
-func InitTracer(serviceName, jaegerEndpoint string) (*sdktrace.TracerProvider, error) {
-	// Create Jaeger exporter
-	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(jaegerEndpoint)))
+// InitTracer initializes the OpenTelemetry tracer and W3C trace context propagation.
+// When tracing is disabled only the propagator is installed, so incoming
+// traceparent headers are still forwarded downstream. The returned function
+// flushes and stops the exporter.
+func InitTracer(serviceName string, cfg *config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !cfg.Enabled {
+		tracer = otel.Tracer(serviceName)
+		return func(context.Context) error { return nil }, nil
+	}
+
+	// Create OTLP/HTTP exporter (e.g. OpenTelemetry Collector, Jaeger, Tempo)
+	exp, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(cfg.Endpoint))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create jaeger exporter: %w", err)
+		return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
 	}
 
 	// Create resource
@@ -40,21 +56,18 @@ func InitTracer(serviceName, jaegerEndpoint string) (*sdktrace.TracerProvider, e
 	tp := sdktrace.NewTracerProvider(
 		sdktrace.WithBatcher(exp),
 		sdktrace.WithResource(r),
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
 	)
 
 	// Set global tracer provider
 	otel.SetTracerProvider(tp)
 	tracer = tp.Tracer(serviceName)
 
-	return tp, nil
+	return tp.Shutdown, nil
 }
 
 // StartSpan starts a new span
 func StartSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
-	if tracer == nil {
-		tracer = otel.Tracer("default")
-	}
 	return tracer.Start(ctx, name, opts...)
 }
 
@@ -84,5 +97,25 @@ func RecordError(ctx context.Context, err error) {
 	span := SpanFromContext(ctx)
 	if span != nil {
 		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 	}
 }
+
+// TraceID returns the W3C trace ID of the span in context, or empty string
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// Inject writes the trace context from ctx into a text map carrier
+func Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+}
+
+// Extract reads the trace context from a text map carrier into ctx
+func Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
@@ -1,6 +1,9 @@
 package validator
 
 import (
+	"reflect"
+	"strings"
+
 	"github.com/go-playground/validator/v10"
 	"github.com/microservices-go/shared/errors"
 )
@@ -14,6 +17,15 @@ type Validator struct {
 func New() *Validator {
 	v := validator.New()
 
+	// Report JSON field names so errors match the API payload
+	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
+		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+		if name == "-" || name == "" {
+			return fld.Name
+		}
+		return name
+	})
+
 	// Register custom validations here if needed
 
 	return &Validator{validate: v}
@@ -38,9 +50,23 @@ func (v *Validator) ValidateVar(field interface{}, tag string) error {
 	return nil
 }
 
+// VarMessage validates a single variable and returns the message of the first
+// failed tag, or "" when the value is valid
+func (v *Validator) VarMessage(field interface{}, tag string) string {
+	err := v.validate.Var(field, tag)
+	if err == nil {
+		return ""
+	}
+	if validationErrors, ok := err.(validator.ValidationErrors); ok && len(validationErrors) > 0 {
+		return getErrorMessage(validationErrors[0].Tag(), validationErrors[0].Param())
+	}
+	return "Invalid value"
+}
+
 // formatValidationErrors converts validator errors to AppError
 func formatValidationErrors(errs validator.ValidationErrors) error {
 	details := make(map[string]string)
+	fields := make([]errors.FieldError, 0, len(errs))
 	for _, err := range errs {
 		field := err.Field()
 		tag := err.Tag()
@@ -48,9 +74,12 @@ func formatValidationErrors(errs validator.ValidationErrors) error {
 
 		msg := getErrorMessage(tag, param)
 		details[field] = msg
+		fields = append(fields, errors.FieldError{Field: field, Message: msg})
 	}
 
-	return errors.New(errors.ErrValidationFailed, "Validation failed").WithDetails(formatDetails(details))
+	return errors.New(errors.ErrValidationFailed, "Validation failed").
+		WithDetails(formatDetails(details)).
+		WithFields(fields)
 }
 
 func getErrorMessage(tag, param string) string {
@@ -81,6 +110,8 @@ func getErrorMessage(tag, param string) string {
 		return "Invalid UUID format"
 	case "url":
 		return "Invalid URL format"
+	case "oneof":
+		return "Value must be one of: " + strings.ReplaceAll(param, " ", ", ")
 	default:
 		return "Invalid value"
 	}